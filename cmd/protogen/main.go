@@ -0,0 +1,75 @@
+// main.go - Generates .proto files for the bridge service manifest
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/protocols"
+)
+
+// Command-line flags
+var (
+	outputDir string
+	clean     bool
+)
+
+func init() {
+	flag.StringVar(&outputDir, "out", "./api/proto", "Directory to write .proto files to")
+	flag.BoolVar(&clean, "clean", false, "Remove existing .proto files under the output directory first")
+}
+
+func main() {
+	flag.Parse()
+
+	if clean {
+		if err := removeProtoFiles(outputDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to clean output directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	manifest := protocols.DefaultServiceManifest()
+	files := manifest.GenerateProtoFiles()
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		target := filepath.Join(outputDir, filepath.FromSlash(path))
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create directory for %s: %v\n", target, err)
+			os.Exit(1)
+		}
+
+		if err := os.WriteFile(target, []byte(files[path]), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", target, err)
+			os.Exit(1)
+		}
+
+		fmt.Println(target)
+	}
+}
+
+// removeProtoFiles deletes generated .proto files below dir
+func removeProtoFiles(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".proto" {
+			return os.Remove(path)
+		}
+		return nil
+	})
+}
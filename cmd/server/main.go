@@ -13,6 +13,8 @@ import (
 
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/api/rest"
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge"
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/manager"
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/config"
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/discovery"
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/metrics"
@@ -149,6 +151,30 @@ func main() {
 		}
 	}()
 
+	// Create the bridge serving API calls and its durable outbox
+	bridges := manager.NewBridgeManager(nil, sugar)
+	apiBridge, err := bridges.CreateBridge(ctx, "api", nil)
+	if err != nil {
+		sugar.Fatalw("Failed to create API bridge", "error", err)
+	}
+	outbox, err := bridge.NewOutbox(apiBridge, nil)
+	if err != nil {
+		sugar.Fatalw("Failed to open bridge outbox", "error", err)
+	}
+	outbox.Start()
+	defer outbox.Close()
+
+	// Create the plugin registry, reconfigured over REST and from its config file
+	pluginLogger := &pluginLoggerAdapter{logger: sugar}
+	pluginRegistry := plugins.NewRegistry(pluginLogger)
+	if path := cfg.Bridge.Plugins.ConfigPath; path != "" {
+		watcher := plugins.NewConfigWatcher(pluginRegistry, path, cfg.Bridge.Plugins.PollInterval, pluginLogger)
+		if err := watcher.Start(ctx); err != nil {
+			sugar.Fatalw("Failed to load plugin config", "path", path, "error", err)
+		}
+		defer watcher.Stop()
+	}
+
 	// Create security monitor with the correct config type
 	monitorConfig := security.DefaultConfig()
 	securityMonitor, err := security.NewMonitor(monitorConfig)
@@ -159,7 +185,7 @@ func main() {
 
 	// Setup API router
 	sugar.Info("Initializing API router")
-	router := rest.NewRouter(cfg, sugar, metricsCollector)
+	router := rest.NewRouter(cfg, sugar, metricsCollector, rest.WithOutbox(outbox), rest.WithPipelines(bridges), rest.WithPlugins(pluginRegistry))
 
 	// Configure HTTP server
 	server := &http.Server{
@@ -249,3 +275,33 @@ func registerWithDiscovery(discoveryService *discovery.BridgeDiscovery, cfg *con
 	logger.Infow("Registered with discovery service", "serviceID", svc.ID)
 	return svc.ID
 }
+
+// pluginLoggerAdapter adapts zap.SugaredLogger to plugins.Logger
+type pluginLoggerAdapter struct {
+	logger *zap.SugaredLogger
+}
+
+func (a *pluginLoggerAdapter) Debug(msg string, fields map[string]interface{}) {
+	a.logger.Debugw(msg, fieldPairs(fields)...)
+}
+
+func (a *pluginLoggerAdapter) Info(msg string, fields map[string]interface{}) {
+	a.logger.Infow(msg, fieldPairs(fields)...)
+}
+
+func (a *pluginLoggerAdapter) Warn(msg string, fields map[string]interface{}) {
+	a.logger.Warnw(msg, fieldPairs(fields)...)
+}
+
+func (a *pluginLoggerAdapter) Error(msg string, fields map[string]interface{}) {
+	a.logger.Errorw(msg, fieldPairs(fields)...)
+}
+
+// fieldPairs converts a map to a slice of alternating key, value pairs for zap
+func fieldPairs(fields map[string]interface{}) []interface{} {
+	pairs := make([]interface{}, 0, len(fields)*2)
+	for key, value := range fields {
+		pairs = append(pairs, key, value)
+	}
+	return pairs
+}
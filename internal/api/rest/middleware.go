@@ -3,14 +3,18 @@
 package rest
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/protocols"
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/metrics"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -342,15 +346,39 @@ func (m *StandardMiddleware) ValidateContentType(contentType string) MiddlewareF
 	}
 }
 
-// ValidateRequest validates request bodies against a schema
+// ValidateRequest validates request bodies against a schema. The schema is
+// anything protocols.ValidateMessage accepts: a bridge method name, a
+// MethodDefinition or a list of ParameterDefinitions.
 func (m *StandardMiddleware) ValidateRequest(schema interface{}) MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Only validate for requests with bodies
-			if r.ContentLength > 0 && schema != nil {
-				// Decode request body
-				var requestData map[string]interface{}
-				if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+			if schema == nil || r.Body == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				m.logger.Warn("[CSM-WARN] Request validation failed", map[string]interface{}{
+					"error": err.Error(),
+				})
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{
+					"error":   "[CSM-ERR-400] Invalid request format",
+					"message": "Could not read request body",
+				})
+				return
+			}
+
+			// Reset the body for the next handler
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			requestData := map[string]interface{}{}
+			if len(bytes.TrimSpace(body)) > 0 {
+				if err := json.Unmarshal(body, &requestData); err != nil {
 					m.logger.Warn("[CSM-WARN] Request validation failed", map[string]interface{}{
 						"error": err.Error(),
 					})
@@ -363,9 +391,33 @@ func (m *StandardMiddleware) ValidateRequest(schema interface{}) MiddlewareFunc
 					})
 					return
 				}
+			}
+
+			if err := protocols.ValidateMessage(requestData, schema); err != nil {
+				var validationErr *protocols.ValidationError
+				if !errors.As(err, &validationErr) {
+					m.logger.Error("[CSM-ERR] Request validation schema error", map[string]interface{}{
+						"error": err.Error(),
+						"path":  r.URL.Path,
+					})
+					next.ServeHTTP(w, r)
+					return
+				}
+
+				m.logger.Warn("[CSM-WARN] Request validation failed", map[string]interface{}{
+					"error": validationErr.Error(),
+					"path":  r.URL.Path,
+				})
 
-				// Reset the body for the next handler
-				// TODO: Implement proper request validation
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":   "[CSM-ERR-400] Invalid request",
+					"code":    protocols.ErrorCodeInvalidRequest,
+					"message": validationErr.Error(),
+					"fields":  validationErr.Fields,
+				})
+				return
 			}
 
 			next.ServeHTTP(w, r)
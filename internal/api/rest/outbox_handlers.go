@@ -0,0 +1,130 @@
+// outbox_handlers.go - REST handlers for the bridge message outbox
+
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge"
+	"github.com/gorilla/mux"
+)
+
+// OutboxService is the subset of the bridge outbox exposed over REST
+type OutboxService interface {
+	List(state bridge.OutboxState) ([]*bridge.OutboxEntry, error)
+	Replay(id string) error
+	Purge(state bridge.OutboxState, id string) (int, error)
+}
+
+// outboxHandlers serves the outbox endpoints
+type outboxHandlers struct {
+	outbox OutboxService
+}
+
+// registerOutboxRoutes registers the outbox endpoints on the bridge router
+func registerOutboxRoutes(bridgeRouter *mux.Router, outbox OutboxService) {
+	h := &outboxHandlers{outbox: outbox}
+
+	bridgeRouter.HandleFunc("/outbox", h.list).Methods("GET")
+	bridgeRouter.HandleFunc("/outbox", h.purgeAll).Methods("DELETE")
+	bridgeRouter.HandleFunc("/outbox/{id}/replay", h.replay).Methods("POST")
+	bridgeRouter.HandleFunc("/outbox/{id}", h.purge).Methods("DELETE")
+}
+
+// list returns outbox entries; ?state=pending|dead_letter, ?limit=n
+func (h *outboxHandlers) list(w http.ResponseWriter, r *http.Request) {
+	state := outboxState(r)
+
+	entries, err := h.outbox.List(state)
+	if err != nil {
+		respondWithOutboxError(w, err)
+		return
+	}
+
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 0 {
+			RespondWithError(w, http.StatusBadRequest, "limit must be a non-negative integer")
+			return
+		}
+		if limit < len(entries) {
+			entries = entries[:limit]
+		}
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"state":   state,
+		"count":   len(entries),
+		"entries": entries,
+	})
+}
+
+// replay moves a dead-lettered message back to pending
+func (h *outboxHandlers) replay(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.outbox.Replay(id); err != nil {
+		respondWithOutboxError(w, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusAccepted, map[string]string{
+		"id":     id,
+		"status": "replayed",
+	})
+}
+
+// purge removes a single entry; ?state=pending|dead_letter
+func (h *outboxHandlers) purge(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if _, err := h.outbox.Purge(outboxState(r), id); err != nil {
+		respondWithOutboxError(w, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]string{
+		"id":     id,
+		"status": "purged",
+	})
+}
+
+// purgeAll removes every entry in a store; ?state=pending|dead_letter
+func (h *outboxHandlers) purgeAll(w http.ResponseWriter, r *http.Request) {
+	state := outboxState(r)
+
+	removed, err := h.outbox.Purge(state, "")
+	if err != nil {
+		respondWithOutboxError(w, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"state":   state,
+		"removed": removed,
+	})
+}
+
+// outboxState reads the requested store, defaulting to the dead-letter store
+func outboxState(r *http.Request) bridge.OutboxState {
+	if state := r.URL.Query().Get("state"); state != "" {
+		return bridge.OutboxState(state)
+	}
+	return bridge.DefaultOutboxState
+}
+
+// respondWithOutboxError maps outbox errors to HTTP responses
+func respondWithOutboxError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, bridge.ErrOutboxEntryNotFound):
+		RespondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, bridge.ErrInvalidOutboxState):
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, bridge.ErrOutboxClosed):
+		RespondWithError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
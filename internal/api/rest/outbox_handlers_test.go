@@ -0,0 +1,155 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutbox is an in-memory OutboxService
+type fakeOutbox struct {
+	entries  map[bridge.OutboxState][]*bridge.OutboxEntry
+	replayed []string
+	closed   bool
+}
+
+func newFakeOutbox() *fakeOutbox {
+	return &fakeOutbox{entries: map[bridge.OutboxState][]*bridge.OutboxEntry{
+		bridge.OutboxPending: {
+			{Message: &bridge.Message{ID: "p1"}, State: bridge.OutboxPending},
+		},
+		bridge.OutboxDeadLetter: {
+			{Message: &bridge.Message{ID: "d1"}, State: bridge.OutboxDeadLetter, LastError: "downstream unavailable"},
+			{Message: &bridge.Message{ID: "d2"}, State: bridge.OutboxDeadLetter},
+		},
+	}}
+}
+
+func (f *fakeOutbox) List(state bridge.OutboxState) ([]*bridge.OutboxEntry, error) {
+	if f.closed {
+		return nil, bridge.ErrOutboxClosed
+	}
+	entries, ok := f.entries[state]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", bridge.ErrInvalidOutboxState, state)
+	}
+	return entries, nil
+}
+
+func (f *fakeOutbox) Replay(id string) error {
+	entries := f.entries[bridge.OutboxDeadLetter]
+	for i, entry := range entries {
+		if entry.Message.ID == id {
+			f.entries[bridge.OutboxDeadLetter] = append(entries[:i:i], entries[i+1:]...)
+			f.entries[bridge.OutboxPending] = append(f.entries[bridge.OutboxPending], entry)
+			f.replayed = append(f.replayed, id)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", bridge.ErrOutboxEntryNotFound, id)
+}
+
+func (f *fakeOutbox) Purge(state bridge.OutboxState, id string) (int, error) {
+	entries, ok := f.entries[state]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", bridge.ErrInvalidOutboxState, state)
+	}
+	if id == "" {
+		f.entries[state] = nil
+		return len(entries), nil
+	}
+	for i, entry := range entries {
+		if entry.Message.ID == id {
+			f.entries[state] = append(entries[:i:i], entries[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", bridge.ErrOutboxEntryNotFound, id)
+}
+
+// serveOutbox sends a request to the outbox routes and decodes the JSON reply
+func serveOutbox(t *testing.T, outbox OutboxService, method, target string) (int, map[string]interface{}) {
+	t.Helper()
+
+	router := mux.NewRouter()
+	registerOutboxRoutes(router.PathPrefix("/api/v1/bridge").Subrouter(), outbox)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	return recorder.Code, body
+}
+
+func TestOutboxHandlersList(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		code   int
+		state  string
+		count  float64
+	}{
+		{"defaults to dead letters", "/api/v1/bridge/outbox", http.StatusOK, "dead_letter", 2},
+		{"pending", "/api/v1/bridge/outbox?state=pending", http.StatusOK, "pending", 1},
+		{"limit", "/api/v1/bridge/outbox?limit=1", http.StatusOK, "dead_letter", 1},
+		{"invalid limit", "/api/v1/bridge/outbox?limit=-1", http.StatusBadRequest, "", 0},
+		{"invalid state", "/api/v1/bridge/outbox?state=archived", http.StatusBadRequest, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := serveOutbox(t, newFakeOutbox(), http.MethodGet, tt.target)
+			assert.Equal(t, tt.code, code)
+			if tt.code != http.StatusOK {
+				assert.NotEmpty(t, body["error"])
+				return
+			}
+			assert.Equal(t, tt.state, body["state"])
+			assert.Equal(t, tt.count, body["count"])
+			assert.Len(t, body["entries"], int(tt.count))
+		})
+	}
+
+	outbox := newFakeOutbox()
+	outbox.closed = true
+	code, _ := serveOutbox(t, outbox, http.MethodGet, "/api/v1/bridge/outbox")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestOutboxHandlersReplay(t *testing.T) {
+	outbox := newFakeOutbox()
+
+	code, body := serveOutbox(t, outbox, http.MethodPost, "/api/v1/bridge/outbox/d1/replay")
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, "replayed", body["status"])
+	assert.Equal(t, []string{"d1"}, outbox.replayed)
+	assert.Len(t, outbox.entries[bridge.OutboxPending], 2)
+
+	code, _ = serveOutbox(t, outbox, http.MethodPost, "/api/v1/bridge/outbox/d1/replay")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestOutboxHandlersPurge(t *testing.T) {
+	outbox := newFakeOutbox()
+
+	code, body := serveOutbox(t, outbox, http.MethodDelete, "/api/v1/bridge/outbox/p1?state=pending")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "purged", body["status"])
+	assert.Empty(t, outbox.entries[bridge.OutboxPending])
+
+	code, _ = serveOutbox(t, outbox, http.MethodDelete, "/api/v1/bridge/outbox/p1?state=pending")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = serveOutbox(t, outbox, http.MethodDelete, "/api/v1/bridge/outbox")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), body["removed"])
+	assert.Empty(t, outbox.entries[bridge.OutboxDeadLetter])
+}
@@ -0,0 +1,123 @@
+// pipeline_handlers.go - REST handlers for bridge link transformation pipelines
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/manager"
+	"github.com/gorilla/mux"
+)
+
+// PipelineService is the subset of the bridge manager exposed over REST
+type PipelineService interface {
+	Links() []manager.LinkInfo
+	TestPipeline(ctx context.Context, config *manager.PipelineConfig, message interface{}) (*manager.PipelineResult, error)
+	TestLink(ctx context.Context, bridgeID, source, target string, message interface{}) (*manager.PipelineResult, error)
+}
+
+// pipelineHandlers serves the link pipeline endpoints
+type pipelineHandlers struct {
+	pipelines PipelineService
+}
+
+// pipelineTestRequest is the body of a dry run; the pipeline is only read
+// when testing a configuration that is not attached to a link
+type pipelineTestRequest struct {
+	Pipeline *manager.PipelineConfig `json:"pipeline"`
+	Message  json.RawMessage         `json:"message"`
+}
+
+// registerPipelineRoutes registers the link pipeline endpoints on the bridge router
+func registerPipelineRoutes(bridgeRouter *mux.Router, pipelines PipelineService) {
+	h := &pipelineHandlers{pipelines: pipelines}
+
+	bridgeRouter.HandleFunc("/links", h.list).Methods("GET")
+	bridgeRouter.HandleFunc("/links/test", h.testPipeline).Methods("POST")
+	bridgeRouter.HandleFunc("/links/{bridge}/{source}/{target}/test", h.testLink).Methods("POST")
+}
+
+// list returns every link with the drop and error counts of its stages
+func (h *pipelineHandlers) list(w http.ResponseWriter, r *http.Request) {
+	links := h.pipelines.Links()
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"count": len(links),
+		"links": links,
+	})
+}
+
+// testPipeline dry-runs a message through a pipeline given in the request
+func (h *pipelineHandlers) testPipeline(w http.ResponseWriter, r *http.Request) {
+	request, ok := decodePipelineTestRequest(w, r)
+	if !ok {
+		return
+	}
+	if request.Pipeline == nil {
+		RespondWithError(w, http.StatusBadRequest, "pipeline is required")
+		return
+	}
+
+	result, err := h.pipelines.TestPipeline(r.Context(), request.Pipeline, []byte(request.Message))
+	if err != nil {
+		respondWithPipelineError(w, err)
+		return
+	}
+
+	respondWithPipelineResult(w, result)
+}
+
+// testLink dry-runs a message through the pipeline of an existing link
+func (h *pipelineHandlers) testLink(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	request, ok := decodePipelineTestRequest(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.pipelines.TestLink(r.Context(), vars["bridge"], vars["source"], vars["target"], []byte(request.Message))
+	if err != nil {
+		respondWithPipelineError(w, err)
+		return
+	}
+
+	respondWithPipelineResult(w, result)
+}
+
+// decodePipelineTestRequest reads a dry run request, responding on failure
+func decodePipelineTestRequest(w http.ResponseWriter, r *http.Request) (*pipelineTestRequest, bool) {
+	request := &pipelineTestRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return nil, false
+	}
+	if len(request.Message) == 0 {
+		RespondWithError(w, http.StatusBadRequest, "message is required")
+		return nil, false
+	}
+	return request, true
+}
+
+// respondWithPipelineResult writes a dry run result, embedding JSON output as-is
+func respondWithPipelineResult(w http.ResponseWriter, result *manager.PipelineResult) {
+	if output, ok := result.Output.([]byte); ok && json.Valid(output) {
+		result.Output = json.RawMessage(output)
+	}
+	RespondWithJSON(w, http.StatusOK, result)
+}
+
+// respondWithPipelineError maps pipeline errors to HTTP responses
+func respondWithPipelineError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, manager.ErrLinkNotFound):
+		RespondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, manager.ErrInvalidPipeline), errors.Is(err, manager.ErrTransformerNotFound):
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
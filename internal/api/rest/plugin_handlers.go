@@ -0,0 +1,118 @@
+// plugin_handlers.go - REST handlers for plugin configuration
+
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
+	"github.com/gorilla/mux"
+)
+
+// PluginConfigService is the subset of the plugin registry exposed over REST
+type PluginConfigService interface {
+	PluginConfig(id string) (map[string]interface{}, *plugins.ConfigSchema, error)
+	ConfigurePlugin(id string, changes map[string]interface{}) error
+}
+
+// pluginHandlers serves the plugin configuration endpoints
+type pluginHandlers struct {
+	plugins PluginConfigService
+}
+
+// registerPluginRoutes registers the plugin configuration endpoints on the API router
+func registerPluginRoutes(apiRouter *mux.Router, service PluginConfigService) {
+	h := &pluginHandlers{plugins: service}
+
+	pluginRouter := apiRouter.PathPrefix("/plugins").Subrouter()
+	pluginRouter.HandleFunc("/{id}/config", h.getConfig).Methods("GET")
+	pluginRouter.HandleFunc("/{id}/config", h.updateConfig).Methods("PATCH")
+	pluginRouter.HandleFunc("/{id}/schema", h.getSchema).Methods("GET")
+}
+
+// getConfig returns a plugin's current configuration and its schema
+func (h *pluginHandlers) getConfig(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	config, schema, err := h.plugins.PluginConfig(id)
+	if err != nil {
+		respondWithPluginError(w, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"plugin_id": id,
+		"config":    config,
+		"schema":    schema,
+	})
+}
+
+// getSchema returns the schema a plugin's configuration is validated against
+func (h *pluginHandlers) getSchema(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	_, schema, err := h.plugins.PluginConfig(id)
+	if err != nil {
+		respondWithPluginError(w, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"plugin_id": id,
+		"schema":    schema,
+	})
+}
+
+// updateConfig merges the request body into a plugin's configuration;
+// invalid changes are rejected and failed ones rolled back
+func (h *pluginHandlers) updateConfig(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var changes map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		RespondWithError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if len(changes) == 0 {
+		RespondWithError(w, http.StatusBadRequest, "no configuration changes given")
+		return
+	}
+
+	if err := h.plugins.ConfigurePlugin(id, changes); err != nil {
+		respondWithPluginError(w, err)
+		return
+	}
+
+	config, _, err := h.plugins.PluginConfig(id)
+	if err != nil {
+		respondWithPluginError(w, err)
+		return
+	}
+
+	RespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"plugin_id": id,
+		"config":    config,
+	})
+}
+
+// respondWithPluginError maps plugin registry errors to HTTP responses,
+// listing schema violations when the configuration was invalid
+func respondWithPluginError(w http.ResponseWriter, err error) {
+	var validationErr *plugins.ConfigValidationError
+	switch {
+	case errors.Is(err, plugins.ErrPluginNotFound):
+		RespondWithError(w, http.StatusNotFound, err.Error())
+	case errors.As(err, &validationErr):
+		RespondWithJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":      err.Error(),
+			"status":     "400",
+			"violations": validationErr.Violations,
+		})
+	case errors.Is(err, plugins.ErrInvalidConfig):
+		RespondWithError(w, http.StatusBadRequest, err.Error())
+	default:
+		RespondWithError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// quietPluginLogger discards registry logs
+type quietPluginLogger struct{}
+
+func (quietPluginLogger) Debug(msg string, fields map[string]interface{}) {}
+func (quietPluginLogger) Info(msg string, fields map[string]interface{})  {}
+func (quietPluginLogger) Warn(msg string, fields map[string]interface{})  {}
+func (quietPluginLogger) Error(msg string, fields map[string]interface{}) {}
+
+// newPluginTestRouter serves the plugin routes of a registry holding one
+// plugin, "cache", whose port must lie in 1-65535 and mode be fast or safe
+func newPluginTestRouter(t *testing.T) *mux.Router {
+	t.Helper()
+
+	minPort, maxPort := 1.0, 65535.0
+	plugin := plugins.NewBasePlugin("cache", plugins.PluginTypeUtility, plugins.PluginMetadata{
+		Name: "cache",
+		ConfigSchema: &plugins.ConfigSchema{
+			Type: plugins.SchemaTypeObject,
+			Properties: map[string]*plugins.ConfigSchema{
+				"port": {Type: plugins.SchemaTypeInteger, Minimum: &minPort, Maximum: &maxPort},
+				"mode": {Type: plugins.SchemaTypeString, Enum: []interface{}{"fast", "safe"}},
+			},
+		},
+	})
+	require.NoError(t, plugin.Initialize(context.Background(), map[string]interface{}{"port": 8080, "mode": "safe"}))
+
+	registry := plugins.NewRegistry(quietPluginLogger{})
+	require.NoError(t, registry.RegisterPlugin(plugin))
+
+	router := mux.NewRouter()
+	registerPluginRoutes(router.PathPrefix("/api/v1").Subrouter(), registry)
+	return router
+}
+
+// servePlugins sends a request to the plugin routes and decodes the JSON reply
+func servePlugins(t *testing.T, router *mux.Router, method, target, body string) (int, map[string]interface{}) {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	var reply map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &reply))
+	return recorder.Code, reply
+}
+
+func TestPluginHandlersUpdateConfigRejectsViolations(t *testing.T) {
+	router := newPluginTestRouter(t)
+
+	code, reply := servePlugins(t, router, http.MethodPatch, "/api/v1/plugins/cache/config", `{"port": 70000, "mode": "turbo"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "400", reply["status"])
+	assert.Contains(t, reply["error"], "invalid plugin configuration")
+
+	violations, ok := reply["violations"].([]interface{})
+	require.True(t, ok, "violations are listed: %v", reply)
+	paths := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		paths = append(paths, violation.(map[string]interface{})["path"].(string))
+	}
+	assert.Equal(t, []string{"config.mode", "config.port"}, paths)
+
+	// Nothing was applied
+	code, reply = servePlugins(t, router, http.MethodGet, "/api/v1/plugins/cache/config", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"port": float64(8080), "mode": "safe"}, reply["config"])
+}
+
+func TestPluginHandlersUpdateConfig(t *testing.T) {
+	router := newPluginTestRouter(t)
+
+	code, reply := servePlugins(t, router, http.MethodPatch, "/api/v1/plugins/cache/config", `{"mode": "fast"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, map[string]interface{}{"port": float64(8080), "mode": "fast"}, reply["config"])
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		code   int
+	}{
+		{"unknown plugin", "/api/v1/plugins/missing/config", `{"mode": "fast"}`, http.StatusNotFound},
+		{"no changes", "/api/v1/plugins/cache/config", `{}`, http.StatusBadRequest},
+		{"invalid body", "/api/v1/plugins/cache/config", `{"mode":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, reply := servePlugins(t, router, http.MethodPatch, tt.target, tt.body)
+			assert.Equal(t, tt.code, code)
+			assert.NotEmpty(t, reply["error"])
+		})
+	}
+}
@@ -148,8 +148,44 @@ type MetricsCollector interface {
 	RecordRateLimit(key string, allowed bool)
 }
 
+// RouterOption configures optional services backing API endpoints
+type RouterOption func(*routerServices)
+
+// routerServices holds the optional services exposed by the router
+type routerServices struct {
+	outbox    OutboxService
+	pipelines PipelineService
+	plugins   PluginConfigService
+}
+
+// WithOutbox exposes the bridge outbox under /api/v1/bridge/outbox
+func WithOutbox(outbox OutboxService) RouterOption {
+	return func(s *routerServices) {
+		s.outbox = outbox
+	}
+}
+
+// WithPipelines exposes bridge link pipelines under /api/v1/bridge/links
+func WithPipelines(pipelines PipelineService) RouterOption {
+	return func(s *routerServices) {
+		s.pipelines = pipelines
+	}
+}
+
+// WithPlugins exposes plugin configuration under /api/v1/plugins
+func WithPlugins(plugins PluginConfigService) RouterOption {
+	return func(s *routerServices) {
+		s.plugins = plugins
+	}
+}
+
 // NewRouter creates a new API router
-func NewRouter(cfg *config.Config, logger *zap.SugaredLogger, metricsCollector *metrics.Collector) *mux.Router {
+func NewRouter(cfg *config.Config, logger *zap.SugaredLogger, metricsCollector *metrics.Collector, opts ...RouterOption) *mux.Router {
+	services := &routerServices{}
+	for _, opt := range opts {
+		opt(services)
+	}
+
 	// Create main router
 	router := mux.NewRouter().StrictSlash(true)
 
@@ -192,9 +228,21 @@ func NewRouter(cfg *config.Config, logger *zap.SugaredLogger, metricsCollector *
 	bridgeRouter.HandleFunc("/status", getBridgeStatus).Methods("GET")
 	bridgeRouter.HandleFunc("/create", createBridgeConnection).Methods("POST")
 	bridgeRouter.HandleFunc("/list", listBridgeConnections).Methods("GET")
+	if services.outbox != nil {
+		// Registered before /{id} so "outbox" isn't captured as a bridge ID
+		registerOutboxRoutes(bridgeRouter, services.outbox)
+	}
+	if services.pipelines != nil {
+		registerPipelineRoutes(bridgeRouter, services.pipelines)
+	}
 	bridgeRouter.HandleFunc("/{id}", getBridgeConnection).Methods("GET")
 	bridgeRouter.HandleFunc("/{id}", deleteBridgeConnection).Methods("DELETE")
 
+	// Plugin endpoints
+	if services.plugins != nil {
+		registerPluginRoutes(apiRouter, services.plugins)
+	}
+
 	// Security endpoints
 	securityRouter := apiRouter.PathPrefix("/security").Subrouter()
 	securityRouter.HandleFunc("/config", getSecurityConfig).Methods("GET")
@@ -6,7 +6,7 @@ import (
 	"context"
 	"time"
 
-	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/protocol"
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/protocols"
 )
 
 // AdapterStatus represents the current status of an adapter
@@ -84,8 +84,10 @@ type AdapterStats struct {
 // MessageHandlerFunc defines a function type for handling messages
 type MessageHandlerFunc func(ctx context.Context, message interface{}) error
 
-// Adapter defines the interface for bridge adapters
-type Adapter interface {
+// LegacyAdapter is the original adapter contract, exchanging untyped messages
+// and returning no reply from Send. Wrap implementations with
+// WrapLegacyAdapter to use them as an Adapter.
+type LegacyAdapter interface {
 	// Lifecycle methods
 	Initialize(ctx context.Context) error
 	Connect(ctx context.Context) error
@@ -108,20 +110,20 @@ type Adapter interface {
 	SetMessageHandler(handler MessageHandlerFunc)
 	
 	// Protocol methods
-	Protocol() protocol.Protocol
-	SetProtocol(protocol protocol.Protocol)
+	Protocol() protocols.Protocol
+	SetProtocol(protocol protocols.Protocol)
 	
 	// Error handling
 	LastError() error
 }
 
-// BaseAdapter provides a basic implementation of the Adapter interface
+// BaseAdapter provides a basic implementation of the LegacyAdapter interface
 type BaseAdapter struct {
 	name        string
 	adapterType string
 	config      AdapterConfig
 	metadata    AdapterMetadata
-	protocol    protocol.Protocol
+	protocol    protocols.Protocol
 	status      AdapterStatus
 	stats       AdapterStats
 	lastError   error
@@ -173,12 +175,12 @@ func (a *BaseAdapter) Stats() AdapterStats {
 }
 
 // Protocol returns the protocol used by the adapter
-func (a *BaseAdapter) Protocol() protocol.Protocol {
+func (a *BaseAdapter) Protocol() protocols.Protocol {
 	return a.protocol
 }
 
 // SetProtocol sets the protocol used by the adapter
-func (a *BaseAdapter) SetProtocol(protocol protocol.Protocol) {
+func (a *BaseAdapter) SetProtocol(protocol protocols.Protocol) {
 	a.protocol = protocol
 }
 
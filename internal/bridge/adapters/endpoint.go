@@ -0,0 +1,46 @@
+// endpoint.go - Per-call endpoint selection for adapters
+
+package adapters
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// endpointContextKey is the context key for a per-call endpoint override
+type endpointContextKey struct{}
+
+// WithEndpoint returns a context directing adapters that support it to send
+// the call to the given address instead of their configured endpoint. The
+// address may be a bare host:port or a full URL.
+func WithEndpoint(ctx context.Context, address string) context.Context {
+	return context.WithValue(ctx, endpointContextKey{}, address)
+}
+
+// EndpointFromContext returns the endpoint override stored in the context, if any
+func EndpointFromContext(ctx context.Context) (string, bool) {
+	address, ok := ctx.Value(endpointContextKey{}).(string)
+	return address, ok && address != ""
+}
+
+// resolveBaseURL applies a context endpoint override to a configured base URL,
+// keeping the configured scheme and path when the override is a bare host:port
+func resolveBaseURL(ctx context.Context, baseURL string) string {
+	address, ok := EndpointFromContext(ctx)
+	if !ok {
+		return baseURL
+	}
+
+	if strings.Contains(address, "://") {
+		return address
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Host == "" {
+		return "http://" + address
+	}
+
+	parsed.Host = address
+	return parsed.String()
+}
@@ -5,6 +5,10 @@ package adapters
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/logging"
@@ -12,11 +16,18 @@ import (
 )
 
 // AdapterFactory creates a new adapter
-type AdapterFactory func(config AdapterConfig) (Adapter, error)
+type AdapterFactory func(config SharedAdapterConfig) (Adapter, error)
 
-// AdapterRegistry manages adapter factories
+// adapterRegistration is a factory and the SPI version it was written against
+type adapterRegistration struct {
+	factory    AdapterFactory
+	spiVersion string
+}
+
+// AdapterRegistry manages adapter factories. It is the single registry used
+// by the bridge, connection pools and the bridge manager.
 type AdapterRegistry struct {
-	factories map[string]AdapterFactory
+	factories map[string]adapterRegistration
 	mutex     sync.RWMutex
 	logger    *zap.SugaredLogger
 }
@@ -29,13 +40,27 @@ func NewAdapterRegistry(logger *zap.SugaredLogger) *AdapterRegistry {
 	}
 
 	return &AdapterRegistry{
-		factories: make(map[string]AdapterFactory),
+		factories: make(map[string]adapterRegistration),
 		logger:    logger,
 	}
 }
 
-// RegisterFactory registers an adapter factory
+// RegisterFactory registers an adapter factory written against the current SPI
 func (r *AdapterRegistry) RegisterFactory(adapterType string, factory AdapterFactory) error {
+	return r.RegisterVersionedFactory(adapterType, SPIVersion, factory)
+}
+
+// RegisterVersionedFactory registers an adapter factory written against the
+// given SPI version, rejecting versions this bridge cannot host
+func (r *AdapterRegistry) RegisterVersionedFactory(adapterType, spiVersion string, factory AdapterFactory) error {
+	if factory == nil {
+		return fmt.Errorf("adapter factory for type '%s' cannot be nil", adapterType)
+	}
+	if !spiCompatible(spiVersion) {
+		return fmt.Errorf("%w: adapter type '%s' targets SPI %s, bridge implements %s",
+			ErrIncompatibleSPI, adapterType, spiVersion, SPIVersion)
+	}
+
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
@@ -43,8 +68,8 @@ func (r *AdapterRegistry) RegisterFactory(adapterType string, factory AdapterFac
 		return fmt.Errorf("adapter factory for type '%s' already registered", adapterType)
 	}
 
-	r.factories[adapterType] = factory
-	r.logger.Infow("Registered adapter factory", "adapter_type", adapterType)
+	r.factories[adapterType] = adapterRegistration{factory: factory, spiVersion: spiVersion}
+	r.logger.Infow("Registered adapter factory", "adapter_type", adapterType, "spi_version", spiVersion)
 	return nil
 }
 
@@ -53,16 +78,17 @@ func (r *AdapterRegistry) GetFactory(adapterType string) (AdapterFactory, error)
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 
-	factory, exists := r.factories[adapterType]
+	registration, exists := r.factories[adapterType]
 	if !exists {
-		return nil, fmt.Errorf("no adapter factory registered for type '%s'", adapterType)
+		return nil, fmt.Errorf("%w: no adapter factory registered for type '%s'", SharedErrUnknownAdapterType, adapterType)
 	}
 
-	return factory, nil
+	return registration.factory, nil
 }
 
-// CreateAdapter creates a new adapter instance
-func (r *AdapterRegistry) CreateAdapter(config AdapterConfig) (Adapter, error) {
+// CreateAdapter creates a new adapter instance, checking that it implements
+// the interfaces its capabilities promise
+func (r *AdapterRegistry) CreateAdapter(config SharedAdapterConfig) (Adapter, error) {
 	factory, err := r.GetFactory(config.Type)
 	if err != nil {
 		return nil, err
@@ -73,7 +99,12 @@ func (r *AdapterRegistry) CreateAdapter(config AdapterConfig) (Adapter, error) {
 		return nil, fmt.Errorf("failed to create adapter of type '%s': %w", config.Type, err)
 	}
 
-	r.logger.Debugw("Created adapter instance", "adapter_type", config.Type, "adapter_name", config.Name)
+	if err := validateCapabilities(adapter); err != nil {
+		return nil, err
+	}
+
+	r.logger.Debugw("Created adapter instance", "adapter_type", config.Type, "adapter_name", config.Name,
+		"capabilities", adapter.Capabilities().String())
 	return adapter, nil
 }
 
@@ -119,13 +150,24 @@ func GetGlobalAdapterRegistry() *AdapterRegistry {
 	return globalRegistry
 }
 
+// init registers the built-in adapter types with the global registry
+func init() {
+	registry := GetGlobalAdapterRegistry()
+	registry.RegisterFactory("rest", newRESTFromConfig)
+	registry.RegisterFactory("websocket", newWebSocketFromConfig)
+	registry.RegisterFactory("websocket-server", newWebSocketServerFromConfig)
+	// WrapGRPCAdapter is the dynamic adapter, so both names share its factory
+	registry.RegisterFactory("grpc", newGRPCDynamicFromConfig)
+	registry.RegisterFactory("grpc-dynamic", newGRPCDynamicFromConfig)
+}
+
 // RegisterAdapterFactory registers a factory with the global registry
 func RegisterAdapterFactory(adapterType string, factory AdapterFactory) error {
 	return GetGlobalAdapterRegistry().RegisterFactory(adapterType, factory)
 }
 
 // CreateAdapterInstance creates a new adapter using the global registry
-func CreateAdapterInstance(config AdapterConfig) (Adapter, error) {
+func CreateAdapterInstance(config SharedAdapterConfig) (Adapter, error) {
 	return GetGlobalAdapterRegistry().CreateAdapter(config)
 }
 
@@ -135,7 +177,7 @@ func ListAvailableAdapterTypes() []string {
 }
 
 // CreateAdapterWithContext creates a new adapter and initializes it with the provided context
-func CreateAdapterWithContext(ctx context.Context, config AdapterConfig) (Adapter, error) {
+func CreateAdapterWithContext(ctx context.Context, config SharedAdapterConfig) (Adapter, error) {
 	adapter, err := CreateAdapterInstance(config)
 	if err != nil {
 		return nil, err
@@ -149,7 +191,7 @@ func CreateAdapterWithContext(ctx context.Context, config AdapterConfig) (Adapte
 }
 
 // ValidateAdapterConfig validates an adapter configuration
-func ValidateAdapterConfig(config AdapterConfig) error {
+func ValidateAdapterConfig(config SharedAdapterConfig) error {
 	if config.Name == "" {
 		return fmt.Errorf("adapter name cannot be empty")
 	}
@@ -166,3 +208,114 @@ func ValidateAdapterConfig(config AdapterConfig) error {
 
 	return nil
 }
+
+// registryAdapterLogger adapts the global registry's zap logger to
+// AdapterLogger for adapters created by built-in factories
+type registryAdapterLogger struct {
+	logger *zap.SugaredLogger
+}
+
+// newRegistryAdapterLogger returns a logger tagged with the adapter name
+func newRegistryAdapterLogger(name string) AdapterLogger {
+	return &registryAdapterLogger{logger: GetGlobalAdapterRegistry().logger.With("adapter", name)}
+}
+
+func (l *registryAdapterLogger) Debug(msg string, fields map[string]interface{}) {
+	l.logger.Debugw(msg, fieldPairs(fields)...)
+}
+
+func (l *registryAdapterLogger) Info(msg string, fields map[string]interface{}) {
+	l.logger.Infow(msg, fieldPairs(fields)...)
+}
+
+func (l *registryAdapterLogger) Warn(msg string, fields map[string]interface{}) {
+	l.logger.Warnw(msg, fieldPairs(fields)...)
+}
+
+func (l *registryAdapterLogger) Error(msg string, fields map[string]interface{}) {
+	l.logger.Errorw(msg, fieldPairs(fields)...)
+}
+
+// fieldPairs converts log fields to alternating key, value pairs for zap
+func fieldPairs(fields map[string]interface{}) []interface{} {
+	pairs := make([]interface{}, 0, len(fields)*2)
+	for key, value := range fields {
+		pairs = append(pairs, key, value)
+	}
+	return pairs
+}
+
+// adapterURL returns Path when it is a full URL, and otherwise the URL with
+// the scheme, Host, Port and Path, or "" when Host is empty too
+func adapterURL(config SharedAdapterConfig, scheme string) string {
+	if strings.Contains(config.Path, "://") {
+		return config.Path
+	}
+	if config.Host == "" {
+		return ""
+	}
+
+	host := config.Host
+	if config.Port > 0 {
+		host = net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	}
+	return (&url.URL{Scheme: scheme, Host: host, Path: config.Path}).String()
+}
+
+// optionInt reads an integer adapter option, accepting the number types
+// produced by JSON and YAML decoding as well as numeric strings
+func optionInt(options map[string]interface{}, key string) (int, bool) {
+	switch value := options[key].(type) {
+	case int:
+		return value, true
+	case int64:
+		return int(value), true
+	case float64:
+		return int(value), true
+	case string:
+		n, err := strconv.Atoi(value)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// optionStringMap reads a string-to-string adapter option
+func optionStringMap(options map[string]interface{}, key string) map[string]string {
+	switch value := options[key].(type) {
+	case map[string]string:
+		return value
+	case map[string]interface{}:
+		result := make(map[string]string, len(value))
+		for k, v := range value {
+			if s, ok := v.(string); ok {
+				result[k] = s
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// optionStringSlice reads a list-of-strings adapter option, accepting a single
+// string as a list of one
+func optionStringSlice(options map[string]interface{}, key string) []string {
+	switch value := options[key].(type) {
+	case []string:
+		return value
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	case string:
+		if value != "" {
+			return []string{value}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package adapters
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinAdaptersFromRegistry(t *testing.T) {
+	tests := []struct {
+		config       SharedAdapterConfig
+		capabilities Capability
+		path         string
+	}{
+		{
+			config:       SharedAdapterConfig{Name: "orders", Type: "rest", Path: "https://orders.local/v1"},
+			capabilities: CapRequestResponse | CapStreaming,
+			path:         "https://orders.local/v1",
+		},
+		{
+			config:       SharedAdapterConfig{Name: "orders", Type: "rest", Host: "orders.local", Port: 8080, Path: "/v1"},
+			capabilities: CapRequestResponse | CapStreaming,
+			path:         "http://orders.local:8080/v1",
+		},
+		{
+			config:       SharedAdapterConfig{Name: "prices", Type: "websocket", Protocol: "wss", Host: "prices.local", Path: "/ws"},
+			capabilities: CapRequestResponse | CapStreaming | CapPush,
+			path:         "wss://prices.local/ws",
+		},
+		{
+			config: SharedAdapterConfig{Name: "edges", Type: "websocket-server", Path: "/edges",
+				Options: map[string]interface{}{"tokens": map[string]string{"t1": "edge-1"}}},
+			capabilities: CapRequestResponse | CapPush,
+			path:         "/edges",
+		},
+		{
+			config:       SharedAdapterConfig{Name: "analyzer", Type: "grpc", Host: "analyzer.local", Port: 50051},
+			capabilities: CapRequestResponse | CapStreaming,
+			path:         "",
+		},
+		{
+			config:       SharedAdapterConfig{Name: "analyzer", Type: "grpc-dynamic", Host: "analyzer.local", Port: 50051},
+			capabilities: CapRequestResponse | CapStreaming,
+			path:         "",
+		},
+	}
+
+	types := ListAvailableAdapterTypes()
+	for _, tt := range tests {
+		assert.Contains(t, types, tt.config.Type)
+
+		adapter, err := CreateAdapterInstance(tt.config)
+		require.NoError(t, err, tt.config.Type)
+		assert.Equal(t, tt.config.Type, adapter.Type())
+		assert.Equal(t, tt.config.Name, adapter.Name())
+		assert.True(t, adapter.Capabilities().Has(tt.capabilities), "%s: %s", tt.config.Type, adapter.Capabilities())
+
+		if tt.path != "" {
+			assert.Equal(t, tt.path, adapter.Config().Path, tt.config.Type)
+		}
+
+		// Every built-in type needs somewhere to connect to
+		_, err = CreateAdapterInstance(SharedAdapterConfig{Name: tt.config.Name, Type: tt.config.Type})
+		assert.ErrorIs(t, err, SharedErrInvalidConfig, tt.config.Type)
+	}
+}
+
+func TestRESTAdapterFromRegistrySends(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/orders/7", r.URL.Path)
+		assert.Equal(t, "bridge", r.Header.Get("X-Caller"))
+		w.Write([]byte(`{"id":7}`))
+	}))
+	defer server.Close()
+
+	adapter, err := CreateAdapterInstance(SharedAdapterConfig{
+		Name:    "orders",
+		Type:    "rest",
+		Path:    server.URL + "/v1",
+		Timeout: 5 * time.Second,
+		Options: map[string]interface{}{"headers": map[string]interface{}{"X-Caller": "bridge"}},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, adapter.Initialize(ctx))
+	defer adapter.Shutdown(ctx)
+
+	reply, err := adapter.Send(ctx, []byte(`{"method":"GET","endpoint":"/orders/7"}`))
+	require.NoError(t, err)
+	assert.Contains(t, string(reply), `{"id":7}`)
+}
@@ -18,7 +18,10 @@ import (
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
@@ -48,6 +51,7 @@ type GRPCAdapterConfig struct {
 	
 	// Client configuration
 	PoolSize            int           // Size of the connection pool
+	MaxConnLifetime     time.Duration // Age after which pooled connections are replaced (0 keeps them)
 	DialTimeout         time.Duration // Timeout for establishing connections
 	ClientKeepalive     time.Duration // Keepalive time for client connections
 	ClientTimeout       time.Duration // Default client timeout for requests
@@ -69,6 +73,11 @@ type GRPCAdapterConfig struct {
 	EnableReflection   bool // Enable gRPC reflection service
 	EnableHealthCheck  bool // Enable health checking
 	EnableTracing      bool // Enable distributed tracing
+	
+	// Dynamic invocation
+	DescriptorSetFiles  []string      // FileDescriptorSet files describing callable services
+	UseServerReflection bool          // Resolve other services through the target's reflection service
+	DescriptorCacheTTL  time.Duration // How long descriptors fetched by reflection are reused
 }
 
 // DefaultGRPCAdapterConfig returns the default configuration for the gRPC adapter
@@ -102,6 +111,10 @@ func DefaultGRPCAdapterConfig() *GRPCAdapterConfig {
 		EnableReflection:  true,
 		EnableHealthCheck: true,
 		EnableTracing:     true,
+		
+		// Dynamic invocation
+		UseServerReflection: true,
+		DescriptorCacheTTL:  5 * time.Minute,
 	}
 }
 
@@ -118,7 +131,7 @@ type GRPCAdapter struct {
 	config          *GRPCAdapterConfig
 	server          *grpc.Server
 	httpServer      *http.Server
-	connections     map[string]*ConnectionPool
+	connections     map[string]*Pool[*grpc.ClientConn]
 	connectionMutex sync.RWMutex
 	logger          Logger
 	metrics         *metrics.Collector
@@ -126,18 +139,13 @@ type GRPCAdapter struct {
 	serviceRegistry map[string]interface{}
 	interceptors    []grpc.UnaryServerInterceptor
 	streamInterceptors []grpc.StreamServerInterceptor
+	descriptors     *grpcDescriptorCache
+	health          *health.Server
 }
 
-// ConnectionPool manages a pool of gRPC client connections
-type ConnectionPool struct {
-	target        string
-	connections   []*grpc.ClientConn
-	currentIndex  int
-	mutex         sync.Mutex
-	dialOptions   []grpc.DialOption
-	size          int
-	healthChecks  bool
-}
+// grpcStreamsPerConnection is how many calls share one pooled connection,
+// matching the usual HTTP/2 concurrent stream limit
+const grpcStreamsPerConnection = 100
 
 // NewGRPCAdapter creates a new gRPC adapter
 func NewGRPCAdapter(
@@ -153,13 +161,14 @@ func NewGRPCAdapter(
 	// Create a new adapter instance
 	adapter := &GRPCAdapter{
 		config:          config,
-		connections:     make(map[string]*ConnectionPool),
+		connections:     make(map[string]*Pool[*grpc.ClientConn]),
 		logger:          logger,
 		metrics:         metrics,
 		configManager:   configManager,
 		serviceRegistry: make(map[string]interface{}),
 		interceptors:    make([]grpc.UnaryServerInterceptor, 0),
 		streamInterceptors: make([]grpc.StreamServerInterceptor, 0),
+		descriptors:     newGRPCDescriptorCache(),
 	}
 	
 	// Add default interceptors
@@ -223,6 +232,11 @@ func (a *GRPCAdapter) initServer() {
 	if a.config.EnableReflection {
 		reflection.Register(a.server)
 	}
+	
+	// Register health service if enabled
+	if a.config.EnableHealthCheck {
+		a.initHealth()
+	}
 }
 
 // getTLSCredentials loads TLS credentials for secure connections
@@ -254,7 +268,9 @@ func (a *GRPCAdapter) getTLSCredentials() (credentials.TransportCredentials, err
 	return credentials.NewTLS(tlsConfig), nil
 }
 
-// RegisterService registers a gRPC service with the adapter
+// RegisterService registers a gRPC service with the adapter. The name is
+// reported by the health service, so use the fully-qualified service name
+// (e.g. "bridge.v1.TokenAnalyzer") for health clients to find it.
 func (a *GRPCAdapter) RegisterService(name string, registerFunc func(s *grpc.Server)) {
 	a.logger.Info("Registering gRPC service", map[string]interface{}{
 		"service": name,
@@ -265,6 +281,7 @@ func (a *GRPCAdapter) RegisterService(name string, registerFunc func(s *grpc.Ser
 	
 	// Add to service registry
 	a.serviceRegistry[name] = true
+	a.SetServiceHealth(name, true)
 	
 	// Track metrics
 	if a.metrics != nil {
@@ -292,6 +309,11 @@ func (a *GRPCAdapter) Start() error {
 	// Add metrics endpoint
 	mux.Handle("/metrics", promhttp.Handler())
 	
+	// Report the server as serving once it accepts connections
+	if a.health != nil {
+		a.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	}
+	
 	// Start server in a goroutine
 	go func() {
 		if err := a.server.Serve(listener); err != nil {
@@ -336,9 +358,14 @@ func (a *GRPCAdapter) Stop() {
 		})
 		pool.Close()
 	}
-	a.connections = make(map[string]*ConnectionPool)
+	a.connections = make(map[string]*Pool[*grpc.ClientConn])
 	a.connectionMutex.Unlock()
 	
+	// Tell health clients, including open Watch streams, before draining
+	if a.health != nil {
+		a.health.Shutdown()
+	}
+	
 	// Gracefully stop the server
 	a.server.GracefulStop()
 }
@@ -353,8 +380,10 @@ func (a *GRPCAdapter) AddStreamInterceptor(interceptor grpc.StreamServerIntercep
 	a.streamInterceptors = append(a.streamInterceptors, interceptor)
 }
 
-// getConnection gets a connection from the pool or creates a new one
-func (a *GRPCAdapter) getConnection(target string, options ...grpc.DialOption) (*grpc.ClientConn, error) {
+// getConnection borrows a connection to target from its pool, creating the
+// pool on first use. Connections are shared by concurrent calls and handed
+// out round-robin; call release when the call or stream is done.
+func (a *GRPCAdapter) getConnection(ctx context.Context, target string, options ...grpc.DialOption) (*grpc.ClientConn, func(), error) {
 	a.connectionMutex.RLock()
 	pool, ok := a.connections[target]
 	a.connectionMutex.RUnlock()
@@ -387,7 +416,7 @@ func (a *GRPCAdapter) getConnection(target string, options ...grpc.DialOption) (
 				creds, err := a.getTLSCredentials()
 				if err != nil {
 					a.connectionMutex.Unlock()
-					return nil, fmt.Errorf("failed to load TLS credentials: %w", err)
+					return nil, nil, fmt.Errorf("failed to load TLS credentials: %w", err)
 				}
 				dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
 			} else {
@@ -395,19 +424,13 @@ func (a *GRPCAdapter) getConnection(target string, options ...grpc.DialOption) (
 			}
 			
 			// Create the connection pool
-			pool = &ConnectionPool{
-				target:      target,
-				connections: make([]*grpc.ClientConn, 0, a.config.PoolSize),
-				dialOptions: dialOpts,
-				size:        a.config.PoolSize,
-				healthChecks: a.config.EnableHealthCheck,
-			}
+			pool = a.newConnectionPool(target, dialOpts)
 			
-			// Initialize the pool
-			err := pool.Initialize()
-			if err != nil {
+			// Open the connections up front so an unreachable target fails fast
+			if err := pool.Warmup(ctx); err != nil {
+				pool.Close()
 				a.connectionMutex.Unlock()
-				return nil, fmt.Errorf("failed to initialize connection pool: %w", err)
+				return nil, nil, fmt.Errorf("failed to initialize connection pool: %w", err)
 			}
 			
 			a.connections[target] = pool
@@ -416,7 +439,56 @@ func (a *GRPCAdapter) getConnection(target string, options ...grpc.DialOption) (
 	}
 	
 	// Get a connection from the pool
-	return pool.Get()
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, func() { pool.Return(conn) }, nil
+}
+
+// newConnectionPool creates the pool of client connections to target
+func (a *GRPCAdapter) newConnectionPool(target string, dialOpts []grpc.DialOption) *Pool[*grpc.ClientConn] {
+	poolConfig := DefaultPoolConfig()
+	poolConfig.MaxConnections = a.config.PoolSize
+	poolConfig.MinIdle = a.config.PoolSize
+	poolConfig.MaxLifetime = a.config.MaxConnLifetime
+	poolConfig.Ordering = PoolFIFO
+	poolConfig.MaxConcurrentUses = grpcStreamsPerConnection
+	poolConfig.ValidateOnBorrow = false
+	if !a.config.EnableHealthCheck {
+		poolConfig.HealthCheckInterval = 0
+	}
+	
+	dialTimeout := a.config.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = time.Second * 10
+	}
+	
+	return NewPool("grpc:"+target, poolConfig, PoolHooks[*grpc.ClientConn]{
+		Create: func(ctx context.Context) (*grpc.ClientConn, error) {
+			// Create connection with timeout
+			ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+			defer cancel()
+			return grpc.DialContext(ctx, target, dialOpts...)
+		},
+		Validate: func(ctx context.Context, conn *grpc.ClientConn) error {
+			switch state := conn.GetState(); state {
+			case connectivity.TransientFailure, connectivity.Shutdown:
+				return fmt.Errorf("%w: connection to %s is %s", ErrConnectionFailed, target, state)
+			}
+			return nil
+		},
+		Close: func(conn *grpc.ClientConn) error {
+			return conn.Close()
+		},
+		OnError: func(operation string, err error) {
+			a.logger.Warn("gRPC connection pool maintenance failed", map[string]interface{}{
+				"target":    target,
+				"operation": operation,
+				"error":     err.Error(),
+			})
+		},
+	})
 }
 
 // Call makes a gRPC call to a service
@@ -435,7 +507,7 @@ func (a *GRPCAdapter) Call(
 	startTime := time.Now()
 	
 	// Get a connection
-	conn, err := a.getConnection(target)
+	conn, release, err := a.getConnection(timeoutCtx, target)
 	if err != nil {
 		a.logger.Error("Failed to get gRPC connection", map[string]interface{}{
 			"target": target,
@@ -450,6 +522,7 @@ func (a *GRPCAdapter) Call(
 		
 		return fmt.Errorf("%w: %v", ErrConnectionFailed, err)
 	}
+	defer release()
 	
 	// Make the call
 	err = conn.Invoke(timeoutCtx, method, request, response, options...)
@@ -479,7 +552,10 @@ func (a *GRPCAdapter) Call(
 	return nil
 }
 
-// Stream creates a gRPC stream to a service
+// Stream returns a pooled connection for opening a stream to a service. The
+// connection stays shared with other calls and may be rotated out of the
+// pool, so streams should be opened right away; OpenStream keeps the
+// connection reserved for the lifetime of the stream.
 func (a *GRPCAdapter) Stream(
 	ctx context.Context,
 	target string,
@@ -487,7 +563,7 @@ func (a *GRPCAdapter) Stream(
 	options ...grpc.CallOption,
 ) (*grpc.ClientConn, error) {
 	// Get a connection
-	conn, err := a.getConnection(target)
+	conn, release, err := a.getConnection(ctx, target)
 	if err != nil {
 		a.logger.Error("Failed to get gRPC connection for streaming", map[string]interface{}{
 			"target": target,
@@ -502,64 +578,11 @@ func (a *GRPCAdapter) Stream(
 		
 		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
 	}
+	release()
 	
 	return conn, nil
 }
 
-// Initialize initializes the connection pool
-func (p *ConnectionPool) Initialize() error {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-	
-	for i := 0; i < p.size; i++ {
-		// Create connection with timeout
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-		conn, err := grpc.DialContext(ctx, p.target, p.dialOptions...)
-		cancel()
-		
-		if err != nil {
-			// Close any open connections
-			for _, c := range p.connections {
-				c.Close()
-			}
-			p.connections = nil
-			return err
-		}
-		
-		p.connections = append(p.connections, conn)
-	}
-	
-	return nil
-}
-
-// Get gets a connection from the pool
-func (p *ConnectionPool) Get() (*grpc.ClientConn, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-	
-	if len(p.connections) == 0 {
-		return nil, errors.New("no connections available in pool")
-	}
-	
-	// Simple round-robin
-	conn := p.connections[p.currentIndex]
-	p.currentIndex = (p.currentIndex + 1) % len(p.connections)
-	
-	return conn, nil
-}
-
-// Close closes all connections in the pool
-func (p *ConnectionPool) Close() {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-	
-	for _, conn := range p.connections {
-		conn.Close()
-	}
-	
-	p.connections = nil
-}
-
 // loggingInterceptor logs incoming requests
 func (a *GRPCAdapter) loggingInterceptor(
 	ctx context.Context,
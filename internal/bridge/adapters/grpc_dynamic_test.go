@@ -0,0 +1,217 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// newReflectionTarget starts a gRPC server offering the health and reflection
+// services. It counts reflection streams, holding each open until gate is
+// closed when one is given.
+func newReflectionTarget(t *testing.T, gate <-chan struct{}) (string, *int32) {
+	t.Helper()
+
+	var streams int32
+	server := grpc.NewServer(grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if info.FullMethod == "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo" {
+			atomic.AddInt32(&streams, 1)
+			if gate != nil {
+				<-gate
+			}
+		}
+		return handler(srv, ss)
+	}))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String(), &streams
+}
+
+// newDynamicGRPCAdapter creates a client-only gRPC adapter for dynamic calls
+func newDynamicGRPCAdapter(t *testing.T, reflection bool) *GRPCAdapter {
+	t.Helper()
+
+	config := DefaultGRPCAdapterConfig()
+	config.PoolSize = 1
+	config.ClientTimeout = 5 * time.Second
+	config.UseServerReflection = reflection
+
+	adapter := NewGRPCAdapter(config, newQuietLogger(), nil, nil)
+	t.Cleanup(adapter.Stop)
+	return adapter
+}
+
+// healthStatus decodes the status of a Health/Check response
+func healthStatus(t *testing.T, response []byte) string {
+	t.Helper()
+
+	var decoded struct {
+		Status string `json:"status"`
+	}
+	require.NoError(t, json.Unmarshal(response, &decoded))
+	return decoded.Status
+}
+
+func TestSplitGRPCMethod(t *testing.T) {
+	tests := []struct {
+		method  string
+		service string
+		name    string
+	}{
+		{"/grpc.health.v1.Health/Check", "grpc.health.v1.Health", "Check"},
+		{"grpc.health.v1.Health/Check", "grpc.health.v1.Health", "Check"},
+		{"grpc.health.v1.Health.Check", "grpc.health.v1.Health", "Check"},
+	}
+
+	for _, tt := range tests {
+		service, name, err := splitGRPCMethod(tt.method)
+		require.NoError(t, err, tt.method)
+		assert.Equal(t, tt.service, service, tt.method)
+		assert.Equal(t, tt.name, name, tt.method)
+	}
+
+	for _, method := range []string{"Check", "/Check", "grpc.health.v1.Health/"} {
+		_, _, err := splitGRPCMethod(method)
+		assert.ErrorIs(t, err, ErrInvalidArgument, method)
+	}
+}
+
+func TestGRPCInvokeDynamicByReflection(t *testing.T) {
+	target, streams := newReflectionTarget(t, nil)
+	adapter := newDynamicGRPCAdapter(t, true)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		response, err := adapter.InvokeDynamic(ctx, target, "grpc.health.v1.Health/Check", []byte(`{"service":""}`))
+		require.NoError(t, err)
+		assert.Equal(t, "SERVING", healthStatus(t, response))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(streams), "descriptors are fetched once per target")
+
+	_, err := adapter.InvokeDynamic(ctx, target, "grpc.health.v1.Health/Watch", nil)
+	assert.ErrorIs(t, err, ErrStreamingMethod)
+
+	_, err = adapter.InvokeDynamic(ctx, target, "grpc.health.v1.Health/Restart", nil)
+	assert.ErrorIs(t, err, ErrMethodNotFound)
+
+	_, err = adapter.InvokeDynamic(ctx, target, "acme.orders.v1.Orders/Get", nil)
+	assert.ErrorIs(t, err, ErrMethodNotFound)
+
+	_, err = adapter.InvokeDynamic(ctx, target, "grpc.health.v1.Health/Check", []byte(`{"unknown":1}`))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
+
+func TestGRPCResolveMethodLocksPerTarget(t *testing.T) {
+	gate := make(chan struct{})
+	slow, slowStreams := newReflectionTarget(t, gate)
+	fast, _ := newReflectionTarget(t, nil)
+	adapter := newDynamicGRPCAdapter(t, true)
+	ctx := context.Background()
+
+	// Two calls to a target whose reflection service is stalled
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := adapter.InvokeDynamic(ctx, slow, "grpc.health.v1.Health/Check", nil)
+			results <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(slowStreams) == 1 }, 2*time.Second, time.Millisecond)
+
+	// Another target is resolved while the fetch is outstanding
+	done := make(chan error, 1)
+	go func() {
+		_, err := adapter.InvokeDynamic(ctx, fast, "grpc.health.v1.Health/Check", nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("call to another target waited for the stalled reflection fetch")
+	}
+
+	close(gate)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, <-results)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(slowStreams), "waiting calls reuse the fetched descriptors")
+}
+
+func TestGRPCInvokeDynamicFromDescriptorSet(t *testing.T) {
+	target, streams := newReflectionTarget(t, nil)
+	adapter := newDynamicGRPCAdapter(t, false)
+	ctx := context.Background()
+
+	_, err := adapter.InvokeDynamic(ctx, target, "grpc.health.v1.Health/Check", nil)
+	assert.ErrorIs(t, err, ErrMethodNotFound)
+
+	require.NoError(t, adapter.AddDescriptorSet(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(healthpb.File_grpc_health_v1_health_proto)},
+	}))
+
+	response, err := adapter.InvokeDynamic(ctx, target, "/grpc.health.v1.Health/Check", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "SERVING", healthStatus(t, response))
+	assert.Zero(t, atomic.LoadInt32(streams))
+}
+
+func TestGRPCDynamicFromRegistry(t *testing.T) {
+	assert.Contains(t, ListAvailableAdapterTypes(), "grpc-dynamic")
+
+	target, _ := newReflectionTarget(t, nil)
+	host, port, err := net.SplitHostPort(target)
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	registry := NewAdapterRegistry(nil)
+	require.NoError(t, registry.RegisterFactory("grpc-dynamic", newGRPCDynamicFromConfig))
+
+	adapter, err := registry.CreateAdapter(SharedAdapterConfig{
+		Name:    "health",
+		Type:    "grpc-dynamic",
+		Host:    host,
+		Port:    portNumber,
+		Timeout: 5 * time.Second,
+		Options: map[string]interface{}{"service": "grpc.health.v1.Health"},
+	})
+	require.NoError(t, err)
+	assert.True(t, adapter.Capabilities().Has(CapRequestResponse|CapStreaming))
+
+	ctx := context.Background()
+	require.NoError(t, adapter.Initialize(ctx))
+	require.NoError(t, adapter.Connect(ctx))
+	defer adapter.Shutdown(ctx)
+
+	reply, err := adapter.Send(ctx, []byte(`{"id":"1","type":"Check","payload":{}}`))
+	require.NoError(t, err)
+
+	var message grpcBridgeMessage
+	require.NoError(t, json.Unmarshal(reply, &message))
+	assert.Equal(t, "1", message.ID)
+	assert.Equal(t, "grpc.health.v1.Health/Check", message.Headers[GRPCMethodHeader])
+	assert.Equal(t, "SERVING", healthStatus(t, message.Payload))
+
+	_, err = registry.CreateAdapter(SharedAdapterConfig{Name: "health", Type: "grpc-dynamic"})
+	assert.ErrorIs(t, err, SharedErrInvalidConfig)
+}
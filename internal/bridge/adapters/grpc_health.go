@@ -0,0 +1,40 @@
+// grpc_health.go - grpc.health.v1 service reporting the state of registered services
+
+package adapters
+
+import (
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// initHealth registers the health service; the server as a whole reports
+// NOT_SERVING until Start and again after Stop
+func (a *GRPCAdapter) initHealth() {
+	a.health = health.NewServer()
+	a.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(a.server, a.health)
+}
+
+// SetServiceHealth reports a registered service as serving or not serving.
+// Health clients, including Watch streams, see the change immediately.
+func (a *GRPCAdapter) SetServiceHealth(service string, serving bool) {
+	if a.health == nil {
+		return
+	}
+
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	a.health.SetServingStatus(service, status)
+
+	a.logger.Debug("gRPC service health changed", map[string]interface{}{
+		"service": service,
+		"status":  status.String(),
+	})
+}
+
+// HealthServer returns the adapter's health service, or nil when health checking is disabled
+func (a *GRPCAdapter) HealthServer() *health.Server {
+	return a.health
+}
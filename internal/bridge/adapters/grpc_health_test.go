@@ -0,0 +1,109 @@
+package adapters
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// newHealthClient serves the adapter's gRPC server on a local port and
+// returns a health client connected to it
+func newHealthClient(t *testing.T, adapter *GRPCAdapter) healthpb.HealthClient {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go adapter.server.Serve(listener)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestGRPCAdapterHealth(t *testing.T) {
+	config := DefaultGRPCAdapterConfig()
+	config.ServerAddress = "127.0.0.1:0"
+	adapter := NewGRPCAdapter(config, newQuietLogger(), nil, nil)
+	client := newHealthClient(t, adapter)
+	ctx := context.Background()
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	// The server is not serving until started, and unknown services are reported as such
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, check(""))
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "acme.Orders"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	adapter.RegisterService("acme.Orders", func(s *grpc.Server) {})
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check("acme.Orders"))
+
+	watchCtx, cancelWatch := context.WithCancel(ctx)
+	defer cancelWatch()
+	watch, err := client.Watch(watchCtx, &healthpb.HealthCheckRequest{Service: "acme.Orders"})
+	require.NoError(t, err)
+	resp, err := watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	// Watchers see changes as they are made
+	adapter.SetServiceHealth("acme.Orders", false)
+	resp, err = watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+	adapter.SetServiceHealth("acme.Orders", true)
+	resp, err = watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	require.NoError(t, adapter.Start())
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(""))
+
+	// Stopping tells open watchers before the server drains, which waits for them
+	stopped := make(chan struct{})
+	go func() {
+		adapter.Stop()
+		close(stopped)
+	}()
+	resp, err = watch.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	cancelWatch()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return after the watcher left")
+	}
+}
+
+func TestGRPCAdapterHealthDisabled(t *testing.T) {
+	config := DefaultGRPCAdapterConfig()
+	config.EnableHealthCheck = false
+	adapter := NewGRPCAdapter(config, newQuietLogger(), nil, nil)
+	assert.Nil(t, adapter.HealthServer())
+
+	// Registering services does not need the health service
+	adapter.RegisterService("acme.Orders", func(s *grpc.Server) {})
+	adapter.SetServiceHealth("acme.Orders", false)
+
+	client := newHealthClient(t, adapter)
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	adapter.server.Stop()
+}
@@ -3,7 +3,6 @@
 package adapters
 
 import (
-	"context"
 	"sync"
 	"time"
 )
@@ -63,84 +62,17 @@ type SharedAdapterStats struct {
 	CustomStats         map[string]interface{} `json:"custom_stats"`
 }
 
-// SharedAdapter defines the interface for bridge adapters
-type SharedAdapter interface {
-	// Lifecycle methods
-	Initialize(ctx context.Context) error
-	Connect(ctx context.Context) error
-	Disconnect(ctx context.Context) error
-	Shutdown(ctx context.Context) error
-
-	// Status methods
-	Status() SharedAdapterStatus
-	Stats() SharedAdapterStats
-
-	// Configuration and information
-	Name() string
-	Type() string
-	Metadata() SharedAdapterMetadata
-	Config() SharedAdapterConfig
-
-	// Communication methods
-	Send(ctx context.Context, data []byte) ([]byte, error)
-	Receive(ctx context.Context) ([]byte, error)
-
-	// Error handling
-	LastError() error
-}
-
-// SharedAdapterFactory creates a new adapter
-type SharedAdapterFactory func(config SharedAdapterConfig) (SharedAdapter, error)
-
-// SharedAdapterRegistry manages adapter factories
-type SharedAdapterRegistry struct {
-	factories map[string]SharedAdapterFactory
-	mutex     sync.RWMutex
-}
-
-// NewSharedAdapterRegistry creates a new adapter registry
-func NewSharedAdapterRegistry() *SharedAdapterRegistry {
-	return &SharedAdapterRegistry{
-		factories: make(map[string]SharedAdapterFactory),
-	}
-}
+// SharedAdapter is the former name of the adapter SPI.
+//
+// Deprecated: implement Adapter.
+type SharedAdapter = Adapter
 
-// RegisterFactory registers an adapter factory
-func (r *SharedAdapterRegistry) RegisterFactory(adapterType string, factory SharedAdapterFactory) {
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
-	r.factories[adapterType] = factory
-}
-
-// GetFactory returns an adapter factory by type
-func (r *SharedAdapterRegistry) GetFactory(adapterType string) (SharedAdapterFactory, bool) {
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
-	factory, exists := r.factories[adapterType]
-	return factory, exists
-}
+// SharedAdapterFactory is the former name of the adapter factory.
+//
+// Deprecated: use AdapterFactory.
+type SharedAdapterFactory = AdapterFactory
 
-// CreateAdapter creates a new adapter using a registered factory
-func (r *SharedAdapterRegistry) CreateAdapter(config SharedAdapterConfig) (SharedAdapter, error) {
-	factory, exists := r.GetFactory(config.Type)
-	if !exists {
-		return nil, SharedErrUnknownAdapterType
-	}
-	return factory(config)
-}
-
-// ListAdapterTypes returns a list of all registered adapter types
-func (r *SharedAdapterRegistry) ListAdapterTypes() []string {
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
-	types := make([]string, 0, len(r.factories))
-	for adapterType := range r.factories {
-		types = append(types, adapterType)
-	}
-	return types
-}
-
-// SharedBaseAdapter provides a basic implementation of the SharedAdapter interface
+// SharedBaseAdapter provides a basic implementation of the Adapter interface
 type SharedBaseAdapter struct {
 	name        string
 	adapterType string
@@ -203,6 +135,11 @@ func (a *SharedBaseAdapter) Config() SharedAdapterConfig {
 	return config
 }
 
+// Capabilities returns the SPI capabilities named in the adapter metadata
+func (a *SharedBaseAdapter) Capabilities() Capability {
+	return ParseCapabilities(a.metadata.Capabilities)
+}
+
 // Status returns the adapter status
 func (a *SharedBaseAdapter) Status() SharedAdapterStatus {
 	a.mutex.RLock()
@@ -337,25 +274,31 @@ func (e *SharedAdapterError) Error() string {
 	return e.msg
 }
 
-// Global adapter registry for convenience
-var sharedGlobalRegistry = NewSharedAdapterRegistry()
-
-// RegisterSharedAdapterFactory registers an adapter factory with the global registry
-func RegisterSharedAdapterFactory(adapterType string, factory SharedAdapterFactory) {
-	sharedGlobalRegistry.RegisterFactory(adapterType, factory)
+// RegisterSharedAdapterFactory registers an adapter factory with the global registry.
+//
+// Deprecated: use RegisterAdapterFactory.
+func RegisterSharedAdapterFactory(adapterType string, factory SharedAdapterFactory) error {
+	return RegisterAdapterFactory(adapterType, factory)
 }
 
-// GetSharedAdapterFactory returns an adapter factory from the global registry
+// GetSharedAdapterFactory returns an adapter factory from the global registry.
+//
+// Deprecated: use GetGlobalAdapterRegistry().GetFactory.
 func GetSharedAdapterFactory(adapterType string) (SharedAdapterFactory, bool) {
-	return sharedGlobalRegistry.GetFactory(adapterType)
+	factory, err := GetGlobalAdapterRegistry().GetFactory(adapterType)
+	return factory, err == nil
 }
 
-// CreateSharedAdapterInstance creates a new adapter using the global registry
+// CreateSharedAdapterInstance creates a new adapter using the global registry.
+//
+// Deprecated: use CreateAdapterInstance.
 func CreateSharedAdapterInstance(config SharedAdapterConfig) (SharedAdapter, error) {
-	return sharedGlobalRegistry.CreateAdapter(config)
+	return CreateAdapterInstance(config)
 }
 
-// ListAvailableSharedAdapterTypes returns a list of all registered adapter types from the global registry
+// ListAvailableSharedAdapterTypes returns a list of all registered adapter types from the global registry.
+//
+// Deprecated: use ListAvailableAdapterTypes.
 func ListAvailableSharedAdapterTypes() []string {
-	return sharedGlobalRegistry.ListAdapterTypes()
+	return ListAvailableAdapterTypes()
 }
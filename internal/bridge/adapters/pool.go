@@ -0,0 +1,794 @@
+// pool.go - Generic connection pool shared by the bridge and its adapters
+
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Common pool errors
+var (
+	ErrPoolClosed     = errors.New("connection pool is closed")
+	ErrAcquireTimeout = errors.New("timeout acquiring connection from pool")
+	ErrPoolExhausted  = errors.New("connection pool exhausted")
+)
+
+// PoolOrdering selects which available connection is handed out next
+type PoolOrdering string
+
+const (
+	// PoolLIFO reuses the most recently returned connection, so surplus
+	// connections sit idle and are evicted
+	PoolLIFO PoolOrdering = "lifo"
+
+	// PoolFIFO rotates through all connections, spreading load evenly
+	PoolFIFO PoolOrdering = "fifo"
+)
+
+// PoolConfig defines configuration for a connection pool
+type PoolConfig struct {
+	// Maximum number of connections to keep in the pool
+	MaxConnections int
+
+	// Number of idle connections kept open ahead of demand
+	MinIdle int
+
+	// Maximum idle time before a connection is removed from the pool
+	MaxIdleTime time.Duration
+
+	// Maximum age of a connection; older connections are closed once no
+	// caller is using them. Zero keeps connections indefinitely.
+	MaxLifetime time.Duration
+
+	// Maximum time to wait for a connection from the pool
+	AcquireTimeout time.Duration
+
+	// Whether to validate connections when taking from pool
+	ValidateOnBorrow bool
+
+	// How often idle connections are validated in the background; zero disables probing
+	HealthCheckInterval time.Duration
+
+	// How often to run cleanup routine
+	CleanupInterval time.Duration
+
+	// Maximum number of connections to create or evict in a single burst
+	MaxBurstSize int
+
+	// Which available connection to hand out next
+	Ordering PoolOrdering
+
+	// Number of callers that may share one connection, for multiplexed
+	// transports; one gives each caller exclusive use
+	MaxConcurrentUses int
+}
+
+// DefaultPoolConfig returns default pool configuration
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxConnections:      100,
+		MaxIdleTime:         5 * time.Minute,
+		AcquireTimeout:      30 * time.Second,
+		ValidateOnBorrow:    true,
+		HealthCheckInterval: 30 * time.Second,
+		CleanupInterval:     1 * time.Minute,
+		MaxBurstSize:        10,
+		Ordering:            PoolLIFO,
+		MaxConcurrentUses:   1,
+	}
+}
+
+// PoolStats tracks statistics about the connection pool
+type PoolStats struct {
+	// Total number of connections managed by the pool
+	TotalConnections int64
+
+	// Number of idle connections in the pool
+	IdleConnections int64
+
+	// Number of active connections currently in use
+	ActiveConnections int64
+
+	// Number of connection acquisitions
+	Acquisitions int64
+
+	// Number of connection returns
+	Returns int64
+
+	// Number of connection creations
+	Creations int64
+
+	// Number of failed connection creations
+	CreationFailures int64
+
+	// Number of connection closures
+	Closures int64
+
+	// Number of connection timeouts during acquisition
+	Timeouts int64
+
+	// Number of failed validations, on borrow or in the background
+	ValidationFailures int64
+
+	// Number of background health checks
+	HealthChecks int64
+
+	// Number of times pool was exhausted
+	Exhaustions int64
+
+	// Number of connections closed for exceeding MaxLifetime
+	Expirations int64
+
+	// Number of connections closed for exceeding MaxIdleTime
+	Evictions int64
+}
+
+// PoolHooks are the operations a pool performs on its connections
+type PoolHooks[T comparable] struct {
+	// Create opens a new connection; required
+	Create func(ctx context.Context) (T, error)
+
+	// Validate reports whether a connection is still usable
+	Validate func(ctx context.Context, conn T) error
+
+	// Close releases a connection
+	Close func(conn T) error
+
+	// OnError is told about failures in background maintenance
+	OnError func(operation string, err error)
+}
+
+// poolEntry is a connection and its bookkeeping
+type poolEntry[T comparable] struct {
+	conn      T
+	created   time.Time
+	lastUsed  time.Time
+	users     int
+	available bool // listed in Pool.available
+	probing   bool // being validated in the background
+	retiring  bool // closed once its last user returns it
+}
+
+// Pool keeps connections of any kind for reuse. Connections are borrowed
+// with Acquire and handed back with Return, or Discard if they broke.
+type Pool[T comparable] struct {
+	name      string
+	config    PoolConfig
+	hooks     PoolHooks[T]
+	entries   map[T]*poolEntry[T]
+	available []*poolEntry[T] // entries with spare uses, in Ordering order
+	creating  int             // connections being opened
+	warming   int             // of which opened to satisfy MinIdle
+	waiters   []chan struct{}
+	stats     PoolStats
+	closed    bool
+	mutex     sync.Mutex
+	stopChan  chan struct{}
+	lifecycle sync.WaitGroup
+}
+
+// NewPool creates a pool and starts its background maintenance, which keeps
+// MinIdle connections open, evicts idle and expired connections and probes
+// idle connections with hooks.Validate. The pool's statistics are exported
+// through the pool metrics collector under name.
+func NewPool[T comparable](name string, config PoolConfig, hooks PoolHooks[T]) *Pool[T] {
+	defaults := DefaultPoolConfig()
+	if config.MaxConnections <= 0 {
+		config.MaxConnections = defaults.MaxConnections
+	}
+	if config.MinIdle > config.MaxConnections {
+		config.MinIdle = config.MaxConnections
+	}
+	if config.MaxIdleTime <= 0 {
+		config.MaxIdleTime = defaults.MaxIdleTime
+	}
+	if config.AcquireTimeout <= 0 {
+		config.AcquireTimeout = defaults.AcquireTimeout
+	}
+	if config.CleanupInterval <= 0 {
+		config.CleanupInterval = defaults.CleanupInterval
+	}
+	if config.MaxBurstSize <= 0 {
+		config.MaxBurstSize = defaults.MaxBurstSize
+	}
+	if config.Ordering != PoolFIFO {
+		config.Ordering = PoolLIFO
+	}
+	if config.MaxConcurrentUses <= 0 {
+		config.MaxConcurrentUses = 1
+	}
+
+	pool := &Pool[T]{
+		name:     name,
+		config:   config,
+		hooks:    hooks,
+		entries:  make(map[T]*poolEntry[T]),
+		stopChan: make(chan struct{}),
+	}
+
+	poolMetrics.add(pool)
+
+	pool.lifecycle.Add(1)
+	go pool.maintain()
+
+	return pool
+}
+
+// Name returns the pool name
+func (p *Pool[T]) Name() string {
+	return p.name
+}
+
+// Config returns the pool configuration with defaults applied
+func (p *Pool[T]) Config() PoolConfig {
+	return p.config
+}
+
+// Acquire borrows a connection, opening one if none is available and the
+// pool has room, or waiting up to AcquireTimeout for one to be returned
+func (p *Pool[T]) Acquire(ctx context.Context) (T, error) {
+	var zero T
+	var timer *time.Timer
+	waited := false
+
+	p.mutex.Lock()
+	p.stats.Acquisitions++
+
+	for {
+		if p.closed {
+			p.mutex.Unlock()
+			return zero, ErrPoolClosed
+		}
+
+		if entry := p.next(); entry != nil {
+			if entry.users == 0 && p.config.ValidateOnBorrow && p.hooks.Validate != nil {
+				entry.probing = true
+				p.removeAvailable(entry)
+				p.mutex.Unlock()
+
+				err := p.hooks.Validate(ctx, entry.conn)
+
+				p.mutex.Lock()
+				entry.probing = false
+				if p.closed {
+					continue
+				}
+				if err != nil {
+					p.stats.ValidationFailures++
+					p.retire(entry)
+					p.mutex.Unlock()
+					p.closeConn(entry.conn)
+					p.mutex.Lock()
+					continue
+				}
+			}
+
+			p.claim(entry)
+			p.mutex.Unlock()
+			return entry.conn, nil
+		}
+
+		if p.open() < p.config.MaxConnections {
+			p.creating++
+			p.mutex.Unlock()
+
+			conn, err := p.create(ctx)
+
+			p.mutex.Lock()
+			p.creating--
+			if err != nil {
+				p.stats.CreationFailures++
+				p.signal()
+				p.mutex.Unlock()
+				return zero, err
+			}
+			p.stats.Creations++
+			if p.closed {
+				p.stats.Closures++
+				p.mutex.Unlock()
+				p.closeConn(conn)
+				return zero, ErrPoolClosed
+			}
+
+			entry := p.track(conn)
+			p.claim(entry)
+			p.mutex.Unlock()
+			return conn, nil
+		}
+
+		// The pool is at capacity; wait for a connection to be returned
+		if !waited {
+			p.stats.Exhaustions++
+			waited = true
+		}
+		wait := make(chan struct{}, 1)
+		p.waiters = append(p.waiters, wait)
+		p.mutex.Unlock()
+
+		if timer == nil {
+			timer = time.NewTimer(p.config.AcquireTimeout)
+			defer timer.Stop()
+		}
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			p.cancelWait(wait)
+			return zero, fmt.Errorf("%w: %v", ErrAcquireTimeout, ctx.Err())
+		case <-timer.C:
+			p.cancelWait(wait)
+			return zero, ErrAcquireTimeout
+		}
+
+		p.mutex.Lock()
+	}
+}
+
+// Return hands a borrowed connection back to the pool
+func (p *Pool[T]) Return(conn T) {
+	p.mutex.Lock()
+
+	entry, exists := p.entries[conn]
+	if !exists || entry.users == 0 {
+		p.mutex.Unlock()
+		return
+	}
+
+	entry.users--
+	entry.lastUsed = time.Now()
+	p.stats.Returns++
+
+	if entry.retiring || p.expired(entry, entry.lastUsed) {
+		if !entry.retiring {
+			p.stats.Expirations++
+		}
+		if entry.users > 0 {
+			// Let the remaining users finish before closing it
+			entry.retiring = true
+			p.removeAvailable(entry)
+			p.mutex.Unlock()
+			return
+		}
+		p.retire(entry)
+		p.mutex.Unlock()
+		p.closeConn(conn)
+		return
+	}
+
+	// Returned connections go to the back of the line
+	p.removeAvailable(entry)
+	p.pushAvailable(entry)
+	p.signal()
+	p.mutex.Unlock()
+}
+
+// Discard closes a borrowed connection that is no longer usable, in place of
+// returning it. A connection shared with other callers stops being handed out
+// and is closed once the last of them returns or discards it.
+func (p *Pool[T]) Discard(conn T) {
+	p.mutex.Lock()
+
+	entry, exists := p.entries[conn]
+	if !exists {
+		p.mutex.Unlock()
+		return
+	}
+
+	if entry.users > 0 {
+		entry.users--
+	}
+	if entry.users > 0 {
+		entry.retiring = true
+		p.removeAvailable(entry)
+		p.mutex.Unlock()
+		return
+	}
+
+	p.retire(entry)
+	p.mutex.Unlock()
+	p.closeConn(conn)
+}
+
+// Warmup opens connections until MinIdle are idle, returning the first error
+func (p *Pool[T]) Warmup(ctx context.Context) error {
+	return p.fill(ctx, 0)
+}
+
+// Stats returns current pool statistics
+func (p *Pool[T]) Stats() PoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := p.stats
+	stats.TotalConnections = int64(len(p.entries))
+	for _, entry := range p.entries {
+		if entry.users > 0 {
+			stats.ActiveConnections++
+		} else if !entry.retiring {
+			stats.IdleConnections++
+		}
+	}
+	return stats
+}
+
+// IsClosed returns whether the pool is closed
+func (p *Pool[T]) IsClosed() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.closed
+}
+
+// Close closes the pool and every connection, including borrowed ones
+func (p *Pool[T]) Close() {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return
+	}
+	p.closed = true
+
+	conns := make([]T, 0, len(p.entries))
+	for conn := range p.entries {
+		conns = append(conns, conn)
+	}
+	p.stats.Closures += int64(len(conns))
+	p.entries = make(map[T]*poolEntry[T])
+	p.available = nil
+
+	// Waiters wake to find the pool closed
+	for len(p.waiters) > 0 {
+		p.signal()
+	}
+	close(p.stopChan)
+	p.mutex.Unlock()
+
+	for _, conn := range conns {
+		p.closeConn(conn)
+	}
+
+	p.lifecycle.Wait()
+	poolMetrics.remove(p)
+}
+
+// maintain runs background eviction, health probing and warmup
+func (p *Pool[T]) maintain() {
+	defer p.lifecycle.Done()
+
+	cleanup := time.NewTicker(p.config.CleanupInterval)
+	defer cleanup.Stop()
+
+	var probe <-chan time.Time
+	if p.config.HealthCheckInterval > 0 && p.hooks.Validate != nil {
+		ticker := time.NewTicker(p.config.HealthCheckInterval)
+		defer ticker.Stop()
+		probe = ticker.C
+	}
+
+	p.refill()
+
+	for {
+		select {
+		case <-p.stopChan:
+			return
+		case <-cleanup.C:
+			p.evict()
+			p.refill()
+		case <-probe:
+			p.probe()
+			p.refill()
+		}
+	}
+}
+
+// refill opens connections in the background to restore MinIdle
+func (p *Pool[T]) refill() {
+	if err := p.fill(context.Background(), p.config.MaxBurstSize); err != nil {
+		p.reportError("warmup", err)
+	}
+}
+
+// fill opens up to limit connections (unbounded if zero) to reach MinIdle
+func (p *Pool[T]) fill(ctx context.Context, limit int) error {
+	for {
+		p.mutex.Lock()
+		if p.closed || p.idle()+p.warming >= p.config.MinIdle || p.open() >= p.config.MaxConnections {
+			p.mutex.Unlock()
+			return nil
+		}
+		p.creating++
+		p.warming++
+		p.mutex.Unlock()
+
+		createCtx, cancel := context.WithTimeout(ctx, p.config.AcquireTimeout)
+		conn, err := p.create(createCtx)
+		cancel()
+
+		p.mutex.Lock()
+		p.creating--
+		p.warming--
+		if err != nil {
+			p.stats.CreationFailures++
+			p.signal()
+			p.mutex.Unlock()
+			return err
+		}
+		p.stats.Creations++
+		if p.closed {
+			p.stats.Closures++
+			p.mutex.Unlock()
+			p.closeConn(conn)
+			return nil
+		}
+		p.pushAvailable(p.track(conn))
+		p.signal()
+		p.mutex.Unlock()
+
+		if limit > 0 {
+			limit--
+			if limit == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// evict closes idle connections past MaxIdleTime, keeping MinIdle, and
+// retires connections past MaxLifetime
+func (p *Pool[T]) evict() {
+	p.mutex.Lock()
+
+	now := time.Now()
+	idle := p.idle()
+	var closing []T
+
+	for _, entry := range p.entries {
+		switch {
+		case entry.retiring || entry.probing:
+			continue
+		case p.config.MaxLifetime > 0 && now.Sub(entry.created) >= p.config.MaxLifetime:
+			p.stats.Expirations++
+			if entry.users > 0 {
+				// Stop handing it out; the last user's Return closes it
+				entry.retiring = true
+				p.removeAvailable(entry)
+				continue
+			}
+		case entry.users == 0 && now.Sub(entry.lastUsed) >= p.config.MaxIdleTime &&
+			idle > p.config.MinIdle && len(closing) < p.config.MaxBurstSize:
+			p.stats.Evictions++
+		default:
+			continue
+		}
+
+		idle--
+		p.retire(entry)
+		closing = append(closing, entry.conn)
+	}
+	p.mutex.Unlock()
+
+	for _, conn := range closing {
+		p.closeConn(conn)
+	}
+}
+
+// probe validates idle connections, closing the ones that fail
+func (p *Pool[T]) probe() {
+	p.mutex.Lock()
+	var batch []*poolEntry[T]
+	for _, entry := range p.available {
+		if entry.users == 0 {
+			batch = append(batch, entry)
+		}
+	}
+	for _, entry := range batch {
+		entry.probing = true
+		p.removeAvailable(entry)
+	}
+	p.mutex.Unlock()
+
+	failures := make([]error, len(batch))
+	for i, entry := range batch {
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.AcquireTimeout)
+		failures[i] = p.hooks.Validate(ctx, entry.conn)
+		cancel()
+	}
+
+	p.mutex.Lock()
+	var healthy []*poolEntry[T]
+	var closing []T
+	for i, entry := range batch {
+		entry.probing = false
+		p.stats.HealthChecks++
+		if p.closed {
+			continue
+		}
+		if failures[i] != nil {
+			p.stats.ValidationFailures++
+			p.retire(entry)
+			closing = append(closing, entry.conn)
+			continue
+		}
+		entry.available = true
+		healthy = append(healthy, entry)
+	}
+
+	// Probed connections keep their place ahead of ones returned meanwhile
+	p.available = append(healthy, p.available...)
+	for range healthy {
+		p.signal()
+	}
+	p.mutex.Unlock()
+
+	for _, conn := range closing {
+		p.closeConn(conn)
+	}
+	for _, err := range failures {
+		if err != nil {
+			p.reportError("health check", err)
+		}
+	}
+}
+
+// next returns the available connection to hand out, closing stale idle
+// ones on the way. The caller must hold the mutex.
+func (p *Pool[T]) next() *poolEntry[T] {
+	now := time.Now()
+	for len(p.available) > 0 {
+		var entry *poolEntry[T]
+		if p.config.Ordering == PoolFIFO {
+			entry = p.available[0]
+		} else {
+			entry = p.available[len(p.available)-1]
+		}
+
+		if entry.users > 0 || !p.stale(entry, now) {
+			return entry
+		}
+
+		if p.expired(entry, now) {
+			p.stats.Expirations++
+		} else {
+			p.stats.Evictions++
+		}
+		p.retire(entry)
+		conn := entry.conn
+		p.mutex.Unlock()
+		p.closeConn(conn)
+		p.mutex.Lock()
+	}
+	return nil
+}
+
+// claim records a use of an entry. The caller must hold the mutex.
+func (p *Pool[T]) claim(entry *poolEntry[T]) {
+	entry.users++
+	entry.lastUsed = time.Now()
+
+	// A shared connection moves to the back so the next caller gets another
+	p.removeAvailable(entry)
+	if entry.users < p.config.MaxConcurrentUses {
+		p.pushAvailable(entry)
+	}
+}
+
+// track adds a new connection. The caller must hold the mutex.
+func (p *Pool[T]) track(conn T) *poolEntry[T] {
+	now := time.Now()
+	entry := &poolEntry[T]{conn: conn, created: now, lastUsed: now}
+	p.entries[conn] = entry
+	return entry
+}
+
+// retire forgets a connection the caller is about to close. The caller must hold the mutex.
+func (p *Pool[T]) retire(entry *poolEntry[T]) {
+	if current, exists := p.entries[entry.conn]; !exists || current != entry {
+		return
+	}
+	delete(p.entries, entry.conn)
+	p.removeAvailable(entry)
+	p.stats.Closures++
+	p.signal()
+}
+
+// pushAvailable appends an entry to the available list
+func (p *Pool[T]) pushAvailable(entry *poolEntry[T]) {
+	if !entry.available {
+		entry.available = true
+		p.available = append(p.available, entry)
+	}
+}
+
+// removeAvailable takes an entry off the available list
+func (p *Pool[T]) removeAvailable(entry *poolEntry[T]) {
+	if !entry.available {
+		return
+	}
+	entry.available = false
+	for i, candidate := range p.available {
+		if candidate == entry {
+			p.available = append(p.available[:i], p.available[i+1:]...)
+			return
+		}
+	}
+}
+
+// signal wakes the longest waiting Acquire. The caller must hold the mutex.
+func (p *Pool[T]) signal() {
+	if len(p.waiters) == 0 {
+		return
+	}
+	wait := p.waiters[0]
+	p.waiters = p.waiters[1:]
+	wait <- struct{}{}
+}
+
+// cancelWait withdraws a waiter, passing on a wakeup it already received
+func (p *Pool[T]) cancelWait(wait chan struct{}) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.stats.Timeouts++
+	for i, candidate := range p.waiters {
+		if candidate == wait {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			return
+		}
+	}
+	p.signal()
+}
+
+// open counts connections open or being opened. The caller must hold the mutex.
+func (p *Pool[T]) open() int {
+	return len(p.entries) + p.creating
+}
+
+// idle counts connections nobody is using. The caller must hold the mutex.
+func (p *Pool[T]) idle() int {
+	count := 0
+	for _, entry := range p.entries {
+		if entry.users == 0 && !entry.retiring {
+			count++
+		}
+	}
+	return count
+}
+
+// expired reports whether a connection has outlived MaxLifetime
+func (p *Pool[T]) expired(entry *poolEntry[T], now time.Time) bool {
+	return p.config.MaxLifetime > 0 && now.Sub(entry.created) >= p.config.MaxLifetime
+}
+
+// stale reports whether an idle connection should be closed rather than reused
+func (p *Pool[T]) stale(entry *poolEntry[T], now time.Time) bool {
+	return p.expired(entry, now) || now.Sub(entry.lastUsed) >= p.config.MaxIdleTime
+}
+
+// create opens a connection with the Create hook
+func (p *Pool[T]) create(ctx context.Context) (T, error) {
+	if p.hooks.Create == nil {
+		var zero T
+		return zero, fmt.Errorf("pool '%s' has no connection factory", p.name)
+	}
+	return p.hooks.Create(ctx)
+}
+
+// closeConn closes a connection with the Close hook
+func (p *Pool[T]) closeConn(conn T) {
+	if p.hooks.Close == nil {
+		return
+	}
+	if err := p.hooks.Close(conn); err != nil {
+		p.reportError("close", err)
+	}
+}
+
+// reportError passes a maintenance failure to the OnError hook
+func (p *Pool[T]) reportError(operation string, err error) {
+	if p.hooks.OnError != nil {
+		p.hooks.OnError(operation, err)
+	}
+}
@@ -0,0 +1,134 @@
+// pool_metrics.go - Prometheus export of connection pool statistics
+
+package adapters
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolStatsSource is a pool whose statistics are exported
+type poolStatsSource interface {
+	Name() string
+	Stats() PoolStats
+}
+
+// poolMetric describes one exported PoolStats field
+type poolMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	value     func(PoolStats) int64
+}
+
+// PoolCollector exports the PoolStats of every open pool, labelled by pool name
+type PoolCollector struct {
+	pools   map[string]poolStatsSource
+	metrics []poolMetric
+	mutex   sync.RWMutex
+}
+
+// poolMetrics collects every pool created with NewPool
+var (
+	poolMetrics         = NewPoolCollector()
+	poolMetricsRegister sync.Once
+)
+
+// NewPoolCollector creates an empty pool collector
+func NewPoolCollector() *PoolCollector {
+	labels := []string{"pool"}
+	metric := func(name, help string, valueType prometheus.ValueType, value func(PoolStats) int64) poolMetric {
+		return poolMetric{
+			desc:      prometheus.NewDesc("bridge_pool_"+name, help, labels, nil),
+			valueType: valueType,
+			value:     value,
+		}
+	}
+
+	return &PoolCollector{
+		pools: make(map[string]poolStatsSource),
+		metrics: []poolMetric{
+			metric("connections", "Number of connections managed by the pool", prometheus.GaugeValue,
+				func(s PoolStats) int64 { return s.TotalConnections }),
+			metric("idle_connections", "Number of idle connections in the pool", prometheus.GaugeValue,
+				func(s PoolStats) int64 { return s.IdleConnections }),
+			metric("active_connections", "Number of connections currently in use", prometheus.GaugeValue,
+				func(s PoolStats) int64 { return s.ActiveConnections }),
+			metric("acquisitions_total", "Total number of connection acquisitions", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.Acquisitions }),
+			metric("returns_total", "Total number of connection returns", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.Returns }),
+			metric("creations_total", "Total number of connections opened", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.Creations }),
+			metric("creation_failures_total", "Total number of failed connection attempts", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.CreationFailures }),
+			metric("closures_total", "Total number of connections closed", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.Closures }),
+			metric("timeouts_total", "Total number of acquisitions that timed out", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.Timeouts }),
+			metric("validation_failures_total", "Total number of connections that failed validation", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.ValidationFailures }),
+			metric("health_checks_total", "Total number of background health checks", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.HealthChecks }),
+			metric("exhaustions_total", "Total number of times the pool was exhausted", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.Exhaustions }),
+			metric("expirations_total", "Total number of connections closed for exceeding their lifetime", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.Expirations }),
+			metric("evictions_total", "Total number of connections closed for idling", prometheus.CounterValue,
+				func(s PoolStats) int64 { return s.Evictions }),
+		},
+	}
+}
+
+// PoolMetrics returns the collector exporting every pool created with
+// NewPool. It is registered with the default Prometheus registry when the
+// first pool is created; register it elsewhere for a custom registry.
+func PoolMetrics() *PoolCollector {
+	return poolMetrics
+}
+
+// Describe implements prometheus.Collector
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range c.metrics {
+		ch <- metric.desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.RLock()
+	pools := make([]poolStatsSource, 0, len(c.pools))
+	for _, pool := range c.pools {
+		pools = append(pools, pool)
+	}
+	c.mutex.RUnlock()
+
+	for _, pool := range pools {
+		stats := pool.Stats()
+		for _, metric := range c.metrics {
+			ch <- prometheus.MustNewConstMetric(metric.desc, metric.valueType, float64(metric.value(stats)), pool.Name())
+		}
+	}
+}
+
+// add starts exporting a pool, replacing any pool with the same name
+func (c *PoolCollector) add(pool poolStatsSource) {
+	if c == poolMetrics {
+		poolMetricsRegister.Do(func() {
+			prometheus.Register(c)
+		})
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pools[pool.Name()] = pool
+}
+
+// remove stops exporting a pool
+func (c *PoolCollector) remove(pool poolStatsSource) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pools[pool.Name()] == pool {
+		delete(c.pools, pool.Name())
+	}
+}
@@ -0,0 +1,280 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// testConn is a pooled connection that can be marked broken
+type testConn struct {
+	id     int
+	broken bool
+	closed bool
+}
+
+// testConns creates numbered connections and records their state
+type testConns struct {
+	created []*testConn
+	mutex   sync.Mutex
+}
+
+func (c *testConns) hooks() PoolHooks[*testConn] {
+	return PoolHooks[*testConn]{
+		Create: func(ctx context.Context) (*testConn, error) {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+			conn := &testConn{id: len(c.created) + 1}
+			c.created = append(c.created, conn)
+			return conn, nil
+		},
+		Validate: func(ctx context.Context, conn *testConn) error {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+			if conn.broken {
+				return errors.New("broken")
+			}
+			return nil
+		},
+		Close: func(conn *testConn) error {
+			c.mutex.Lock()
+			defer c.mutex.Unlock()
+			conn.closed = true
+			return nil
+		},
+	}
+}
+
+func (c *testConns) breakConn(conn *testConn) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	conn.broken = true
+}
+
+func (c *testConns) isClosed(conn *testConn) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return conn.closed
+}
+
+func TestPoolOrdering(t *testing.T) {
+	for _, ordering := range []PoolOrdering{PoolLIFO, PoolFIFO} {
+		conns := &testConns{}
+		config := DefaultPoolConfig()
+		config.MinIdle = 3
+		config.Ordering = ordering
+		pool := NewPool("ordering-"+string(ordering), config, conns.hooks())
+
+		assert.NoError(t, pool.Warmup(context.Background()))
+		assert.Equal(t, int64(3), pool.Stats().IdleConnections)
+
+		// Connections come back in the order 1, 2, 3
+		for i := 0; i < 3; i++ {
+			conn, err := pool.Acquire(context.Background())
+			assert.NoError(t, err)
+			pool.Return(conn)
+		}
+
+		conn, err := pool.Acquire(context.Background())
+		assert.NoError(t, err)
+		if ordering == PoolLIFO {
+			assert.Equal(t, conns.created[2], conn, "LIFO reuses the most recently returned")
+		} else {
+			assert.Equal(t, conns.created[0], conn, "FIFO hands out the least recently returned")
+		}
+		pool.Return(conn)
+		pool.Close()
+	}
+}
+
+func TestPoolWaitAndTimeout(t *testing.T) {
+	conns := &testConns{}
+	config := DefaultPoolConfig()
+	config.MaxConnections = 1
+	config.AcquireTimeout = 50 * time.Millisecond
+	pool := NewPool("wait", config, conns.hooks())
+	defer pool.Close()
+
+	conn, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+
+	_, err = pool.Acquire(context.Background())
+	assert.ErrorIs(t, err, ErrAcquireTimeout)
+
+	// A waiter gets the connection once it is returned
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pool.Return(conn)
+	}()
+	again, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, conn, again)
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(1), stats.Creations)
+	assert.Equal(t, int64(1), stats.Timeouts)
+	assert.Equal(t, int64(2), stats.Exhaustions)
+}
+
+func TestPoolSharedConnections(t *testing.T) {
+	conns := &testConns{}
+	config := DefaultPoolConfig()
+	config.MaxConnections = 2
+	config.MaxConcurrentUses = 2
+	config.AcquireTimeout = 50 * time.Millisecond
+	pool := NewPool("shared", config, conns.hooks())
+	defer pool.Close()
+
+	// Each connection is shared by two callers before a third must wait
+	held := make([]*testConn, 0, 4)
+	for i := 0; i < 4; i++ {
+		conn, err := pool.Acquire(context.Background())
+		assert.NoError(t, err)
+		held = append(held, conn)
+	}
+	assert.Len(t, conns.created, 2)
+
+	_, err := pool.Acquire(context.Background())
+	assert.ErrorIs(t, err, ErrAcquireTimeout)
+
+	pool.Return(held[0])
+	_, err = pool.Acquire(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestPoolMaxLifetime(t *testing.T) {
+	conns := &testConns{}
+	config := DefaultPoolConfig()
+	config.MaxLifetime = 30 * time.Millisecond
+	config.MaxConcurrentUses = 2
+	pool := NewPool("lifetime", config, conns.hooks())
+	defer pool.Close()
+
+	first, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+	second, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	time.Sleep(40 * time.Millisecond)
+
+	// An expired connection is closed once its last user returns it
+	pool.Return(first)
+	assert.False(t, conns.isClosed(first))
+	pool.Return(second)
+	assert.True(t, conns.isClosed(first))
+
+	conn, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, conn)
+	assert.Equal(t, int64(1), pool.Stats().Expirations)
+}
+
+func TestPoolDiscardSharedConnection(t *testing.T) {
+	conns := &testConns{}
+	config := DefaultPoolConfig()
+	config.MaxConcurrentUses = 2
+	pool := NewPool("discard", config, conns.hooks())
+	defer pool.Close()
+
+	shared, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+
+	acquired := make(chan struct{})
+	discarded := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := pool.Acquire(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, shared, conn)
+		close(acquired)
+
+		// The connection stays open while this user still holds it
+		<-discarded
+		assert.False(t, conns.isClosed(conn))
+		pool.Return(conn)
+	}()
+	<-acquired
+
+	// One user finds the connection broken while the other is using it
+	conns.breakConn(shared)
+	pool.Discard(shared)
+	assert.False(t, conns.isClosed(shared))
+
+	// It is no longer handed out
+	other, err := pool.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.NotEqual(t, shared, other)
+
+	close(discarded)
+	<-done
+	assert.True(t, conns.isClosed(shared), "closed on the last user's return")
+
+	// A connection with no other users is closed right away
+	pool.Discard(other)
+	assert.True(t, conns.isClosed(other))
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(2), stats.Closures)
+	assert.Zero(t, stats.TotalConnections)
+}
+
+func TestPoolHealthProbe(t *testing.T) {
+	conns := &testConns{}
+	config := DefaultPoolConfig()
+	config.MinIdle = 2
+	config.HealthCheckInterval = 20 * time.Millisecond
+	pool := NewPool("health", config, conns.hooks())
+	defer pool.Close()
+
+	assert.NoError(t, pool.Warmup(context.Background()))
+	broken := conns.created[0]
+	conns.breakConn(broken)
+
+	// The probe closes the broken connection and warmup replaces it
+	assert.Eventually(t, func() bool {
+		stats := pool.Stats()
+		return conns.isClosed(broken) && stats.IdleConnections == 2 && stats.Creations == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), pool.Stats().ValidationFailures)
+}
+
+func TestPoolMetrics(t *testing.T) {
+	conns := &testConns{}
+	config := DefaultPoolConfig()
+	config.MinIdle = 2
+	pool := NewPool("metrics", config, conns.hooks())
+	assert.NoError(t, pool.Warmup(context.Background()))
+
+	collector := NewPoolCollector()
+	collector.add(pool)
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, registry.Register(collector))
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	values := make(map[string]float64)
+	for _, family := range families {
+		metric := family.GetMetric()[0]
+		assert.Equal(t, "metrics", metric.GetLabel()[0].GetValue())
+		if metric.GetGauge() != nil {
+			values[family.GetName()] = metric.GetGauge().GetValue()
+		} else {
+			values[family.GetName()] = metric.GetCounter().GetValue()
+		}
+	}
+	assert.Equal(t, float64(2), values["bridge_pool_idle_connections"])
+	assert.Equal(t, float64(2), values["bridge_pool_creations_total"])
+
+	pool.Close()
+	collector.remove(pool)
+	families, err = registry.Gather()
+	assert.NoError(t, err)
+	assert.Empty(t, families)
+}
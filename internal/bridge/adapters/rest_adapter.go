@@ -3,7 +3,6 @@
 package adapters
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -36,9 +35,12 @@ type RESTAdapterConfig struct {
 	DefaultEndpoint string
 	RetryCount      int
 	RetryDelay      time.Duration
+	RetryPolicy     RESTRetryPolicy
 	MaxConnections  int
 	KeepAlive       time.Duration
 	TLSSkipVerify   bool
+	TLS             RESTTLSConfig
+	Auth            RESTAuthenticator
 }
 
 // DefaultRESTAdapterConfig returns the default configuration
@@ -48,6 +50,7 @@ func DefaultRESTAdapterConfig() *RESTAdapterConfig {
 		Headers:        make(map[string]string),
 		RetryCount:     3,
 		RetryDelay:     time.Second,
+		RetryPolicy:    DefaultRESTRetryPolicy(),
 		MaxConnections: 10,
 		KeepAlive:      60 * time.Second,
 	}
@@ -91,11 +94,18 @@ func NewRESTAdapter(name string, config *RESTAdapterConfig, metricsCollector *me
 func (a *RESTAdapter) Initialize(ctx context.Context) error {
 	a.logger.Info(fmt.Sprintf("Initializing REST adapter '%s'", a.name), nil)
 
+	tlsConfig, err := buildTLSConfig(a.config)
+	if err != nil {
+		return err
+	}
+
 	// Create HTTP transport with custom settings
 	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
 		MaxIdleConns:        a.config.MaxConnections,
 		MaxIdleConnsPerHost: a.config.MaxConnections,
 		IdleConnTimeout:     a.config.KeepAlive,
+		TLSClientConfig:     tlsConfig,
 	}
 
 	// Create HTTP client
@@ -104,6 +114,11 @@ func (a *RESTAdapter) Initialize(ctx context.Context) error {
 		Transport: transport,
 	}
 
+	// Token requests go through the same client, so they also present the client certificate
+	if oauth, ok := a.config.Auth.(*OAuth2ClientCredentials); ok {
+		oauth.useClient(a.client)
+	}
+
 	a.initialized = true
 	return nil
 }
@@ -146,37 +161,34 @@ func (a *RESTAdapter) Send(ctx context.Context, data []byte) ([]byte, error) {
 		requestData.Endpoint = a.config.DefaultEndpoint
 	}
 
-	// Construct full URL
-	url := a.config.BaseURL
+	// Construct full URL, honouring a per-call endpoint from service discovery
+	url := resolveBaseURL(ctx, a.config.BaseURL)
 	if url[len(url)-1] != '/' && requestData.Endpoint[0] != '/' {
 		url += "/"
 	}
 	url += requestData.Endpoint
 
 	// Create request
-	var reqBody io.Reader
+	var reqBody []byte
 	if len(requestData.Body) > 0 && requestData.Body[0] != 'n' { // Check if not "null"
-		reqBody = bytes.NewReader(requestData.Body)
+		reqBody = requestData.Body
 	}
 
-	req, err := http.NewRequestWithContext(ctx, requestData.Method, url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+	header := make(http.Header)
 
 	// Add default headers
 	for key, value := range a.config.Headers {
-		req.Header.Set(key, value)
+		header.Set(key, value)
 	}
 
 	// Add request-specific headers
 	for key, value := range requestData.Headers {
-		req.Header.Set(key, value)
+		header.Set(key, value)
 	}
 
 	// Set content type if not already set
-	if req.Header.Get("Content-Type") == "" && reqBody != nil {
-		req.Header.Set("Content-Type", "application/json")
+	if header.Get("Content-Type") == "" && reqBody != nil {
+		header.Set("Content-Type", "application/json")
 	}
 
 	// Register active request
@@ -194,43 +206,10 @@ func (a *RESTAdapter) Send(ctx context.Context, data []byte) ([]byte, error) {
 		a.requestsMutex.Unlock()
 	}()
 
-	// Execute request with retry logic
-	var response *http.Response
-	var lastErr error
-	startTime := time.Now()
-
-	for attempt := 0; attempt <= a.config.RetryCount; attempt++ {
-		if attempt > 0 {
-			select {
-			case <-requestCtx.Done():
-				return nil, requestCtx.Err()
-			case <-time.After(a.config.RetryDelay):
-				// Continue with retry
-			}
-		}
-
-		response, err = a.client.Do(req)
-		if err == nil {
-			break
-		}
-
-		lastErr = err
-		a.logger.Warn(fmt.Sprintf("REST request failed (attempt %d/%d): %v",
-			attempt+1, a.config.RetryCount+1, err), map[string]interface{}{
-			"url":     url,
-			"method":  requestData.Method,
-			"adapter": a.name,
-		})
-	}
-
-	if response == nil {
-		return nil, fmt.Errorf("request failed after %d attempts: %w", a.config.RetryCount+1, lastErr)
-	}
-
-	// Record metrics
-	if a.metricsCollector != nil {
-		duration := time.Since(startTime).Seconds()
-		a.metricsCollector.RecordHTTP(requestData.Method, url, response.StatusCode, duration)
+	// Execute request under the retry policy
+	response, err := a.doWithRetry(requestCtx, requestData.Method, url, reqBody, header)
+	if err != nil {
+		return nil, err
 	}
 
 	// Read response body
@@ -240,12 +219,6 @@ func (a *RESTAdapter) Send(ctx context.Context, data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Check for error status codes
-	if response.StatusCode >= 400 {
-		return nil, fmt.Errorf("request failed with status code %d: %s",
-			response.StatusCode, string(respBody))
-	}
-
 	// Create response data
 	responseData := struct {
 		StatusCode int               `json:"status_code"`
@@ -319,15 +292,9 @@ func (a *RESTAdapter) Config() map[string]interface{} {
 		"base_url":    a.config.BaseURL,
 		"timeout":     a.config.Timeout.String(),
 		"retry":       a.config.RetryCount,
+		"retry_on":    a.config.RetryPolicy.RetryableStatuses,
 		"connections": a.config.MaxConnections,
+		"mtls":        a.config.TLS.CertFile != "",
+		"auth":        a.config.Auth != nil,
 	}
 }
-
-// Extend metrics.Collector with required methods for the adapter
-// These should be implemented properly in the metrics package
-func init() {
-	// This registration will be moved to a central adapter registry implementation
-	// RegisterAdapterFactory("rest", func(config AdapterConfig) (Adapter, error) {
-	//     // Implementation will go here once the AdapterFactory and AdapterConfig are properly defined
-	// })
-}
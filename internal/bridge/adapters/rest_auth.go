@@ -0,0 +1,358 @@
+// rest_auth.go - OAuth2 client-credentials and mutual TLS authentication for the REST adapter
+
+package adapters
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Common REST authentication errors
+var (
+	ErrTokenFetchFailed = errors.New("failed to fetch access token")
+	ErrInvalidTLSConfig = errors.New("invalid TLS configuration")
+)
+
+// RESTAuthenticator adds credentials to outgoing REST requests
+type RESTAuthenticator interface {
+	// Authenticate sets the credentials on the request
+	Authenticate(ctx context.Context, req *http.Request) error
+	// Invalidate discards the credentials used by a request the server
+	// rejected, so the next Authenticate obtains fresh ones
+	Invalidate(req *http.Request)
+}
+
+// OAuth2Config contains configuration for the OAuth2 client-credentials grant
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	Audience     string
+	// Params are extra form parameters sent to the token endpoint
+	Params map[string]string
+	// AuthInBody sends the client credentials as form parameters instead of
+	// HTTP Basic authentication
+	AuthInBody bool
+	// RefreshBefore is how long before expiry a token is refreshed in the background
+	RefreshBefore time.Duration
+	// HTTPClient fetches tokens; when nil the REST adapter's client is used,
+	// so token requests share its TLS configuration
+	HTTPClient *http.Client
+}
+
+// OAuth2Error is returned by a token endpoint that rejects the request
+type OAuth2Error struct {
+	StatusCode  int
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// Error implements the error interface
+func (e *OAuth2Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("token endpoint returned %d %s: %s", e.StatusCode, e.Code, e.Description)
+	}
+	return fmt.Sprintf("token endpoint returned %d %s", e.StatusCode, e.Code)
+}
+
+// oauth2Token is a cached access token
+type oauth2Token struct {
+	AccessToken string
+	TokenType   string
+	Expiry      time.Time // zero if the server gave no lifetime
+}
+
+// valid reports whether the token can still be used at the given time
+func (t *oauth2Token) valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Before(t.Expiry))
+}
+
+// OAuth2ClientCredentials authenticates requests with bearer tokens from the
+// client-credentials grant. Tokens are cached until they expire and refreshed
+// in the background once they come within RefreshBefore of expiry.
+type OAuth2ClientCredentials struct {
+	config       OAuth2Config
+	token        *oauth2Token
+	refreshing   bool
+	fetchMutex   sync.Mutex // serializes token endpoint requests
+	tokenMutex   sync.RWMutex
+	clientMutex  sync.RWMutex
+	fetchTimeout time.Duration
+}
+
+// NewOAuth2ClientCredentials creates a client-credentials authenticator
+func NewOAuth2ClientCredentials(config OAuth2Config) (*OAuth2ClientCredentials, error) {
+	if config.TokenURL == "" {
+		return nil, fmt.Errorf("token URL cannot be empty")
+	}
+	if config.ClientID == "" {
+		return nil, fmt.Errorf("client ID cannot be empty")
+	}
+	if config.RefreshBefore <= 0 {
+		config.RefreshBefore = 30 * time.Second
+	}
+
+	return &OAuth2ClientCredentials{
+		config:       config,
+		fetchTimeout: 30 * time.Second,
+	}, nil
+}
+
+// Authenticate sets a bearer token on the request
+func (o *OAuth2ClientCredentials) Authenticate(ctx context.Context, req *http.Request) error {
+	token, err := o.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	req.Header.Set("Authorization", tokenType+" "+token.AccessToken)
+	return nil
+}
+
+// Invalidate discards the cached token if the request carried it; a token
+// already replaced by a concurrent refresh is kept
+func (o *OAuth2ClientCredentials) Invalidate(req *http.Request) {
+	o.tokenMutex.Lock()
+	defer o.tokenMutex.Unlock()
+
+	if o.token != nil && strings.HasSuffix(req.Header.Get("Authorization"), " "+o.token.AccessToken) {
+		o.token = nil
+	}
+}
+
+// Token returns a valid access token, fetching one if the cache is empty or expired
+func (o *OAuth2ClientCredentials) Token(ctx context.Context) (*oauth2Token, error) {
+	now := time.Now()
+
+	o.tokenMutex.Lock()
+	token := o.token
+	if token.valid(now) {
+		// Refresh ahead of expiry so callers never wait on the token endpoint
+		if !token.Expiry.IsZero() && token.Expiry.Sub(now) < o.config.RefreshBefore && !o.refreshing {
+			o.refreshing = true
+			go o.refresh()
+		}
+		o.tokenMutex.Unlock()
+		return token, nil
+	}
+	o.tokenMutex.Unlock()
+
+	return o.fetch(ctx, token)
+}
+
+// refresh fetches a new token in the background
+func (o *OAuth2ClientCredentials) refresh() {
+	defer func() {
+		o.tokenMutex.Lock()
+		o.refreshing = false
+		o.tokenMutex.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.fetchTimeout)
+	defer cancel()
+
+	o.tokenMutex.RLock()
+	stale := o.token
+	o.tokenMutex.RUnlock()
+
+	// On failure the cached token stays in use until it expires
+	o.fetch(ctx, stale)
+}
+
+// fetch requests a token from the token endpoint unless another caller has
+// already replaced the stale one
+func (o *OAuth2ClientCredentials) fetch(ctx context.Context, stale *oauth2Token) (*oauth2Token, error) {
+	o.fetchMutex.Lock()
+	defer o.fetchMutex.Unlock()
+
+	o.tokenMutex.RLock()
+	current := o.token
+	o.tokenMutex.RUnlock()
+	if current != stale && current.valid(time.Now()) {
+		return current, nil
+	}
+
+	token, err := o.requestToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	o.tokenMutex.Lock()
+	o.token = token
+	o.tokenMutex.Unlock()
+
+	return token, nil
+}
+
+// requestToken performs the client-credentials grant
+func (o *OAuth2ClientCredentials) requestToken(ctx context.Context) (*oauth2Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(o.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.config.Scopes, " "))
+	}
+	if o.config.Audience != "" {
+		form.Set("audience", o.config.Audience)
+	}
+	for key, value := range o.config.Params {
+		form.Set(key, value)
+	}
+	if o.config.AuthInBody {
+		form.Set("client_id", o.config.ClientID)
+		form.Set("client_secret", o.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenFetchFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !o.config.AuthInBody {
+		req.SetBasicAuth(url.QueryEscape(o.config.ClientID), url.QueryEscape(o.config.ClientSecret))
+	}
+
+	requestTime := time.Now()
+	response, err := o.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenFetchFailed, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenFetchFailed, err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		oauthErr := &OAuth2Error{StatusCode: response.StatusCode}
+		if json.Unmarshal(body, oauthErr) != nil || oauthErr.Code == "" {
+			oauthErr.Code = http.StatusText(response.StatusCode)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrTokenFetchFailed, oauthErr)
+	}
+
+	var tokenResponse struct {
+		AccessToken string          `json:"access_token"`
+		TokenType   string          `json:"token_type"`
+		ExpiresIn   json.RawMessage `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return nil, fmt.Errorf("%w: invalid token response: %v", ErrTokenFetchFailed, err)
+	}
+	if tokenResponse.AccessToken == "" {
+		return nil, fmt.Errorf("%w: token response has no access_token", ErrTokenFetchFailed)
+	}
+
+	token := &oauth2Token{
+		AccessToken: tokenResponse.AccessToken,
+		TokenType:   tokenResponse.TokenType,
+	}
+
+	// Some servers send expires_in as a string
+	if expiresIn := strings.Trim(string(tokenResponse.ExpiresIn), `"`); expiresIn != "" && expiresIn != "null" {
+		seconds, err := strconv.ParseInt(expiresIn, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid expires_in %q", ErrTokenFetchFailed, expiresIn)
+		}
+		if seconds > 0 {
+			// Measure from the request so the token never outlives the server's view
+			token.Expiry = requestTime.Add(time.Duration(seconds) * time.Second)
+		}
+	}
+
+	return token, nil
+}
+
+// httpClient returns the client used for token requests
+func (o *OAuth2ClientCredentials) httpClient() *http.Client {
+	o.clientMutex.RLock()
+	defer o.clientMutex.RUnlock()
+
+	if o.config.HTTPClient != nil {
+		return o.config.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// useClient sets the token client if none was configured
+func (o *OAuth2ClientCredentials) useClient(client *http.Client) {
+	o.clientMutex.Lock()
+	defer o.clientMutex.Unlock()
+
+	if o.config.HTTPClient == nil {
+		o.config.HTTPClient = client
+	}
+}
+
+// RESTTLSConfig configures server verification and client certificates for mutual TLS
+type RESTTLSConfig struct {
+	CertFile   string // client certificate, PEM
+	KeyFile    string // client private key, PEM
+	CAFile     string // CA bundle used instead of the system roots, PEM
+	ServerName string
+	MinVersion uint16
+}
+
+// enabled reports whether any TLS setting was configured
+func (c *RESTTLSConfig) enabled() bool {
+	return c.CertFile != "" || c.KeyFile != "" || c.CAFile != "" || c.ServerName != "" || c.MinVersion != 0
+}
+
+// buildTLSConfig creates the transport TLS configuration from the adapter config
+func buildTLSConfig(config *RESTAdapterConfig) (*tls.Config, error) {
+	if !config.TLS.enabled() && !config.TLSSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         config.TLS.ServerName,
+		InsecureSkipVerify: config.TLSSkipVerify,
+	}
+	if config.TLS.MinVersion != 0 {
+		tlsConfig.MinVersion = config.TLS.MinVersion
+	}
+
+	if config.TLS.CertFile != "" || config.TLS.KeyFile != "" {
+		if config.TLS.CertFile == "" || config.TLS.KeyFile == "" {
+			return nil, fmt.Errorf("%w: client certificate and key must be set together", ErrInvalidTLSConfig)
+		}
+		cert, err := tls.LoadX509KeyPair(config.TLS.CertFile, config.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTLSConfig, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if config.TLS.CAFile != "" {
+		caPEM, err := os.ReadFile(config.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTLSConfig, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("%w: no certificates found in %s", ErrInvalidTLSConfig, config.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,182 @@
+package adapters
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newQuietLogger returns a MockLogger accepting any log call
+func newQuietLogger() *MockLogger {
+	logger := &MockLogger{}
+	for _, level := range []string{"Debug", "Info", "Warn", "Error"} {
+		logger.On(level, mock.Anything, mock.Anything).Maybe()
+	}
+	return logger
+}
+
+// newTokenServer issues numbered tokens valid for expiresIn seconds
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int32) {
+	var issued int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, ok := r.BasicAuth()
+		if !ok || clientID != "bridge" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		assert.Equal(t, "client_credentials", r.FormValue("grant_type"))
+		assert.Equal(t, "read write", r.FormValue("scope"))
+
+		n := atomic.AddInt32(&issued, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token-" + string(rune('0'+n)),
+			"token_type":   "bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 3600)
+
+	// The API rejects the first token as revoked
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"authorization":"` + r.Header.Get("Authorization") + `"}`))
+	}))
+	defer api.Close()
+
+	auth, err := NewOAuth2ClientCredentials(OAuth2Config{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "bridge",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"read", "write"},
+	})
+	assert.NoError(t, err)
+
+	config := DefaultRESTAdapterConfig()
+	config.BaseURL = api.URL
+	config.Auth = auth
+	adapter, err := NewRESTAdapter("api", config, nil, newQuietLogger())
+	assert.NoError(t, err)
+	assert.NoError(t, adapter.Initialize(context.Background()))
+
+	// A 401 fetches a fresh token and repeats the request once
+	response, err := adapter.Send(context.Background(), []byte(`{"method":"POST","endpoint":"orders"}`))
+	assert.NoError(t, err)
+	assert.Contains(t, string(response), "Bearer token-2")
+	assert.Equal(t, int32(2), atomic.LoadInt32(issued))
+
+	// The cached token is reused
+	_, err = adapter.Send(context.Background(), []byte(`{"endpoint":"orders"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(issued))
+}
+
+func TestOAuth2ProactiveRefresh(t *testing.T) {
+	tokenServer, issued := newTokenServer(t, 1)
+
+	auth, err := NewOAuth2ClientCredentials(OAuth2Config{
+		TokenURL:      tokenServer.URL,
+		ClientID:      "bridge",
+		ClientSecret:  "s3cret",
+		Scopes:        []string{"read", "write"},
+		RefreshBefore: 900 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	first, err := auth.Token(context.Background())
+	assert.NoError(t, err)
+
+	// Inside the refresh window the current token is returned while a new one is fetched
+	time.Sleep(200 * time.Millisecond)
+	current, err := auth.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, first.AccessToken, current.AccessToken)
+
+	assert.Eventually(t, func() bool {
+		token, _ := auth.Token(context.Background())
+		return token != nil && token.AccessToken != first.AccessToken
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, atomic.LoadInt32(issued), int32(2))
+}
+
+func TestOAuth2TokenEndpointError(t *testing.T) {
+	tokenServer, _ := newTokenServer(t, 3600)
+
+	auth, err := NewOAuth2ClientCredentials(OAuth2Config{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "bridge",
+		ClientSecret: "wrong",
+	})
+	assert.NoError(t, err)
+
+	_, err = auth.Token(context.Background())
+	assert.ErrorIs(t, err, ErrTokenFetchFailed)
+	assert.Contains(t, err.Error(), "invalid_client")
+}
+
+func TestRESTAdapterMutualTLS(t *testing.T) {
+	api := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"client_certificates":` + strconv.Itoa(len(r.TLS.PeerCertificates)) + `}`))
+	}))
+	api.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	api.StartTLS()
+	defer api.Close()
+
+	// The test server's certificate doubles as the CA and the client certificate
+	dir := t.TempDir()
+	serverCert := api.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(serverCert.PrivateKey)
+	assert.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "cert.pem"), "CERTIFICATE", serverCert.Certificate[0])
+	writePEM(t, filepath.Join(dir, "key.pem"), "PRIVATE KEY", key)
+
+	config := DefaultRESTAdapterConfig()
+	config.BaseURL = api.URL
+	config.RetryCount = 0
+	config.TLS = RESTTLSConfig{CAFile: filepath.Join(dir, "cert.pem")}
+	adapter, err := NewRESTAdapter("api", config, nil, newQuietLogger())
+	assert.NoError(t, err)
+	assert.NoError(t, adapter.Initialize(context.Background()))
+
+	// The CA is trusted, but the server refuses a handshake without a client certificate
+	_, err = adapter.Send(context.Background(), []byte(`{"endpoint":"status"}`))
+	assert.Error(t, err)
+
+	config.TLS.CertFile = filepath.Join(dir, "cert.pem")
+	config.TLS.KeyFile = filepath.Join(dir, "key.pem")
+	assert.NoError(t, adapter.Initialize(context.Background()))
+
+	response, err := adapter.Send(context.Background(), []byte(`{"endpoint":"status"}`))
+	assert.NoError(t, err)
+	assert.Contains(t, string(response), `"client_certificates":1`)
+
+	config.TLS.KeyFile = filepath.Join(dir, "missing.pem")
+	assert.ErrorIs(t, adapter.Initialize(context.Background()), ErrInvalidTLSConfig)
+}
+
+// writePEM writes a single PEM block to a file
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	assert.NoError(t, os.WriteFile(path, data, 0600))
+}
@@ -0,0 +1,373 @@
+// rest_retry.go - Retry policy, idempotency keys and Retry-After handling for the REST adapter
+
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultIdempotencyHeader is the header carrying the idempotency key of a logical call
+const DefaultIdempotencyHeader = "Idempotency-Key"
+
+// maxErrorBodySize bounds how much of a failed response body is kept in errors
+const maxErrorBodySize = 4096
+
+// idempotencyKeyContextKey is the context key for the idempotency key of a logical call
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey returns a context naming the logical call a send belongs
+// to. Adapters that deduplicate retries use it as the idempotency key, so a
+// call resent by the bridge after the adapter gave up keeps the same key.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key stored in the context, if any
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// RESTRetryPolicy decides which REST requests are retried and when
+type RESTRetryPolicy struct {
+	// RetryableStatuses are the response codes worth retrying; every other
+	// error status fails the call immediately
+	RetryableStatuses []int
+	BackoffFactor     float64
+	Jitter            float64
+	// MaxDelay caps the backoff; a Retry-After longer than this ends the call
+	MaxDelay time.Duration
+	// AutoIdempotencyKey adds a key to non-idempotent requests that lack one,
+	// making them safe to retry against servers that deduplicate on it. The
+	// key of the context's logical call is used when there is one.
+	AutoIdempotencyKey bool
+	IdempotencyHeader  string
+}
+
+// DefaultRESTRetryPolicy returns the default retry policy
+func DefaultRESTRetryPolicy() RESTRetryPolicy {
+	return RESTRetryPolicy{
+		RetryableStatuses: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		BackoffFactor:      2.0,
+		Jitter:             0.2,
+		MaxDelay:           30 * time.Second,
+		AutoIdempotencyKey: true,
+		IdempotencyHeader:  DefaultIdempotencyHeader,
+	}
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func (p *RESTRetryPolicy) retryableStatus(code int) bool {
+	statuses := p.RetryableStatuses
+	if statuses == nil {
+		statuses = DefaultRESTRetryPolicy().RetryableStatuses
+	}
+	for _, status := range statuses {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// idempotencyHeader returns the configured idempotency header name
+func (p *RESTRetryPolicy) idempotencyHeader() string {
+	if p.IdempotencyHeader == "" {
+		return DefaultIdempotencyHeader
+	}
+	return p.IdempotencyHeader
+}
+
+// backoff returns the jittered exponential delay before the given retry attempt
+func (p *RESTRetryPolicy) backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	factor := p.BackoffFactor
+	if factor < 1 {
+		factor = 1
+	}
+
+	delay := float64(base) * pow(factor, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	if jitter := p.Jitter; jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay = delay * (1 - jitter + 2*jitter*rand())
+	}
+
+	return time.Duration(delay)
+}
+
+// isIdempotentMethod reports whether repeating a request with the method is safe
+func isIdempotentMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			seconds = 0
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// RESTAttempt records the outcome of one attempt of a REST call
+type RESTAttempt struct {
+	Attempt    int
+	StatusCode int // zero when no response was received
+	Err        error
+	Duration   time.Duration
+	RetryAfter time.Duration // as requested by the server
+	Delay      time.Duration // wait before the next attempt
+}
+
+// String summarizes the attempt
+func (a RESTAttempt) String() string {
+	var outcome string
+	switch {
+	case a.StatusCode != 0:
+		outcome = fmt.Sprintf("status %d", a.StatusCode)
+	case a.Err != nil:
+		// The request URL is already part of RESTRequestError
+		outcome = a.Err.Error()
+		var urlErr *neturl.Error
+		if errors.As(a.Err, &urlErr) {
+			outcome = urlErr.Err.Error()
+		}
+	default:
+		outcome = "ok"
+	}
+
+	summary := fmt.Sprintf("#%d %s in %s", a.Attempt, outcome, a.Duration.Round(time.Millisecond))
+	if a.RetryAfter > 0 {
+		summary += fmt.Sprintf(", retry after %s", a.RetryAfter)
+	}
+	return summary
+}
+
+// RESTStatusError is returned for an error response status
+type RESTStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface
+func (e *RESTStatusError) Error() string {
+	return fmt.Sprintf("request failed with status code %d: %s", e.StatusCode, e.Body)
+}
+
+// RESTRequestError is returned when a REST call fails, carrying every attempt made
+type RESTRequestError struct {
+	Method   string
+	URL      string
+	Attempts []RESTAttempt
+	// Retryable is false when the failure would recur on retry, e.g. a fatal
+	// status or a non-idempotent request without an idempotency key
+	Retryable bool
+	// Exhausted is true when the call used up the adapter's retry budget
+	Exhausted bool
+}
+
+// Error implements the error interface
+func (e *RESTRequestError) Error() string {
+	history := make([]string, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		history[i] = attempt.String()
+	}
+
+	return fmt.Sprintf("%s %s failed after %d attempt(s): %v [%s]",
+		e.Method, e.URL, len(e.Attempts), e.Unwrap(), strings.Join(history, "; "))
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RESTRequestError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// Temporary reports whether the call may succeed if made again later. A call
+// that already used up the retry budget is not temporary, so callers that
+// retry themselves don't multiply the attempts.
+func (e *RESTRequestError) Temporary() bool {
+	return e.Retryable && !e.Exhausted
+}
+
+// StatusCode returns the status of the last response, or zero if none was received
+func (e *RESTRequestError) StatusCode() int {
+	if len(e.Attempts) == 0 {
+		return 0
+	}
+	return e.Attempts[len(e.Attempts)-1].StatusCode
+}
+
+// doWithRetry executes a request under the retry policy. It returns the first
+// successful response; error responses are read, closed and reported in a
+// *RESTRequestError along with the attempt history.
+func (a *RESTAdapter) doWithRetry(ctx context.Context, method, url string, body []byte, header http.Header) (*http.Response, error) {
+	policy := &a.config.RetryPolicy
+
+	// One key per logical call, reused by every attempt
+	keyHeader := policy.idempotencyHeader()
+	if !isIdempotentMethod(method) && header.Get(keyHeader) == "" && policy.AutoIdempotencyKey {
+		key, ok := IdempotencyKeyFromContext(ctx)
+		if !ok {
+			key = uuid.New().String()
+		}
+		header.Set(keyHeader, key)
+	}
+	canRetry := isIdempotentMethod(method) || header.Get(keyHeader) != ""
+
+	failure := &RESTRequestError{Method: method, URL: url}
+	retries := 0
+	reauthenticated := false
+
+	for attempt := 1; ; attempt++ {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header = header.Clone()
+
+		if a.config.Auth != nil {
+			if err := a.config.Auth.Authenticate(ctx, req); err != nil {
+				return nil, fmt.Errorf("failed to authenticate request: %w", err)
+			}
+		}
+
+		record := RESTAttempt{Attempt: attempt}
+		start := time.Now()
+		response, err := a.client.Do(req)
+		record.Duration = time.Since(start)
+
+		retryable := false
+		if err != nil {
+			record.Err = err
+			retryable = ctx.Err() == nil
+		} else {
+			record.StatusCode = response.StatusCode
+
+			// Record metrics
+			if a.metricsCollector != nil {
+				a.metricsCollector.RecordHTTP(method, url, response.StatusCode, record.Duration.Seconds())
+			}
+
+			if response.StatusCode < 400 {
+				return response, nil
+			}
+
+			respBody, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodySize))
+			response.Body.Close()
+
+			record.Err = &RESTStatusError{StatusCode: response.StatusCode, Body: string(respBody)}
+
+			// A rejected token is replaced and the request repeated once; the
+			// server did not act on it, so this is safe for any method
+			if response.StatusCode == http.StatusUnauthorized && a.config.Auth != nil && !reauthenticated {
+				reauthenticated = true
+				a.config.Auth.Invalidate(req)
+				failure.Attempts = append(failure.Attempts, record)
+				continue
+			}
+
+			retryable = policy.retryableStatus(response.StatusCode)
+			if delay, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now()); ok {
+				record.RetryAfter = delay
+			}
+		}
+
+		failure.Retryable = canRetry && retryable
+		if !failure.Retryable || retries >= a.config.RetryCount {
+			failure.Exhausted = failure.Retryable
+			failure.Attempts = append(failure.Attempts, record)
+			return nil, failure
+		}
+
+		retries++
+		delay := policy.backoff(a.config.RetryDelay, retries)
+		if record.RetryAfter > 0 {
+			// Retrying before the server asked would only be refused again
+			if policy.MaxDelay > 0 && record.RetryAfter > policy.MaxDelay {
+				failure.Attempts = append(failure.Attempts, record)
+				return nil, failure
+			}
+			delay = record.RetryAfter
+		}
+
+		// Don't start a retry that cannot complete before the deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			failure.Attempts = append(failure.Attempts, record)
+			return nil, failure
+		}
+
+		record.Delay = delay
+		failure.Attempts = append(failure.Attempts, record)
+
+		a.logger.Warn(fmt.Sprintf("REST request failed (retry %d/%d): %v",
+			retries, a.config.RetryCount, record.Err), map[string]interface{}{
+			"url":     url,
+			"method":  method,
+			"adapter": a.name,
+			"delay":   delay.String(),
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, failure
+		case <-timer.C:
+		}
+	}
+}
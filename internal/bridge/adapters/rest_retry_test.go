@@ -0,0 +1,251 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedRESTServer answers requests with the given statuses in turn, then
+// with 200, recording the idempotency key of every request
+type scriptedRESTServer struct {
+	*httptest.Server
+	statuses   []int
+	retryAfter string
+	keys       []string
+	mutex      sync.Mutex
+}
+
+func newScriptedRESTServer(t *testing.T, statuses ...int) *scriptedRESTServer {
+	s := &scriptedRESTServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		attempt := len(s.keys)
+		s.keys = append(s.keys, r.Header.Get(DefaultIdempotencyHeader))
+		s.mutex.Unlock()
+
+		if attempt < len(s.statuses) {
+			if s.retryAfter != "" {
+				w.Header().Set("Retry-After", s.retryAfter)
+			}
+			w.WriteHeader(s.statuses[attempt])
+			w.Write([]byte("unavailable"))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requestKeys returns the idempotency keys received so far
+func (s *scriptedRESTServer) requestKeys() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.keys...)
+}
+
+// newRetryingRESTAdapter creates an initialized adapter retrying quickly
+func newRetryingRESTAdapter(t *testing.T, baseURL string, retries int, policy RESTRetryPolicy) *RESTAdapter {
+	t.Helper()
+
+	config := DefaultRESTAdapterConfig()
+	config.BaseURL = baseURL
+	config.RetryCount = retries
+	config.RetryDelay = time.Millisecond
+	config.RetryPolicy = policy
+
+	adapter, err := NewRESTAdapter("api", config, nil, newQuietLogger())
+	require.NoError(t, err)
+	require.NoError(t, adapter.Initialize(context.Background()))
+	return adapter
+}
+
+func TestRESTRetryPolicyBackoff(t *testing.T) {
+	policy := RESTRetryPolicy{BackoffFactor: 2, MaxDelay: 500 * time.Millisecond}
+	for attempt, want := range []time.Duration{100, 200, 400, 500, 500} {
+		assert.Equal(t, want*time.Millisecond, policy.backoff(100*time.Millisecond, attempt+1), "attempt %d", attempt+1)
+	}
+	assert.Zero(t, policy.backoff(0, 3))
+
+	// A factor below one does not shrink the delay
+	policy.BackoffFactor = 0.5
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(100*time.Millisecond, 3))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 50; i++ {
+		delay := policy.backoff(100*time.Millisecond, 1)
+		assert.GreaterOrEqual(t, delay, 50*time.Millisecond)
+		assert.LessOrEqual(t, delay, 150*time.Millisecond)
+	}
+}
+
+func TestRESTRetryPolicyDefaults(t *testing.T) {
+	var policy RESTRetryPolicy
+	assert.True(t, policy.retryableStatus(http.StatusServiceUnavailable))
+	assert.False(t, policy.retryableStatus(http.StatusInternalServerError))
+	assert.Equal(t, DefaultIdempotencyHeader, policy.idempotencyHeader())
+
+	policy = RESTRetryPolicy{RetryableStatuses: []int{http.StatusInternalServerError}, IdempotencyHeader: "X-Request-Key"}
+	assert.True(t, policy.retryableStatus(http.StatusInternalServerError))
+	assert.False(t, policy.retryableStatus(http.StatusServiceUnavailable))
+	assert.Equal(t, "X-Request-Key", policy.idempotencyHeader())
+
+	for method, want := range map[string]bool{"get": true, "PUT": true, "DELETE": true, "POST": false, "PATCH": false} {
+		assert.Equal(t, want, isIdempotentMethod(method), method)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{" 5 ", 5 * time.Second, true},
+		{"-3", 0, true},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		delay, ok := parseRetryAfter(tt.value, now)
+		assert.Equal(t, tt.ok, ok, tt.value)
+		assert.Equal(t, tt.want, delay, tt.value)
+	}
+}
+
+func TestRESTRetryRecoversFromRetryableStatus(t *testing.T) {
+	server := newScriptedRESTServer(t, http.StatusServiceUnavailable, http.StatusBadGateway)
+	adapter := newRetryingRESTAdapter(t, server.URL, 3, DefaultRESTRetryPolicy())
+
+	response, err := adapter.doWithRetry(context.Background(), http.MethodPost, server.URL+"/orders", []byte(`{}`), http.Header{})
+	require.NoError(t, err)
+	response.Body.Close()
+
+	// Every attempt of the logical call carries the same generated key
+	keys := server.requestKeys()
+	require.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+}
+
+func TestRESTRetryIdempotencyKeyFromCall(t *testing.T) {
+	server := newScriptedRESTServer(t, 503, 503, 503, 503)
+	adapter := newRetryingRESTAdapter(t, server.URL, 1, DefaultRESTRetryPolicy())
+
+	// A resend of the same logical call keeps its key
+	ctx := WithIdempotencyKey(context.Background(), "msg-42")
+	for i := 0; i < 2; i++ {
+		_, err := adapter.doWithRetry(ctx, http.MethodPost, server.URL, []byte(`{}`), http.Header{})
+		assert.Error(t, err)
+	}
+	assert.Equal(t, []string{"msg-42", "msg-42", "msg-42", "msg-42"}, server.requestKeys())
+
+	// A key given in the request headers wins over the call's
+	header := http.Header{}
+	header.Set(DefaultIdempotencyHeader, "client-key")
+	response, err := adapter.doWithRetry(ctx, http.MethodPost, server.URL, []byte(`{}`), header)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, "client-key", server.requestKeys()[4])
+}
+
+func TestRESTRetryFailures(t *testing.T) {
+	t.Run("fatal status is not retried", func(t *testing.T) {
+		server := newScriptedRESTServer(t, http.StatusBadRequest)
+		adapter := newRetryingRESTAdapter(t, server.URL, 3, DefaultRESTRetryPolicy())
+
+		_, err := adapter.doWithRetry(context.Background(), http.MethodGet, server.URL, nil, http.Header{})
+		var failure *RESTRequestError
+		require.ErrorAs(t, err, &failure)
+		assert.Len(t, failure.Attempts, 1)
+		assert.False(t, failure.Temporary())
+		assert.Equal(t, http.StatusBadRequest, failure.StatusCode())
+
+		var status *RESTStatusError
+		require.ErrorAs(t, err, &status)
+		assert.Equal(t, "unavailable", status.Body)
+	})
+
+	t.Run("gives up after the retry count", func(t *testing.T) {
+		server := newScriptedRESTServer(t, 503, 503, 503, 503)
+		adapter := newRetryingRESTAdapter(t, server.URL, 2, DefaultRESTRetryPolicy())
+
+		_, err := adapter.doWithRetry(context.Background(), http.MethodGet, server.URL, nil, http.Header{})
+		var failure *RESTRequestError
+		require.ErrorAs(t, err, &failure)
+		assert.Len(t, failure.Attempts, 3)
+		assert.True(t, failure.Retryable)
+		assert.True(t, failure.Exhausted)
+		assert.False(t, failure.Temporary(), "a spent retry budget is not retried again by callers")
+		assert.Contains(t, err.Error(), "failed after 3 attempt(s)")
+		assert.Positive(t, failure.Attempts[0].Delay)
+	})
+
+	t.Run("non-idempotent request without a key is not retried", func(t *testing.T) {
+		server := newScriptedRESTServer(t, http.StatusServiceUnavailable)
+		policy := DefaultRESTRetryPolicy()
+		policy.AutoIdempotencyKey = false
+		adapter := newRetryingRESTAdapter(t, server.URL, 3, policy)
+
+		_, err := adapter.doWithRetry(context.Background(), http.MethodPost, server.URL, []byte(`{}`), http.Header{})
+		var failure *RESTRequestError
+		require.ErrorAs(t, err, &failure)
+		assert.Len(t, failure.Attempts, 1)
+		assert.False(t, failure.Retryable)
+		assert.Equal(t, []string{""}, server.requestKeys())
+	})
+
+	t.Run("Retry-After beyond the maximum delay ends the call", func(t *testing.T) {
+		server := newScriptedRESTServer(t, http.StatusTooManyRequests)
+		server.retryAfter = "3600"
+		adapter := newRetryingRESTAdapter(t, server.URL, 3, DefaultRESTRetryPolicy())
+
+		_, err := adapter.doWithRetry(context.Background(), http.MethodGet, server.URL, nil, http.Header{})
+		var failure *RESTRequestError
+		require.ErrorAs(t, err, &failure)
+		require.Len(t, failure.Attempts, 1)
+		assert.Equal(t, time.Hour, failure.Attempts[0].RetryAfter)
+		assert.Contains(t, failure.Attempts[0].String(), "retry after 1h0m0s")
+	})
+
+	t.Run("retry that cannot finish before the deadline is skipped", func(t *testing.T) {
+		server := newScriptedRESTServer(t, http.StatusServiceUnavailable)
+		server.retryAfter = "1"
+		adapter := newRetryingRESTAdapter(t, server.URL, 3, DefaultRESTRetryPolicy())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := adapter.doWithRetry(ctx, http.MethodGet, server.URL, nil, http.Header{})
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), 150*time.Millisecond, "returned without waiting")
+		assert.Len(t, server.requestKeys(), 1)
+	})
+}
+
+func TestRESTAttemptString(t *testing.T) {
+	attempt := RESTAttempt{Attempt: 2, StatusCode: 503, Duration: 1500 * time.Microsecond}
+	assert.Equal(t, "#2 status 503 in 2ms", attempt.String())
+
+	attempt = RESTAttempt{Attempt: 1, Err: errors.New("connection refused")}
+	assert.Equal(t, "#1 connection refused in 0s", attempt.String())
+
+	var empty RESTRequestError
+	assert.Nil(t, empty.Unwrap())
+	assert.Zero(t, empty.StatusCode())
+}
@@ -0,0 +1,195 @@
+// rest_stream.go - Chunked HTTP streaming for the REST adapter
+
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// StreamContentType is the content type of streamed REST bodies: one message per line
+const StreamContentType = "application/x-ndjson"
+
+// restStreamResult carries the outcome of the streaming HTTP request
+type restStreamResult struct {
+	response *http.Response
+	err      error
+}
+
+// restStream carries a stream over a single chunked HTTP request. Flow control
+// comes from the transport: Send blocks while the peer is not reading the
+// request body, and the response is only read as fast as Recv is called.
+type restStream struct {
+	cancel     context.CancelFunc
+	writer     *io.PipeWriter
+	resultChan chan restStreamResult
+	response   *http.Response
+	reader     *bufio.Reader
+	recvErr    error
+	sendMutex  sync.Mutex
+	recvMutex  sync.Mutex
+	closeOnce  sync.Once
+}
+
+// OpenStream starts a chunked POST to options.Method, relative to the base URL.
+// Messages are newline-delimited in both directions, so payloads must not
+// contain raw newlines.
+func (a *RESTAdapter) OpenStream(ctx context.Context, options StreamOptions) (AdapterStream, error) {
+	if !a.initialized {
+		return nil, fmt.Errorf("adapter not initialized")
+	}
+
+	endpoint := options.Method
+	if endpoint == "" {
+		endpoint = a.config.DefaultEndpoint
+	}
+
+	url := resolveBaseURL(ctx, a.config.BaseURL)
+	if endpoint != "" && url[len(url)-1] != '/' && endpoint[0] != '/' {
+		url += "/"
+	}
+	url += endpoint
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	reader, writer := io.Pipe()
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodPost, url, reader)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for key, value := range a.config.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range options.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", StreamContentType)
+	req.Header.Set("Accept", StreamContentType)
+	req.Header.Set("X-Stream-Kind", string(options.Kind))
+
+	// The streamed body cannot be replayed, so a rejected token is not retried here
+	if a.config.Auth != nil {
+		if err := a.config.Auth.Authenticate(ctx, req); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to authenticate request: %w", err)
+		}
+	}
+
+	// The configured client timeout covers the whole exchange, which would cut
+	// long-lived streams short; cancellation comes from the context instead.
+	client := &http.Client{Transport: a.client.Transport}
+
+	stream := &restStream{
+		cancel:     cancel,
+		writer:     writer,
+		resultChan: make(chan restStreamResult, 1),
+	}
+
+	go func() {
+		response, err := client.Do(req)
+		stream.resultChan <- restStreamResult{response: response, err: err}
+	}()
+
+	a.logger.Debug("Opened REST stream", map[string]interface{}{
+		"adapter": a.name,
+		"url":     url,
+		"kind":    string(options.Kind),
+	})
+
+	return stream, nil
+}
+
+// Send writes one message as a line of the request body
+func (s *restStream) Send(data []byte) error {
+	if bytes.IndexByte(data, '\n') >= 0 {
+		return fmt.Errorf("stream message contains a newline and cannot be framed")
+	}
+
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
+
+	line := make([]byte, 0, len(data)+1)
+	line = append(line, data...)
+	line = append(line, '\n')
+
+	if _, err := s.writer.Write(line); err != nil {
+		if err == io.ErrClosedPipe {
+			return ErrStreamSendClosed
+		}
+		return fmt.Errorf("%w: %v", ErrStreamClosed, err)
+	}
+	return nil
+}
+
+// Recv reads the next line of the response body
+func (s *restStream) Recv() ([]byte, error) {
+	s.recvMutex.Lock()
+	defer s.recvMutex.Unlock()
+
+	if s.recvErr != nil {
+		return nil, s.recvErr
+	}
+
+	if s.reader == nil {
+		result := <-s.resultChan
+		if result.err != nil {
+			s.recvErr = fmt.Errorf("stream request failed: %w", result.err)
+			return nil, s.recvErr
+		}
+
+		s.response = result.response
+		if s.response.StatusCode >= 400 {
+			body, _ := io.ReadAll(io.LimitReader(s.response.Body, 4096))
+			s.response.Body.Close()
+			s.recvErr = fmt.Errorf("stream request failed with status code %d: %s",
+				s.response.StatusCode, string(body))
+			return nil, s.recvErr
+		}
+		s.reader = bufio.NewReader(s.response.Body)
+	}
+
+	for {
+		line, err := s.reader.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			if err != io.EOF {
+				err = fmt.Errorf("%w: %v", ErrStreamClosed, err)
+			}
+			s.recvErr = err
+			return nil, err
+		}
+	}
+}
+
+// CloseSend ends the request body
+func (s *restStream) CloseSend() error {
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
+	return s.writer.Close()
+}
+
+// Close aborts the request and releases the response
+func (s *restStream) Close() error {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		s.writer.CloseWithError(ErrStreamClosed)
+
+		s.recvMutex.Lock()
+		response := s.response
+		s.recvMutex.Unlock()
+		if response != nil {
+			response.Body.Close()
+		}
+	})
+	return nil
+}
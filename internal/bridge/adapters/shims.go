@@ -0,0 +1,399 @@
+// shims.go - Adapter SPI shims for the REST, WebSocket, gRPC, legacy and pooled adapters
+
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// shimAdapter implements the Adapter SPI over the operations of a wrapped
+// adapter. Capabilities follow from which operations are set.
+type shimAdapter struct {
+	*SharedBaseAdapter
+	initialize func(ctx context.Context) error
+	connect    func(ctx context.Context) error
+	disconnect func(ctx context.Context) error
+	shutdown   func(ctx context.Context) error
+	send       func(ctx context.Context, data []byte) ([]byte, error)
+	receive    func(ctx context.Context) ([]byte, error)
+	notify     func(ctx context.Context, data []byte) error
+	openStream func(ctx context.Context, options StreamOptions) (AdapterStream, error)
+}
+
+// newShimAdapter creates a shim; set its operations, then call seal
+func newShimAdapter(config SharedAdapterConfig, version string) *shimAdapter {
+	return &shimAdapter{
+		SharedBaseAdapter: NewSharedBaseAdapter(config.Name, config.Type, config, SharedAdapterMetadata{
+			Version: version,
+		}),
+	}
+}
+
+// seal records the capabilities provided by the operations that were set
+func (s *shimAdapter) seal() *shimAdapter {
+	var capabilities Capability
+	if s.send != nil {
+		capabilities |= CapRequestResponse
+	}
+	if s.openStream != nil {
+		capabilities |= CapStreaming
+	}
+	if s.notify != nil {
+		capabilities |= CapPush
+	}
+	s.metadata.Capabilities = capabilities.Names()
+	return s
+}
+
+// run performs a lifecycle operation and moves to status on success
+func (s *shimAdapter) run(ctx context.Context, operation func(context.Context) error, status SharedAdapterStatus) error {
+	if operation != nil {
+		if err := operation(ctx); err != nil {
+			s.setError(err)
+			s.setStatus(SharedStatusError)
+			return err
+		}
+	}
+	s.setStatus(status)
+	return nil
+}
+
+// Initialize initializes the wrapped adapter
+func (s *shimAdapter) Initialize(ctx context.Context) error {
+	return s.run(ctx, s.initialize, SharedStatusInitialized)
+}
+
+// Connect connects the wrapped adapter
+func (s *shimAdapter) Connect(ctx context.Context) error {
+	return s.run(ctx, s.connect, SharedStatusConnected)
+}
+
+// Disconnect disconnects the wrapped adapter
+func (s *shimAdapter) Disconnect(ctx context.Context) error {
+	return s.run(ctx, s.disconnect, SharedStatusDisconnected)
+}
+
+// Shutdown releases the wrapped adapter
+func (s *shimAdapter) Shutdown(ctx context.Context) error {
+	return s.run(ctx, s.shutdown, SharedStatusDisconnected)
+}
+
+// Send sends a request and returns the reply
+func (s *shimAdapter) Send(ctx context.Context, data []byte) ([]byte, error) {
+	if s.send == nil {
+		return nil, fmt.Errorf("%w: %s is not request/response", ErrCapabilityNotSupported, s.Name())
+	}
+
+	start := time.Now()
+	s.recordSend(len(data))
+
+	response, err := s.send(ctx, data)
+	if err != nil {
+		s.setError(err)
+		return nil, err
+	}
+
+	s.recordReceive(len(response))
+	s.updateResponseTime(time.Since(start))
+	return response, nil
+}
+
+// Receive returns the next message pushed by the peer
+func (s *shimAdapter) Receive(ctx context.Context) ([]byte, error) {
+	if s.receive == nil {
+		return nil, fmt.Errorf("%w: %s does not receive", ErrCapabilityNotSupported, s.Name())
+	}
+
+	data, err := s.receive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordReceive(len(data))
+	return data, nil
+}
+
+// Notify sends a message without waiting for a reply
+func (s *shimAdapter) Notify(ctx context.Context, data []byte) error {
+	if s.notify == nil {
+		return fmt.Errorf("%w: %s does not push", ErrCapabilityNotSupported, s.Name())
+	}
+
+	if err := s.notify(ctx, data); err != nil {
+		s.setError(err)
+		return err
+	}
+
+	s.recordSend(len(data))
+	return nil
+}
+
+// OpenStream opens a stream on the wrapped adapter
+func (s *shimAdapter) OpenStream(ctx context.Context, options StreamOptions) (AdapterStream, error) {
+	if s.openStream == nil {
+		return nil, fmt.Errorf("%w: %s", ErrStreamingNotSupported, s.Name())
+	}
+	return s.openStream(ctx, options)
+}
+
+// WrapRESTAdapter exposes a REST adapter through the adapter SPI. It supports
+// request/response and streaming.
+func WrapRESTAdapter(rest *RESTAdapter) Adapter {
+	shim := newShimAdapter(SharedAdapterConfig{
+		Name:       rest.name,
+		Type:       "rest",
+		Protocol:   "http",
+		Path:       rest.config.BaseURL,
+		Timeout:    rest.config.Timeout,
+		RetryCount: rest.config.RetryCount,
+		RetryDelay: rest.config.RetryDelay,
+		Options:    rest.Config(),
+	}, "1.0")
+
+	shim.initialize = rest.Initialize
+	shim.shutdown = func(ctx context.Context) error { return rest.Close() }
+	shim.send = rest.Send
+	shim.openStream = rest.OpenStream
+
+	return shim.seal()
+}
+
+// WrapWebSocketAdapter exposes a WebSocket client adapter through the adapter
+// SPI. It supports request/response, streaming and push.
+func WrapWebSocketAdapter(ws *WebSocketAdapter) Adapter {
+	shim := newShimAdapter(SharedAdapterConfig{
+		Name:       ws.name,
+		Type:       "websocket",
+		Protocol:   "websocket",
+		Path:       ws.config.URL,
+		Timeout:    ws.config.ReplyTimeout,
+		RetryCount: ws.config.ReconnectStrategy.MaxAttempts,
+		RetryDelay: ws.config.ReconnectStrategy.InitialDelay,
+		Options:    ws.Config(),
+	}, "1.0")
+
+	shim.initialize = ws.Initialize
+	shim.connect = ws.Connect
+	shim.disconnect = func(ctx context.Context) error { return ws.Close() }
+	shim.shutdown = func(ctx context.Context) error { return ws.Close() }
+	shim.send = ws.Send
+	shim.receive = ws.Receive
+	shim.notify = ws.Notify
+	shim.openStream = ws.OpenStream
+
+	return shim.seal()
+}
+
+// newRESTFromConfig is the registry factory for "rest" adapters. The base URL
+// is Path, or is built from Host, Port and Path with an "https" Protocol
+// selecting TLS; the "headers" option sets default request headers.
+func newRESTFromConfig(config SharedAdapterConfig) (Adapter, error) {
+	scheme := "http"
+	if config.Protocol == "https" {
+		scheme = "https"
+	}
+
+	restConfig := DefaultRESTAdapterConfig()
+	restConfig.BaseURL = adapterURL(config, scheme)
+	if restConfig.BaseURL == "" {
+		return nil, fmt.Errorf("%w: rest adapter requires a path or host", SharedErrInvalidConfig)
+	}
+	if config.Timeout > 0 {
+		restConfig.Timeout = config.Timeout
+	}
+	if config.RetryCount > 0 {
+		restConfig.RetryCount = config.RetryCount
+	}
+	if config.RetryDelay > 0 {
+		restConfig.RetryDelay = config.RetryDelay
+	}
+	if headers := optionStringMap(config.Options, "headers"); headers != nil {
+		restConfig.Headers = headers
+	}
+	restConfig.DefaultEndpoint, _ = config.Options["default_endpoint"].(string)
+
+	rest, err := NewRESTAdapter(config.Name, restConfig, nil, newRegistryAdapterLogger(config.Name))
+	if err != nil {
+		return nil, err
+	}
+	return WrapRESTAdapter(rest), nil
+}
+
+// newWebSocketFromConfig is the registry factory for "websocket" client
+// adapters. The URL is Path, or is built from Host, Port and Path with a "wss"
+// Protocol selecting TLS; RetryCount and RetryDelay bound reconnection and the
+// "headers" option sets the handshake headers.
+func newWebSocketFromConfig(config SharedAdapterConfig) (Adapter, error) {
+	scheme := "ws"
+	if config.Protocol == "wss" {
+		scheme = "wss"
+	}
+
+	wsConfig := DefaultWebSocketAdapterConfig()
+	wsConfig.URL = adapterURL(config, scheme)
+	if wsConfig.URL == "" {
+		return nil, fmt.Errorf("%w: websocket adapter requires a path or host", SharedErrInvalidConfig)
+	}
+	if config.Timeout > 0 {
+		wsConfig.ReplyTimeout = config.Timeout
+	}
+	if config.RetryCount > 0 {
+		wsConfig.ReconnectStrategy.MaxAttempts = config.RetryCount
+	}
+	if config.RetryDelay > 0 {
+		wsConfig.ReconnectStrategy.InitialDelay = config.RetryDelay
+	}
+	if headers := optionStringMap(config.Options, "headers"); headers != nil {
+		wsConfig.Headers = headers
+	}
+
+	ws, err := NewWebSocketAdapter(config.Name, wsConfig, nil, newRegistryAdapterLogger(config.Name))
+	if err != nil {
+		return nil, err
+	}
+	return WrapWebSocketAdapter(ws), nil
+}
+
+// WrapGRPCAdapter exposes calls to services on target through the adapter
+// SPI, using dynamic invocation for request/response and raw streams for
+// streaming methods. Bare method names are qualified with service.
+func WrapGRPCAdapter(name string, grpcAdapter *GRPCAdapter, target, service string) Adapter {
+	return NewGRPCDynamicAdapter(name, grpcAdapter, target, service)
+}
+
+// WrapLegacyAdapter exposes a LegacyAdapter through the adapter SPI. Legacy
+// adapters return no reply, so they only support push: Notify hands the
+// encoded message to Send, and Receive encodes what the adapter receives.
+func WrapLegacyAdapter(legacy LegacyAdapter) Adapter {
+	config := legacy.Config()
+	shim := newShimAdapter(SharedAdapterConfig{
+		Name:       legacy.Name(),
+		Type:       legacy.Type(),
+		Protocol:   config.Protocol,
+		Path:       config.Endpoint,
+		Timeout:    config.Timeout,
+		RetryCount: config.RetryConfig.MaxRetries,
+		RetryDelay: config.RetryConfig.InitialInterval,
+		Options:    config.Options,
+	}, legacy.Metadata().Version)
+
+	shim.initialize = legacy.Initialize
+	shim.connect = legacy.Connect
+	shim.disconnect = legacy.Disconnect
+	shim.shutdown = legacy.Shutdown
+	shim.notify = func(ctx context.Context, data []byte) error {
+		return legacy.Send(ctx, data)
+	}
+	shim.receive = func(ctx context.Context) ([]byte, error) {
+		message, err := legacy.Receive(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return legacyMessageBytes(message)
+	}
+
+	return shim.seal()
+}
+
+// NewPooledAdapter exposes a pool of adapters as a single adapter. Every
+// request borrows a member for its duration and every stream holds one until
+// it is closed; members left in the error state are discarded. A member is
+// borrowed up front to learn the capabilities, of which request/response and
+// streaming are offered. Shutting down the adapter closes the pool.
+func NewPooledAdapter(ctx context.Context, config SharedAdapterConfig, pool *Pool[Adapter]) (Adapter, error) {
+	member, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	capabilities := member.Capabilities()
+	version := member.Metadata().Version
+	pool.Return(member)
+
+	shim := newShimAdapter(config, version)
+	shim.connect = pool.Warmup
+	shim.shutdown = func(ctx context.Context) error {
+		pool.Close()
+		return nil
+	}
+
+	// release hands a member back, discarding it if it broke
+	release := func(member Adapter) {
+		if member.Status() == SharedStatusError {
+			pool.Discard(member)
+			return
+		}
+		pool.Return(member)
+	}
+
+	if capabilities.Has(CapRequestResponse) {
+		shim.send = func(ctx context.Context, data []byte) ([]byte, error) {
+			member, err := pool.Acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
+			defer release(member)
+			return member.Send(ctx, data)
+		}
+	}
+
+	if capabilities.Has(CapStreaming) {
+		shim.openStream = func(ctx context.Context, options StreamOptions) (AdapterStream, error) {
+			member, err := pool.Acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			streaming, err := Streaming(member)
+			if err != nil {
+				release(member)
+				return nil, err
+			}
+			stream, err := streaming.OpenStream(ctx, options)
+			if err != nil {
+				release(member)
+				return nil, err
+			}
+			return &pooledStream{AdapterStream: stream, release: func() { release(member) }}, nil
+		}
+	}
+
+	return shim.seal(), nil
+}
+
+// pooledStream returns its pool member when it is closed
+type pooledStream struct {
+	AdapterStream
+	release func()
+	once    sync.Once
+}
+
+// Close closes the stream and returns the member to the pool
+func (s *pooledStream) Close() error {
+	err := s.AdapterStream.Close()
+	s.once.Do(s.release)
+	return err
+}
+
+// legacyMessageBytes encodes a message received from a legacy adapter
+func legacyMessageBytes(message interface{}) ([]byte, error) {
+	switch m := message.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return m, nil
+	case string:
+		return []byte(m), nil
+	case json.RawMessage:
+		return m, nil
+	default:
+		data, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", SharedErrInvalidData, err)
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,182 @@
+// spi.go - Versioned adapter SPI implemented by every bridge adapter
+
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SPIVersion is the version of the adapter SPI. Factories written against a
+// different major version, or a newer minor version, are rejected when they
+// are registered.
+const SPIVersion = "1.0"
+
+// Common SPI errors
+var (
+	ErrCapabilityNotSupported = errors.New("adapter does not support capability")
+	ErrIncompatibleSPI        = errors.New("incompatible adapter SPI version")
+)
+
+// Capability is a set of communication styles an adapter supports
+type Capability uint32
+
+const (
+	// CapRequestResponse adapters return the peer's reply from Send
+	CapRequestResponse Capability = 1 << iota
+
+	// CapStreaming adapters implement StreamingAdapter
+	CapStreaming
+
+	// CapPush adapters implement PushAdapter for one-way messages and deliver
+	// messages the peer pushes through Receive
+	CapPush
+)
+
+// capabilityNames are the names used in SharedAdapterMetadata.Capabilities
+var capabilityNames = []struct {
+	capability Capability
+	name       string
+}{
+	{CapRequestResponse, "request_response"},
+	{CapStreaming, "streaming"},
+	{CapPush, "push"},
+}
+
+// Has reports whether every capability in other is supported
+func (c Capability) Has(other Capability) bool {
+	return other != 0 && c&other == other
+}
+
+// Names returns the metadata names of the capabilities
+func (c Capability) Names() []string {
+	names := make([]string, 0, len(capabilityNames))
+	for _, entry := range capabilityNames {
+		if c.Has(entry.capability) {
+			names = append(names, entry.name)
+		}
+	}
+	return names
+}
+
+// String implements fmt.Stringer
+func (c Capability) String() string {
+	if c == 0 {
+		return "none"
+	}
+	return strings.Join(c.Names(), "|")
+}
+
+// ParseCapabilities reads capability flags from metadata names. Names that
+// are not SPI capabilities, such as "reflection", are ignored.
+func ParseCapabilities(names []string) Capability {
+	var capabilities Capability
+	for _, name := range names {
+		for _, entry := range capabilityNames {
+			if strings.EqualFold(name, entry.name) {
+				capabilities |= entry.capability
+			}
+		}
+	}
+	return capabilities
+}
+
+// Adapter is the SPI every bridge adapter implements. Messages are encoded
+// bytes; which of Send, StreamingAdapter and PushAdapter are usable is given
+// by Capabilities, so callers check the flags rather than the method set.
+type Adapter interface {
+	// Lifecycle methods
+	Initialize(ctx context.Context) error
+	Connect(ctx context.Context) error
+	Disconnect(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+
+	// Status methods
+	Status() SharedAdapterStatus
+	Stats() SharedAdapterStats
+
+	// Configuration and information
+	Name() string
+	Type() string
+	Metadata() SharedAdapterMetadata
+	Config() SharedAdapterConfig
+	Capabilities() Capability
+
+	// Communication methods
+	Send(ctx context.Context, data []byte) ([]byte, error)
+	Receive(ctx context.Context) ([]byte, error)
+
+	// Error handling
+	LastError() error
+}
+
+// PushAdapter is implemented by adapters with CapPush
+type PushAdapter interface {
+	// Notify sends a message without waiting for a reply
+	Notify(ctx context.Context, data []byte) error
+}
+
+// Streaming returns the adapter's streaming interface if it has CapStreaming
+func Streaming(adapter Adapter) (StreamingAdapter, error) {
+	streaming, ok := adapter.(StreamingAdapter)
+	if !ok || !adapter.Capabilities().Has(CapStreaming) {
+		return nil, fmt.Errorf("%w: %s", ErrStreamingNotSupported, adapter.Name())
+	}
+	return streaming, nil
+}
+
+// Push returns the adapter's push interface if it has CapPush
+func Push(adapter Adapter) (PushAdapter, error) {
+	push, ok := adapter.(PushAdapter)
+	if !ok || !adapter.Capabilities().Has(CapPush) {
+		return nil, fmt.Errorf("%w: %s does not push", ErrCapabilityNotSupported, adapter.Name())
+	}
+	return push, nil
+}
+
+// validateCapabilities checks that an adapter implements what its flags claim
+func validateCapabilities(adapter Adapter) error {
+	capabilities := adapter.Capabilities()
+	if capabilities == 0 {
+		return fmt.Errorf("%w: adapter '%s' declares no capabilities", ErrCapabilityNotSupported, adapter.Name())
+	}
+	if _, ok := adapter.(StreamingAdapter); capabilities.Has(CapStreaming) && !ok {
+		return fmt.Errorf("%w: adapter '%s' declares streaming without OpenStream", ErrCapabilityNotSupported, adapter.Name())
+	}
+	if _, ok := adapter.(PushAdapter); capabilities.Has(CapPush) && !ok {
+		return fmt.Errorf("%w: adapter '%s' declares push without Notify", ErrCapabilityNotSupported, adapter.Name())
+	}
+	return nil
+}
+
+// spiCompatible reports whether an adapter written against an SPI version can
+// be hosted: the major version must match and the minor version must not be
+// newer than this SPI's
+func spiCompatible(version string) bool {
+	major, minor, ok := parseSPIVersion(version)
+	if !ok {
+		return false
+	}
+	currentMajor, currentMinor, _ := parseSPIVersion(SPIVersion)
+	return major == currentMajor && minor <= currentMinor
+}
+
+// parseSPIVersion splits a "major.minor" version; the minor part is optional
+func parseSPIVersion(version string) (int, int, bool) {
+	majorPart, minorPart, hasMinor := strings.Cut(version, ".")
+	major, err := strconv.Atoi(majorPart)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !hasMinor {
+		return major, 0, true
+	}
+	minor, err := strconv.Atoi(minorPart)
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,75 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingLegacyAdapter is a LegacyAdapter that records what it is sent
+type recordingLegacyAdapter struct {
+	*BaseAdapter
+	sent []interface{}
+}
+
+func (l *recordingLegacyAdapter) Send(ctx context.Context, message interface{}) error {
+	l.sent = append(l.sent, message)
+	return nil
+}
+
+func (l *recordingLegacyAdapter) Receive(ctx context.Context) (interface{}, error) {
+	return map[string]int{"sequence": 1}, nil
+}
+
+func TestCapabilities(t *testing.T) {
+	capabilities := ParseCapabilities([]string{"request_response", "reflection", "PUSH"})
+	assert.True(t, capabilities.Has(CapRequestResponse|CapPush))
+	assert.False(t, capabilities.Has(CapStreaming))
+	assert.Equal(t, "request_response|push", capabilities.String())
+	assert.Equal(t, "none", Capability(0).String())
+}
+
+func TestAdapterRegistrySPIVersion(t *testing.T) {
+	registry := NewAdapterRegistry(nil)
+	factory := func(config SharedAdapterConfig) (Adapter, error) {
+		return NewGRPCDynamicAdapter(config.Name, &GRPCAdapter{config: DefaultGRPCAdapterConfig()}, "localhost:0", ""), nil
+	}
+
+	assert.NoError(t, registry.RegisterVersionedFactory("grpc", "1", factory))
+	assert.ErrorIs(t, registry.RegisterVersionedFactory("grpc-v2", "2.0", factory), ErrIncompatibleSPI)
+	assert.ErrorIs(t, registry.RegisterVersionedFactory("grpc-next", "1.9", factory), ErrIncompatibleSPI)
+
+	adapter, err := registry.CreateAdapter(SharedAdapterConfig{Name: "orders", Type: "grpc"})
+	assert.NoError(t, err)
+	assert.True(t, adapter.Capabilities().Has(CapRequestResponse|CapStreaming))
+
+	_, err = registry.CreateAdapter(SharedAdapterConfig{Name: "orders", Type: "soap"})
+	assert.ErrorIs(t, err, SharedErrUnknownAdapterType)
+}
+
+func TestWrapLegacyAdapter(t *testing.T) {
+	legacy := &recordingLegacyAdapter{
+		BaseAdapter: NewBaseAdapter("events", "legacy", AdapterConfig{Endpoint: "queue://events"}, AdapterMetadata{Version: "0.9"}),
+	}
+	adapter := WrapLegacyAdapter(legacy)
+	ctx := context.Background()
+
+	assert.Equal(t, CapPush, adapter.Capabilities())
+	assert.Equal(t, "queue://events", adapter.Config().Path)
+
+	// Legacy adapters return no reply, so they are push-only
+	_, err := adapter.Send(ctx, []byte("ping"))
+	assert.ErrorIs(t, err, ErrCapabilityNotSupported)
+	_, err = Streaming(adapter)
+	assert.ErrorIs(t, err, ErrStreamingNotSupported)
+
+	push, err := Push(adapter)
+	assert.NoError(t, err)
+	assert.NoError(t, push.Notify(ctx, []byte("ping")))
+	assert.Equal(t, []interface{}{[]byte("ping")}, legacy.sent)
+
+	received, err := adapter.Receive(ctx)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"sequence":1}`, string(received))
+}
@@ -0,0 +1,180 @@
+// stream.go - Streaming support shared by bridge adapters
+
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// Common streaming errors
+var (
+	ErrStreamingNotSupported = errors.New("adapter does not support streaming")
+	ErrStreamClosed          = errors.New("stream closed")
+	ErrStreamSendClosed      = errors.New("stream send side closed")
+)
+
+// StreamKind identifies which sides of a stream carry multiple messages
+type StreamKind string
+
+const (
+	StreamServer StreamKind = "server_stream"
+	StreamClient StreamKind = "client_stream"
+	StreamBidi   StreamKind = "bidi_stream"
+)
+
+// ClientStreams reports whether the caller sends multiple messages
+func (k StreamKind) ClientStreams() bool {
+	return k == StreamClient || k == StreamBidi
+}
+
+// ServerStreams reports whether the peer replies with multiple messages
+func (k StreamKind) ServerStreams() bool {
+	return k == StreamServer || k == StreamBidi
+}
+
+// StreamOptions describes a stream to open
+type StreamOptions struct {
+	Kind     StreamKind
+	Target   string            // Peer address, for adapters that dial per call
+	Method   string            // Fully qualified method or endpoint
+	Headers  map[string]string // Metadata sent when the stream opens
+	Window   int               // Messages the receiver may buffer before the sender waits
+	StreamID string            // Identifier for multiplexed transports
+	Encoding string            // Encoding of the message bytes, e.g. "json" or "protobuf"
+}
+
+// AdapterStream is an open stream of encoded messages. Recv returns io.EOF
+// once the peer has finished sending.
+type AdapterStream interface {
+	Send(data []byte) error
+	Recv() ([]byte, error)
+	CloseSend() error
+	Close() error
+}
+
+// StreamingAdapter is implemented by adapters that can open streams
+type StreamingAdapter interface {
+	OpenStream(ctx context.Context, options StreamOptions) (AdapterStream, error)
+}
+
+// passthroughCodec passes already-encoded message bytes through gRPC
+// unchanged. Its name is sent as the content-subtype, so it reports the
+// encoding the bytes are actually in rather than always claiming "proto".
+type passthroughCodec struct {
+	subtype string
+}
+
+// newPassthroughCodec returns a codec advertising the given message encoding;
+// protobuf, and an unspecified encoding, use gRPC's standard "proto" subtype
+func newPassthroughCodec(encoding string) passthroughCodec {
+	switch subtype := strings.ToLower(encoding); subtype {
+	case "", "proto", "protobuf":
+		return passthroughCodec{subtype: "proto"}
+	default:
+		return passthroughCodec{subtype: subtype}
+	}
+}
+
+func (passthroughCodec) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case []byte:
+		return m, nil
+	case *[]byte:
+		return *m, nil
+	default:
+		return nil, fmt.Errorf("passthrough codec cannot marshal %T", v)
+	}
+}
+
+func (passthroughCodec) Unmarshal(data []byte, v interface{}) error {
+	p, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("passthrough codec cannot unmarshal into %T", v)
+	}
+	*p = append((*p)[:0], data...)
+	return nil
+}
+
+func (c passthroughCodec) Name() string { return c.subtype }
+
+// grpcStream adapts a grpc.ClientStream to AdapterStream
+type grpcStream struct {
+	stream  grpc.ClientStream
+	cancel  context.CancelFunc
+	release func()
+	once    sync.Once
+	kind    StreamKind
+}
+
+// OpenStream opens a gRPC stream to options.Target for options.Method
+func (a *GRPCAdapter) OpenStream(ctx context.Context, options StreamOptions) (AdapterStream, error) {
+	target := options.Target
+	if address, ok := EndpointFromContext(ctx); ok {
+		target = address
+	}
+
+	// The connection stays reserved until the stream is closed
+	conn, release, err := a.getConnection(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	desc := &grpc.StreamDesc{
+		StreamName:    options.Method,
+		ServerStreams: options.Kind.ServerStreams(),
+		ClientStreams: options.Kind.ClientStreams(),
+	}
+
+	stream, err := conn.NewStream(streamCtx, desc, options.Method, grpc.ForceCodec(newPassthroughCodec(options.Encoding)))
+	if err != nil {
+		cancel()
+		release()
+		return nil, mapGRPCError(err)
+	}
+
+	return &grpcStream{stream: stream, cancel: cancel, release: release, kind: options.Kind}, nil
+}
+
+// Send sends one encoded message; gRPC flow control blocks when the peer's window is full
+func (s *grpcStream) Send(data []byte) error {
+	if err := s.stream.SendMsg(data); err != nil {
+		if err == io.EOF {
+			// The stream has ended; its status is reported by Recv
+			return fmt.Errorf("%w: peer ended the stream", ErrStreamClosed)
+		}
+		return mapGRPCError(err)
+	}
+	return nil
+}
+
+// Recv receives one encoded message
+func (s *grpcStream) Recv() ([]byte, error) {
+	var data []byte
+	if err := s.stream.RecvMsg(&data); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, mapGRPCError(err)
+	}
+	return data, nil
+}
+
+// CloseSend half-closes the stream
+func (s *grpcStream) CloseSend() error {
+	return s.stream.CloseSend()
+}
+
+// Close cancels the stream and returns its connection to the pool
+func (s *grpcStream) Close() error {
+	s.cancel()
+	s.once.Do(s.release)
+	return nil
+}
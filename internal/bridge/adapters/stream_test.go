@@ -0,0 +1,32 @@
+package adapters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassthroughCodec(t *testing.T) {
+	for encoding, want := range map[string]string{
+		"":         "proto",
+		"protobuf": "proto",
+		"json":     "json",
+		"MsgPack":  "msgpack",
+	} {
+		assert.Equal(t, want, newPassthroughCodec(encoding).Name(), "encoding %q", encoding)
+	}
+
+	codec := newPassthroughCodec("json")
+	data, err := codec.Marshal([]byte(`{"id":1}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1}`, string(data))
+
+	var decoded []byte
+	require.NoError(t, codec.Unmarshal(data, &decoded))
+	assert.Equal(t, data, decoded)
+
+	_, err = codec.Marshal("not bytes")
+	assert.Error(t, err)
+	assert.Error(t, codec.Unmarshal(data, new(string)))
+}
@@ -33,7 +33,9 @@ type WebSocketAdapterConfig struct {
 	WriteTimeout      time.Duration
 	ReadTimeout       time.Duration
 	MessageBufferSize int
+	ReplyTimeout      time.Duration
 	ReconnectStrategy ReconnectStrategy
+	OfflineQueue      OfflineQueueConfig
 }
 
 // ReconnectStrategy defines how reconnection attempts should be handled
@@ -56,6 +58,7 @@ func DefaultWebSocketAdapterConfig() *WebSocketAdapterConfig {
 		WriteTimeout:      10 * time.Second,
 		ReadTimeout:       10 * time.Second,
 		MessageBufferSize: 100,
+		ReplyTimeout:      30 * time.Second,
 		ReconnectStrategy: ReconnectStrategy{
 			MaxAttempts:       10,
 			InitialDelay:      time.Second,
@@ -64,6 +67,11 @@ func DefaultWebSocketAdapterConfig() *WebSocketAdapterConfig {
 			JitterFactor:      0.2,
 			ResetAfterSuccess: 60 * time.Second,
 		},
+		OfflineQueue: OfflineQueueConfig{
+			MaxMessages: defaultOfflineQueueSize,
+			Overflow:    OverflowDropOldest,
+			TTL:         5 * time.Minute,
+		},
 	}
 }
 
@@ -78,14 +86,20 @@ type WebSocketAdapter struct {
 	lastConnectTime  time.Time
 	reconnectTimer   *time.Timer
 	messageHandler   MessageHandler
+	requestHandler   RequestHandler
 	sendChan         chan []byte
 	receiveChan      chan []byte
 	errChan          chan error
 	stopChan         chan struct{}
 	metrics          *metrics.Collector
 	logger           AdapterLogger
-	pendingMessages  [][]byte
-	pendingMutex     sync.Mutex
+	offlineQueue     *wsOfflineQueue
+	flushChan        chan struct{}
+	streams          map[string]*wsStream
+	streamsMutex     sync.RWMutex
+	calls            wsCallTable
+	endpoints        map[string]*WebSocketAdapter
+	endpointsMutex   sync.Mutex
 	initialized      bool
 	ctx              context.Context
 	cancel           context.CancelFunc
@@ -111,6 +125,11 @@ func NewWebSocketAdapter(name string, config *WebSocketAdapterConfig, metrics *m
 		return nil, fmt.Errorf("invalid WebSocket URL: %w", err)
 	}
 
+	offlineQueue, err := newOfflineQueue(config.OfflineQueue)
+	if err != nil {
+		return nil, err
+	}
+
 	adapter := &WebSocketAdapter{
 		name:             name,
 		config:           config,
@@ -122,7 +141,8 @@ func NewWebSocketAdapter(name string, config *WebSocketAdapterConfig, metrics *m
 		stopChan:         make(chan struct{}),
 		metrics:          metrics,
 		logger:           logger,
-		pendingMessages:  make([][]byte, 0),
+		offlineQueue:     offlineQueue,
+		flushChan:        make(chan struct{}, 1),
 	}
 
 	return adapter, nil
@@ -177,63 +197,56 @@ func (a *WebSocketAdapter) Connect(ctx context.Context) error {
 		return nil
 	})
 
-	// Start handler goroutines
+	// Start handler goroutines; the write pump sends queued messages first
 	go a.readPump()
 	go a.writePump()
 	go a.pingPump()
 
-	// Send any pending messages
-	a.pendingMutex.Lock()
-	if len(a.pendingMessages) > 0 {
-		a.logger.Info(fmt.Sprintf("Sending %d pending messages", len(a.pendingMessages)), nil)
-		for _, msg := range a.pendingMessages {
-			select {
-			case a.sendChan <- msg:
-				// Message queued for sending
-			default:
-				a.logger.Warn("Send channel full, dropping pending message", nil)
-			}
-		}
-		a.pendingMessages = make([][]byte, 0)
-	}
-	a.pendingMutex.Unlock()
-
 	a.recordReconnectMetrics(true)
 	a.logger.Info(fmt.Sprintf("Connected to WebSocket: %s", a.config.URL), nil)
 	return nil
 }
 
-// Send sends data through the WebSocket
-func (a *WebSocketAdapter) Send(ctx context.Context, data []byte) ([]byte, error) {
-	if !a.initialized {
-		return nil, fmt.Errorf("adapter not initialized")
+// endpointAdapter returns the adapter that carries a call: this one, unless
+// the context names another endpoint with WithEndpoint, in which case a
+// connection to that endpoint is opened on first use and kept for later calls
+func (a *WebSocketAdapter) endpointAdapter(ctx context.Context) (*WebSocketAdapter, error) {
+	target := resolveBaseURL(ctx, a.config.URL)
+	if target == a.config.URL {
+		return a, nil
 	}
 
-	if !a.isConnected {
-		// Store message for later sending
-		a.pendingMutex.Lock()
-		a.pendingMessages = append(a.pendingMessages, data)
-		a.pendingMutex.Unlock()
-
-		// Try to reconnect
-		go a.tryReconnect()
+	a.endpointsMutex.Lock()
+	defer a.endpointsMutex.Unlock()
 
-		return nil, ErrWebSocketNotConnected
+	if endpoint, exists := a.endpoints[target]; exists {
+		return endpoint, nil
 	}
 
-	// Send the message
-	select {
-	case a.sendChan <- data:
-		// Message queued for sending
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
-		return nil, fmt.Errorf("send channel full")
+	// A persistent offline queue belongs to the configured endpoint only
+	config := *a.config
+	config.URL = target
+	config.OfflineQueue.Path = ""
+
+	endpoint, err := NewWebSocketAdapter(a.name, &config, a.metrics, a.logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := endpoint.Initialize(a.ctx); err != nil {
+		return nil, err
+	}
+	endpoint.messageHandler = a.messageHandler
+	endpoint.requestHandler = a.requestHandler
+	if err := endpoint.Connect(ctx); err != nil {
+		endpoint.Close()
+		return nil, err
 	}
 
-	// WebSocket is asynchronous, so we don't wait for a response here
-	// If a reply is needed, it should come via the message handler
-	return nil, nil
+	if a.endpoints == nil {
+		a.endpoints = make(map[string]*WebSocketAdapter)
+	}
+	a.endpoints[target] = endpoint
+	return endpoint, nil
 }
 
 // Receive synchronously waits for a message from the WebSocket
@@ -287,7 +300,32 @@ func (a *WebSocketAdapter) readPump() {
 			return
 		}
 
-		// Handle message
+		// Route stream frames to their streams
+		if a.dispatchStreamFrame(message) {
+			if a.metrics != nil {
+				a.recordMessageMetrics("received", int64(len(message)))
+			}
+			continue
+		}
+
+		// Deliver replies to the requests waiting for them
+		if a.calls.deliver(message) {
+			if a.metrics != nil {
+				a.recordMessageMetrics("received", int64(len(message)))
+			}
+			continue
+		}
+
+		// Answer requests from the peer and unwrap its notifications
+		message, answered := a.handleCallFrame(message)
+		if answered {
+			if a.metrics != nil {
+				a.recordMessageMetrics("received", int64(len(message)))
+			}
+			continue
+		}
+
+		// Handle unsolicited message
 		if a.messageHandler != nil {
 			if err := a.messageHandler(message); err != nil {
 				a.logger.Warn(fmt.Sprintf("Error handling WebSocket message: %v", err), nil)
@@ -322,8 +360,20 @@ func (a *WebSocketAdapter) writePump() {
 		a.closeConnection("write pump ending")
 	}()
 
+	// Send messages queued while disconnected before anything new
+	if err := a.flushOfflineQueue(); err != nil {
+		a.errChan <- fmt.Errorf("error flushing offline queue: %w", err)
+		return
+	}
+
 	for {
 		select {
+		case <-a.flushChan:
+			if err := a.flushOfflineQueue(); err != nil {
+				a.errChan <- fmt.Errorf("error flushing offline queue: %w", err)
+				return
+			}
+
 		case message := <-a.sendChan:
 			if a.conn == nil {
 				return
@@ -409,6 +459,10 @@ func (a *WebSocketAdapter) closeConnection(reason string) {
 	a.isConnected = false
 	a.conn = nil
 
+	// Streams and in-flight requests cannot survive a reconnect
+	a.failStreams(fmt.Errorf("%w: %s", ErrConnectionClosed, reason))
+	a.calls.failAll(reason)
+
 	// Schedule reconnection if not explicitly stopped
 	select {
 	case <-a.stopChan:
@@ -508,7 +562,9 @@ func pow(x, y float64) float64 {
 	return result
 }
 
-// recordMessageMetrics records metrics for messages
+// recordMessageMetrics records metrics for messages. Besides "sent" and
+// "received", the offline queue reports "queued", "flushed", "dropped",
+// "expired" and "rejected" messages along with the resulting queue depth.
 func (a *WebSocketAdapter) recordMessageMetrics(direction string, size int64) {
 	if a.metrics == nil {
 		return
@@ -520,6 +576,14 @@ func (a *WebSocketAdapter) recordMessageMetrics(direction string, size int64) {
 	}
 
 	a.metrics.Collect("websocket", "message_size", float64(size), tags)
+
+	switch direction {
+	case "queued", "flushed", "dropped", "expired", "rejected":
+		a.metrics.Collect("websocket", "offline_"+direction, 1.0, tags)
+		a.metrics.Collect("websocket", "offline_queue_depth", float64(a.offlineQueue.len()), map[string]string{
+			"adapter": a.name,
+		})
+	}
 }
 
 // recordReconnectMetrics records metrics for reconnection attempts
@@ -564,6 +628,22 @@ func (a *WebSocketAdapter) Close() error {
 	a.isConnected = false
 	a.connMutex.Unlock()
 
+	a.failStreams(fmt.Errorf("%w: adapter closed", ErrConnectionClosed))
+	a.calls.failAll("adapter closed")
+
+	// Close the connections opened for endpoint overrides
+	a.endpointsMutex.Lock()
+	for target, endpoint := range a.endpoints {
+		endpoint.Close()
+		delete(a.endpoints, target)
+	}
+	a.endpointsMutex.Unlock()
+
+	// Persisted messages stay on disk for the next run
+	if err := a.offlineQueue.close(); err != nil {
+		a.logger.Warn(fmt.Sprintf("Error closing offline queue: %v", err), nil)
+	}
+
 	a.initialized = false
 	return nil
 }
@@ -586,10 +666,6 @@ func (a *WebSocketAdapter) Config() map[string]interface{} {
 		"url":         a.config.URL,
 		"connected":   a.isConnected,
 		"buffer_size": a.config.MessageBufferSize,
+		"queued":      a.offlineQueue.len(),
 	}
 }
-
-// This function will be replaced by the central adapter registry
-// func init() {
-// 	// Adapter factory registration code will go here
-// }
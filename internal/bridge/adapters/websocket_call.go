@@ -0,0 +1,359 @@
+// websocket_call.go - Request/response correlation over a WebSocket connection
+
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Common WebSocket call errors
+var (
+	ErrPeerError            = errors.New("peer returned an error")
+	ErrRequestHandlerNotSet = errors.New("request handler not set")
+)
+
+// WebSocket call frame kinds
+const (
+	wsFrameRequest = "request"
+	wsFrameNotify  = "notify"
+	wsFrameReply   = "reply"
+	wsFrameFailed  = "failed"
+)
+
+// defaultReplyTimeout bounds Send when neither the context nor the config sets a deadline
+const defaultReplyTimeout = 30 * time.Second
+
+// wsCallFrame is the envelope for request/response traffic. Either side answers
+// a request frame with a reply (or failed) frame carrying the same correlation ID.
+type wsCallFrame struct {
+	CorrelationID string `json:"correlation_id"`
+	Kind          string `json:"kind"`
+	Payload       []byte `json:"payload,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// RequestHandler answers a request frame from the peer. The returned bytes are
+// sent back in a reply frame, and an error in a failed frame.
+type RequestHandler func(ctx context.Context, payload []byte) ([]byte, error)
+
+// wsCallResult is delivered to a waiting Send
+type wsCallResult struct {
+	data []byte
+	err  error
+}
+
+// CallAbortedError is returned by Send when the connection drops while the
+// request is waiting for its reply
+type CallAbortedError struct {
+	CorrelationID string
+	Reason        string
+}
+
+// Error implements the error interface
+func (e *CallAbortedError) Error() string {
+	return fmt.Sprintf("websocket call %s aborted: %s", e.CorrelationID, e.Reason)
+}
+
+// Unwrap returns ErrConnectionClosed so callers can match on it
+func (e *CallAbortedError) Unwrap() error {
+	return ErrConnectionClosed
+}
+
+// Send sends a request and waits for the peer's reply with the same
+// correlation ID, the context to end or the reply timeout to pass. The
+// request goes to the endpoint set with WithEndpoint, if any, and otherwise
+// to the configured URL.
+func (a *WebSocketAdapter) Send(ctx context.Context, data []byte) ([]byte, error) {
+	if !a.initialized {
+		return nil, fmt.Errorf("adapter not initialized")
+	}
+
+	endpoint, err := a.endpointAdapter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint != a {
+		return endpoint.Send(ctx, data)
+	}
+
+	id := uuid.New().String()
+	frame, err := json.Marshal(&wsCallFrame{
+		CorrelationID: id,
+		Kind:          wsFrameRequest,
+		Payload:       data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request frame: %w", err)
+	}
+
+	// Bound the wait for the reply
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.replyTimeout())
+		defer cancel()
+	}
+
+	// Register before checking the connection so a concurrent drop fails this call
+	replyChan := a.calls.register(id)
+	defer a.calls.unregister(id)
+
+	if !a.isConnected {
+		// Queue the request until reconnected; it expires when we stop waiting
+		deadline, _ := ctx.Deadline()
+		if err := a.enqueueOffline(id, frame, deadline); err != nil {
+			return nil, err
+		}
+		defer a.offlineQueue.remove(id)
+
+		// Try to reconnect
+		go a.tryReconnect()
+	} else {
+		// Send the message
+		select {
+		case a.sendChan <- frame:
+			// Message queued for sending
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			return nil, fmt.Errorf("send channel full")
+		}
+	}
+
+	// Wait for the correlated reply
+	select {
+	case result := <-replyChan:
+		return result.data, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-a.stopChan:
+		return nil, &CallAbortedError{CorrelationID: id, Reason: "adapter closed"}
+	}
+}
+
+// Notify sends a one-way message that expects no reply, to the endpoint set
+// with WithEndpoint if any. While disconnected the message goes to the
+// offline queue and is sent after reconnecting.
+func (a *WebSocketAdapter) Notify(ctx context.Context, data []byte) error {
+	if !a.initialized {
+		return fmt.Errorf("adapter not initialized")
+	}
+
+	endpoint, err := a.endpointAdapter(ctx)
+	if err != nil {
+		return err
+	}
+	if endpoint != a {
+		return endpoint.Notify(ctx, data)
+	}
+
+	id := uuid.New().String()
+	frame, err := json.Marshal(&wsCallFrame{
+		CorrelationID: id,
+		Kind:          wsFrameNotify,
+		Payload:       data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode notify frame: %w", err)
+	}
+
+	if !a.isConnected {
+		if err := a.enqueueOffline(id, frame, time.Time{}); err != nil {
+			return err
+		}
+
+		// Try to reconnect
+		go a.tryReconnect()
+		return nil
+	}
+
+	select {
+	case a.sendChan <- frame:
+		return nil
+	case <-a.stopChan:
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetRequestHandler sets the handler answering requests sent by the peer.
+// Without one, requests are answered with a failed frame.
+func (a *WebSocketAdapter) SetRequestHandler(handler RequestHandler) {
+	a.requestHandler = handler
+}
+
+// handleCallFrame answers a request frame from the peer and unwraps a notify
+// frame to its payload; other messages are returned unchanged. It reports
+// whether the message was a request, which needs no further handling.
+func (a *WebSocketAdapter) handleCallFrame(message []byte) ([]byte, bool) {
+	var frame wsCallFrame
+	if err := json.Unmarshal(message, &frame); err != nil || frame.CorrelationID == "" {
+		return message, false
+	}
+
+	switch frame.Kind {
+	case wsFrameNotify:
+		return frame.Payload, false
+	case wsFrameRequest:
+		// Answer off the read pump so replies to our own calls keep flowing
+		go a.answerRequest(&frame)
+		return message, true
+	default:
+		return message, false
+	}
+}
+
+// answerRequest runs the request handler and sends its result back to the
+// peer in a reply or failed frame
+func (a *WebSocketAdapter) answerRequest(request *wsCallFrame) {
+	reply := &wsCallFrame{
+		CorrelationID: request.CorrelationID,
+		Kind:          wsFrameReply,
+	}
+
+	handler := a.requestHandler
+	if handler == nil {
+		reply.Kind = wsFrameFailed
+		reply.Error = ErrRequestHandlerNotSet.Error()
+	} else {
+		ctx, cancel := context.WithTimeout(a.ctx, a.replyTimeout())
+		payload, err := handler(ctx, request.Payload)
+		cancel()
+
+		if err != nil {
+			reply.Kind = wsFrameFailed
+			reply.Error = err.Error()
+		} else {
+			reply.Payload = payload
+		}
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("Failed to encode reply frame: %v", err), map[string]interface{}{
+			"correlation_id": request.CorrelationID,
+		})
+		return
+	}
+
+	select {
+	case a.sendChan <- data:
+	case <-a.stopChan:
+		a.logger.Warn("Adapter closed before the reply was sent", map[string]interface{}{
+			"correlation_id": request.CorrelationID,
+		})
+	}
+}
+
+// replyTimeout returns how long a call may wait for its reply
+func (a *WebSocketAdapter) replyTimeout() time.Duration {
+	if a.config.ReplyTimeout > 0 {
+		return a.config.ReplyTimeout
+	}
+	return defaultReplyTimeout
+}
+
+// InFlightCalls returns the number of requests waiting for a reply
+func (a *WebSocketAdapter) InFlightCalls() int {
+	return a.calls.len()
+}
+
+// wsCallTable tracks requests waiting for their correlated replies
+type wsCallTable struct {
+	calls map[string]chan wsCallResult
+	mutex sync.Mutex
+}
+
+// register records a request waiting for its reply
+func (t *wsCallTable) register(id string) chan wsCallResult {
+	replyChan := make(chan wsCallResult, 1)
+
+	t.mutex.Lock()
+	if t.calls == nil {
+		t.calls = make(map[string]chan wsCallResult)
+	}
+	t.calls[id] = replyChan
+	t.mutex.Unlock()
+
+	return replyChan
+}
+
+// unregister forgets a request once its caller returns
+func (t *wsCallTable) unregister(id string) {
+	t.mutex.Lock()
+	delete(t.calls, id)
+	t.mutex.Unlock()
+}
+
+// len returns the number of requests waiting for a reply
+func (t *wsCallTable) len() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.calls)
+}
+
+// deliver hands a received reply to the request waiting for it; it reports
+// whether the message was such a reply
+func (t *wsCallTable) deliver(message []byte) bool {
+	if t.len() == 0 {
+		return false
+	}
+
+	var frame wsCallFrame
+	if err := json.Unmarshal(message, &frame); err != nil || frame.CorrelationID == "" {
+		return false
+	}
+	if frame.Kind != wsFrameReply && frame.Kind != wsFrameFailed {
+		return false
+	}
+
+	t.mutex.Lock()
+	replyChan, exists := t.calls[frame.CorrelationID]
+	delete(t.calls, frame.CorrelationID)
+	t.mutex.Unlock()
+
+	if !exists {
+		// Late reply for a call that already gave up
+		return false
+	}
+
+	result := wsCallResult{data: frame.Payload}
+	if frame.Kind == wsFrameFailed {
+		result = wsCallResult{err: fmt.Errorf("%w: %s", ErrPeerError, frame.Error)}
+	}
+	replyChan <- result
+	return true
+}
+
+// fail completes a waiting request with an error; it reports whether the
+// request was waiting
+func (t *wsCallTable) fail(id string, err error) bool {
+	t.mutex.Lock()
+	replyChan, exists := t.calls[id]
+	delete(t.calls, id)
+	t.mutex.Unlock()
+
+	if exists {
+		replyChan <- wsCallResult{err: err}
+	}
+	return exists
+}
+
+// failAll aborts every request waiting for a reply, e.g. when the connection drops
+func (t *wsCallTable) failAll(reason string) {
+	t.mutex.Lock()
+	calls := t.calls
+	t.calls = make(map[string]chan wsCallResult)
+	t.mutex.Unlock()
+
+	for id, replyChan := range calls {
+		replyChan <- wsCallResult{err: &CallAbortedError{CorrelationID: id, Reason: reason}}
+	}
+}
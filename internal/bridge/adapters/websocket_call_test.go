@@ -0,0 +1,205 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLogger discards adapter log output
+type testLogger struct{}
+
+func (testLogger) Debug(msg string, fields map[string]interface{}) {}
+func (testLogger) Info(msg string, fields map[string]interface{})  {}
+func (testLogger) Warn(msg string, fields map[string]interface{})  {}
+func (testLogger) Error(msg string, fields map[string]interface{}) {}
+
+// newReplyingPeer starts a WebSocket server that answers every request
+// frame with a reply frame of the form "<name>:<payload>"
+func newReplyingPeer(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var frame wsCallFrame
+			if err := json.Unmarshal(message, &frame); err != nil || frame.Kind != wsFrameRequest {
+				continue
+			}
+
+			reply, _ := json.Marshal(&wsCallFrame{
+				CorrelationID: frame.CorrelationID,
+				Kind:          wsFrameReply,
+				Payload:       []byte(name + ":" + string(frame.Payload)),
+			})
+			if err := conn.WriteMessage(websocket.TextMessage, reply); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// wsURL converts an httptest server URL to a WebSocket URL
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// newConnectedWebSocketAdapter creates, initializes and connects an adapter
+func newConnectedWebSocketAdapter(t *testing.T, url string) *WebSocketAdapter {
+	t.Helper()
+
+	config := DefaultWebSocketAdapterConfig()
+	config.URL = url
+	config.ReplyTimeout = 2 * time.Second
+
+	adapter, err := NewWebSocketAdapter("peer", config, nil, testLogger{})
+	require.NoError(t, err)
+	require.NoError(t, adapter.Initialize(context.Background()))
+	require.NoError(t, adapter.Connect(context.Background()))
+	t.Cleanup(func() { adapter.Close() })
+	return adapter
+}
+
+func TestWebSocketSendUsesContextEndpoint(t *testing.T) {
+	primary := newReplyingPeer(t, "primary")
+	secondary := newReplyingPeer(t, "secondary")
+	adapter := newConnectedWebSocketAdapter(t, wsURL(primary))
+
+	reply, err := adapter.Send(context.Background(), []byte("ping"))
+	require.NoError(t, err)
+	assert.Equal(t, "primary:ping", string(reply))
+
+	// A bare host:port keeps the configured scheme and path
+	host := strings.TrimPrefix(secondary.URL, "http://")
+	reply, err = adapter.Send(WithEndpoint(context.Background(), host), []byte("ping"))
+	require.NoError(t, err)
+	assert.Equal(t, "secondary:ping", string(reply))
+
+	reply, err = adapter.Send(WithEndpoint(context.Background(), wsURL(secondary)), []byte("again"))
+	require.NoError(t, err)
+	assert.Equal(t, "secondary:again", string(reply))
+
+	// The endpoint connection is reused rather than redialled
+	adapter.endpointsMutex.Lock()
+	assert.Len(t, adapter.endpoints, 1)
+	adapter.endpointsMutex.Unlock()
+
+	_, err = adapter.Send(WithEndpoint(context.Background(), "127.0.0.1:1"), []byte("ping"))
+	assert.Error(t, err, "an unreachable endpoint fails the call")
+}
+
+func TestWebSocketAnswersPeerRequests(t *testing.T) {
+	frames := make(chan *wsCallFrame, 4)
+	upgrader := websocket.Upgrader{}
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for _, frame := range []*wsCallFrame{
+			{CorrelationID: "n1", Kind: wsFrameNotify, Payload: []byte("hello")},
+			{CorrelationID: "r1", Kind: wsFrameRequest, Payload: []byte("ping")},
+			{CorrelationID: "r2", Kind: wsFrameRequest, Payload: []byte("boom")},
+		} {
+			data, _ := json.Marshal(frame)
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		}
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var frame wsCallFrame
+			if err := json.Unmarshal(message, &frame); err == nil {
+				frames <- &frame
+			}
+		}
+	}))
+	t.Cleanup(peer.Close)
+
+	config := DefaultWebSocketAdapterConfig()
+	config.URL = wsURL(peer)
+	adapter, err := NewWebSocketAdapter("peer", config, nil, testLogger{})
+	require.NoError(t, err)
+	require.NoError(t, adapter.Initialize(context.Background()))
+	adapter.SetRequestHandler(func(ctx context.Context, payload []byte) ([]byte, error) {
+		if string(payload) == "boom" {
+			return nil, errors.New("cannot handle boom")
+		}
+		return []byte("pong:" + string(payload)), nil
+	})
+	require.NoError(t, adapter.Connect(context.Background()))
+	t.Cleanup(func() { adapter.Close() })
+
+	// Notifications reach the receive side as their bare payload
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	message, err := adapter.Receive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(message))
+
+	// Requests are answered with a reply or failed frame
+	replies := make(map[string]*wsCallFrame)
+	for len(replies) < 2 {
+		select {
+		case frame := <-frames:
+			replies[frame.CorrelationID] = frame
+		case <-ctx.Done():
+			t.Fatalf("peer received %d of 2 replies", len(replies))
+		}
+	}
+	assert.Equal(t, wsFrameReply, replies["r1"].Kind)
+	assert.Equal(t, "pong:ping", string(replies["r1"].Payload))
+	assert.Equal(t, wsFrameFailed, replies["r2"].Kind)
+	assert.Equal(t, "cannot handle boom", replies["r2"].Error)
+
+	// Requests are not passed on as unsolicited messages
+	select {
+	case message := <-adapter.receiveChan:
+		t.Fatalf("unexpected message %q", message)
+	default:
+	}
+}
+
+func TestWebSocketRequestWithoutHandlerFails(t *testing.T) {
+	config := DefaultWebSocketAdapterConfig()
+	config.URL = "ws://127.0.0.1:1"
+	adapter, err := NewWebSocketAdapter("peer", config, nil, testLogger{})
+	require.NoError(t, err)
+	require.NoError(t, adapter.Initialize(context.Background()))
+
+	adapter.answerRequest(&wsCallFrame{CorrelationID: "r1", Kind: wsFrameRequest, Payload: []byte("ping")})
+
+	var reply wsCallFrame
+	require.NoError(t, json.Unmarshal(<-adapter.sendChan, &reply))
+	assert.Equal(t, "r1", reply.CorrelationID)
+	assert.Equal(t, wsFrameFailed, reply.Kind)
+	assert.Equal(t, ErrRequestHandlerNotSet.Error(), reply.Error)
+}
@@ -0,0 +1,354 @@
+// websocket_queue.go - Bounded offline queue for the WebSocket adapter
+
+package adapters
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Common offline queue errors
+var (
+	ErrOfflineQueueFull      = errors.New("offline queue full")
+	ErrOfflineMessageDropped = errors.New("queued message dropped")
+)
+
+// QueueOverflowPolicy decides what happens when the offline queue is full
+type QueueOverflowPolicy string
+
+const (
+	// OverflowDropOldest evicts the oldest queued message to make room; a Send
+	// waiting on an evicted request fails with ErrOfflineMessageDropped
+	OverflowDropOldest QueueOverflowPolicy = "drop_oldest"
+	// OverflowReject refuses the new message with ErrOfflineQueueFull
+	OverflowReject QueueOverflowPolicy = "reject"
+)
+
+// defaultOfflineQueueSize is used when OfflineQueueConfig.MaxMessages is not set
+const defaultOfflineQueueSize = 1000
+
+// offlineQueueBucket holds persisted entries keyed by big-endian sequence number
+var offlineQueueBucket = []byte("websocket_offline_queue")
+
+// OfflineQueueConfig configures the queue that holds messages while disconnected
+type OfflineQueueConfig struct {
+	MaxMessages int
+	Overflow    QueueOverflowPolicy
+	TTL         time.Duration // zero keeps messages until sent or evicted
+	Path        string        // bbolt file; empty keeps the queue in memory
+}
+
+// wsQueueEntry is a frame waiting to be sent
+type wsQueueEntry struct {
+	Seq        uint64    `json:"seq"`
+	ID         string    `json:"id"`
+	Data       []byte    `json:"data"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+}
+
+// expired reports whether the entry's TTL has passed
+func (e *wsQueueEntry) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// wsOfflineQueue is a FIFO of frames, mirrored to bbolt when a path is configured
+type wsOfflineQueue struct {
+	config  OfflineQueueConfig
+	entries []*wsQueueEntry
+	nextSeq uint64
+	db      *bolt.DB
+	mutex   sync.Mutex
+}
+
+// newOfflineQueue creates the queue, restoring entries persisted by a previous run
+func newOfflineQueue(config OfflineQueueConfig) (*wsOfflineQueue, error) {
+	if config.MaxMessages <= 0 {
+		config.MaxMessages = defaultOfflineQueueSize
+	}
+	if config.Overflow == "" {
+		config.Overflow = OverflowDropOldest
+	}
+	if config.Overflow != OverflowDropOldest && config.Overflow != OverflowReject {
+		return nil, fmt.Errorf("unknown offline queue overflow policy '%s'", config.Overflow)
+	}
+
+	q := &wsOfflineQueue{
+		config:  config,
+		entries: make([]*wsQueueEntry, 0),
+		nextSeq: 1,
+	}
+
+	if config.Path == "" {
+		return q, nil
+	}
+
+	db, err := bolt.Open(config.Path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open offline queue database: %w", err)
+	}
+
+	now := time.Now()
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(offlineQueueBucket)
+		if err != nil {
+			return err
+		}
+
+		var stale [][]byte
+		err = bucket.ForEach(func(key, value []byte) error {
+			var entry wsQueueEntry
+			if err := json.Unmarshal(value, &entry); err != nil || entry.expired(now) {
+				stale = append(stale, append([]byte{}, key...))
+				return nil
+			}
+			q.entries = append(q.entries, &entry)
+			if entry.Seq >= q.nextSeq {
+				q.nextSeq = entry.Seq + 1
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range stale {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load offline queue: %w", err)
+	}
+
+	q.db = db
+	return q, nil
+}
+
+// push appends a frame, first discarding expired entries and then applying the
+// overflow policy; it returns the entries that were removed to make room
+func (q *wsOfflineQueue) push(id string, data []byte, expiresAt time.Time) (expired, dropped []*wsQueueEntry, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	expired = q.removeExpiredLocked(now)
+
+	if len(q.entries) >= q.config.MaxMessages {
+		if q.config.Overflow == OverflowReject {
+			return expired, nil, fmt.Errorf("%w: %d messages", ErrOfflineQueueFull, len(q.entries))
+		}
+
+		excess := len(q.entries) - q.config.MaxMessages + 1
+		dropped = append(dropped, q.entries[:excess]...)
+		if err := q.deleteLocked(dropped); err != nil {
+			return expired, nil, err
+		}
+		q.entries = q.entries[excess:]
+	}
+
+	entry := &wsQueueEntry{
+		Seq:        q.nextSeq,
+		ID:         id,
+		Data:       data,
+		EnqueuedAt: now,
+		ExpiresAt:  expiresAt,
+	}
+
+	if q.db != nil {
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return expired, dropped, err
+		}
+		err = q.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(offlineQueueBucket).Put(queueKey(entry.Seq), value)
+		})
+		if err != nil {
+			return expired, dropped, fmt.Errorf("failed to persist queued message: %w", err)
+		}
+	}
+
+	q.nextSeq++
+	q.entries = append(q.entries, entry)
+	return expired, dropped, nil
+}
+
+// front returns the oldest unexpired entry without removing it, discarding expired entries ahead of it
+func (q *wsOfflineQueue) front() (*wsQueueEntry, []*wsQueueEntry) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	expired := q.removeExpiredLocked(time.Now())
+	if len(q.entries) == 0 {
+		return nil, expired
+	}
+	return q.entries[0], expired
+}
+
+// remove deletes the entry with the given ID; it reports whether it was queued
+func (q *wsOfflineQueue) remove(id string) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, entry := range q.entries {
+		if entry.ID == id {
+			if err := q.deleteLocked([]*wsQueueEntry{entry}); err != nil {
+				return false
+			}
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// len returns the number of queued entries
+func (q *wsOfflineQueue) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.entries)
+}
+
+// close closes the backing database; persisted entries are kept for the next run
+func (q *wsOfflineQueue) close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.db == nil {
+		return nil
+	}
+	err := q.db.Close()
+	q.db = nil
+	return err
+}
+
+// removeExpiredLocked drops entries whose TTL has passed
+func (q *wsOfflineQueue) removeExpiredLocked(now time.Time) []*wsQueueEntry {
+	var expired []*wsQueueEntry
+	kept := q.entries[:0]
+	for _, entry := range q.entries {
+		if entry.expired(now) {
+			expired = append(expired, entry)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	q.entries = kept
+
+	if len(expired) > 0 {
+		// Expired entries are skipped on load if this fails
+		q.deleteLocked(expired)
+	}
+	return expired
+}
+
+// deleteLocked removes entries from the backing database
+func (q *wsOfflineQueue) deleteLocked(entries []*wsQueueEntry) error {
+	if q.db == nil || len(entries) == 0 {
+		return nil
+	}
+
+	return q.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(offlineQueueBucket)
+		for _, entry := range entries {
+			if err := bucket.Delete(queueKey(entry.Seq)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// queueKey encodes a sequence number so bbolt keeps entries in order
+func queueKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// enqueueOffline holds a frame until the connection is re-established. The
+// message expires after the configured TTL or at the deadline, if earlier.
+func (a *WebSocketAdapter) enqueueOffline(id string, frame []byte, deadline time.Time) error {
+	var expiresAt time.Time
+	if a.config.OfflineQueue.TTL > 0 {
+		expiresAt = time.Now().Add(a.config.OfflineQueue.TTL)
+	}
+	if !deadline.IsZero() && (expiresAt.IsZero() || deadline.Before(expiresAt)) {
+		expiresAt = deadline
+	}
+
+	expired, dropped, err := a.offlineQueue.push(id, frame, expiresAt)
+	for _, entry := range expired {
+		a.recordMessageMetrics("expired", int64(len(entry.Data)))
+	}
+	for _, entry := range dropped {
+		a.recordMessageMetrics("dropped", int64(len(entry.Data)))
+		// A request waiting for its reply will never be sent; fail it now
+		a.calls.fail(entry.ID, fmt.Errorf("%w: evicted from the full offline queue", ErrOfflineMessageDropped))
+	}
+	if len(dropped) > 0 {
+		a.logger.Warn(fmt.Sprintf("Offline queue full, dropped %d oldest messages", len(dropped)), nil)
+	}
+	if err != nil {
+		a.recordMessageMetrics("rejected", int64(len(frame)))
+		return err
+	}
+	a.recordMessageMetrics("queued", int64(len(frame)))
+
+	// The connection may have come back while the frame was being queued
+	if a.isConnected {
+		select {
+		case a.flushChan <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// flushOfflineQueue writes queued frames in order. It runs on the write pump
+// and stops at the first failed write, leaving that frame and the rest queued.
+func (a *WebSocketAdapter) flushOfflineQueue() error {
+	flushed := 0
+	for {
+		entry, expired := a.offlineQueue.front()
+		for _, e := range expired {
+			a.recordMessageMetrics("expired", int64(len(e.Data)))
+		}
+		if entry == nil {
+			break
+		}
+		if a.conn == nil {
+			return ErrWebSocketNotConnected
+		}
+
+		a.conn.SetWriteDeadline(time.Now().Add(a.config.WriteTimeout))
+		if err := a.conn.WriteMessage(websocket.TextMessage, entry.Data); err != nil {
+			a.logger.Warn(fmt.Sprintf("Offline queue flush stopped after %d messages: %v", flushed, err), nil)
+			return err
+		}
+
+		a.offlineQueue.remove(entry.ID)
+		a.recordMessageMetrics("flushed", int64(len(entry.Data)))
+		flushed++
+	}
+
+	if flushed > 0 {
+		a.logger.Info(fmt.Sprintf("Sent %d queued messages", flushed), nil)
+	}
+	return nil
+}
+
+// QueuedMessages returns the number of messages waiting for a connection
+func (a *WebSocketAdapter) QueuedMessages() int {
+	return a.offlineQueue.len()
+}
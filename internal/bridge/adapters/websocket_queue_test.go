@@ -0,0 +1,104 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOfflineWebSocketAdapter creates an initialized adapter that never connects
+func newOfflineWebSocketAdapter(t *testing.T, queue OfflineQueueConfig) *WebSocketAdapter {
+	t.Helper()
+
+	config := DefaultWebSocketAdapterConfig()
+	config.URL = "ws://127.0.0.1:1"
+	config.OfflineQueue = queue
+	config.ReconnectStrategy.MaxAttempts = 1
+
+	adapter, err := NewWebSocketAdapter("offline", config, nil, testLogger{})
+	require.NoError(t, err)
+	require.NoError(t, adapter.Initialize(context.Background()))
+	t.Cleanup(func() { adapter.Close() })
+	return adapter
+}
+
+func TestOfflineQueueOverflow(t *testing.T) {
+	t.Run("drop oldest", func(t *testing.T) {
+		q, err := newOfflineQueue(OfflineQueueConfig{MaxMessages: 2})
+		require.NoError(t, err)
+
+		for _, id := range []string{"a", "b"} {
+			_, dropped, err := q.push(id, []byte(id), time.Time{})
+			require.NoError(t, err)
+			assert.Empty(t, dropped)
+		}
+		_, dropped, err := q.push("c", []byte("c"), time.Time{})
+		require.NoError(t, err)
+		require.Len(t, dropped, 1)
+		assert.Equal(t, "a", dropped[0].ID)
+
+		front, _ := q.front()
+		assert.Equal(t, "b", front.ID)
+		assert.Equal(t, 2, q.len())
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		q, err := newOfflineQueue(OfflineQueueConfig{MaxMessages: 1, Overflow: OverflowReject})
+		require.NoError(t, err)
+
+		_, _, err = q.push("a", []byte("a"), time.Time{})
+		require.NoError(t, err)
+		_, _, err = q.push("b", []byte("b"), time.Time{})
+		assert.ErrorIs(t, err, ErrOfflineQueueFull)
+		assert.Equal(t, 1, q.len())
+	})
+
+	t.Run("expired entries make room first", func(t *testing.T) {
+		q, err := newOfflineQueue(OfflineQueueConfig{MaxMessages: 1, Overflow: OverflowReject})
+		require.NoError(t, err)
+
+		_, _, err = q.push("a", []byte("a"), time.Now().Add(-time.Second))
+		require.NoError(t, err)
+		expired, _, err := q.push("b", []byte("b"), time.Time{})
+		require.NoError(t, err)
+		require.Len(t, expired, 1)
+		assert.Equal(t, "a", expired[0].ID)
+	})
+
+	_, err := newOfflineQueue(OfflineQueueConfig{Overflow: "drop_newest"})
+	assert.Error(t, err)
+}
+
+func TestSendFailsWhenQueuedRequestIsDropped(t *testing.T) {
+	adapter := newOfflineWebSocketAdapter(t, OfflineQueueConfig{MaxMessages: 1, Overflow: OverflowDropOldest})
+
+	first := make(chan error, 1)
+	go func() {
+		_, err := adapter.Send(context.Background(), []byte("first"))
+		first <- err
+	}()
+	require.Eventually(t, func() bool { return adapter.QueuedMessages() == 1 }, time.Second, time.Millisecond)
+
+	// Queueing a second request evicts the first, whose Send fails at once
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	second := make(chan error, 1)
+	go func() {
+		_, err := adapter.Send(ctx, []byte("second"))
+		second <- err
+	}()
+
+	select {
+	case err := <-first:
+		assert.ErrorIs(t, err, ErrOfflineMessageDropped)
+	case <-time.After(time.Second):
+		t.Fatal("the evicted request's Send did not return")
+	}
+
+	assert.ErrorIs(t, <-second, context.DeadlineExceeded)
+	assert.Zero(t, adapter.QueuedMessages())
+	assert.Zero(t, adapter.InFlightCalls())
+}
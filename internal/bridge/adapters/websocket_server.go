@@ -0,0 +1,681 @@
+// websocket_server.go - Server-side WebSocket adapter accepting inbound bridge connections
+
+package adapters
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/metrics"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Common WebSocket server errors
+var (
+	ErrPeerUnauthorized = errors.New("peer unauthorized")
+	ErrPeerNotConnected = errors.New("peer not connected")
+	ErrPeerRequired     = errors.New("peer must be selected with an endpoint")
+	ErrTooManyPeers     = errors.New("too many peers")
+)
+
+// PeerAuthenticator authenticates an upgrade request and returns the peer ID
+// the connection will be addressable under
+type PeerAuthenticator func(r *http.Request) (string, error)
+
+// PeerMessageHandler handles unsolicited messages received from a peer
+type PeerMessageHandler func(peerID string, message []byte) error
+
+// PeerInfo describes a connected peer
+type PeerInfo struct {
+	ID            string    `json:"id"`
+	RemoteAddr    string    `json:"remote_addr"`
+	ConnectedAt   time.Time `json:"connected_at"`
+	InFlightCalls int       `json:"in_flight_calls"`
+}
+
+// WebSocketServerConfig contains configuration for the WebSocket server adapter
+type WebSocketServerConfig struct {
+	ListenAddress     string // optional; when empty, mount the adapter on an existing server
+	Path              string
+	Authenticate      PeerAuthenticator
+	CheckOrigin       func(r *http.Request) bool
+	MaxPeers          int
+	PingInterval      time.Duration
+	PongTimeout       time.Duration
+	WriteTimeout      time.Duration
+	ReplyTimeout      time.Duration
+	MessageBufferSize int
+	OnPeerConnected   func(peer PeerInfo)
+	OnPeerDisconnect  func(peer PeerInfo, reason string)
+}
+
+// DefaultWebSocketServerConfig returns the default configuration
+func DefaultWebSocketServerConfig() *WebSocketServerConfig {
+	return &WebSocketServerConfig{
+		Path:              "/bridge/ws",
+		MaxPeers:          1000,
+		PingInterval:      30 * time.Second,
+		PongTimeout:       60 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		ReplyTimeout:      30 * time.Second,
+		MessageBufferSize: 100,
+	}
+}
+
+// TokenPeerAuthenticator authenticates peers by bearer token, mapping each
+// token to the peer ID it grants. The token is read from the Authorization
+// header or, for clients that cannot set headers, the access_token query parameter.
+func TokenPeerAuthenticator(tokens map[string]string) PeerAuthenticator {
+	return func(r *http.Request) (string, error) {
+		var token string
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			token = strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+		} else {
+			token = r.URL.Query().Get("access_token")
+		}
+		if token == "" {
+			return "", fmt.Errorf("%w: missing token", ErrPeerUnauthorized)
+		}
+
+		for candidate, peerID := range tokens {
+			if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+				return peerID, nil
+			}
+		}
+		return "", fmt.Errorf("%w: invalid token", ErrPeerUnauthorized)
+	}
+}
+
+// WebSocketServerAdapter accepts WebSocket connections from bridge peers, such
+// as edge agents behind NAT, and sends calls to them. The peer for a call is
+// selected with WithEndpoint(ctx, peerID); when only one peer is connected it
+// is used by default.
+type WebSocketServerAdapter struct {
+	*SharedBaseAdapter
+	config         *WebSocketServerConfig
+	upgrader       websocket.Upgrader
+	peers          map[string]*wsPeer
+	peersMutex     sync.RWMutex
+	server         *http.Server
+	messageHandler PeerMessageHandler
+	receiveChan    chan []byte
+	accepting      bool
+	metrics        *metrics.Collector
+	logger         AdapterLogger
+}
+
+// wsPeer is one authenticated inbound connection
+type wsPeer struct {
+	id          string
+	conn        *websocket.Conn
+	remoteAddr  string
+	connectedAt time.Time
+	sendChan    chan []byte
+	calls       wsCallTable
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+// NewWebSocketServerAdapter creates a new WebSocket server adapter
+func NewWebSocketServerAdapter(name string, config *WebSocketServerConfig, metrics *metrics.Collector, logger AdapterLogger) (*WebSocketServerAdapter, error) {
+	if config == nil {
+		config = DefaultWebSocketServerConfig()
+	}
+
+	// Validate configuration
+	if config.Authenticate == nil {
+		return nil, fmt.Errorf("WebSocket server requires a peer authenticator")
+	}
+	if config.Path == "" {
+		config.Path = "/"
+	}
+	if config.MessageBufferSize <= 0 {
+		config.MessageBufferSize = 100
+	}
+
+	adapter := &WebSocketServerAdapter{
+		SharedBaseAdapter: NewSharedBaseAdapter(name, "websocket-server", SharedAdapterConfig{
+			Name:     name,
+			Type:     "websocket-server",
+			Protocol: "websocket",
+			Path:     config.Path,
+		}, SharedAdapterMetadata{
+			Version:      "1.0",
+			Capabilities: []string{"request_response", "push", "inbound"},
+		}),
+		config:      config,
+		peers:       make(map[string]*wsPeer),
+		receiveChan: make(chan []byte, config.MessageBufferSize),
+		metrics:     metrics,
+		logger:      logger,
+	}
+
+	adapter.upgrader = websocket.Upgrader{
+		HandshakeTimeout: 10 * time.Second,
+		CheckOrigin:      config.CheckOrigin,
+	}
+
+	return adapter, nil
+}
+
+// newWebSocketServerFromConfig is the registry factory for "websocket-server"
+// adapters. The "tokens" option maps the bearer tokens peers authenticate with
+// to their peer IDs, and "max_peers" limits connected peers. With Port set the
+// adapter listens on Host:Port; otherwise mount it on an existing server.
+func newWebSocketServerFromConfig(config SharedAdapterConfig) (Adapter, error) {
+	serverConfig := DefaultWebSocketServerConfig()
+	if config.Path != "" {
+		serverConfig.Path = config.Path
+	}
+	if config.Port > 0 {
+		serverConfig.ListenAddress = net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	}
+	if config.Timeout > 0 {
+		serverConfig.ReplyTimeout = config.Timeout
+	}
+	if maxPeers, ok := optionInt(config.Options, "max_peers"); ok {
+		serverConfig.MaxPeers = maxPeers
+	}
+
+	tokens := optionStringMap(config.Options, "tokens")
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: websocket-server adapter requires the tokens option", SharedErrInvalidConfig)
+	}
+	serverConfig.Authenticate = TokenPeerAuthenticator(tokens)
+
+	adapter, err := NewWebSocketServerAdapter(config.Name, serverConfig, nil, newRegistryAdapterLogger(config.Name))
+	if err != nil {
+		return nil, err
+	}
+	return adapter, nil
+}
+
+// Initialize initializes the adapter
+func (a *WebSocketServerAdapter) Initialize(ctx context.Context) error {
+	a.logger.Info(fmt.Sprintf("Initializing WebSocket server adapter '%s'", a.Name()), nil)
+	a.setStatus(SharedStatusInitialized)
+	return nil
+}
+
+// Connect starts accepting peers, listening on ListenAddress when configured
+func (a *WebSocketServerAdapter) Connect(ctx context.Context) error {
+	a.peersMutex.Lock()
+	if a.accepting {
+		a.peersMutex.Unlock()
+		return SharedErrAlreadyConnected
+	}
+	a.accepting = true
+	a.peersMutex.Unlock()
+
+	if a.config.ListenAddress != "" {
+		listener, err := net.Listen("tcp", a.config.ListenAddress)
+		if err != nil {
+			a.peersMutex.Lock()
+			a.accepting = false
+			a.peersMutex.Unlock()
+			a.setError(err)
+			return fmt.Errorf("%w: %v", SharedErrConnectionFailed, err)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle(a.config.Path, a)
+		server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+		a.peersMutex.Lock()
+		a.server = server
+		a.peersMutex.Unlock()
+
+		go func() {
+			if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				a.logger.Error(fmt.Sprintf("WebSocket server stopped: %v", err), nil)
+				a.setError(err)
+			}
+		}()
+
+		a.logger.Info(fmt.Sprintf("WebSocket server listening on %s%s", listener.Addr(), a.config.Path), nil)
+	}
+
+	a.setStatus(SharedStatusConnected)
+	return nil
+}
+
+// Disconnect stops accepting peers and closes every peer connection
+func (a *WebSocketServerAdapter) Disconnect(ctx context.Context) error {
+	a.peersMutex.Lock()
+	a.accepting = false
+	server := a.server
+	a.server = nil
+	peers := make([]*wsPeer, 0, len(a.peers))
+	for _, peer := range a.peers {
+		peers = append(peers, peer)
+	}
+	a.peersMutex.Unlock()
+
+	var err error
+	if server != nil {
+		err = server.Shutdown(ctx)
+	}
+
+	for _, peer := range peers {
+		a.closePeer(peer, "server disconnecting")
+	}
+
+	a.setStatus(SharedStatusDisconnected)
+	return err
+}
+
+// Shutdown disconnects all peers and releases the adapter
+func (a *WebSocketServerAdapter) Shutdown(ctx context.Context) error {
+	return a.Disconnect(ctx)
+}
+
+// SetMessageHandler sets the handler for unsolicited peer messages
+func (a *WebSocketServerAdapter) SetMessageHandler(handler PeerMessageHandler) {
+	a.messageHandler = handler
+}
+
+// Path returns the HTTP path peers connect to
+func (a *WebSocketServerAdapter) Path() string {
+	return a.config.Path
+}
+
+// ServeHTTP authenticates and upgrades a peer connection
+func (a *WebSocketServerAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.peersMutex.RLock()
+	accepting := a.accepting
+	a.peersMutex.RUnlock()
+
+	if !accepting {
+		http.Error(w, "bridge not accepting connections", http.StatusServiceUnavailable)
+		return
+	}
+
+	peerID, err := a.config.Authenticate(r)
+	if err != nil || peerID == "" {
+		a.logger.Warn("Rejected WebSocket peer", map[string]interface{}{
+			"remote_addr": r.RemoteAddr,
+			"error":       fmt.Sprint(err),
+		})
+		a.recordPeerMetrics("rejected")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Refuse early when full; the check is repeated when the peer is added
+	a.peersMutex.RLock()
+	full := a.peersFullLocked(peerID)
+	a.peersMutex.RUnlock()
+
+	if full {
+		a.recordPeerMetrics("rejected")
+		http.Error(w, ErrTooManyPeers.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	conn, err := a.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		a.logger.Warn(fmt.Sprintf("WebSocket upgrade failed for peer '%s': %v", peerID, err), nil)
+		return
+	}
+
+	peer := &wsPeer{
+		id:          peerID,
+		conn:        conn,
+		remoteAddr:  r.RemoteAddr,
+		connectedAt: time.Now(),
+		sendChan:    make(chan []byte, a.config.MessageBufferSize),
+		done:        make(chan struct{}),
+	}
+
+	// A reconnecting peer replaces its previous connection. The limit is
+	// checked again with the insert, as other peers may have connected
+	// during the upgrade.
+	a.peersMutex.Lock()
+	if !a.accepting || a.peersFullLocked(peerID) {
+		reason := ErrTooManyPeers.Error()
+		if !a.accepting {
+			reason = "bridge not accepting connections"
+		}
+		a.peersMutex.Unlock()
+
+		a.recordPeerMetrics("rejected")
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseTryAgainLater, reason),
+			time.Now().Add(a.config.WriteTimeout))
+		conn.Close()
+		return
+	}
+	previous := a.peers[peerID]
+	a.peers[peerID] = peer
+	a.peersMutex.Unlock()
+
+	if previous != nil {
+		a.closePeer(previous, "replaced by new connection")
+	}
+
+	a.logger.Info(fmt.Sprintf("WebSocket peer '%s' connected", peerID), map[string]interface{}{
+		"remote_addr": r.RemoteAddr,
+	})
+	a.recordPeerMetrics("connected")
+	if a.config.OnPeerConnected != nil {
+		a.config.OnPeerConnected(peer.info())
+	}
+
+	go a.peerWritePump(peer)
+	go a.peerReadPump(peer)
+}
+
+// peersFullLocked reports whether adding the peer would exceed MaxPeers; a
+// reconnecting peer takes over its previous slot. The caller holds peersMutex.
+func (a *WebSocketServerAdapter) peersFullLocked(peerID string) bool {
+	if a.config.MaxPeers <= 0 {
+		return false
+	}
+	if _, reconnecting := a.peers[peerID]; reconnecting {
+		return false
+	}
+	return len(a.peers) >= a.config.MaxPeers
+}
+
+// Peers returns the connected peers ordered by ID
+func (a *WebSocketServerAdapter) Peers() []PeerInfo {
+	a.peersMutex.RLock()
+	defer a.peersMutex.RUnlock()
+
+	peers := make([]PeerInfo, 0, len(a.peers))
+	for _, peer := range a.peers {
+		peers = append(peers, peer.info())
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].ID < peers[j].ID
+	})
+	return peers
+}
+
+// DisconnectPeer closes a peer's connection
+func (a *WebSocketServerAdapter) DisconnectPeer(peerID string) error {
+	a.peersMutex.RLock()
+	peer, exists := a.peers[peerID]
+	a.peersMutex.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrPeerNotConnected, peerID)
+	}
+	a.closePeer(peer, "disconnected by server")
+	return nil
+}
+
+// Send sends a request to the peer selected by the context endpoint and waits for its reply
+func (a *WebSocketServerAdapter) Send(ctx context.Context, data []byte) ([]byte, error) {
+	peer, err := a.selectPeer(ctx)
+	if err != nil {
+		a.setError(err)
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	frame, err := json.Marshal(&wsCallFrame{
+		CorrelationID: id,
+		Kind:          wsFrameRequest,
+		Payload:       data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request frame: %w", err)
+	}
+
+	// Bound the wait for the reply
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := a.config.ReplyTimeout
+		if timeout <= 0 {
+			timeout = defaultReplyTimeout
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	replyChan := peer.calls.register(id)
+	defer peer.calls.unregister(id)
+
+	select {
+	case peer.sendChan <- frame:
+		a.recordSend(len(data))
+	case <-peer.done:
+		return nil, &CallAbortedError{CorrelationID: id, Reason: "peer disconnected"}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case result := <-replyChan:
+		if result.err != nil {
+			a.setError(result.err)
+			return nil, result.err
+		}
+		a.recordReceive(len(result.data))
+		a.updateResponseTime(time.Since(start))
+		return result.data, nil
+	case <-peer.done:
+		return nil, &CallAbortedError{CorrelationID: id, Reason: "peer disconnected"}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Notify sends a one-way message to the peer selected by the context endpoint
+func (a *WebSocketServerAdapter) Notify(ctx context.Context, data []byte) error {
+	peer, err := a.selectPeer(ctx)
+	if err != nil {
+		return err
+	}
+
+	frame, err := json.Marshal(&wsCallFrame{
+		CorrelationID: uuid.New().String(),
+		Kind:          wsFrameNotify,
+		Payload:       data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode notify frame: %w", err)
+	}
+
+	select {
+	case peer.sendChan <- frame:
+		a.recordSend(len(data))
+		return nil
+	case <-peer.done:
+		return fmt.Errorf("%w: %s", ErrPeerNotConnected, peer.id)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive waits for the next unsolicited message from any peer
+func (a *WebSocketServerAdapter) Receive(ctx context.Context) ([]byte, error) {
+	select {
+	case message := <-a.receiveChan:
+		return message, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// selectPeer returns the peer named by the context endpoint, or the only connected peer
+func (a *WebSocketServerAdapter) selectPeer(ctx context.Context) (*wsPeer, error) {
+	a.peersMutex.RLock()
+	defer a.peersMutex.RUnlock()
+
+	if peerID, ok := EndpointFromContext(ctx); ok {
+		peer, exists := a.peers[peerID]
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", ErrPeerNotConnected, peerID)
+		}
+		return peer, nil
+	}
+
+	switch len(a.peers) {
+	case 0:
+		return nil, ErrPeerNotConnected
+	case 1:
+		for _, peer := range a.peers {
+			return peer, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d peers connected", ErrPeerRequired, len(a.peers))
+}
+
+// peerReadPump reads frames from a peer until the connection fails
+func (a *WebSocketServerAdapter) peerReadPump(peer *wsPeer) {
+	reason := "read pump ending"
+	defer func() {
+		a.closePeer(peer, reason)
+	}()
+
+	peer.conn.SetReadDeadline(time.Now().Add(a.config.PongTimeout))
+	peer.conn.SetPongHandler(func(string) error {
+		peer.conn.SetReadDeadline(time.Now().Add(a.config.PongTimeout))
+		return nil
+	})
+
+	for {
+		_, message, err := peer.conn.ReadMessage()
+		if err != nil {
+			reason = err.Error()
+			return
+		}
+		peer.conn.SetReadDeadline(time.Now().Add(a.config.PongTimeout))
+		a.recordMessageMetrics(peer.id, "received", int64(len(message)))
+
+		// Deliver replies to the requests waiting for them
+		if peer.calls.deliver(message) {
+			continue
+		}
+
+		// Handle unsolicited message
+		if a.messageHandler != nil {
+			if err := a.messageHandler(peer.id, message); err != nil {
+				a.logger.Warn(fmt.Sprintf("Error handling message from peer '%s': %v", peer.id, err), nil)
+			}
+		}
+
+		select {
+		case a.receiveChan <- message:
+		default:
+			a.logger.Warn("Receive channel full, dropping message", nil)
+		}
+	}
+}
+
+// peerWritePump is the only writer for a peer connection, sending frames and pings
+func (a *WebSocketServerAdapter) peerWritePump(peer *wsPeer) {
+	ticker := time.NewTicker(a.config.PingInterval)
+	defer func() {
+		ticker.Stop()
+		a.closePeer(peer, "write pump ending")
+	}()
+
+	for {
+		select {
+		case message := <-peer.sendChan:
+			peer.conn.SetWriteDeadline(time.Now().Add(a.config.WriteTimeout))
+			if err := peer.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				a.logger.Warn(fmt.Sprintf("Error writing to peer '%s': %v", peer.id, err), nil)
+				return
+			}
+			a.recordMessageMetrics(peer.id, "sent", int64(len(message)))
+
+		case <-ticker.C:
+			peer.conn.SetWriteDeadline(time.Now().Add(a.config.WriteTimeout))
+			if err := peer.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+
+		case <-peer.done:
+			peer.conn.SetWriteDeadline(time.Now().Add(time.Second))
+			peer.conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
+
+// closePeer closes a peer connection once, failing its in-flight calls
+func (a *WebSocketServerAdapter) closePeer(peer *wsPeer, reason string) {
+	peer.closeOnce.Do(func() {
+		close(peer.done)
+
+		a.peersMutex.Lock()
+		if a.peers[peer.id] == peer {
+			delete(a.peers, peer.id)
+		}
+		a.peersMutex.Unlock()
+
+		// Give the write pump a moment to send the close frame
+		time.AfterFunc(time.Second, func() {
+			peer.conn.Close()
+		})
+		peer.calls.failAll(reason)
+
+		a.logger.Info(fmt.Sprintf("WebSocket peer '%s' disconnected: %s", peer.id, reason), nil)
+		a.recordPeerMetrics("disconnected")
+		if a.config.OnPeerDisconnect != nil {
+			a.config.OnPeerDisconnect(peer.info(), reason)
+		}
+	})
+}
+
+// info describes the peer
+func (p *wsPeer) info() PeerInfo {
+	return PeerInfo{
+		ID:            p.id,
+		RemoteAddr:    p.remoteAddr,
+		ConnectedAt:   p.connectedAt,
+		InFlightCalls: p.calls.len(),
+	}
+}
+
+// recordMessageMetrics records metrics for peer messages
+func (a *WebSocketServerAdapter) recordMessageMetrics(peerID, direction string, size int64) {
+	if a.metrics == nil {
+		return
+	}
+
+	tags := map[string]string{
+		"adapter":   a.Name(),
+		"peer":      peerID,
+		"direction": direction,
+	}
+
+	a.metrics.Collect("websocket_server", "message_size", float64(size), tags)
+}
+
+// recordPeerMetrics records peer connection events and the resulting peer count
+func (a *WebSocketServerAdapter) recordPeerMetrics(event string) {
+	if a.metrics == nil {
+		return
+	}
+
+	a.peersMutex.RLock()
+	count := len(a.peers)
+	a.peersMutex.RUnlock()
+
+	tags := map[string]string{
+		"adapter": a.Name(),
+	}
+
+	a.metrics.Collect("websocket_server", "peer_"+event, 1.0, tags)
+	a.metrics.Collect("websocket_server", "peers", float64(count), tags)
+}
@@ -0,0 +1,181 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestWebSocketServer starts a server adapter accepting the peers
+// "edge-1" (token t1) and "edge-2" (token t2)
+func newTestWebSocketServer(t *testing.T, maxPeers int) (*WebSocketServerAdapter, string) {
+	t.Helper()
+
+	config := DefaultWebSocketServerConfig()
+	config.Authenticate = TokenPeerAuthenticator(map[string]string{"t1": "edge-1", "t2": "edge-2"})
+	config.MaxPeers = maxPeers
+	config.ReplyTimeout = 2 * time.Second
+
+	adapter, err := NewWebSocketServerAdapter("edges", config, nil, testLogger{})
+	require.NoError(t, err)
+	require.NoError(t, adapter.Initialize(context.Background()))
+	require.NoError(t, adapter.Connect(context.Background()))
+	t.Cleanup(func() { adapter.Disconnect(context.Background()) })
+
+	server := httptest.NewServer(adapter)
+	t.Cleanup(server.Close)
+	return adapter, wsURL(server) + config.Path
+}
+
+// dialServerPeer connects a peer with the given token, answering request
+// frames with "<payload>:ok" when reply is set
+func dialServerPeer(t *testing.T, url, token string, reply bool) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
+	conn, response, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + token}})
+	if err != nil {
+		return nil, response, err
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var frame wsCallFrame
+			if err := json.Unmarshal(message, &frame); err != nil || frame.Kind != wsFrameRequest || !reply {
+				continue
+			}
+			data, _ := json.Marshal(&wsCallFrame{
+				CorrelationID: frame.CorrelationID,
+				Kind:          wsFrameReply,
+				Payload:       []byte(string(frame.Payload) + ":ok"),
+			})
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		}
+	}()
+	return conn, response, nil
+}
+
+func TestWebSocketServerSendsToPeers(t *testing.T) {
+	adapter, url := newTestWebSocketServer(t, 0)
+
+	_, response, err := dialServerPeer(t, url, "wrong", true)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	_, err = adapter.Send(context.Background(), []byte("ping"))
+	assert.ErrorIs(t, err, ErrPeerNotConnected)
+
+	_, _, err = dialServerPeer(t, url, "t1", true)
+	require.NoError(t, err)
+	_, _, err = dialServerPeer(t, url, "t2", true)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(adapter.Peers()) == 2 }, time.Second, time.Millisecond)
+
+	// With several peers connected, calls must name one
+	_, err = adapter.Send(context.Background(), []byte("ping"))
+	assert.ErrorIs(t, err, ErrPeerRequired)
+
+	reply, err := adapter.Send(WithEndpoint(context.Background(), "edge-2"), []byte("ping"))
+	require.NoError(t, err)
+	assert.Equal(t, "ping:ok", string(reply))
+}
+
+func TestWebSocketServerMaxPeers(t *testing.T) {
+	adapter, url := newTestWebSocketServer(t, 1)
+
+	_, _, err := dialServerPeer(t, url, "t1", true)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(adapter.Peers()) == 1 }, time.Second, time.Millisecond)
+
+	_, response, err := dialServerPeer(t, url, "t2", true)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	// A reconnecting peer takes over its own slot
+	first := adapter.Peers()[0].ConnectedAt
+	_, _, err = dialServerPeer(t, url, "t1", true)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		peers := adapter.Peers()
+		return len(peers) == 1 && peers[0].ConnectedAt.After(first)
+	}, time.Second, time.Millisecond)
+
+	reply, err := adapter.Send(context.Background(), []byte("ping"))
+	require.NoError(t, err)
+	assert.Equal(t, "ping:ok", string(reply))
+}
+
+func TestWebSocketServerSendFailsWhenPeerDisconnects(t *testing.T) {
+	adapter, url := newTestWebSocketServer(t, 0)
+
+	_, _, err := dialServerPeer(t, url, "t1", false)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(adapter.Peers()) == 1 }, time.Second, time.Millisecond)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := adapter.Send(context.Background(), []byte("ping"))
+		result <- err
+	}()
+	require.Eventually(t, func() bool {
+		peers := adapter.Peers()
+		return len(peers) == 1 && peers[0].InFlightCalls == 1
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, adapter.DisconnectPeer("edge-1"))
+	select {
+	case err := <-result:
+		var aborted *CallAbortedError
+		require.ErrorAs(t, err, &aborted)
+		assert.ErrorIs(t, err, ErrConnectionClosed)
+	case <-time.After(time.Second):
+		t.Fatal("Send did not return after the peer disconnected")
+	}
+}
+
+func TestWebSocketServerFromRegistry(t *testing.T) {
+	types := ListAvailableAdapterTypes()
+	assert.Contains(t, types, "websocket-server")
+
+	registry := NewAdapterRegistry(nil)
+	require.NoError(t, registry.RegisterFactory("websocket-server", newWebSocketServerFromConfig))
+
+	adapter, err := registry.CreateAdapter(SharedAdapterConfig{
+		Name:    "edges",
+		Type:    "websocket-server",
+		Path:    "/edges",
+		Timeout: 5 * time.Second,
+		Options: map[string]interface{}{
+			"tokens":    map[string]interface{}{"t1": "edge-1"},
+			"max_peers": 10.0,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "websocket-server", adapter.Type())
+	assert.True(t, adapter.Capabilities().Has(CapRequestResponse|CapPush))
+
+	server := adapter.(*WebSocketServerAdapter)
+	assert.Equal(t, "/edges", server.Path())
+	assert.Equal(t, 10, server.config.MaxPeers)
+	assert.Equal(t, 5*time.Second, server.config.ReplyTimeout)
+	assert.Empty(t, server.config.ListenAddress)
+
+	_, err = registry.CreateAdapter(SharedAdapterConfig{Name: "edges", Type: "websocket-server"})
+	assert.ErrorIs(t, err, SharedErrInvalidConfig)
+	assert.True(t, strings.Contains(err.Error(), "tokens"))
+}
@@ -0,0 +1,406 @@
+// websocket_stream.go - Multiplexed streams over a WebSocket connection
+
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// WebSocket stream frame kinds
+const (
+	wsFrameOpen   = "open"
+	wsFrameData   = "data"
+	wsFrameEnd    = "end"
+	wsFrameCancel = "cancel"
+	wsFrameWindow = "window"
+	wsFrameError  = "error"
+)
+
+// defaultStreamWindow is used when StreamOptions.Window is not set
+const defaultStreamWindow = 64
+
+// wsStreamFrame is the envelope for stream traffic. Each side may send as many
+// data frames as the credit granted by the other side: the window announced in
+// the open frame initially, topped up by window frames as messages are consumed.
+type wsStreamFrame struct {
+	StreamID string            `json:"stream_id"`
+	Kind     string            `json:"kind"`
+	Method   string            `json:"method,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Payload  []byte            `json:"payload,omitempty"`
+	Credit   int               `json:"credit,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// wsStream is one logical stream multiplexed over the adapter's connection
+type wsStream struct {
+	id         string
+	adapter    *WebSocketAdapter
+	ctx        context.Context
+	cancel     context.CancelFunc
+	window     int
+	inbound    chan []byte
+	consumed   int
+	credit     int
+	creditChan chan struct{}
+	recvDone   chan struct{}
+	done       chan struct{}
+	recvErr    error
+	recvClosed bool
+	sendClosed bool
+	finished   bool
+	mutex      sync.Mutex
+}
+
+// OpenStream opens a stream multiplexed over the WebSocket connection to
+// the endpoint set with WithEndpoint, or the configured URL
+func (a *WebSocketAdapter) OpenStream(ctx context.Context, options StreamOptions) (AdapterStream, error) {
+	if !a.initialized {
+		return nil, fmt.Errorf("adapter not initialized")
+	}
+
+	endpoint, err := a.endpointAdapter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint != a {
+		return endpoint.OpenStream(ctx, options)
+	}
+	if !a.isConnected {
+		return nil, ErrWebSocketNotConnected
+	}
+
+	window := options.Window
+	if window <= 0 {
+		window = defaultStreamWindow
+	}
+
+	id := options.StreamID
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	stream := &wsStream{
+		id:         id,
+		adapter:    a,
+		ctx:        streamCtx,
+		cancel:     cancel,
+		window:     window,
+		inbound:    make(chan []byte, window),
+		credit:     window,
+		creditChan: make(chan struct{}, 1),
+		recvDone:   make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	a.streamsMutex.Lock()
+	if a.streams == nil {
+		a.streams = make(map[string]*wsStream)
+	}
+	if _, exists := a.streams[id]; exists {
+		a.streamsMutex.Unlock()
+		cancel()
+		return nil, fmt.Errorf("stream '%s' already open", id)
+	}
+	a.streams[id] = stream
+	a.streamsMutex.Unlock()
+
+	err = a.sendFrame(streamCtx, &wsStreamFrame{
+		StreamID: id,
+		Kind:     wsFrameOpen,
+		Method:   options.Method,
+		Headers:  options.Headers,
+		Credit:   window,
+	})
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+
+	// Propagate caller cancellation to the peer
+	go func() {
+		select {
+		case <-streamCtx.Done():
+			stream.Close()
+		case <-stream.done:
+		}
+	}()
+
+	return stream, nil
+}
+
+// Send sends one message, waiting for credit from the peer
+func (s *wsStream) Send(data []byte) error {
+	for {
+		s.mutex.Lock()
+		if s.finished {
+			s.mutex.Unlock()
+			return ErrStreamClosed
+		}
+		if s.sendClosed {
+			s.mutex.Unlock()
+			return ErrStreamSendClosed
+		}
+		if s.credit > 0 {
+			s.credit--
+			s.mutex.Unlock()
+			break
+		}
+		s.mutex.Unlock()
+
+		select {
+		case <-s.creditChan:
+		case <-s.done:
+			return ErrStreamClosed
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		}
+	}
+
+	return s.adapter.sendFrame(s.ctx, &wsStreamFrame{
+		StreamID: s.id,
+		Kind:     wsFrameData,
+		Payload:  data,
+	})
+}
+
+// Recv returns the next message, or io.EOF once the peer has ended its side
+func (s *wsStream) Recv() ([]byte, error) {
+	select {
+	case data := <-s.inbound:
+		s.consumedOne()
+		return data, nil
+	default:
+	}
+
+	select {
+	case data := <-s.inbound:
+		s.consumedOne()
+		return data, nil
+	case <-s.recvDone:
+	case <-s.done:
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+
+	// Deliver anything that arrived before the receive side ended
+	select {
+	case data := <-s.inbound:
+		s.consumedOne()
+		return data, nil
+	default:
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.recvClosed {
+		return nil, io.EOF
+	}
+	return nil, s.recvErr
+}
+
+// consumedOne grants the peer more credit once half the window has been consumed
+func (s *wsStream) consumedOne() {
+	s.mutex.Lock()
+	s.consumed++
+	grant := 0
+	if s.consumed >= (s.window+1)/2 {
+		grant = s.consumed
+		s.consumed = 0
+	}
+	s.mutex.Unlock()
+
+	if grant > 0 {
+		s.adapter.sendFrame(s.ctx, &wsStreamFrame{
+			StreamID: s.id,
+			Kind:     wsFrameWindow,
+			Credit:   grant,
+		})
+	}
+}
+
+// CloseSend tells the peer no more messages will be sent; the stream
+// finishes once the peer has ended its side too
+func (s *wsStream) CloseSend() error {
+	s.mutex.Lock()
+	if s.sendClosed || s.finished {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.sendClosed = true
+	recvClosed := s.recvClosed
+	s.mutex.Unlock()
+
+	err := s.adapter.sendFrame(s.ctx, &wsStreamFrame{
+		StreamID: s.id,
+		Kind:     wsFrameEnd,
+	})
+	if recvClosed {
+		s.finish(io.EOF)
+	}
+	return err
+}
+
+// closeRecv records that the peer has ended its side. Sending keeps working
+// until the local side closes too, which finishes the stream.
+func (s *wsStream) closeRecv() {
+	s.mutex.Lock()
+	if s.recvClosed || s.finished {
+		s.mutex.Unlock()
+		return
+	}
+	s.recvClosed = true
+	close(s.recvDone)
+	sendClosed := s.sendClosed
+	s.mutex.Unlock()
+
+	if sendClosed {
+		s.finish(io.EOF)
+	}
+}
+
+// Close cancels the stream, notifying the peer if it is still open
+func (s *wsStream) Close() error {
+	notify := s.finish(ErrStreamClosed)
+
+	if notify {
+		// Use the adapter context; the stream context may already be canceled
+		s.adapter.sendFrame(s.adapter.ctx, &wsStreamFrame{
+			StreamID: s.id,
+			Kind:     wsFrameCancel,
+		})
+	}
+
+	s.cancel()
+	return nil
+}
+
+// finish marks the stream finished with the given receive error and unregisters it;
+// it reports whether this call finished the stream
+func (s *wsStream) finish(recvErr error) bool {
+	s.mutex.Lock()
+	if s.finished {
+		s.mutex.Unlock()
+		return false
+	}
+	s.finished = true
+	s.recvErr = recvErr
+	close(s.done)
+	s.mutex.Unlock()
+
+	s.adapter.streamsMutex.Lock()
+	delete(s.adapter.streams, s.id)
+	s.adapter.streamsMutex.Unlock()
+
+	return true
+}
+
+// handleFrame applies a frame received from the peer
+func (s *wsStream) handleFrame(frame *wsStreamFrame) {
+	switch frame.Kind {
+	case wsFrameData:
+		s.mutex.Lock()
+		recvClosed := s.recvClosed
+		s.mutex.Unlock()
+		if recvClosed {
+			// Data after the peer's end frame is a protocol error; drop it
+			return
+		}
+
+		select {
+		case s.inbound <- frame.Payload:
+		default:
+			// The peer ignored our window
+			s.finish(fmt.Errorf("%w: peer exceeded flow control window", ErrStreamClosed))
+			s.cancel()
+		}
+
+	case wsFrameWindow:
+		s.mutex.Lock()
+		s.credit += frame.Credit
+		s.mutex.Unlock()
+		select {
+		case s.creditChan <- struct{}{}:
+		default:
+		}
+
+	case wsFrameEnd:
+		s.closeRecv()
+
+	case wsFrameCancel:
+		s.finish(fmt.Errorf("%w: canceled by peer", ErrStreamClosed))
+		s.cancel()
+
+	case wsFrameError:
+		s.finish(fmt.Errorf("stream error from peer: %s", frame.Error))
+		s.cancel()
+	}
+}
+
+// dispatchStreamFrame routes a received message to its stream; it reports
+// whether the message was stream traffic
+func (a *WebSocketAdapter) dispatchStreamFrame(message []byte) bool {
+	a.streamsMutex.RLock()
+	active := len(a.streams) > 0
+	a.streamsMutex.RUnlock()
+	if !active {
+		return false
+	}
+
+	var frame wsStreamFrame
+	if err := json.Unmarshal(message, &frame); err != nil || frame.StreamID == "" || frame.Kind == "" {
+		return false
+	}
+
+	a.streamsMutex.RLock()
+	stream, exists := a.streams[frame.StreamID]
+	a.streamsMutex.RUnlock()
+
+	if exists {
+		stream.handleFrame(&frame)
+	}
+	return true
+}
+
+// failStreams ends every open stream with an error, e.g. when the connection drops
+func (a *WebSocketAdapter) failStreams(err error) {
+	a.streamsMutex.RLock()
+	streams := make([]*wsStream, 0, len(a.streams))
+	for _, stream := range a.streams {
+		streams = append(streams, stream)
+	}
+	a.streamsMutex.RUnlock()
+
+	for _, stream := range streams {
+		stream.finish(err)
+		stream.cancel()
+	}
+}
+
+// sendFrame queues a stream frame for writing
+func (a *WebSocketAdapter) sendFrame(ctx context.Context, frame *wsStreamFrame) error {
+	if !a.isConnected {
+		return ErrWebSocketNotConnected
+	}
+
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return fmt.Errorf("failed to encode stream frame: %w", err)
+	}
+
+	select {
+	case a.sendChan <- data:
+		return nil
+	case <-a.stopChan:
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
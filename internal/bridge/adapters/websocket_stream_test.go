@@ -0,0 +1,96 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHalfClosingPeer starts a WebSocket server that ends its side of every
+// stream as soon as it opens and reports the data frames it receives
+func newHalfClosingPeer(t *testing.T) (*httptest.Server, <-chan *wsStreamFrame) {
+	t.Helper()
+
+	received := make(chan *wsStreamFrame, 16)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var frame wsStreamFrame
+			if err := json.Unmarshal(message, &frame); err != nil || frame.StreamID == "" {
+				continue
+			}
+
+			switch frame.Kind {
+			case wsFrameOpen:
+				end, _ := json.Marshal(&wsStreamFrame{StreamID: frame.StreamID, Kind: wsFrameEnd})
+				if err := conn.WriteMessage(websocket.TextMessage, end); err != nil {
+					return
+				}
+			case wsFrameData, wsFrameEnd:
+				received <- &frame
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func TestWebSocketStreamHalfClose(t *testing.T) {
+	peer, received := newHalfClosingPeer(t)
+	adapter := newConnectedWebSocketAdapter(t, wsURL(peer))
+
+	stream, err := adapter.OpenStream(context.Background(), StreamOptions{Kind: StreamBidi, Method: "prices"})
+	require.NoError(t, err)
+	defer stream.Close()
+
+	// The peer's end frame ends only the receive side
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	for _, payload := range []string{"one", "two"} {
+		require.NoError(t, stream.Send([]byte(payload)))
+		select {
+		case frame := <-received:
+			assert.Equal(t, wsFrameData, frame.Kind)
+			assert.Equal(t, payload, string(frame.Payload))
+		case <-time.After(2 * time.Second):
+			t.Fatalf("peer did not receive %q", payload)
+		}
+	}
+
+	// Closing the local side as well finishes the stream
+	require.NoError(t, stream.CloseSend())
+	select {
+	case frame := <-received:
+		assert.Equal(t, wsFrameEnd, frame.Kind)
+	case <-time.After(2 * time.Second):
+		t.Fatal("peer did not receive the end frame")
+	}
+
+	assert.ErrorIs(t, stream.Send([]byte("three")), ErrStreamClosed)
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	adapter.streamsMutex.RLock()
+	assert.Empty(t, adapter.streams)
+	adapter.streamsMutex.RUnlock()
+}
@@ -0,0 +1,303 @@
+// admission.go - Concurrency limiting and priority queueing for bridge calls
+
+package bridge
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// priorityContextKey is the context key for call priority
+type priorityContextKey struct{}
+
+// WithPriority returns a context carrying the admission priority for bridge calls
+func WithPriority(ctx context.Context, priority MessagePriority) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, priority)
+}
+
+// PriorityFromContext returns the call priority stored in the context, if any
+func PriorityFromContext(ctx context.Context) (MessagePriority, bool) {
+	priority, ok := ctx.Value(priorityContextKey{}).(MessagePriority)
+	return priority, ok
+}
+
+// callPriority determines the priority of a call from the context or target options
+func callPriority(ctx context.Context, target BridgeTarget) MessagePriority {
+	if priority, ok := PriorityFromContext(ctx); ok {
+		return priority
+	}
+
+	switch p := target.Options["priority"].(type) {
+	case MessagePriority:
+		return p
+	case int:
+		return MessagePriority(p)
+	case float64:
+		return MessagePriority(p)
+	case string:
+		if n, err := strconv.Atoi(p); err == nil {
+			return MessagePriority(n)
+		}
+	}
+
+	return PriorityNormal
+}
+
+// AdmissionStats contains statistics for a concurrency limiter
+type AdmissionStats struct {
+	Name     string        `json:"name"`
+	Limit    int           `json:"limit"`
+	InUse    int           `json:"in_use"`
+	Queued   int           `json:"queued"`
+	Admitted int64         `json:"admitted"`
+	Rejected int64         `json:"rejected"`
+	AvgHold  time.Duration `json:"avg_hold"`
+}
+
+// admissionWaiter is a call waiting for a concurrency slot
+type admissionWaiter struct {
+	priority MessagePriority
+	seq      uint64
+	ready    chan struct{}
+	index    int
+}
+
+// waiterQueue orders waiters by priority, then arrival
+type waiterQueue []*admissionWaiter
+
+func (q waiterQueue) Len() int { return len(q) }
+
+func (q waiterQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waiterQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waiterQueue) Push(x interface{}) {
+	w := x.(*admissionWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waiterQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
+
+// concurrencyLimiter bounds in-flight calls and queues the excess by priority
+type concurrencyLimiter struct {
+	name     string
+	limit    int
+	maxQueue int
+	inUse    int
+	seq      uint64
+	waiters  waiterQueue
+	admitted int64
+	rejected int64
+	avgHold  time.Duration
+	mutex    sync.Mutex
+}
+
+// newConcurrencyLimiter creates a limiter allowing limit concurrent calls and maxQueue waiters
+func newConcurrencyLimiter(name string, limit, maxQueue int) *concurrencyLimiter {
+	if maxQueue < 0 {
+		maxQueue = 0
+	}
+
+	return &concurrencyLimiter{
+		name:     name,
+		limit:    limit,
+		maxQueue: maxQueue,
+	}
+}
+
+// acquire obtains a slot, waiting in priority order if none is free
+func (l *concurrencyLimiter) acquire(ctx context.Context, priority MessagePriority) error {
+	l.mutex.Lock()
+
+	if l.inUse < l.limit && len(l.waiters) == 0 {
+		l.inUse++
+		l.admitted++
+		l.mutex.Unlock()
+		return nil
+	}
+
+	if len(l.waiters) >= l.maxQueue {
+		l.rejected++
+		l.mutex.Unlock()
+		return fmt.Errorf("%w: %s wait queue is full", ErrBridgeOverloaded, l.name)
+	}
+
+	// Reject immediately when the expected wait already exceeds the deadline
+	if deadline, ok := ctx.Deadline(); ok {
+		if wait := l.estimatedWait(len(l.waiters) + 1); wait > 0 && wait >= time.Until(deadline) {
+			l.rejected++
+			l.mutex.Unlock()
+			return fmt.Errorf("%w: %s expected wait %s exceeds deadline", ErrBridgeOverloaded, l.name, wait)
+		}
+	}
+
+	l.seq++
+	waiter := &admissionWaiter{
+		priority: priority,
+		seq:      l.seq,
+		ready:    make(chan struct{}),
+	}
+	heap.Push(&l.waiters, waiter)
+	l.mutex.Unlock()
+
+	select {
+	case <-waiter.ready:
+		return nil
+	case <-ctx.Done():
+		l.mutex.Lock()
+		if waiter.index >= 0 {
+			heap.Remove(&l.waiters, waiter.index)
+			l.rejected++
+			l.mutex.Unlock()
+			return fmt.Errorf("%w: %s: %v", ErrBridgeOverloaded, l.name, ctx.Err())
+		}
+		l.mutex.Unlock()
+
+		// The slot was handed over as the context expired; pass it on
+		l.release(0)
+		return fmt.Errorf("%w: %s: %v", ErrBridgeOverloaded, l.name, ctx.Err())
+	}
+}
+
+// release returns a slot, handing it directly to the highest priority waiter
+func (l *concurrencyLimiter) release(held time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if held > 0 {
+		// Exponentially weighted moving average of slot hold time
+		if l.avgHold == 0 {
+			l.avgHold = held
+		} else {
+			l.avgHold = (l.avgHold*7 + held) / 8
+		}
+	}
+
+	if len(l.waiters) > 0 {
+		waiter := heap.Pop(&l.waiters).(*admissionWaiter)
+		l.admitted++
+		close(waiter.ready)
+		return
+	}
+
+	if l.inUse > 0 {
+		l.inUse--
+	}
+}
+
+// estimatedWait approximates how long a waiter at the given queue position will wait;
+// must be called with the mutex held
+func (l *concurrencyLimiter) estimatedWait(position int) time.Duration {
+	if l.avgHold == 0 || l.limit <= 0 {
+		return 0
+	}
+
+	rounds := (position + l.limit - 1) / l.limit
+	return time.Duration(rounds) * l.avgHold
+}
+
+// stats returns the limiter statistics
+func (l *concurrencyLimiter) stats() AdmissionStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return AdmissionStats{
+		Name:     l.name,
+		Limit:    l.limit,
+		InUse:    l.inUse,
+		Queued:   len(l.waiters),
+		Admitted: l.admitted,
+		Rejected: l.rejected,
+		AvgHold:  l.avgHold,
+	}
+}
+
+// getAdapterLimiter returns the concurrency limiter for an adapter, creating it on first use
+func (b *Bridge) getAdapterLimiter(adapterName string) *concurrencyLimiter {
+	if b.options.MaxAdapterConcurrency <= 0 {
+		return nil
+	}
+
+	b.limitersMutex.Lock()
+	defer b.limitersMutex.Unlock()
+
+	limiter, exists := b.adapterLimiters[adapterName]
+	if !exists {
+		limiter = newConcurrencyLimiter("adapter "+adapterName, b.options.MaxAdapterConcurrency, b.options.MaxQueueSize)
+		b.adapterLimiters[adapterName] = limiter
+	}
+
+	return limiter
+}
+
+// admit acquires the adapter and bridge concurrency slots for a call and returns
+// a function that releases them. The adapter slot is taken first so a saturated
+// adapter queues its own callers without holding bridge-wide capacity.
+func (b *Bridge) admit(ctx context.Context, target BridgeTarget) (func(), error) {
+	priority := callPriority(ctx, target)
+
+	adapterLimiter := b.getAdapterLimiter(target.Adapter)
+	if adapterLimiter != nil {
+		if err := adapterLimiter.acquire(ctx, priority); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.limiter != nil {
+		if err := b.limiter.acquire(ctx, priority); err != nil {
+			if adapterLimiter != nil {
+				adapterLimiter.release(0)
+			}
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	return func() {
+		held := time.Since(start)
+		if b.limiter != nil {
+			b.limiter.release(held)
+		}
+		if adapterLimiter != nil {
+			adapterLimiter.release(held)
+		}
+	}, nil
+}
+
+// AdmissionStats returns concurrency statistics for the bridge and each adapter
+func (b *Bridge) AdmissionStats() []AdmissionStats {
+	stats := make([]AdmissionStats, 0)
+	if b.limiter != nil {
+		stats = append(stats, b.limiter.stats())
+	}
+
+	b.limitersMutex.Lock()
+	for _, limiter := range b.adapterLimiters {
+		stats = append(stats, limiter.stats())
+	}
+	b.limitersMutex.Unlock()
+
+	return stats
+}
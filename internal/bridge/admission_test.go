@@ -0,0 +1,153 @@
+package bridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queueWaiter starts an acquire in the background once the previous waiters
+// are queued, so arrival order is deterministic
+func queueWaiter(t *testing.T, limiter *concurrencyLimiter, priority MessagePriority, admitted chan<- MessagePriority) {
+	t.Helper()
+
+	queued := limiter.stats().Queued
+	go func() {
+		if err := limiter.acquire(context.Background(), priority); err == nil {
+			admitted <- priority
+		}
+	}()
+	require.Eventually(t, func() bool {
+		return limiter.stats().Queued == queued+1
+	}, time.Second, time.Millisecond)
+}
+
+func TestCallPriority(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		option interface{}
+		want   MessagePriority
+	}{
+		{"default", context.Background(), nil, PriorityNormal},
+		{"context", WithPriority(context.Background(), PriorityCritical), PriorityLow, PriorityCritical},
+		{"typed option", context.Background(), PriorityHigh, PriorityHigh},
+		{"int option", context.Background(), 1, PriorityLow},
+		{"JSON option", context.Background(), 3.0, PriorityHigh},
+		{"string option", context.Background(), "4", PriorityCritical},
+		{"unreadable option", context.Background(), "urgent", PriorityNormal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := BridgeTarget{Options: map[string]interface{}{}}
+			if tt.option != nil {
+				target.Options["priority"] = tt.option
+			}
+			assert.Equal(t, tt.want, callPriority(tt.ctx, target))
+		})
+	}
+}
+
+func TestConcurrencyLimiterAdmitsByPriority(t *testing.T) {
+	limiter := newConcurrencyLimiter("bridge", 1, 10)
+	require.NoError(t, limiter.acquire(context.Background(), PriorityNormal))
+
+	admitted := make(chan MessagePriority, 4)
+	queueWaiter(t, limiter, PriorityLow, admitted)
+	queueWaiter(t, limiter, PriorityNormal, admitted)
+	queueWaiter(t, limiter, PriorityHigh, admitted)
+	queueWaiter(t, limiter, PriorityNormal, admitted)
+
+	var order []MessagePriority
+	for i := 0; i < 4; i++ {
+		limiter.release(time.Millisecond)
+		order = append(order, <-admitted)
+	}
+	assert.Equal(t, []MessagePriority{PriorityHigh, PriorityNormal, PriorityNormal, PriorityLow}, order)
+
+	limiter.release(time.Millisecond)
+	stats := limiter.stats()
+	assert.Equal(t, 0, stats.InUse)
+	assert.Equal(t, int64(5), stats.Admitted)
+	assert.Equal(t, time.Millisecond, stats.AvgHold)
+}
+
+func TestConcurrencyLimiterRejects(t *testing.T) {
+	t.Run("full queue", func(t *testing.T) {
+		limiter := newConcurrencyLimiter("adapter orders", 1, 1)
+		require.NoError(t, limiter.acquire(context.Background(), PriorityNormal))
+		queueWaiter(t, limiter, PriorityNormal, make(chan MessagePriority, 1))
+
+		err := limiter.acquire(context.Background(), PriorityCritical)
+		assert.ErrorIs(t, err, ErrBridgeOverloaded)
+		assert.Contains(t, err.Error(), "wait queue is full")
+		assert.Equal(t, int64(1), limiter.stats().Rejected)
+	})
+
+	t.Run("cancelled waiter leaves the queue", func(t *testing.T) {
+		limiter := newConcurrencyLimiter("bridge", 1, 1)
+		require.NoError(t, limiter.acquire(context.Background(), PriorityNormal))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, limiter.acquire(ctx, PriorityNormal), ErrBridgeOverloaded)
+		assert.Equal(t, 0, limiter.stats().Queued)
+
+		// The slot goes back to the pool rather than to the departed waiter
+		limiter.release(0)
+		assert.NoError(t, limiter.acquire(context.Background(), PriorityNormal))
+	})
+
+	t.Run("expected wait exceeds deadline", func(t *testing.T) {
+		limiter := newConcurrencyLimiter("bridge", 1, 10)
+		require.NoError(t, limiter.acquire(context.Background(), PriorityNormal))
+		limiter.release(time.Second)
+		require.NoError(t, limiter.acquire(context.Background(), PriorityNormal))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := limiter.acquire(ctx, PriorityNormal)
+		assert.ErrorIs(t, err, ErrBridgeOverloaded)
+		assert.Contains(t, err.Error(), "exceeds deadline")
+		assert.Less(t, time.Since(start), 50*time.Millisecond, "rejected without waiting")
+	})
+}
+
+func TestAdmitBulkheads(t *testing.T) {
+	b := NewBridge(&BridgeOptions{
+		MaxConcurrency:        2,
+		MaxAdapterConcurrency: 1,
+		MaxQueueSize:          0,
+	}, &defaultLogger{})
+	ctx := context.Background()
+
+	releaseOrders, err := b.admit(ctx, BridgeTarget{Adapter: "orders"})
+	require.NoError(t, err)
+
+	// A saturated adapter does not take bridge capacity from the others
+	_, err = b.admit(ctx, BridgeTarget{Adapter: "orders"})
+	assert.ErrorIs(t, err, ErrBridgeOverloaded)
+
+	releaseRisk, err := b.admit(ctx, BridgeTarget{Adapter: "risk"})
+	require.NoError(t, err)
+
+	// The bridge-wide limit still applies, and a rejected call frees its adapter slot
+	_, err = b.admit(ctx, BridgeTarget{Adapter: "prices"})
+	assert.ErrorIs(t, err, ErrBridgeOverloaded)
+
+	releaseOrders()
+	releasePrices, err := b.admit(ctx, BridgeTarget{Adapter: "prices"})
+	require.NoError(t, err)
+
+	releaseRisk()
+	releasePrices()
+	for _, stats := range b.AdmissionStats() {
+		assert.Zero(t, stats.InUse, stats.Name)
+	}
+	assert.Len(t, b.AdmissionStats(), 4)
+}
@@ -6,11 +6,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/adapters"
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/protocols"
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/discovery"
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/metrics"
 )
@@ -27,6 +29,8 @@ var (
 	ErrProtocolInitFailed       = errors.New("protocol initialization failed")
 	ErrBridgeServiceUnavailable = errors.New("service unavailable")
 	ErrBridgeShuttingDown       = errors.New("bridge is shutting down")
+	ErrCircuitOpen              = errors.New("circuit breaker is open")
+	ErrBridgeOverloaded         = errors.New("bridge overloaded")
 )
 
 // BridgeStatus represents the status of the bridge
@@ -51,47 +55,87 @@ type BridgeTarget struct {
 
 // BridgeOptions contains configuration for the bridge
 type BridgeOptions struct {
-	DefaultTimeout    time.Duration
-	RetryCount        int
-	RetryDelay        time.Duration
-	MaxConcurrency    int
-	EnableDiscovery   bool
-	EnableMetrics     bool
-	EnableCompression bool
-	BufferSize        int
-	LogLevel          string
+	DefaultTimeout        time.Duration
+	RetryCount            int
+	RetryDelay            time.Duration
+	RetryBackoffFactor    float64
+	RetryMaxDelay         time.Duration
+	RetryJitter           float64
+	CircuitBreaker        CircuitBreakerOptions
+	LoadBalancing         LoadBalancingStrategy
+	MaxConcurrency        int
+	MaxAdapterConcurrency int
+	MaxQueueSize          int
+	EnableDiscovery       bool
+	EnableMetrics         bool
+	EnableCompression     bool
+	CompressionAlgorithm  string
+	CompressionThreshold  int
+	BufferSize            int
+	StreamWindow          int
+	TokenLimits           TokenLimits          // Limits on call payload tokens; zero values disable a limit
+	TokenModel            string               // Model whose token rate is used to estimate tokens
+	Validator             *protocols.Validator // Checks call payloads against service methods; nil uses DefaultServiceManifest
+	LogLevel              string
 }
 
 // DefaultBridgeOptions returns default options
 func DefaultBridgeOptions() *BridgeOptions {
 	return &BridgeOptions{
-		DefaultTimeout:    30 * time.Second,
-		RetryCount:        3,
-		RetryDelay:        time.Second,
-		MaxConcurrency:    100,
-		EnableDiscovery:   true,
-		EnableMetrics:     true,
-		EnableCompression: true,
-		BufferSize:        1024,
-		LogLevel:          "info",
+		DefaultTimeout:        30 * time.Second,
+		RetryCount:            3,
+		RetryDelay:            time.Second,
+		RetryBackoffFactor:    2.0,
+		RetryMaxDelay:         10 * time.Second,
+		RetryJitter:           0.2,
+		CircuitBreaker:        DefaultCircuitBreakerOptions(),
+		LoadBalancing:         LoadBalanceRoundRobin,
+		MaxConcurrency:        100,
+		MaxAdapterConcurrency: 50,
+		MaxQueueSize:          256,
+		EnableDiscovery:       true,
+		EnableMetrics:         true,
+		EnableCompression:     true,
+		CompressionAlgorithm:  CompressionGzip,
+		CompressionThreshold:  1024,
+		BufferSize:            1024,
+		StreamWindow:          64,
+		LogLevel:              "info",
 	}
 }
 
 // Bridge provides cross-component communication
 type Bridge struct {
-	adapters         map[string]adapters.SharedAdapter
-	protocols        map[string]*plugins.ProtocolPlugin
-	discoveryClient  *discovery.BridgeDiscovery
-	metricsCollector *metrics.Collector
-	options          *BridgeOptions
-	status           BridgeStatus
-	adaptersMutex    sync.RWMutex
-	protocolsMutex   sync.RWMutex
-	statusMutex      sync.RWMutex
-	logger           BridgeLogger
-	lastError        error
-	ctx              context.Context
-	cancel           context.CancelFunc
+	adapters           map[string]adapters.Adapter
+	adapterRegistry    *adapters.AdapterRegistry
+	protocols          map[string]*plugins.ProtocolPlugin
+	breakers           map[string]*circuitBreaker
+	limiter            *concurrencyLimiter
+	adapterLimiters    map[string]*concurrencyLimiter
+	peerEncodings      map[string][]string
+	discoveryClient    *discovery.BridgeDiscovery
+	healthChecker      *discovery.HealthCheckerImpl
+	balancer           *loadBalancer
+	subscriptions      map[string]*TopicSubscription
+	outbox             *Outbox
+	tokens             *tokenBudget
+	validator          *protocols.Validator
+	metricsCollector   *metrics.Collector
+	options            *BridgeOptions
+	status             BridgeStatus
+	adaptersMutex      sync.RWMutex
+	protocolsMutex     sync.RWMutex
+	breakersMutex      sync.RWMutex
+	limitersMutex      sync.Mutex
+	encodingsMutex     sync.RWMutex
+	discoveryMutex     sync.RWMutex
+	subscriptionsMutex sync.RWMutex
+	outboxMutex        sync.RWMutex
+	statusMutex        sync.RWMutex
+	logger             BridgeLogger
+	lastError          error
+	ctx                context.Context
+	cancel             context.CancelFunc
 }
 
 // BridgeLogger interface
@@ -108,17 +152,36 @@ func NewBridge(options *BridgeOptions, logger BridgeLogger) *Bridge {
 		options = DefaultBridgeOptions()
 	}
 
+	validator := options.Validator
+	if validator == nil {
+		validator = protocols.DefaultValidator()
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Bridge{
-		adapters:  make(map[string]adapters.SharedAdapter),
-		protocols: make(map[string]*plugins.ProtocolPlugin),
-		options:   options,
-		status:    StatusUninitialized,
-		logger:    logger,
-		ctx:       ctx,
-		cancel:    cancel,
+	b := &Bridge{
+		adapters:        make(map[string]adapters.Adapter),
+		adapterRegistry: adapters.GetGlobalAdapterRegistry(),
+		protocols:       make(map[string]*plugins.ProtocolPlugin),
+		breakers:        make(map[string]*circuitBreaker),
+		adapterLimiters: make(map[string]*concurrencyLimiter),
+		peerEncodings:   make(map[string][]string),
+		balancer:        newLoadBalancer(),
+		subscriptions:   make(map[string]*TopicSubscription),
+		tokens:          newTokenBudget(options.TokenLimits, options.TokenModel),
+		validator:       validator,
+		options:         options,
+		status:          StatusUninitialized,
+		logger:          logger,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+
+	if options.MaxConcurrency > 0 {
+		b.limiter = newConcurrencyLimiter("bridge", options.MaxConcurrency, options.MaxQueueSize)
 	}
+
+	return b
 }
 
 // Initialize initializes the bridge
@@ -140,12 +203,19 @@ func (b *Bridge) Initialize(ctx context.Context) error {
 		b.metricsCollector = metrics.NewCollector(nil)
 	}
 
-	// Initialize discovery if enabled (skip for now due to compatibility issues)
-	// We'll need to adapt the discovery system to match the interface expected here
-	// or create a mock implementation for testing
+	// Service routing requires a discovery client set through SetDiscovery
 	if b.options.EnableDiscovery {
-		b.logger.Info("Discovery service integration disabled pending implementation", nil)
-		// This would be implemented later to properly initialize the discovery system
+		b.discoveryMutex.RLock()
+		configured := b.discoveryClient != nil
+		b.discoveryMutex.RUnlock()
+
+		if configured {
+			b.logger.Info("Service discovery routing enabled", map[string]interface{}{
+				"strategy": string(b.options.LoadBalancing),
+			})
+		} else {
+			b.logger.Info("No discovery client configured, service names will not be resolved", nil)
+		}
 	}
 
 	// Initialize adapters
@@ -178,7 +248,7 @@ func (b *Bridge) Initialize(ctx context.Context) error {
 }
 
 // RegisterAdapter registers an adapter with the bridge
-func (b *Bridge) RegisterAdapter(name string, adapter adapters.SharedAdapter) error {
+func (b *Bridge) RegisterAdapter(name string, adapter adapters.Adapter) error {
 	b.adaptersMutex.Lock()
 	defer b.adaptersMutex.Unlock()
 
@@ -191,6 +261,43 @@ func (b *Bridge) RegisterAdapter(name string, adapter adapters.SharedAdapter) er
 	return nil
 }
 
+// SetAdapterRegistry sets the factory registry used by CreateAdapter
+func (b *Bridge) SetAdapterRegistry(registry *adapters.AdapterRegistry) {
+	b.adaptersMutex.Lock()
+	defer b.adaptersMutex.Unlock()
+	b.adapterRegistry = registry
+}
+
+// AdapterRegistry returns the factory registry used by CreateAdapter
+func (b *Bridge) AdapterRegistry() *adapters.AdapterRegistry {
+	b.adaptersMutex.RLock()
+	defer b.adaptersMutex.RUnlock()
+	return b.adapterRegistry
+}
+
+// CreateAdapter creates an adapter from the registry and registers it under
+// config.Name. On a bridge that is already initialized the adapter is
+// initialized as well.
+func (b *Bridge) CreateAdapter(ctx context.Context, config adapters.SharedAdapterConfig) (adapters.Adapter, error) {
+	adapter, err := b.AdapterRegistry().CreateAdapter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.Status() == StatusReady {
+		if err := adapter.Initialize(ctx); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrAdapterInitFailed, config.Name, err)
+		}
+	}
+
+	if err := b.RegisterAdapter(config.Name, adapter); err != nil {
+		adapter.Shutdown(ctx)
+		return nil, err
+	}
+
+	return adapter, nil
+}
+
 // RegisterProtocol registers a protocol with the bridge
 func (b *Bridge) RegisterProtocol(name string, protocol *plugins.ProtocolPlugin) error {
 	b.protocolsMutex.Lock()
@@ -214,14 +321,24 @@ func (b *Bridge) Status() BridgeStatus {
 
 // Call sends a message through the bridge
 func (b *Bridge) Call(ctx context.Context, target BridgeTarget, operation string, data interface{}) (interface{}, error) {
+	result, _, err := b.CallWithTokenInfo(ctx, target, operation, data)
+	return result, err
+}
+
+// CallWithTokenInfo sends a message through the bridge and reports its token
+// usage. Payloads over the input token limit are rejected with
+// ErrTokenBudgetExceeded, or with chunking enabled are sent to the same
+// instance as a correlated sequence of overlapping chunks whose replies are
+// reassembled. Usage is accounted to the caller set with WithCaller.
+func (b *Bridge) CallWithTokenInfo(ctx context.Context, target BridgeTarget, operation string, data interface{}) (interface{}, *TokenInfo, error) {
 	// Check bridge status
 	if b.Status() != StatusReady {
-		return nil, ErrBridgeNotInitialized
+		return nil, nil, ErrBridgeNotInitialized
 	}
 
 	// Validate target
 	if target.Adapter == "" || target.Protocol == "" {
-		return nil, ErrInvalidTarget
+		return nil, nil, ErrInvalidTarget
 	}
 
 	// Create timeout context if not already specified
@@ -236,12 +353,18 @@ func (b *Bridge) Call(ctx context.Context, target BridgeTarget, operation string
 		target.Operation = operation
 	}
 
+	// Check the payload against the service method it calls, whatever the
+	// protocol; operations outside the manifest are passed through
+	if err := b.validator.Validate(target.Operation, data); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+
 	// Get protocol
 	b.protocolsMutex.RLock()
 	protocol, exists := b.protocols[target.Protocol]
 	b.protocolsMutex.RUnlock()
 	if !exists {
-		return nil, fmt.Errorf("%w: %s", ErrProtocolNotFound, target.Protocol)
+		return nil, nil, fmt.Errorf("%w: %s", ErrProtocolNotFound, target.Protocol)
 	}
 
 	// Get adapter
@@ -249,46 +372,133 @@ func (b *Bridge) Call(ctx context.Context, target BridgeTarget, operation string
 	adapter, exists := b.adapters[target.Adapter]
 	b.adaptersMutex.RUnlock()
 	if !exists {
-		return nil, fmt.Errorf("%w: %s", ErrAdapterNotFound, target.Adapter)
+		return nil, nil, fmt.Errorf("%w: %s", ErrAdapterNotFound, target.Adapter)
+	}
+	if !adapter.Capabilities().Has(adapters.CapRequestResponse) {
+		return nil, nil, fmt.Errorf("%w: %s is not request/response", adapters.ErrCapabilityNotSupported, target.Adapter)
 	}
 
-	// Create protocol message
-	message := &plugins.ProtocolMessage{
-		ID:        generateID(),
-		Type:      target.Operation,
-		Payload:   data,
-		Timestamp: time.Now(),
+	// Check the payload against the token limits
+	caller := callCaller(ctx, target)
+	plan, err := b.tokens.plan(caller, data)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Encode message
-	encodedData, err := protocol.Encode(ctx, message)
+	// Create protocol messages
+	var messages []*plugins.ProtocolMessage
+	if plan.chunks == nil {
+		messages = []*plugins.ProtocolMessage{{
+			ID:        generateID(),
+			Type:      target.Operation,
+			Headers:   make(map[string]string),
+			Payload:   data,
+			Timestamp: time.Now(),
+		}}
+	} else {
+		messages = plan.chunkMessages(b.tokens, target.Operation)
+		b.logger.Debug("Chunking bridge call payload", map[string]interface{}{
+			"caller": caller,
+			"tokens": plan.tokens,
+			"chunks": len(messages),
+		})
+	}
+
+	// Resolve the target service to a healthy instance; chunks share it
+	ctx, done, err := b.routeToService(ctx, target)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode message: %w", err)
+		return nil, nil, err
 	}
+	defer done()
 
-	// Send message through adapter
-	start := time.Now()
-	response, err := adapter.Send(ctx, encodedData)
-	duration := time.Since(start)
+	// Wait for a concurrency slot
+	release, err := b.admit(ctx, target)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	info := &TokenInfo{
+		ModelContext: b.options.TokenModel,
+		IsChunked:    plan.chunks != nil,
+		TotalChunks:  len(messages),
+	}
+	defer b.tokens.record(caller, info)
 
-	// Record metrics
-	if b.metricsCollector != nil {
-		tags := map[string]string{
-			"adapter":   target.Adapter,
-			"protocol":  target.Protocol,
-			"operation": target.Operation,
+	responses := make([]*plugins.ProtocolMessage, 0, len(messages))
+	for i, message := range messages {
+		input := plan.tokens
+		if info.IsChunked {
+			input = b.tokens.count(plan.chunks[i])
 		}
+		info.InputTokens += input
+		info.TotalTokens += input
 
-		b.metricsCollector.Collect("bridge", "request_duration", duration.Seconds(), tags)
+		response, err := b.exchange(ctx, protocol, adapter, target, message)
+		if err != nil {
+			return nil, info, err
+		}
 
-		// Record error metric if needed
+		// Responses are always counted, even when they break a limit
+		output, _, err := payloadText(response.Payload)
 		if err != nil {
-			b.metricsCollector.IncCounter("errors", tags)
+			return nil, info, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		outputTokens := b.tokens.count(output)
+		info.OutputTokens += outputTokens
+		info.TotalTokens += outputTokens
+		if err := b.tokens.checkExchange(input, outputTokens); err != nil {
+			return nil, info, err
 		}
+
+		responses = append(responses, response)
+	}
+
+	if !info.IsChunked {
+		return responses[0].Payload, info, nil
+	}
+
+	result, err := reassembleResponses(responses, plan.binary)
+	if err != nil {
+		return nil, info, err
+	}
+	return result, info, nil
+}
+
+// exchange encodes and sends one protocol message and decodes the reply
+func (b *Bridge) exchange(ctx context.Context, protocol *plugins.ProtocolPlugin, adapter adapters.Adapter, target BridgeTarget, message *plugins.ProtocolMessage) (*plugins.ProtocolMessage, error) {
+	// Advertise the encodings we can decompress so the peer may compress its
+	// reply; compressed frames repeat this in their uncompressed header
+	if b.options.EnableCompression {
+		message.Headers[HeaderAcceptEncoding] = strings.Join(ListCompressors(), ",")
+	}
+
+	// Encode message
+	encodedData, err := protocol.Encode(ctx, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	// Compress encoded payload
+	encodedData, err = b.compressForTarget(target, encodedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress message: %w", err)
 	}
 
+	// Send message through adapter, retrying transient failures; every resend
+	// carries the message ID as the idempotency key of the call
+	response, err := b.sendWithRetry(adapters.WithIdempotencyKey(ctx, message.ID), adapter, target, encodedData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decompress response if the peer compressed it
+	if accepted, ok := PayloadAcceptEncoding(response); ok && b.options.EnableCompression {
+		b.rememberPeerEncodings(target, accepted)
+	}
+	response, _, err = DecompressPayload(response)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send message: %w", err)
+		return nil, fmt.Errorf("failed to decompress response: %w", err)
 	}
 
 	// Decode response
@@ -297,7 +507,31 @@ func (b *Bridge) Call(ctx context.Context, target BridgeTarget, operation string
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return responseMsg.Payload, nil
+	if value, ok := responseMsg.Headers[HeaderAcceptEncoding]; ok && b.options.EnableCompression {
+		b.rememberPeerEncodings(target, splitEncodings(value))
+	}
+
+	return responseMsg, nil
+}
+
+// recordCallMetrics records metrics for a single adapter send attempt
+func (b *Bridge) recordCallMetrics(target BridgeTarget, duration time.Duration, err error) {
+	if b.metricsCollector == nil {
+		return
+	}
+
+	tags := map[string]string{
+		"adapter":   target.Adapter,
+		"protocol":  target.Protocol,
+		"operation": target.Operation,
+	}
+
+	b.metricsCollector.Collect("bridge", "request_duration", duration.Seconds(), tags)
+
+	// Record error metric if needed
+	if err != nil {
+		b.metricsCollector.IncCounter("errors", tags)
+	}
 }
 
 // Shutdown shuts down the bridge
@@ -315,6 +549,19 @@ func (b *Bridge) Shutdown(ctx context.Context) error {
 	// Cancel context
 	b.cancel()
 
+	// Stop event delivery
+	b.closeSubscriptions()
+
+	// Stop outbox delivery; undelivered messages stay persisted for the next start
+	b.outboxMutex.Lock()
+	if b.outbox != nil {
+		if err := b.outbox.Close(); err != nil {
+			b.logger.Error(fmt.Sprintf("Error closing outbox: %v", err), nil)
+		}
+		b.outbox = nil
+	}
+	b.outboxMutex.Unlock()
+
 	// Shutdown adapters
 	b.adaptersMutex.Lock()
 	for name, adapter := range b.adapters {
@@ -326,7 +573,7 @@ func (b *Bridge) Shutdown(ctx context.Context) error {
 
 	// Clear registries
 	b.adaptersMutex.Lock()
-	b.adapters = make(map[string]adapters.SharedAdapter)
+	b.adapters = make(map[string]adapters.Adapter)
 	b.adaptersMutex.Unlock()
 
 	b.protocolsMutex.Lock()
@@ -338,7 +585,7 @@ func (b *Bridge) Shutdown(ctx context.Context) error {
 }
 
 // GetAdapter returns a registered adapter
-func (b *Bridge) GetAdapter(name string) (adapters.SharedAdapter, error) {
+func (b *Bridge) GetAdapter(name string) (adapters.Adapter, error) {
 	b.adaptersMutex.RLock()
 	defer b.adaptersMutex.RUnlock()
 
@@ -0,0 +1,499 @@
+// circuit_breaker.go - Retry backoff and per-target circuit breaking for bridge calls
+
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/adapters"
+)
+
+// CircuitState represents the state of a circuit breaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreakerOptions configures the per-target circuit breakers of a bridge
+type CircuitBreakerOptions struct {
+	Enabled          bool
+	FailureThreshold int           // Consecutive failures before the circuit opens
+	SuccessThreshold int           // Consecutive half-open successes before the circuit closes
+	OpenTimeout      time.Duration // Time the circuit stays open before allowing trial calls
+	HalfOpenMaxCalls int           // Maximum concurrent trial calls while half-open
+}
+
+// DefaultCircuitBreakerOptions returns default circuit breaker options
+func DefaultCircuitBreakerOptions() CircuitBreakerOptions {
+	return CircuitBreakerOptions{
+		Enabled:          true,
+		FailureThreshold: 5,
+		SuccessThreshold: 2,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenMaxCalls: 1,
+	}
+}
+
+// CircuitBreakerSnapshot is a point-in-time view of a circuit breaker
+type CircuitBreakerSnapshot struct {
+	Key                 string       `json:"key"`
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	TotalSuccesses      int64        `json:"total_successes"`
+	TotalFailures       int64        `json:"total_failures"`
+	Rejected            int64        `json:"rejected"`
+	LastTransition      time.Time    `json:"last_transition"`
+	OpenedAt            time.Time    `json:"opened_at,omitempty"`
+}
+
+// circuitBreaker tracks call outcomes for a single adapter/protocol/service target
+type circuitBreaker struct {
+	key              string
+	options          CircuitBreakerOptions
+	state            CircuitState
+	failures         int
+	successes        int
+	halfOpenInFlight int
+	openedAt         time.Time
+	lastTransition   time.Time
+	totalSuccesses   int64
+	totalFailures    int64
+	rejected         int64
+	onTransition     func(key string, from, to CircuitState)
+	mutex            sync.Mutex
+}
+
+// newCircuitBreaker creates a closed circuit breaker
+func newCircuitBreaker(key string, options CircuitBreakerOptions, onTransition func(string, CircuitState, CircuitState)) *circuitBreaker {
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = 1
+	}
+	if options.SuccessThreshold <= 0 {
+		options.SuccessThreshold = 1
+	}
+	if options.HalfOpenMaxCalls <= 0 {
+		options.HalfOpenMaxCalls = 1
+	}
+
+	return &circuitBreaker{
+		key:            key,
+		options:        options,
+		state:          CircuitClosed,
+		lastTransition: time.Now(),
+		onTransition:   onTransition,
+	}
+}
+
+// allow reports whether a call may proceed, moving an expired open circuit to half-open
+func (cb *circuitBreaker) allow() error {
+	cb.mutex.Lock()
+	var from, to CircuitState
+
+	if cb.state == CircuitOpen && time.Since(cb.openedAt) >= cb.options.OpenTimeout {
+		from, to = cb.setState(CircuitHalfOpen)
+	}
+
+	var err error
+	switch cb.state {
+	case CircuitOpen:
+		cb.rejected++
+		err = fmt.Errorf("%w: %s", ErrCircuitOpen, cb.key)
+	case CircuitHalfOpen:
+		if cb.halfOpenInFlight >= cb.options.HalfOpenMaxCalls {
+			cb.rejected++
+			err = fmt.Errorf("%w: %s", ErrCircuitOpen, cb.key)
+		} else {
+			cb.halfOpenInFlight++
+		}
+	}
+	cb.mutex.Unlock()
+
+	cb.notify(from, to)
+	return err
+}
+
+// recordSuccess records a successful call
+func (cb *circuitBreaker) recordSuccess() {
+	cb.mutex.Lock()
+	var from, to CircuitState
+
+	cb.totalSuccesses++
+	cb.failures = 0
+
+	if cb.state == CircuitHalfOpen {
+		cb.releaseTrial()
+		cb.successes++
+		if cb.successes >= cb.options.SuccessThreshold {
+			from, to = cb.setState(CircuitClosed)
+		}
+	}
+	cb.mutex.Unlock()
+
+	cb.notify(from, to)
+}
+
+// recordFailure records a failed call, opening the circuit when the threshold is reached
+func (cb *circuitBreaker) recordFailure() {
+	cb.mutex.Lock()
+	var from, to CircuitState
+
+	cb.totalFailures++
+	cb.failures++
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		cb.releaseTrial()
+		from, to = cb.setState(CircuitOpen)
+	case CircuitClosed:
+		if cb.failures >= cb.options.FailureThreshold {
+			from, to = cb.setState(CircuitOpen)
+		}
+	}
+	cb.mutex.Unlock()
+
+	cb.notify(from, to)
+}
+
+// release frees a half-open trial slot for a call whose outcome says nothing about target health
+func (cb *circuitBreaker) release() {
+	cb.mutex.Lock()
+	if cb.state == CircuitHalfOpen {
+		cb.releaseTrial()
+	}
+	cb.mutex.Unlock()
+}
+
+// reset forces the circuit back to closed
+func (cb *circuitBreaker) reset() {
+	cb.mutex.Lock()
+	from, to := cb.setState(CircuitClosed)
+	cb.mutex.Unlock()
+
+	cb.notify(from, to)
+}
+
+// snapshot returns the current breaker state
+func (cb *circuitBreaker) snapshot() CircuitBreakerSnapshot {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return CircuitBreakerSnapshot{
+		Key:                 cb.key,
+		State:               cb.state,
+		ConsecutiveFailures: cb.failures,
+		TotalSuccesses:      cb.totalSuccesses,
+		TotalFailures:       cb.totalFailures,
+		Rejected:            cb.rejected,
+		LastTransition:      cb.lastTransition,
+		OpenedAt:            cb.openedAt,
+	}
+}
+
+// setState changes state and returns the transition; must be called with the mutex held
+func (cb *circuitBreaker) setState(state CircuitState) (CircuitState, CircuitState) {
+	from := cb.state
+	if from == state {
+		return "", ""
+	}
+
+	cb.state = state
+	cb.lastTransition = time.Now()
+	cb.successes = 0
+	cb.halfOpenInFlight = 0
+
+	switch state {
+	case CircuitOpen:
+		cb.openedAt = cb.lastTransition
+	case CircuitClosed:
+		cb.failures = 0
+		cb.openedAt = time.Time{}
+	}
+
+	return from, state
+}
+
+// releaseTrial decrements the half-open in-flight count; must be called with the mutex held
+func (cb *circuitBreaker) releaseTrial() {
+	if cb.halfOpenInFlight > 0 {
+		cb.halfOpenInFlight--
+	}
+}
+
+// notify invokes the transition callback outside the breaker lock
+func (cb *circuitBreaker) notify(from, to CircuitState) {
+	if to != "" && cb.onTransition != nil {
+		cb.onTransition(cb.key, from, to)
+	}
+}
+
+// circuitBreakerKey returns the breaker key for a target
+func circuitBreakerKey(target BridgeTarget) string {
+	return target.Adapter + "/" + target.Protocol + "/" + target.Service
+}
+
+// getCircuitBreaker returns the breaker for a target, creating it on first use
+func (b *Bridge) getCircuitBreaker(target BridgeTarget) *circuitBreaker {
+	if !b.options.CircuitBreaker.Enabled {
+		return nil
+	}
+
+	key := circuitBreakerKey(target)
+
+	b.breakersMutex.RLock()
+	breaker, exists := b.breakers[key]
+	b.breakersMutex.RUnlock()
+	if exists {
+		return breaker
+	}
+
+	b.breakersMutex.Lock()
+	defer b.breakersMutex.Unlock()
+
+	if breaker, exists = b.breakers[key]; exists {
+		return breaker
+	}
+
+	breaker = newCircuitBreaker(key, b.options.CircuitBreaker, b.onCircuitTransition)
+	b.breakers[key] = breaker
+	return breaker
+}
+
+// onCircuitTransition logs and exports circuit breaker state changes
+func (b *Bridge) onCircuitTransition(key string, from, to CircuitState) {
+	b.logger.Warn(fmt.Sprintf("Circuit breaker '%s' changed state", key), map[string]interface{}{
+		"from": string(from),
+		"to":   string(to),
+	})
+
+	if b.metricsCollector != nil {
+		b.metricsCollector.RecordCircuitBreakerTransition(key, string(from), string(to))
+	}
+}
+
+// CircuitBreakerState returns the circuit breaker state for a target
+func (b *Bridge) CircuitBreakerState(target BridgeTarget) (CircuitBreakerSnapshot, bool) {
+	b.breakersMutex.RLock()
+	breaker, exists := b.breakers[circuitBreakerKey(target)]
+	b.breakersMutex.RUnlock()
+	if !exists {
+		return CircuitBreakerSnapshot{}, false
+	}
+
+	return breaker.snapshot(), true
+}
+
+// CircuitBreakerStates returns the state of every circuit breaker, sorted by key
+func (b *Bridge) CircuitBreakerStates() []CircuitBreakerSnapshot {
+	b.breakersMutex.RLock()
+	snapshots := make([]CircuitBreakerSnapshot, 0, len(b.breakers))
+	for _, breaker := range b.breakers {
+		snapshots = append(snapshots, breaker.snapshot())
+	}
+	b.breakersMutex.RUnlock()
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Key < snapshots[j].Key
+	})
+
+	return snapshots
+}
+
+// ResetCircuitBreaker forces the circuit breaker for a target back to closed
+func (b *Bridge) ResetCircuitBreaker(target BridgeTarget) error {
+	b.breakersMutex.RLock()
+	breaker, exists := b.breakers[circuitBreakerKey(target)]
+	b.breakersMutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("no circuit breaker for target '%s'", circuitBreakerKey(target))
+	}
+
+	breaker.reset()
+	return nil
+}
+
+// sendWithRetry sends encoded data through an adapter, retrying transient failures
+// with jittered exponential backoff and consulting the target's circuit breaker
+func (b *Bridge) sendWithRetry(ctx context.Context, adapter adapters.Adapter, target BridgeTarget, data []byte) ([]byte, error) {
+	breaker := b.getCircuitBreaker(target)
+
+	var lastErr error
+	attempts := 0
+
+	for {
+		if breaker != nil {
+			if err := breaker.allow(); err != nil {
+				if lastErr != nil {
+					return nil, fmt.Errorf("%w (after %d attempts, last error: %v)", err, attempts, lastErr)
+				}
+				return nil, err
+			}
+		}
+
+		attempts++
+		start := time.Now()
+		response, err := adapter.Send(ctx, data)
+		b.recordCallMetrics(target, time.Since(start), err)
+
+		if err == nil {
+			if breaker != nil {
+				breaker.recordSuccess()
+			}
+			return response, nil
+		}
+
+		lastErr = err
+		retryable := ctx.Err() == nil && isRetryableError(err)
+
+		if breaker != nil {
+			if isTargetFailure(err) {
+				breaker.recordFailure()
+			} else {
+				breaker.release()
+			}
+		}
+
+		if !retryable || attempts > b.options.RetryCount {
+			break
+		}
+
+		delay := b.retryBackoff(attempts)
+
+		// Don't start a retry that cannot complete before the deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			break
+		}
+
+		b.logger.Debug(fmt.Sprintf("Retrying call to '%s'", circuitBreakerKey(target)), map[string]interface{}{
+			"attempt": attempts,
+			"delay":   delay.String(),
+			"error":   err.Error(),
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("failed to send message after %d attempts: %w", attempts, lastErr)
+		case <-b.ctx.Done():
+			timer.Stop()
+			return nil, ErrBridgeShuttingDown
+		case <-timer.C:
+		}
+	}
+
+	return nil, fmt.Errorf("failed to send message after %d attempts: %w", attempts, lastErr)
+}
+
+// retryBackoff returns the jittered exponential delay before the given retry attempt
+func (b *Bridge) retryBackoff(attempt int) time.Duration {
+	base := float64(b.options.RetryDelay)
+	if base <= 0 {
+		return 0
+	}
+
+	factor := b.options.RetryBackoffFactor
+	if factor < 1 {
+		factor = 1
+	}
+
+	delay := base * math.Pow(factor, float64(attempt-1))
+	if b.options.RetryMaxDelay > 0 && delay > float64(b.options.RetryMaxDelay) {
+		delay = float64(b.options.RetryMaxDelay)
+	}
+
+	// Spread retries across [delay*(1-jitter), delay*(1+jitter)]
+	if jitter := b.options.RetryJitter; jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay = delay * (1 - jitter + 2*jitter*rand.Float64())
+	}
+
+	return time.Duration(delay)
+}
+
+// isTargetFailure reports whether a send error counts against the target's
+// circuit breaker; a call that ran out its deadline or failed without being
+// retryable still says the target is unhealthy, while a caller cancellation
+// or a request the caller got wrong says nothing about it
+func isTargetFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	for _, callerErr := range []error{
+		context.Canceled,
+		adapters.ErrCanceled,
+		ErrCircuitOpen,
+		adapters.ErrInvalidArgument,
+		adapters.SharedErrInvalidData,
+	} {
+		if errors.Is(err, callerErr) {
+			return false
+		}
+	}
+
+	var bridgeErr *adapters.BridgeError
+	if errors.As(err, &bridgeErr) {
+		switch bridgeErr.Code {
+		case "CANCELED", "INVALID_ARGUMENT":
+			return false
+		}
+	}
+
+	return true
+}
+
+// isRetryableError reports whether a send error is transient and worth retrying
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// Caller cancellation and open circuits are never retried
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	// Errors caused by the request itself will fail again
+	for _, fatal := range []error{
+		adapters.ErrInvalidArgument,
+		adapters.ErrPermissionDenied,
+		adapters.ErrUnauthenticated,
+		adapters.ErrCanceled,
+		adapters.SharedErrInvalidData,
+		adapters.SharedErrInvalidConfig,
+		adapters.SharedErrNotInitialized,
+	} {
+		if errors.Is(err, fatal) {
+			return false
+		}
+	}
+
+	var bridgeErr *adapters.BridgeError
+	if errors.As(err, &bridgeErr) {
+		switch bridgeErr.Code {
+		case "UNAVAILABLE", "DEADLINE_EXCEEDED", "RESOURCE_EXHAUSTED", "ABORTED", "INTERNAL", "UNKNOWN":
+			return true
+		default:
+			return false
+		}
+	}
+
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) {
+		return temporary.Temporary()
+	}
+
+	return true
+}
@@ -0,0 +1,314 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedAdapter is a request/response adapter whose Send returns a scripted
+// sequence of errors before succeeding; the embedded interface leaves the
+// methods the tests don't use unimplemented
+type scriptedAdapter struct {
+	adapters.Adapter
+	name     string
+	errs     []error
+	handler  func(ctx context.Context, data []byte) ([]byte, error)
+	calls    int
+	callsMux sync.Mutex
+}
+
+func (a *scriptedAdapter) Name() string { return a.name }
+
+func (a *scriptedAdapter) Capabilities() adapters.Capability { return adapters.CapRequestResponse }
+
+func (a *scriptedAdapter) Send(ctx context.Context, data []byte) ([]byte, error) {
+	a.callsMux.Lock()
+	call := a.calls
+	a.calls++
+	a.callsMux.Unlock()
+
+	if call < len(a.errs) && a.errs[call] != nil {
+		return nil, a.errs[call]
+	}
+	if a.handler != nil {
+		return a.handler(ctx, data)
+	}
+	return data, nil
+}
+
+func (a *scriptedAdapter) sendCalls() int {
+	a.callsMux.Lock()
+	defer a.callsMux.Unlock()
+	return a.calls
+}
+
+// temporaryError is a transient network-style error
+type temporaryError struct{ temporary bool }
+
+func (e temporaryError) Error() string   { return fmt.Sprintf("temporary=%v", e.temporary) }
+func (e temporaryError) Temporary() bool { return e.temporary }
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	var transitions []string
+	breaker := newCircuitBreaker("rest/json/orders", CircuitBreakerOptions{
+		Enabled:          true,
+		FailureThreshold: 2,
+		SuccessThreshold: 2,
+		OpenTimeout:      20 * time.Millisecond,
+		HalfOpenMaxCalls: 1,
+	}, func(key string, from, to CircuitState) {
+		transitions = append(transitions, string(from)+">"+string(to))
+	})
+
+	// A success resets the consecutive failure count
+	require.NoError(t, breaker.allow())
+	breaker.recordFailure()
+	breaker.recordSuccess()
+	breaker.recordFailure()
+	assert.Equal(t, CircuitClosed, breaker.snapshot().State)
+
+	breaker.recordFailure()
+	assert.Equal(t, CircuitOpen, breaker.snapshot().State)
+	assert.ErrorIs(t, breaker.allow(), ErrCircuitOpen)
+	assert.Equal(t, int64(1), breaker.snapshot().Rejected)
+
+	// After the open timeout one trial call is let through at a time
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, breaker.allow())
+	assert.Equal(t, CircuitHalfOpen, breaker.snapshot().State)
+	assert.ErrorIs(t, breaker.allow(), ErrCircuitOpen)
+
+	breaker.recordSuccess()
+	require.NoError(t, breaker.allow())
+	breaker.recordSuccess()
+	assert.Equal(t, CircuitClosed, breaker.snapshot().State)
+
+	assert.Equal(t, []string{"closed>open", "open>half_open", "half_open>closed"}, transitions)
+}
+
+func TestCircuitBreakerHalfOpenFailureReopens(t *testing.T) {
+	breaker := newCircuitBreaker("grpc/protobuf/risk", CircuitBreakerOptions{
+		Enabled:          true,
+		FailureThreshold: 1,
+		OpenTimeout:      time.Millisecond,
+	}, nil)
+
+	breaker.recordFailure()
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, breaker.allow())
+
+	breaker.recordFailure()
+	snapshot := breaker.snapshot()
+	assert.Equal(t, CircuitOpen, snapshot.State)
+	assert.False(t, snapshot.OpenedAt.IsZero())
+
+	// A call whose outcome says nothing about health frees its trial slot
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, breaker.allow())
+	breaker.release()
+	assert.NoError(t, breaker.allow())
+
+	breaker.reset()
+	assert.Equal(t, CircuitClosed, breaker.snapshot().State)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	b := NewBridge(&BridgeOptions{
+		RetryDelay:         100 * time.Millisecond,
+		RetryBackoffFactor: 2,
+		RetryMaxDelay:      500 * time.Millisecond,
+	}, &defaultLogger{})
+
+	for attempt, want := range []time.Duration{100, 200, 400, 500, 500} {
+		assert.Equal(t, want*time.Millisecond, b.retryBackoff(attempt+1), "attempt %d", attempt+1)
+	}
+
+	b.options.RetryJitter = 0.5
+	for i := 0; i < 50; i++ {
+		delay := b.retryBackoff(2)
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.LessOrEqual(t, delay, 300*time.Millisecond)
+	}
+
+	b.options.RetryDelay = 0
+	assert.Zero(t, b.retryBackoff(3))
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", errors.New("connection reset"), true},
+		{"canceled", fmt.Errorf("send: %w", context.Canceled), false},
+		{"deadline", context.DeadlineExceeded, true},
+		{"circuit open", ErrCircuitOpen, false},
+		{"invalid argument", fmt.Errorf("call: %w", adapters.ErrInvalidArgument), false},
+		{"unauthenticated", adapters.ErrUnauthenticated, false},
+		{"unavailable code", adapters.NewBridgeError("UNAVAILABLE", "down", nil), true},
+		{"not found code", adapters.NewBridgeError("NOT_FOUND", "missing", nil), false},
+		{"temporary", temporaryError{temporary: true}, true},
+		{"permanent", temporaryError{temporary: false}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isRetryableError(tt.err))
+		})
+	}
+}
+
+func TestIsTargetFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"plain", errors.New("connection reset"), true},
+		{"deadline", fmt.Errorf("send: %w", context.DeadlineExceeded), true},
+		{"permanent", temporaryError{temporary: false}, true},
+		{"not found code", adapters.NewBridgeError("NOT_FOUND", "missing", nil), true},
+		{"canceled", fmt.Errorf("send: %w", context.Canceled), false},
+		{"canceled code", adapters.NewBridgeError("CANCELED", "gone", nil), false},
+		{"circuit open", ErrCircuitOpen, false},
+		{"invalid argument", fmt.Errorf("call: %w", adapters.ErrInvalidArgument), false},
+		{"invalid argument code", adapters.NewBridgeError("INVALID_ARGUMENT", "bad", nil), false},
+		{"invalid data", adapters.SharedErrInvalidData, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isTargetFailure(tt.err))
+		})
+	}
+}
+
+func TestSendWithRetry(t *testing.T) {
+	newBridge := func(retries, threshold int) *Bridge {
+		return NewBridge(&BridgeOptions{
+			RetryCount:         retries,
+			RetryDelay:         time.Millisecond,
+			RetryBackoffFactor: 1,
+			CircuitBreaker: CircuitBreakerOptions{
+				Enabled:          true,
+				FailureThreshold: threshold,
+				OpenTimeout:      time.Minute,
+			},
+		}, &defaultLogger{})
+	}
+	target := BridgeTarget{Adapter: "orders", Protocol: "json", Service: "orders"}
+	transient := errors.New("connection reset")
+
+	t.Run("recovers from transient errors", func(t *testing.T) {
+		adapter := &scriptedAdapter{name: "orders", errs: []error{transient, transient}}
+		response, err := newBridge(3, 10).sendWithRetry(context.Background(), adapter, target, []byte("ping"))
+		require.NoError(t, err)
+		assert.Equal(t, []byte("ping"), response)
+		assert.Equal(t, 3, adapter.sendCalls())
+	})
+
+	t.Run("gives up after the retry count", func(t *testing.T) {
+		adapter := &scriptedAdapter{name: "orders", errs: []error{transient, transient, transient}}
+		_, err := newBridge(2, 10).sendWithRetry(context.Background(), adapter, target, []byte("ping"))
+		assert.ErrorIs(t, err, transient)
+		assert.Contains(t, err.Error(), "after 3 attempts")
+		assert.Equal(t, 3, adapter.sendCalls())
+	})
+
+	t.Run("does not retry request errors", func(t *testing.T) {
+		b := newBridge(3, 1)
+		adapter := &scriptedAdapter{name: "orders", errs: []error{adapters.ErrInvalidArgument}}
+		_, err := b.sendWithRetry(context.Background(), adapter, target, []byte("ping"))
+		assert.ErrorIs(t, err, adapters.ErrInvalidArgument)
+		assert.Equal(t, 1, adapter.sendCalls())
+
+		// and they do not count against the circuit
+		state, ok := b.CircuitBreakerState(target)
+		require.True(t, ok)
+		assert.Equal(t, CircuitClosed, state.State)
+	})
+
+	t.Run("opens on calls that hang until the deadline", func(t *testing.T) {
+		b := newBridge(3, 2)
+		adapter := &scriptedAdapter{name: "orders", handler: func(ctx context.Context, data []byte) ([]byte, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}}
+
+		for i := 0; i < 2; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			_, err := b.sendWithRetry(ctx, adapter, target, []byte("ping"))
+			cancel()
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		}
+		assert.Equal(t, 2, adapter.sendCalls())
+
+		state, ok := b.CircuitBreakerState(target)
+		require.True(t, ok)
+		assert.Equal(t, CircuitOpen, state.State)
+
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := b.sendWithRetry(ctx, adapter, target, []byte("ping"))
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.Less(t, time.Since(start), 100*time.Millisecond, "an open circuit does not wait for the deadline")
+	})
+
+	t.Run("counts errors that are not retried", func(t *testing.T) {
+		b := newBridge(3, 2)
+		adapter := &scriptedAdapter{name: "orders", errs: []error{temporaryError{}, temporaryError{}}}
+		for i := 0; i < 2; i++ {
+			_, err := b.sendWithRetry(context.Background(), adapter, target, []byte("ping"))
+			assert.Error(t, err)
+		}
+		assert.Equal(t, 2, adapter.sendCalls())
+
+		state, ok := b.CircuitBreakerState(target)
+		require.True(t, ok)
+		assert.Equal(t, CircuitOpen, state.State)
+	})
+
+	t.Run("does not count caller cancellation", func(t *testing.T) {
+		b := newBridge(3, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		adapter := &scriptedAdapter{name: "orders", handler: func(ctx context.Context, data []byte) ([]byte, error) {
+			cancel()
+			return nil, ctx.Err()
+		}}
+		_, err := b.sendWithRetry(ctx, adapter, target, []byte("ping"))
+		assert.ErrorIs(t, err, context.Canceled)
+
+		state, ok := b.CircuitBreakerState(target)
+		require.True(t, ok)
+		assert.Equal(t, CircuitClosed, state.State)
+	})
+
+	t.Run("stops retrying once the circuit opens", func(t *testing.T) {
+		b := newBridge(5, 2)
+		adapter := &scriptedAdapter{name: "orders", errs: []error{transient, transient, transient}}
+		_, err := b.sendWithRetry(context.Background(), adapter, target, []byte("ping"))
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.Equal(t, 2, adapter.sendCalls())
+
+		_, err = b.sendWithRetry(context.Background(), adapter, target, []byte("ping"))
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.Equal(t, 2, adapter.sendCalls(), "an open circuit rejects without sending")
+
+		require.NoError(t, b.ResetCircuitBreaker(target))
+		_, err = b.sendWithRetry(context.Background(), adapter, target, []byte("ping"))
+		assert.NoError(t, err)
+		assert.Len(t, b.CircuitBreakerStates(), 1)
+	})
+}
@@ -0,0 +1,357 @@
+// compression.go - Pluggable payload compression for encoded bridge messages
+
+package bridge
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression algorithm names
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionZstd   = "zstd"
+	CompressionSnappy = "snappy"
+)
+
+// Compression negotiation headers carried in protocol message headers
+const (
+	HeaderAcceptEncoding  = "accept-encoding"
+	HeaderContentEncoding = "content-encoding"
+)
+
+// compressionMagic prefixes compressed frames so receivers can detect them.
+// Frame layout: magic (3 bytes), version (1 byte), name length (1 byte), name,
+// accept-encoding length (1 byte), accept-encoding, data. Version 1 frames
+// have no accept-encoding field.
+var compressionMagic = []byte{'Q', 'W', 'Z'}
+
+const compressionFrameVersion = 2
+
+// Common compression errors
+var (
+	ErrCompressorNotFound = errors.New("compressor not found")
+	ErrInvalidCompression = errors.New("invalid compressed frame")
+)
+
+// Compressor compresses and decompresses encoded payloads
+type Compressor interface {
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// compressorRegistry holds the available compressors
+var (
+	compressors      = make(map[string]Compressor)
+	compressorsMutex sync.RWMutex
+)
+
+// RegisterCompressor registers a compressor, replacing any existing one with the same name
+func RegisterCompressor(compressor Compressor) {
+	compressorsMutex.Lock()
+	defer compressorsMutex.Unlock()
+	compressors[compressor.Name()] = compressor
+}
+
+// GetCompressor returns a registered compressor
+func GetCompressor(name string) (Compressor, error) {
+	compressorsMutex.RLock()
+	defer compressorsMutex.RUnlock()
+
+	compressor, exists := compressors[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrCompressorNotFound, name)
+	}
+	return compressor, nil
+}
+
+// ListCompressors returns the names of all registered compressors
+func ListCompressors() []string {
+	compressorsMutex.RLock()
+	defer compressorsMutex.RUnlock()
+
+	names := make([]string, 0, len(compressors))
+	for name := range compressors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterCompressor(&gzipCompressor{})
+	RegisterCompressor(newZstdCompressor())
+	RegisterCompressor(&snappyCompressor{})
+}
+
+// gzipCompressor implements gzip compression
+type gzipCompressor struct{}
+
+func (c *gzipCompressor) Name() string { return CompressionGzip }
+
+func (c *gzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *gzipCompressor) Decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// zstdCompressor implements zstd compression with shared stateless encoder and decoder
+type zstdCompressor struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	initErr error
+}
+
+func newZstdCompressor() *zstdCompressor {
+	c := &zstdCompressor{}
+	if c.encoder, c.initErr = zstd.NewWriter(nil); c.initErr != nil {
+		return c
+	}
+	c.decoder, c.initErr = zstd.NewReader(nil)
+	return c
+}
+
+func (c *zstdCompressor) Name() string { return CompressionZstd }
+
+func (c *zstdCompressor) Compress(data []byte) ([]byte, error) {
+	if c.initErr != nil {
+		return nil, c.initErr
+	}
+	return c.encoder.EncodeAll(data, nil), nil
+}
+
+func (c *zstdCompressor) Decompress(data []byte) ([]byte, error) {
+	if c.initErr != nil {
+		return nil, c.initErr
+	}
+	return c.decoder.DecodeAll(data, nil)
+}
+
+// snappyCompressor implements snappy block compression
+type snappyCompressor struct{}
+
+func (c *snappyCompressor) Name() string { return CompressionSnappy }
+
+func (c *snappyCompressor) Compress(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+func (c *snappyCompressor) Decompress(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
+// CompressPayload compresses data with the named algorithm and wraps it in a compression frame
+func CompressPayload(algorithm string, data []byte) ([]byte, error) {
+	return CompressPayloadAdvertising(algorithm, nil, data)
+}
+
+// CompressPayloadAdvertising compresses data like CompressPayload and carries
+// the encodings the sender can decompress in the frame header, where the
+// receiver can read them without decompressing the payload
+func CompressPayloadAdvertising(algorithm string, accept []string, data []byte) ([]byte, error) {
+	compressor, err := GetCompressor(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	advertised := strings.Join(accept, ",")
+	if len(advertised) > 255 {
+		return nil, fmt.Errorf("%w: accept-encoding list too long", ErrInvalidCompression)
+	}
+
+	compressed, err := compressor.Compress(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s compression failed: %w", algorithm, err)
+	}
+
+	name := compressor.Name()
+	frame := make([]byte, 0, len(compressionMagic)+3+len(name)+len(advertised)+len(compressed))
+	frame = append(frame, compressionMagic...)
+	frame = append(frame, compressionFrameVersion, byte(len(name)))
+	frame = append(frame, name...)
+	frame = append(frame, byte(len(advertised)))
+	frame = append(frame, advertised...)
+	frame = append(frame, compressed...)
+	return frame, nil
+}
+
+// IsCompressedPayload reports whether data carries a compression frame
+func IsCompressedPayload(data []byte) bool {
+	return len(data) > len(compressionMagic)+1 && bytes.HasPrefix(data, compressionMagic)
+}
+
+// PayloadAcceptEncoding returns the encodings advertised in the header of a
+// compression frame, reporting false for data without a frame or frames that
+// carry no advertisement
+func PayloadAcceptEncoding(data []byte) ([]string, bool) {
+	if !IsCompressedPayload(data) {
+		return nil, false
+	}
+
+	_, accept, _, err := parseCompressionFrame(data)
+	if err != nil || accept == "" {
+		return nil, false
+	}
+	return splitEncodings(accept), true
+}
+
+// DecompressPayload unwraps and decompresses a compression frame; data without a
+// frame is returned unchanged along with the algorithm "none"
+func DecompressPayload(data []byte) ([]byte, string, error) {
+	if !IsCompressedPayload(data) {
+		return data, CompressionNone, nil
+	}
+
+	algorithm, _, compressed, err := parseCompressionFrame(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	compressor, err := GetCompressor(algorithm)
+	if err != nil {
+		return nil, "", err
+	}
+
+	decompressed, err := compressor.Decompress(compressed)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s decompression failed: %w", algorithm, err)
+	}
+
+	return decompressed, algorithm, nil
+}
+
+// parseCompressionFrame splits a compression frame into its algorithm, the
+// advertised accept-encoding list and the compressed data
+func parseCompressionFrame(data []byte) (string, string, []byte, error) {
+	header := len(compressionMagic)
+	version := data[header]
+	if version < 1 || version > compressionFrameVersion {
+		return "", "", nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidCompression, version)
+	}
+
+	nameLen := int(data[header+1])
+	start := header + 2
+	if len(data) < start+nameLen {
+		return "", "", nil, fmt.Errorf("%w: truncated header", ErrInvalidCompression)
+	}
+	algorithm := string(data[start : start+nameLen])
+	start += nameLen
+
+	if version == 1 {
+		return algorithm, "", data[start:], nil
+	}
+
+	if len(data) < start+1 || len(data) < start+1+int(data[start]) {
+		return "", "", nil, fmt.Errorf("%w: truncated header", ErrInvalidCompression)
+	}
+	acceptLen := int(data[start])
+	accept := string(data[start+1 : start+1+acceptLen])
+	return algorithm, accept, data[start+1+acceptLen:], nil
+}
+
+// splitEncodings parses a comma-separated accept-encoding list
+func splitEncodings(value string) []string {
+	accepted := make([]string, 0)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			accepted = append(accepted, name)
+		}
+	}
+	return accepted
+}
+
+// compressionAlgorithm selects the algorithm for a target, honouring per-target
+// overrides and the encodings the peer has advertised. Nothing is compressed
+// until the peer has advertised what it can decompress.
+func (b *Bridge) compressionAlgorithm(target BridgeTarget) string {
+	if !b.options.EnableCompression {
+		return CompressionNone
+	}
+
+	algorithm := b.options.CompressionAlgorithm
+	if override, ok := target.Options["compression"].(string); ok && override != "" {
+		algorithm = override
+	}
+	if algorithm == "" || algorithm == CompressionNone {
+		return CompressionNone
+	}
+
+	b.encodingsMutex.RLock()
+	accepted, known := b.peerEncodings[circuitBreakerKey(target)]
+	b.encodingsMutex.RUnlock()
+
+	if !known {
+		return CompressionNone
+	}
+
+	for _, name := range accepted {
+		if name == algorithm {
+			return algorithm
+		}
+	}
+	for _, name := range accepted {
+		if _, err := GetCompressor(name); err == nil {
+			return name
+		}
+	}
+
+	return CompressionNone
+}
+
+// rememberPeerEncodings records the encodings a peer advertised
+func (b *Bridge) rememberPeerEncodings(target BridgeTarget, accepted []string) {
+	b.encodingsMutex.Lock()
+	b.peerEncodings[circuitBreakerKey(target)] = accepted
+	b.encodingsMutex.Unlock()
+}
+
+// compressForTarget compresses an encoded payload when it exceeds the
+// threshold, advertising the local encodings in the frame header
+func (b *Bridge) compressForTarget(target BridgeTarget, data []byte) ([]byte, error) {
+	algorithm := b.compressionAlgorithm(target)
+	if algorithm == CompressionNone || len(data) < b.options.CompressionThreshold {
+		return data, nil
+	}
+
+	compressed, err := CompressPayloadAdvertising(algorithm, ListCompressors(), data)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.metricsCollector != nil {
+		b.metricsCollector.RecordCompression(algorithm, len(data), len(compressed))
+	}
+
+	// Compression that doesn't pay for its frame is skipped
+	if len(compressed) >= len(data) {
+		return data, nil
+	}
+
+	return compressed, nil
+}
@@ -0,0 +1,164 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressPayloadRoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat("quant webwork bridge payload ", 64))
+
+	for _, algorithm := range []string{CompressionGzip, CompressionZstd, CompressionSnappy} {
+		t.Run(algorithm, func(t *testing.T) {
+			frame, err := CompressPayloadAdvertising(algorithm, []string{"zstd", "gzip"}, data)
+			require.NoError(t, err)
+			assert.True(t, IsCompressedPayload(frame))
+			assert.Less(t, len(frame), len(data))
+
+			accept, ok := PayloadAcceptEncoding(frame)
+			assert.True(t, ok)
+			assert.Equal(t, []string{"zstd", "gzip"}, accept)
+
+			decompressed, used, err := DecompressPayload(frame)
+			require.NoError(t, err)
+			assert.Equal(t, algorithm, used)
+			assert.Equal(t, data, decompressed)
+		})
+	}
+}
+
+func TestDecompressPayloadFrames(t *testing.T) {
+	data := []byte(`{"id":"1"}`)
+
+	decompressed, used, err := DecompressPayload(data)
+	require.NoError(t, err)
+	assert.Equal(t, CompressionNone, used)
+	assert.Equal(t, data, decompressed)
+	_, ok := PayloadAcceptEncoding(data)
+	assert.False(t, ok)
+
+	// Frames without an advertisement decode, and so do version 1 frames
+	frame, err := CompressPayload(CompressionSnappy, data)
+	require.NoError(t, err)
+	_, ok = PayloadAcceptEncoding(frame)
+	assert.False(t, ok)
+
+	compressed := frame[len(compressionMagic)+2+len(CompressionSnappy)+1:]
+	legacy := append([]byte{'Q', 'W', 'Z', 1, byte(len(CompressionSnappy))}, CompressionSnappy...)
+	legacy = append(legacy, compressed...)
+	decompressed, used, err = DecompressPayload(legacy)
+	require.NoError(t, err)
+	assert.Equal(t, CompressionSnappy, used)
+	assert.Equal(t, data, decompressed)
+
+	for name, bad := range map[string][]byte{
+		"future version":   {'Q', 'W', 'Z', 9, 0, 0},
+		"truncated name":   {'Q', 'W', 'Z', 2, 10, 'g'},
+		"truncated accept": append([]byte{'Q', 'W', 'Z', 2, 4, 'g', 'z', 'i', 'p', 20}, "gz"...),
+	} {
+		_, _, err := DecompressPayload(bad)
+		assert.ErrorIs(t, err, ErrInvalidCompression, name)
+	}
+
+	_, _, err = DecompressPayload([]byte{'Q', 'W', 'Z', 2, 3, 'l', 'z', '4', 0, 1})
+	assert.ErrorIs(t, err, ErrCompressorNotFound)
+}
+
+func TestCompressionAlgorithmNegotiation(t *testing.T) {
+	target := BridgeTarget{Adapter: "orders", Protocol: "json"}
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		configured string
+		override   string
+		advertised []string
+		want       string
+	}{
+		{"disabled", false, CompressionZstd, "", []string{"zstd"}, CompressionNone},
+		{"peer not yet advertised", true, CompressionZstd, "", nil, CompressionNone},
+		{"peer accepts configured", true, CompressionZstd, "", []string{"gzip", "zstd"}, CompressionZstd},
+		{"falls back to a peer encoding", true, CompressionZstd, "", []string{"br", "snappy"}, CompressionSnappy},
+		{"peer accepts nothing known", true, CompressionZstd, "", []string{"br"}, CompressionNone},
+		{"peer accepts nothing", true, CompressionZstd, "", []string{}, CompressionNone},
+		{"target override", true, CompressionZstd, CompressionGzip, []string{"gzip", "zstd"}, CompressionGzip},
+		{"override disables", true, CompressionZstd, CompressionNone, []string{"zstd"}, CompressionNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBridge(&BridgeOptions{
+				EnableCompression:    tt.enabled,
+				CompressionAlgorithm: tt.configured,
+			}, &defaultLogger{})
+
+			target := target
+			target.Options = map[string]interface{}{"compression": tt.override}
+			if tt.advertised != nil {
+				b.rememberPeerEncodings(target, tt.advertised)
+			}
+			assert.Equal(t, tt.want, b.compressionAlgorithm(target))
+		})
+	}
+}
+
+func TestExchangeNegotiatesCompression(t *testing.T) {
+	b := NewBridge(&BridgeOptions{
+		EnableCompression:    true,
+		CompressionAlgorithm: CompressionSnappy,
+		CompressionThreshold: 64,
+	}, &defaultLogger{})
+	protocol := plugins.NewProtocolPlugin("json", plugins.ProtocolJSON, "1.0", "application/json")
+	target := BridgeTarget{Adapter: "orders", Protocol: "json", Operation: "Echo"}
+
+	var requests [][]byte
+	adapter := &scriptedAdapter{name: "orders", handler: func(ctx context.Context, data []byte) ([]byte, error) {
+		requests = append(requests, data)
+		decompressed, _, err := DecompressPayload(data)
+		if err != nil {
+			return nil, err
+		}
+
+		var request plugins.ProtocolMessage
+		if err := json.Unmarshal(decompressed, &request); err != nil {
+			return nil, err
+		}
+		request.Headers = map[string]string{HeaderAcceptEncoding: "snappy"}
+		return json.Marshal(request)
+	}}
+
+	call := func() *plugins.ProtocolMessage {
+		t.Helper()
+		message := &plugins.ProtocolMessage{
+			ID:      generateID(),
+			Type:    "Echo",
+			Headers: map[string]string{},
+			Payload: strings.Repeat("x", 512),
+		}
+		reply, err := b.exchange(context.Background(), protocol, adapter, target, message)
+		require.NoError(t, err)
+		return reply
+	}
+
+	// The first request goes out uncompressed with the advertisement readable
+	call()
+	require.Len(t, requests, 1)
+	assert.False(t, IsCompressedPayload(requests[0]))
+	assert.Contains(t, string(requests[0]), HeaderAcceptEncoding)
+
+	// Once the peer has advertised, requests are compressed and repeat the
+	// advertisement in the frame header
+	reply := call()
+	require.Len(t, requests, 2)
+	assert.True(t, IsCompressedPayload(requests[1]))
+	accept, ok := PayloadAcceptEncoding(requests[1])
+	require.True(t, ok)
+	assert.Equal(t, ListCompressors(), accept)
+	assert.Equal(t, strings.Repeat("x", 512), reply.Payload)
+}
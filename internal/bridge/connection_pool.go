@@ -2,10 +2,7 @@ package bridge
 
 import (
 	"context"
-	"errors"
-	"sync"
-	"sync/atomic"
-	"time"
+	"fmt"
 
 	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/adapters"
 	"go.uber.org/zap"
@@ -13,250 +10,91 @@ import (
 
 // Pool errors
 var (
-	ErrPoolClosed     = errors.New("connection pool is closed")
-	ErrAcquireTimeout = errors.New("timeout acquiring connection from pool")
-	ErrPoolExhausted  = errors.New("connection pool exhausted")
+	ErrPoolClosed     = adapters.ErrPoolClosed
+	ErrAcquireTimeout = adapters.ErrAcquireTimeout
+	ErrPoolExhausted  = adapters.ErrPoolExhausted
 )
 
 // PoolConfig defines configuration for the connection pool
-type PoolConfig struct {
-	// Maximum number of connections to keep in the pool
-	MaxConnections int
+type PoolConfig = adapters.PoolConfig
 
-	// Maximum idle time before a connection is removed from the pool
-	MaxIdleTime time.Duration
-
-	// Maximum time to wait for a connection from the pool
-	AcquireTimeout time.Duration
-
-	// Whether to validate connections when taking from pool
-	ValidateOnBorrow bool
-
-	// How often to run cleanup routine
-	CleanupInterval time.Duration
-
-	// Maximum number of connections to create in a single burst
-	MaxBurstSize int
-}
+// PoolStats tracks statistics about the connection pool
+type PoolStats = adapters.PoolStats
 
 // DefaultPoolConfig returns default pool configuration
 func DefaultPoolConfig() PoolConfig {
-	return PoolConfig{
-		MaxConnections:   100,
-		MaxIdleTime:      5 * time.Minute,
-		AcquireTimeout:   30 * time.Second,
-		ValidateOnBorrow: true,
-		CleanupInterval:  1 * time.Minute,
-		MaxBurstSize:     10,
-	}
-}
-
-// PoolStats tracks statistics about the connection pool
-type PoolStats struct {
-	// Total number of connections managed by the pool
-	TotalConnections int64
-
-	// Number of idle connections in the pool
-	IdleConnections int64
-
-	// Number of active connections currently in use
-	ActiveConnections int64
-
-	// Number of connection acquisitions
-	Acquisitions int64
-
-	// Number of connection returns
-	Returns int64
-
-	// Number of connection creations
-	Creations int64
-
-	// Number of connection closures
-	Closures int64
-
-	// Number of connection timeouts during acquisition
-	Timeouts int64
-
-	// Number of failed validations
-	ValidationFailures int64
-
-	// Number of times pool was exhausted
-	Exhaustions int64
+	return adapters.DefaultPoolConfig()
 }
 
 // ConnectionPool manages a pool of connections for a specific adapter
 type ConnectionPool struct {
-	config         PoolConfig
+	pool           *adapters.Pool[adapters.Adapter]
 	adapterFactory adapters.AdapterFactory
-	adapterConfig  adapters.AdapterConfig
-	available      chan adapters.Adapter
-	inUse          sync.Map // adapters.Adapter -> time.Time
+	adapterConfig  adapters.SharedAdapterConfig
 	logger         *zap.SugaredLogger
-	closed         int32 // atomic flag
-	stats          PoolStats
-	statsMutex     sync.Mutex // Only needed for methods that update multiple stats fields atomically
-	lastCleanup    time.Time
-	lifecycle      *sync.WaitGroup // For graceful shutdown
 }
 
-// NewConnectionPool creates a new connection pool
+// NewConnectionPool creates a new connection pool. Adapters are created by
+// factory, then initialized and connected; idle adapters are checked with
+// validateAdapter on borrow and every HealthCheckInterval.
 func NewConnectionPool(
 	factory adapters.AdapterFactory,
-	config adapters.AdapterConfig,
+	config adapters.SharedAdapterConfig,
 	poolConfig PoolConfig,
 	logger *zap.SugaredLogger,
 ) *ConnectionPool {
-	// Apply defaults for unspecified options
-	if poolConfig.MaxConnections <= 0 {
-		poolConfig.MaxConnections = DefaultPoolConfig().MaxConnections
-	}
-
-	if poolConfig.MaxIdleTime <= 0 {
-		poolConfig.MaxIdleTime = DefaultPoolConfig().MaxIdleTime
-	}
-
-	if poolConfig.AcquireTimeout <= 0 {
-		poolConfig.AcquireTimeout = DefaultPoolConfig().AcquireTimeout
-	}
-
-	if poolConfig.CleanupInterval <= 0 {
-		poolConfig.CleanupInterval = DefaultPoolConfig().CleanupInterval
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
 	}
 
-	if poolConfig.MaxBurstSize <= 0 {
-		poolConfig.MaxBurstSize = DefaultPoolConfig().MaxBurstSize
-	}
+	return newConnectionPool(factory, config, poolConfig, logger, func(operation string, err error) {
+		logger.Warnw("Connection pool maintenance failed", "pool", config.Name, "operation", operation, "error", err)
+	})
+}
 
-	pool := &ConnectionPool{
-		config:         poolConfig,
+// newConnectionPool creates a connection pool reporting maintenance failures to onError
+func newConnectionPool(
+	factory adapters.AdapterFactory,
+	config adapters.SharedAdapterConfig,
+	poolConfig PoolConfig,
+	logger *zap.SugaredLogger,
+	onError func(operation string, err error),
+) *ConnectionPool {
+	p := &ConnectionPool{
 		adapterFactory: factory,
 		adapterConfig:  config,
-		available:      make(chan adapters.Adapter, poolConfig.MaxConnections),
 		logger:         logger,
-		lastCleanup:    time.Now(),
-		lifecycle:      &sync.WaitGroup{},
-	}
-
-	// Start background cleanup
-	pool.lifecycle.Add(1)
-	go func() {
-		defer pool.lifecycle.Done()
-		pool.periodicCleanup()
-	}()
-
-	return pool
-}
-
-// Acquire gets a connection from the pool or creates a new one
-func (p *ConnectionPool) Acquire(ctx context.Context) (adapters.Adapter, error) {
-	if atomic.LoadInt32(&p.closed) == 1 {
-		return nil, ErrPoolClosed
 	}
 
-	// Track acquisition attempt
-	atomic.AddInt64(&p.stats.Acquisitions, 1)
-
-	// Try to get from pool first (non-blocking check)
-	select {
-	case adapter := <-p.available:
-		atomic.AddInt64(&p.stats.IdleConnections, -1)
-
-		if p.config.ValidateOnBorrow {
-			// Check if the connection is still valid
-			if err := p.validateAdapter(adapter); err != nil {
-				atomic.AddInt64(&p.stats.ValidationFailures, 1)
-				p.logger.Warnw("Invalid connection in pool, creating new one", "error", err)
-
-				// Close the invalid connection
-				adapter.Close()
-				atomic.AddInt64(&p.stats.Closures, 1)
-				atomic.AddInt64(&p.stats.TotalConnections, -1)
-
-				// Create a new connection
-				return p.createNewConnection(ctx)
-			}
-		}
-
-		// Mark as in use with current timestamp
-		p.inUse.Store(adapter, time.Now())
-		atomic.AddInt64(&p.stats.ActiveConnections, 1)
-
-		return adapter, nil
-	default:
-		// Pool is empty, check if we can create a new connection
-		totalConns := atomic.LoadInt64(&p.stats.TotalConnections)
-		if totalConns < int64(p.config.MaxConnections) {
-			return p.createNewConnection(ctx)
-		}
+	p.pool = adapters.NewPool(config.Name, poolConfig, adapters.PoolHooks[adapters.Adapter]{
+		Create:   p.createAdapter,
+		Validate: p.validateAdapter,
+		Close:    p.closeAdapter,
+		OnError:  onError,
+	})
 
-		// Pool is at capacity, need to wait for a connection
-		return p.waitForConnection(ctx)
-	}
+	return p
 }
 
-// createNewConnection creates a new adapter connection
-func (p *ConnectionPool) createNewConnection(ctx context.Context) (adapters.Adapter, error) {
-	// Create a new adapter
-	adapter, err := p.adapterFactory(p.adapterConfig)
+// NewConnectionPoolFromRegistry creates a connection pool for adapters of
+// config.Type, created by the factory registered in registry
+func NewConnectionPoolFromRegistry(
+	registry *adapters.AdapterRegistry,
+	config adapters.SharedAdapterConfig,
+	poolConfig PoolConfig,
+	logger *zap.SugaredLogger,
+) (*ConnectionPool, error) {
+	factory, err := registry.GetFactory(config.Type)
 	if err != nil {
 		return nil, err
 	}
 
-	// Connect the adapter
-	if err := adapter.Connect(ctx); err != nil {
-		return nil, err
-	}
-
-	// Update stats
-	atomic.AddInt64(&p.stats.Creations, 1)
-	atomic.AddInt64(&p.stats.TotalConnections, 1)
-	atomic.AddInt64(&p.stats.ActiveConnections, 1)
-
-	// Mark as in use
-	p.inUse.Store(adapter, time.Now())
-
-	return adapter, nil
+	return NewConnectionPool(factory, config, poolConfig, logger), nil
 }
 
-// waitForConnection waits for a connection to become available or creates new ones if possible
-func (p *ConnectionPool) waitForConnection(ctx context.Context) (adapters.Adapter, error) {
-	// Set up a timeout for waiting
-	timeoutCtx, cancel := context.WithTimeout(ctx, p.config.AcquireTimeout)
-	defer cancel()
-
-	// Wait for a connection to become available or timeout
-	select {
-	case adapter := <-p.available:
-		atomic.AddInt64(&p.stats.IdleConnections, -1)
-
-		if p.config.ValidateOnBorrow {
-			if err := p.validateAdapter(adapter); err != nil {
-				atomic.AddInt64(&p.stats.ValidationFailures, 1)
-				adapter.Close()
-				atomic.AddInt64(&p.stats.Closures, 1)
-				atomic.AddInt64(&p.stats.TotalConnections, -1)
-
-				// Try again with a reduced timeout
-				remainingTime := p.config.AcquireTimeout - time.Since(time.Now().Add(-p.config.AcquireTimeout))
-				if remainingTime <= 0 {
-					atomic.AddInt64(&p.stats.Timeouts, 1)
-					return nil, ErrAcquireTimeout
-				}
-
-				newCtx, newCancel := context.WithTimeout(ctx, remainingTime)
-				defer newCancel()
-				return p.Acquire(newCtx)
-			}
-		}
-
-		p.inUse.Store(adapter, time.Now())
-		atomic.AddInt64(&p.stats.ActiveConnections, 1)
-		return adapter, nil
-
-	case <-timeoutCtx.Done():
-		atomic.AddInt64(&p.stats.Timeouts, 1)
-		return nil, ErrAcquireTimeout
-	}
+// Acquire gets a connection from the pool or creates a new one
+func (p *ConnectionPool) Acquire(ctx context.Context) (adapters.Adapter, error) {
+	return p.pool.Acquire(ctx)
 }
 
 // Return returns a connection to the pool
@@ -264,173 +102,114 @@ func (p *ConnectionPool) Return(adapter adapters.Adapter) {
 	if adapter == nil {
 		return
 	}
+	p.pool.Return(adapter)
+}
 
-	if atomic.LoadInt32(&p.closed) == 1 {
-		// Pool is closed, just close the adapter
-		adapter.Close()
-		atomic.AddInt64(&p.stats.Closures, 1)
-		atomic.AddInt64(&p.stats.TotalConnections, -1)
+// Discard closes a borrowed connection that is no longer usable
+func (p *ConnectionPool) Discard(adapter adapters.Adapter) {
+	if adapter == nil {
 		return
 	}
+	p.pool.Discard(adapter)
+}
 
-	// Remove from in-use map
-	p.inUse.Delete(adapter)
-	atomic.AddInt64(&p.stats.ActiveConnections, -1)
-	atomic.AddInt64(&p.stats.Returns, 1)
-
-	// Try to add back to available pool, if full just close it
-	select {
-	case p.available <- adapter:
-		atomic.AddInt64(&p.stats.IdleConnections, 1)
-	default:
-		// Pool is full, close the connection
-		adapter.Close()
-		atomic.AddInt64(&p.stats.Closures, 1)
-		atomic.AddInt64(&p.stats.TotalConnections, -1)
-	}
+// Warmup opens connections until MinIdle are idle
+func (p *ConnectionPool) Warmup(ctx context.Context) error {
+	return p.pool.Warmup(ctx)
 }
 
 // Close closes the connection pool and all connections
 func (p *ConnectionPool) Close() {
-	// Only allow Close to run once
-	if !atomic.CompareAndSwapInt32(&p.closed, 0, 1) {
+	if p.pool.IsClosed() {
 		return
 	}
 
 	p.logger.Info("Shutting down connection pool")
-
-	// Close all connections in the available pool
-	// We use a separate goroutine to drain to avoid blocking if channel is full
-	go func() {
-		for {
-			select {
-			case adapter := <-p.available:
-				atomic.AddInt64(&p.stats.IdleConnections, -1)
-				if adapter != nil {
-					adapter.Close()
-					atomic.AddInt64(&p.stats.Closures, 1)
-					atomic.AddInt64(&p.stats.TotalConnections, -1)
-				}
-			default:
-				// Channel drained
-				close(p.available)
-				return
-			}
-		}
-	}()
-
-	// Close all in-use connections
-	p.inUse.Range(func(key, value interface{}) bool {
-		adapter := key.(adapters.Adapter)
-		adapter.Close()
-		atomic.AddInt64(&p.stats.Closures, 1)
-		atomic.AddInt64(&p.stats.TotalConnections, -1)
-		p.inUse.Delete(key)
-		atomic.AddInt64(&p.stats.ActiveConnections, -1)
-		return true
-	})
-
-	// Wait for cleanup goroutine to finish
-	p.lifecycle.Wait()
-
+	p.pool.Close()
 	p.logger.Info("Connection pool shutdown complete")
 }
 
-// validateAdapter checks if an adapter is still valid
-func (p *ConnectionPool) validateAdapter(adapter adapters.Adapter) error {
-	// In a real implementation, we would ping or otherwise check
-	// that the connection is still valid. This could involve:
-	// 1. Checking last activity time
-	// 2. Sending a ping/heartbeat message
-	// 3. Checking connection state
+// createAdapter creates, initializes and connects a new adapter
+func (p *ConnectionPool) createAdapter(ctx context.Context) (adapters.Adapter, error) {
+	adapter, err := p.adapterFactory(p.adapterConfig)
+	if err != nil {
+		return nil, err
+	}
 
-	// Basic implementation - just check it's not nil
-	if adapter == nil {
-		return errors.New("nil adapter")
+	if err := adapter.Initialize(ctx); err != nil {
+		return nil, err
+	}
+	if err := adapter.Connect(ctx); err != nil {
+		p.closeAdapter(adapter)
+		return nil, err
 	}
 
-	return nil
+	return adapter, nil
 }
 
-// periodicCleanup periodically cleans up idle connections
-func (p *ConnectionPool) periodicCleanup() {
-	ticker := time.NewTicker(p.config.CleanupInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			if atomic.LoadInt32(&p.closed) == 1 {
-				return
-			}
+// closeAdapter shuts down an adapter leaving the pool
+func (p *ConnectionPool) closeAdapter(adapter adapters.Adapter) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.pool.Config().AcquireTimeout)
+	defer cancel()
 
-			p.cleanupIdleConnections()
-		}
+	if err := adapter.Shutdown(ctx); err != nil {
+		p.logger.Debugw("Error shutting down pooled adapter", "adapter", adapter.Name(), "error", err)
 	}
+	return nil
 }
 
-// cleanupIdleConnections removes idle connections from the pool
-func (p *ConnectionPool) cleanupIdleConnections() {
-	// Skip if already cleaned up recently
-	if time.Since(p.lastCleanup) < p.config.CleanupInterval/2 {
-		return
+// validateAdapter checks that an adapter is still connected
+func (p *ConnectionPool) validateAdapter(ctx context.Context, adapter adapters.Adapter) error {
+	if adapter == nil {
+		return fmt.Errorf("nil adapter")
 	}
 
-	p.lastCleanup = time.Now()
-
-	// Calculate how many connections to remove based on utilization patterns
-	idleCount := atomic.LoadInt64(&p.stats.IdleConnections)
-	totalCount := atomic.LoadInt64(&p.stats.TotalConnections)
-	activeCount := atomic.LoadInt64(&p.stats.ActiveConnections)
-
-	// Only remove connections if we have more than 25% of connections idle
-	// and maintain at least 10% buffer of idle connections for bursts
-	targetIdle := totalCount / 10   // 10% buffer
-	if idleCount > targetIdle*2.5 { // more than 25% idle
-		toRemove := idleCount - targetIdle
-		if toRemove > int64(p.config.MaxBurstSize) {
-			toRemove = int64(p.config.MaxBurstSize) // Don't remove too many at once
-		}
-
-		p.logger.Debugw("Cleaning up idle connections",
-			"total", totalCount,
-			"active", activeCount,
-			"idle", idleCount,
-			"toRemove", toRemove)
-
-		// Remove connections
-		for i := int64(0); i < toRemove; i++ {
-			select {
-			case adapter := <-p.available:
-				atomic.AddInt64(&p.stats.IdleConnections, -1)
-				adapter.Close()
-				atomic.AddInt64(&p.stats.Closures, 1)
-				atomic.AddInt64(&p.stats.TotalConnections, -1)
-			default:
-				// No more idle connections to remove
-				return
-			}
+	switch status := adapter.Status(); status {
+	case adapters.SharedStatusError:
+		if err := adapter.LastError(); err != nil {
+			return fmt.Errorf("adapter '%s' failed: %w", adapter.Name(), err)
 		}
+		return fmt.Errorf("adapter '%s' failed", adapter.Name())
+	case adapters.SharedStatusDisconnecting, adapters.SharedStatusDisconnected, adapters.SharedStatusUninitialized:
+		return fmt.Errorf("adapter '%s' is %s", adapter.Name(), status)
 	}
+
+	return ctx.Err()
 }
 
 // GetStats returns current pool statistics
 func (p *ConnectionPool) GetStats() PoolStats {
-	return PoolStats{
-		TotalConnections:   atomic.LoadInt64(&p.stats.TotalConnections),
-		IdleConnections:    atomic.LoadInt64(&p.stats.IdleConnections),
-		ActiveConnections:  atomic.LoadInt64(&p.stats.ActiveConnections),
-		Acquisitions:       atomic.LoadInt64(&p.stats.Acquisitions),
-		Returns:            atomic.LoadInt64(&p.stats.Returns),
-		Creations:          atomic.LoadInt64(&p.stats.Creations),
-		Closures:           atomic.LoadInt64(&p.stats.Closures),
-		Timeouts:           atomic.LoadInt64(&p.stats.Timeouts),
-		ValidationFailures: atomic.LoadInt64(&p.stats.ValidationFailures),
-		Exhaustions:        atomic.LoadInt64(&p.stats.Exhaustions),
-	}
+	return p.pool.Stats()
 }
 
 // IsClosed returns whether the pool is closed
 func (p *ConnectionPool) IsClosed() bool {
-	return atomic.LoadInt32(&p.closed) == 1
+	return p.pool.IsClosed()
+}
+
+// CreateAdapterPool creates a pool of adapters from the registry and registers
+// a single adapter under config.Name that borrows a pool member for each
+// request or stream. Shutting down the bridge closes the pool.
+func (b *Bridge) CreateAdapterPool(ctx context.Context, config adapters.SharedAdapterConfig, poolConfig PoolConfig) (*ConnectionPool, error) {
+	factory, err := b.AdapterRegistry().GetFactory(config.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := newConnectionPool(factory, config, poolConfig, zap.NewNop().Sugar(), func(operation string, err error) {
+		b.logger.Warn(fmt.Sprintf("Adapter pool '%s' %s failed: %v", config.Name, operation, err), nil)
+	})
+
+	adapter, err := adapters.NewPooledAdapter(ctx, config, pool.pool)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("%w: %s: %v", ErrAdapterInitFailed, config.Name, err)
+	}
+
+	if err := b.RegisterAdapter(config.Name, adapter); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
 }
@@ -0,0 +1,227 @@
+// load_balancer.go - Service discovery routing and instance selection for bridge calls
+
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/adapters"
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/discovery"
+)
+
+// LoadBalancingStrategy selects a service instance for a call
+type LoadBalancingStrategy string
+
+const (
+	LoadBalanceRoundRobin       LoadBalancingStrategy = "round_robin"
+	LoadBalanceWeighted         LoadBalancingStrategy = "weighted"
+	LoadBalanceLeastOutstanding LoadBalancingStrategy = "least_outstanding"
+	LoadBalanceConsistentHash   LoadBalancingStrategy = "consistent_hash"
+)
+
+// loadBalancer keeps the selection state shared across calls
+type loadBalancer struct {
+	counters    map[string]uint64
+	outstanding map[string]int
+	mutex       sync.Mutex
+}
+
+// newLoadBalancer creates a new load balancer
+func newLoadBalancer() *loadBalancer {
+	return &loadBalancer{
+		counters:    make(map[string]uint64),
+		outstanding: make(map[string]int),
+	}
+}
+
+// pick selects an instance and marks it as having an outstanding request
+func (lb *loadBalancer) pick(service string, instances []*discovery.ServiceInstance, strategy LoadBalancingStrategy, hashKey string) *discovery.ServiceInstance {
+	// Stable order so round-robin and hashing don't depend on registry map order
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].ID < instances[j].ID
+	})
+
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	var selected *discovery.ServiceInstance
+
+	switch strategy {
+	case LoadBalanceWeighted:
+		selected = pickWeighted(instances)
+	case LoadBalanceLeastOutstanding:
+		selected = instances[0]
+		for _, instance := range instances[1:] {
+			if lb.outstanding[instance.ID] < lb.outstanding[selected.ID] {
+				selected = instance
+			}
+		}
+	case LoadBalanceConsistentHash:
+		if hashKey != "" {
+			selected = pickRendezvous(instances, hashKey)
+			break
+		}
+		fallthrough
+	default:
+		counter := lb.counters[service]
+		lb.counters[service] = counter + 1
+		selected = instances[counter%uint64(len(instances))]
+	}
+
+	lb.outstanding[selected.ID]++
+	return selected
+}
+
+// done marks an outstanding request to an instance as finished
+func (lb *loadBalancer) done(instanceID string) {
+	lb.mutex.Lock()
+	defer lb.mutex.Unlock()
+
+	if lb.outstanding[instanceID] <= 1 {
+		delete(lb.outstanding, instanceID)
+		return
+	}
+	lb.outstanding[instanceID]--
+}
+
+// pickWeighted selects an instance at random in proportion to its weight;
+// instances without a positive weight count as weight 1
+func pickWeighted(instances []*discovery.ServiceInstance) *discovery.ServiceInstance {
+	total := 0
+	for _, instance := range instances {
+		total += instanceWeight(instance)
+	}
+
+	n := rand.Intn(total)
+	for _, instance := range instances {
+		n -= instanceWeight(instance)
+		if n < 0 {
+			return instance
+		}
+	}
+
+	return instances[len(instances)-1]
+}
+
+// pickRendezvous selects an instance by highest random weight hashing, so a key
+// keeps mapping to the same instance while the instance set is unchanged and
+// only keys owned by a removed instance move
+func pickRendezvous(instances []*discovery.ServiceInstance, key string) *discovery.ServiceInstance {
+	var selected *discovery.ServiceInstance
+	var best uint64
+
+	for _, instance := range instances {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(instance.ID))
+		score := h.Sum64()
+
+		if selected == nil || score > best {
+			selected = instance
+			best = score
+		}
+	}
+
+	return selected
+}
+
+// instanceWeight returns the effective load balancing weight of an instance
+func instanceWeight(instance *discovery.ServiceInstance) int {
+	if instance.Weight > 0 {
+		return instance.Weight
+	}
+	return 1
+}
+
+// SetDiscovery configures the discovery client used to resolve BridgeTarget.Service
+// and, optionally, the health checker whose results exclude unhealthy instances
+func (b *Bridge) SetDiscovery(client *discovery.BridgeDiscovery, healthChecker *discovery.HealthCheckerImpl) {
+	b.discoveryMutex.Lock()
+	defer b.discoveryMutex.Unlock()
+
+	b.discoveryClient = client
+	b.healthChecker = healthChecker
+}
+
+// resolveServiceInstance picks a healthy instance of the target service; the
+// returned release function must be called when the call completes
+func (b *Bridge) resolveServiceInstance(target BridgeTarget) (*discovery.ServiceInstance, func(), error) {
+	b.discoveryMutex.RLock()
+	client := b.discoveryClient
+	healthChecker := b.healthChecker
+	b.discoveryMutex.RUnlock()
+
+	if client == nil || client.Registry() == nil {
+		return nil, nil, fmt.Errorf("%w: discovery is not configured for service '%s'", ErrBridgeServiceUnavailable, target.Service)
+	}
+
+	instances, err := client.Registry().Query(&discovery.ServiceQuery{Name: target.Service})
+	if err != nil {
+		if errors.Is(err, discovery.ErrServiceNotFound) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrBridgeServiceUnavailable, target.Service)
+		}
+		return nil, nil, fmt.Errorf("failed to query service '%s': %w", target.Service, err)
+	}
+
+	healthy := make([]*discovery.ServiceInstance, 0, len(instances))
+	for _, instance := range instances {
+		if !instance.Status.IsAvailable() {
+			continue
+		}
+		if healthChecker != nil {
+			if status, checked := healthChecker.LastStatus(instance.ID); checked && !status.IsAvailable() {
+				continue
+			}
+		}
+		healthy = append(healthy, instance)
+	}
+
+	if len(healthy) == 0 {
+		return nil, nil, fmt.Errorf("%w: no healthy instances of '%s'", ErrBridgeServiceUnavailable, target.Service)
+	}
+
+	strategy := b.options.LoadBalancing
+	if override, ok := target.Options["load_balancing"].(string); ok && override != "" {
+		strategy = LoadBalancingStrategy(override)
+	}
+	hashKey, _ := target.Options["hash_key"].(string)
+
+	instance := b.balancer.pick(target.Service, healthy, strategy, hashKey)
+	return instance, func() { b.balancer.done(instance.ID) }, nil
+}
+
+// routeToService resolves the target service and directs the adapter to the chosen
+// instance; calls pass through unchanged when discovery is disabled or not configured.
+// An explicit "endpoint" option, such as the ID of a peer connected to a WebSocket
+// server adapter, is passed to the adapter without consulting discovery.
+func (b *Bridge) routeToService(ctx context.Context, target BridgeTarget) (context.Context, func(), error) {
+	if endpoint, ok := target.Options["endpoint"].(string); ok && endpoint != "" {
+		return adapters.WithEndpoint(ctx, endpoint), func() {}, nil
+	}
+
+	b.discoveryMutex.RLock()
+	configured := b.discoveryClient != nil
+	b.discoveryMutex.RUnlock()
+
+	if target.Service == "" || !b.options.EnableDiscovery || !configured {
+		return ctx, func() {}, nil
+	}
+
+	instance, release, err := b.resolveServiceInstance(target)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	b.logger.Debug(fmt.Sprintf("Routing call for service '%s' to instance '%s'", target.Service, instance.ID), map[string]interface{}{
+		"address": instance.Address,
+	})
+
+	return adapters.WithEndpoint(ctx, instance.Address), release, nil
+}
@@ -0,0 +1,211 @@
+package bridge
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/discovery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testInstances builds service instances with the given IDs and weights
+func testInstances(weights map[string]int) []*discovery.ServiceInstance {
+	instances := make([]*discovery.ServiceInstance, 0, len(weights))
+	for id, weight := range weights {
+		instances = append(instances, &discovery.ServiceInstance{ID: id, Weight: weight})
+	}
+	return instances
+}
+
+func TestLoadBalancerWeighted(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]int
+		want    map[string]float64
+	}{
+		{"proportional to weight", map[string]int{"a": 1, "b": 3}, map[string]float64{"a": 0.25, "b": 0.75}},
+		{"unset weight counts as one", map[string]int{"a": 0, "b": 1, "c": 2}, map[string]float64{"a": 0.25, "b": 0.25, "c": 0.5}},
+		{"equal weights", map[string]int{"a": 5, "b": 5}, map[string]float64{"a": 0.5, "b": 0.5}},
+		{"single instance", map[string]int{"a": 7}, map[string]float64{"a": 1}},
+	}
+
+	const picks = 20000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := newLoadBalancer()
+			instances := testInstances(tt.weights)
+
+			counts := make(map[string]int)
+			for i := 0; i < picks; i++ {
+				selected := lb.pick("orders", instances, LoadBalanceWeighted, "")
+				counts[selected.ID]++
+				lb.done(selected.ID)
+			}
+
+			for id, share := range tt.want {
+				assert.InDelta(t, share, float64(counts[id])/picks, 0.03, "share of instance '%s'", id)
+			}
+			assert.Empty(t, lb.outstanding, "every pick was released")
+		})
+	}
+}
+
+func TestLoadBalancerLeastOutstanding(t *testing.T) {
+	tests := []struct {
+		name    string
+		held    []string // instances picked and not yet released, in order
+		release []string // instances then marked done
+		want    string
+	}{
+		{"idle instances in ID order", nil, nil, "a"},
+		{"prefers an idle instance", []string{"a"}, nil, "b"},
+		{"spreads across instances", []string{"a", "b"}, nil, "c"},
+		{"ties go to the lowest ID", []string{"a", "b", "c"}, nil, "a"},
+		{"released instance is preferred", []string{"a", "b", "c"}, []string{"b"}, "b"},
+		{"releases are counted", []string{"a", "b", "c", "a", "b", "c"}, []string{"c", "c"}, "c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := newLoadBalancer()
+			instances := testInstances(map[string]int{"c": 1, "a": 1, "b": 1})
+
+			for _, id := range tt.held {
+				require.Equal(t, id, lb.pick("orders", instances, LoadBalanceLeastOutstanding, "").ID)
+			}
+			for _, id := range tt.release {
+				lb.done(id)
+			}
+
+			assert.Equal(t, tt.want, lb.pick("orders", instances, LoadBalanceLeastOutstanding, "").ID)
+		})
+	}
+
+	// Releasing more than was picked does not leave negative counts behind
+	lb := newLoadBalancer()
+	lb.done("a")
+	assert.Empty(t, lb.outstanding)
+}
+
+func TestLoadBalancerRendezvousStability(t *testing.T) {
+	lb := newLoadBalancer()
+	instances := testInstances(map[string]int{"a": 1, "b": 1, "c": 1, "d": 1})
+
+	owners := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("session-%d", i)
+		owners[key] = lb.pick("orders", instances, LoadBalanceConsistentHash, key).ID
+		lb.done(owners[key])
+
+		// The same key keeps mapping to the same instance
+		assert.Equal(t, owners[key], lb.pick("orders", instances, LoadBalanceConsistentHash, key).ID)
+		lb.done(owners[key])
+	}
+
+	// Every instance owns some keys
+	shares := make(map[string]int)
+	for _, owner := range owners {
+		shares[owner]++
+	}
+	assert.Len(t, shares, 4)
+
+	// Removing an instance only moves the keys it owned
+	remaining := make([]*discovery.ServiceInstance, 0, 3)
+	for _, instance := range instances {
+		if instance.ID != "c" {
+			remaining = append(remaining, instance)
+		}
+	}
+
+	moved := 0
+	for key, owner := range owners {
+		selected := pickRendezvous(remaining, key).ID
+		if owner == "c" {
+			assert.NotEqual(t, "c", selected)
+			moved++
+			continue
+		}
+		assert.Equal(t, owner, selected, "key '%s' moved off a remaining instance", key)
+	}
+	assert.Equal(t, shares["c"], moved)
+}
+
+func TestLoadBalancerConsistentHashWithoutKey(t *testing.T) {
+	lb := newLoadBalancer()
+	instances := testInstances(map[string]int{"a": 1, "b": 1})
+
+	// Without a hash key the strategy falls back to round-robin
+	var ids []string
+	for i := 0; i < 4; i++ {
+		ids = append(ids, lb.pick("orders", instances, LoadBalanceConsistentHash, "").ID)
+	}
+	assert.Equal(t, []string{"a", "b", "a", "b"}, ids)
+}
+
+func TestResolveServiceInstanceExcludesUnhealthy(t *testing.T) {
+	registry := discovery.NewRegistry(nil)
+	defer registry.Stop()
+
+	for _, instance := range []*discovery.ServiceInstance{
+		{ID: "a", Name: "orders", Address: "10.0.0.1:8080", Status: discovery.StatusUp},
+		{ID: "b", Name: "orders", Address: "10.0.0.2:8080", Status: discovery.StatusUp},
+		{ID: "c", Name: "orders", Address: "10.0.0.3:8080", Status: discovery.StatusDown},
+		{ID: "d", Name: "orders", Address: "10.0.0.4:8080", Status: discovery.StatusDegraded},
+	} {
+		require.NoError(t, registry.Register(instance, nil))
+	}
+
+	// The health checker has found "b" down, though the registry still lists it as up
+	healthChecker := discovery.NewHealthChecker(time.Second)
+	defer healthChecker.Stop()
+	require.NoError(t, healthChecker.StartMonitoring(&discovery.ServiceInstance{ID: "b", Name: "orders", Address: "10.0.0.2:8080"}, 5*time.Millisecond))
+	require.NoError(t, healthChecker.SetHealthCheckHandler("b", func() (discovery.ServiceStatus, error) {
+		return discovery.StatusDown, nil
+	}))
+	require.Eventually(t, func() bool {
+		status, checked := healthChecker.LastStatus("b")
+		return checked && status == discovery.StatusDown
+	}, time.Second, 5*time.Millisecond)
+
+	b := NewBridge(&BridgeOptions{LoadBalancing: LoadBalanceRoundRobin}, &defaultLogger{})
+	b.SetDiscovery(discovery.NewBridgeDiscovery(registry, discovery.HealthCheckConfig{}), healthChecker)
+
+	tests := []struct {
+		name   string
+		target BridgeTarget
+		want   []string
+	}{
+		{"skips unhealthy instances", BridgeTarget{Service: "orders"}, []string{"a", "d", "a", "d"}},
+		{"least outstanding override", BridgeTarget{Service: "orders", Options: map[string]interface{}{"load_balancing": "least_outstanding"}}, []string{"a", "d", "a", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			var releases []func()
+			for range tt.want {
+				instance, release, err := b.resolveServiceInstance(tt.target)
+				require.NoError(t, err)
+				ids = append(ids, instance.ID)
+				releases = append(releases, release)
+			}
+			for _, release := range releases {
+				release()
+			}
+
+			assert.Equal(t, tt.want, ids)
+			assert.Empty(t, b.balancer.outstanding, "releasing the calls clears outstanding counts")
+		})
+	}
+
+	_, _, err := b.resolveServiceInstance(BridgeTarget{Service: "billing"})
+	assert.ErrorIs(t, err, ErrBridgeServiceUnavailable)
+
+	// Once every instance is unhealthy the service is unavailable
+	require.NoError(t, registry.UpdateStatus("a", discovery.StatusDown))
+	require.NoError(t, registry.UpdateStatus("d", discovery.StatusMaintenance))
+	_, _, err = b.resolveServiceInstance(BridgeTarget{Service: "orders"})
+	assert.ErrorIs(t, err, ErrBridgeServiceUnavailable)
+}
@@ -5,6 +5,8 @@ package manager
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -36,8 +38,12 @@ var (
 type BridgeManager struct {
 	bridges     map[string]*bridge.Bridge
 	bridgesMu   sync.RWMutex
-	adapterReg  *adapters.SharedAdapterRegistry
+	adapterReg  *adapters.AdapterRegistry
 	protocolReg *protocols.ProtocolRegistry
+	protocols   map[string]protocols.Protocol // Provide transformers for pipelines
+	protocolsMu sync.RWMutex
+	links       map[string]*bridgeLink // by bridge and source adapter
+	linksMu     sync.RWMutex
 	logger      *zap.SugaredLogger
 	config      *BridgeManagerConfig
 }
@@ -64,8 +70,10 @@ func NewBridgeManager(config *BridgeManagerConfig, logger *zap.SugaredLogger) *B
 
 	return &BridgeManager{
 		bridges:     make(map[string]*bridge.Bridge),
-		adapterReg:  adapters.NewSharedAdapterRegistry(),
+		adapterReg:  adapters.GetGlobalAdapterRegistry(),
 		protocolReg: protocols.NewProtocolRegistry(),
+		protocols:   make(map[string]protocols.Protocol),
+		links:       make(map[string]*bridgeLink),
 		logger:      logger,
 		config:      config,
 	}
@@ -96,8 +104,9 @@ func (m *BridgeManager) CreateBridge(ctx context.Context, id string, options *br
 		logger: m.logger.With("bridge_id", id),
 	}
 
-	// Create new bridge
+	// Create new bridge, creating adapters from the manager's registry
 	b := bridge.NewBridge(options, bridgeLogger)
+	b.SetAdapterRegistry(m.adapterReg)
 
 	// Initialize the bridge
 	if err := b.Initialize(ctx); err != nil {
@@ -140,8 +149,9 @@ func (m *BridgeManager) RemoveBridge(ctx context.Context, id string) error {
 		m.logger.Warnw("Error shutting down bridge during removal", "bridge_id", id, "error", err)
 	}
 
-	// Remove the bridge
+	// Remove the bridge and stop its links
 	delete(m.bridges, id)
+	m.removeLinks(id)
 	m.logger.Infow("Removed bridge", "bridge_id", id)
 
 	return nil
@@ -209,9 +219,12 @@ func (m *BridgeManager) Stop(ctx context.Context, id string) error {
 }
 
 // RegisterAdapter registers an adapter factory with the bridge manager
-func (m *BridgeManager) RegisterAdapter(adapterType string, factory adapters.SharedAdapterFactory) {
-	m.adapterReg.RegisterFactory(adapterType, factory)
+func (m *BridgeManager) RegisterAdapter(adapterType string, factory adapters.AdapterFactory) error {
+	if err := m.adapterReg.RegisterFactory(adapterType, factory); err != nil {
+		return err
+	}
 	m.logger.Infow("Registered adapter factory", "type", adapterType)
+	return nil
 }
 
 // RegisterProtocol registers a protocol factory with the bridge manager
@@ -221,7 +234,7 @@ func (m *BridgeManager) RegisterProtocol(protocolType string, factory protocols.
 }
 
 // GetAdapterRegistry returns the adapter registry
-func (m *BridgeManager) GetAdapterRegistry() *adapters.SharedAdapterRegistry {
+func (m *BridgeManager) GetAdapterRegistry() *adapters.AdapterRegistry {
 	return m.adapterReg
 }
 
@@ -230,6 +243,249 @@ func (m *BridgeManager) GetProtocolRegistry() *protocols.ProtocolRegistry {
 	return m.protocolReg
 }
 
+// AddProtocol makes a protocol's message transformers available to the
+// convert stages of link pipelines
+func (m *BridgeManager) AddProtocol(protocol protocols.Protocol) {
+	m.protocolsMu.Lock()
+	defer m.protocolsMu.Unlock()
+	m.protocols[protocol.Name()] = protocol
+}
+
+// CreateLink forwards every message the source adapter of a bridge receives
+// to its target adapter, through a transformation pipeline. The source must
+// push messages; messages dropped by a stage are not forwarded and stage
+// errors are logged. A source adapter feeds a single link, so this replaces
+// any link from the same source.
+func (m *BridgeManager) CreateLink(bridgeID, sourceName, targetName string, config *PipelineConfig) error {
+	b, err := m.GetBridge(bridgeID)
+	if err != nil {
+		return err
+	}
+
+	source, err := b.GetAdapter(sourceName)
+	if err != nil {
+		return err
+	}
+	target, err := b.GetAdapter(targetName)
+	if err != nil {
+		return err
+	}
+	if !source.Capabilities().Has(adapters.CapPush) {
+		return fmt.Errorf("%w: adapter '%s' does not deliver pushed messages", adapters.ErrCapabilityNotSupported, sourceName)
+	}
+
+	if config == nil {
+		config = &PipelineConfig{}
+	}
+	if config.Envelope {
+		return fmt.Errorf("%w: links carry encoded adapter messages, not envelopes", ErrInvalidPipeline)
+	}
+	pipeline, err := NewPipeline(*config, m.findTransformer)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	link := &bridgeLink{
+		bridge:   bridgeID,
+		source:   sourceName,
+		target:   targetName,
+		pipeline: pipeline.forLink(bridgeID, sourceName, targetName),
+		cancel:   cancel,
+	}
+
+	m.linksMu.Lock()
+	key := linkKey(bridgeID, sourceName)
+	if previous, exists := m.links[key]; exists {
+		previous.cancel()
+	}
+	m.links[key] = link
+	m.linksMu.Unlock()
+
+	go m.forward(ctx, link, source, target)
+
+	m.logger.Infow("Created link", "bridge_id", bridgeID, "source", sourceName, "target", targetName, "stages", len(config.Stages))
+	return nil
+}
+
+// RemoveLink stops the link from a source adapter of a bridge
+func (m *BridgeManager) RemoveLink(bridgeID, sourceName string) error {
+	m.linksMu.Lock()
+	defer m.linksMu.Unlock()
+
+	key := linkKey(bridgeID, sourceName)
+	link, exists := m.links[key]
+	if !exists {
+		return fmt.Errorf("%w: %s/%s", ErrLinkNotFound, bridgeID, sourceName)
+	}
+	link.cancel()
+	delete(m.links, key)
+
+	m.logger.Infow("Removed link", "bridge_id", bridgeID, "source", sourceName, "target", link.target)
+	return nil
+}
+
+// removeLinks stops every link of a bridge
+func (m *BridgeManager) removeLinks(bridgeID string) {
+	m.linksMu.Lock()
+	defer m.linksMu.Unlock()
+
+	for key, link := range m.links {
+		if link.bridge == bridgeID {
+			link.cancel()
+			delete(m.links, key)
+		}
+	}
+}
+
+// forward receives messages from the source until the link is removed and
+// delivers those passing the pipeline to the target, without waiting for a
+// reply when the target accepts pushed messages
+func (m *BridgeManager) forward(ctx context.Context, link *bridgeLink, source, target adapters.Adapter) {
+	push, pushErr := adapters.Push(target)
+
+	for {
+		data, err := source.Receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			m.logger.Warnw("Link failed to receive", "bridge_id", link.bridge, "source", link.source, "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		output, deliver, err := link.pipeline.Process(ctx, data)
+		if err != nil {
+			m.logger.Warnw("Link pipeline rejected message", "bridge_id", link.bridge, "source", link.source, "target", link.target, "error", err)
+			continue
+		}
+		if !deliver {
+			continue
+		}
+
+		encoded, _ := output.([]byte)
+		if pushErr == nil {
+			err = push.Notify(ctx, encoded)
+		} else {
+			_, err = target.Send(ctx, encoded)
+		}
+		if err != nil {
+			m.logger.Warnw("Link failed to forward message", "bridge_id", link.bridge, "source", link.source, "target", link.target, "error", err)
+		}
+	}
+}
+
+// Links returns every link and the counters of its pipeline stages, ordered
+// by bridge and source adapter
+func (m *BridgeManager) Links() []LinkInfo {
+	m.linksMu.RLock()
+	defer m.linksMu.RUnlock()
+
+	links := make([]LinkInfo, 0, len(m.links))
+	for _, link := range m.links {
+		links = append(links, LinkInfo{
+			Bridge: link.bridge,
+			Source: link.source,
+			Target: link.target,
+			Stages: link.pipeline.Stats(),
+		})
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Bridge != links[j].Bridge {
+			return links[i].Bridge < links[j].Bridge
+		}
+		return links[i].Source < links[j].Source
+	})
+
+	return links
+}
+
+// LinkStats returns the stage counters of the link between two adapters of a bridge
+func (m *BridgeManager) LinkStats(bridgeID, sourceName, targetName string) ([]StageStats, error) {
+	link, err := m.getLink(bridgeID, sourceName, targetName)
+	if err != nil {
+		return nil, err
+	}
+	return link.pipeline.Stats(), nil
+}
+
+// TestLink dry-runs a message through the pipeline of the link between two
+// adapters of a bridge. Nothing is forwarded and no counters are recorded.
+func (m *BridgeManager) TestLink(ctx context.Context, bridgeID, sourceName, targetName string, message interface{}) (*PipelineResult, error) {
+	link, err := m.getLink(bridgeID, sourceName, targetName)
+	if err != nil {
+		return nil, err
+	}
+	return link.pipeline.DryRun(ctx, message), nil
+}
+
+// TestPipeline dry-runs a message through a pipeline configuration, using
+// the transformers of the added protocols
+func (m *BridgeManager) TestPipeline(ctx context.Context, config *PipelineConfig, message interface{}) (*PipelineResult, error) {
+	if config == nil {
+		return nil, fmt.Errorf("%w: no pipeline given", ErrInvalidPipeline)
+	}
+
+	pipeline, err := NewPipeline(*config, m.findTransformer)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.DryRun(ctx, message), nil
+}
+
+// getLink returns the link between two adapters of a bridge
+func (m *BridgeManager) getLink(bridgeID, sourceName, targetName string) (*bridgeLink, error) {
+	m.linksMu.RLock()
+	defer m.linksMu.RUnlock()
+
+	link, exists := m.links[linkKey(bridgeID, sourceName)]
+	if !exists || link.target != targetName {
+		return nil, fmt.Errorf("%w: %s/%s -> %s", ErrLinkNotFound, bridgeID, sourceName, targetName)
+	}
+	return link, nil
+}
+
+// linkKey identifies a link by its bridge and source adapter
+func linkKey(bridgeID, sourceName string) string {
+	return bridgeID + "/" + sourceName
+}
+
+// transformerFinder is implemented by protocols built on protocols.BaseProtocol
+type transformerFinder interface {
+	FindTransformer(sourceType, targetType string) protocols.MessageTransformer
+}
+
+// findTransformer searches the added protocols, in name order, for a
+// transformer between two formats
+func (m *BridgeManager) findTransformer(protocolName, sourceType, targetType string) protocols.MessageTransformer {
+	m.protocolsMu.RLock()
+	defer m.protocolsMu.RUnlock()
+
+	names := make([]string, 0, len(m.protocols))
+	for name := range m.protocols {
+		if protocolName == "" || name == protocolName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		finder, ok := m.protocols[name].(transformerFinder)
+		if !ok {
+			continue
+		}
+		if transformer := finder.FindTransformer(sourceType, targetType); transformer != nil {
+			return transformer
+		}
+	}
+	return nil
+}
+
 // bridgeLoggerAdapter adapts zap.SugaredLogger to bridge.BridgeLogger
 type bridgeLoggerAdapter struct {
 	logger *zap.SugaredLogger
@@ -256,7 +512,7 @@ func toSlice(m map[string]interface{}) []interface{} {
 	if m == nil {
 		return nil
 	}
-	
+
 	slice := make([]interface{}, 0, len(m)*2)
 	for k, v := range m {
 		slice = append(slice, k, v)
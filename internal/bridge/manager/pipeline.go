@@ -0,0 +1,1043 @@
+// pipeline.go - Message transformation pipelines for links between adapters
+
+package manager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/protocols"
+)
+
+// Pipeline stage types
+const (
+	StageMap     = "map"     // Copy or move fields
+	StageFilter  = "filter"  // Drop messages not matching a predicate
+	StageEnrich  = "enrich"  // Set fields from templates over the message and link metadata
+	StageConvert = "convert" // Convert the format with a protocol's MessageTransformer
+)
+
+// Common pipeline errors
+var (
+	ErrInvalidPipeline     = errors.New("invalid pipeline configuration")
+	ErrTransformerNotFound = errors.New("no message transformer for conversion")
+	ErrStageFailed         = errors.New("pipeline stage failed")
+	ErrLinkNotFound        = errors.New("bridge link not found")
+)
+
+// PipelineConfig declares the ordered stages applied to every message
+// crossing a link, and metadata available to enrich stages as ${link.<key>}.
+//
+// Unless Envelope is set, messages are bare payloads: only payload fields
+// survive the pipeline, so map and enrich stages may not write any other path.
+type PipelineConfig struct {
+	Stages   []StageConfig          `json:"stages" yaml:"stages"`
+	Metadata map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Envelope bool                   `json:"envelope,omitempty" yaml:"envelope,omitempty"` // Messages are protocols.Message envelopes
+}
+
+// StageConfig declares one pipeline stage; which fields apply depends on Type.
+//
+// Fields are addressed by path: id, type, source, destination, priority,
+// headers.<name>, metadata.<key>[.<key>...] and payload[.<key>...]. JSON
+// payloads are decoded so their fields can be addressed.
+type StageConfig struct {
+	Type string `json:"type" yaml:"type"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	// Map: fields copied, or moved, within the message
+	Mappings []FieldMapping `json:"mappings,omitempty" yaml:"mappings,omitempty"`
+
+	// Filter: messages for which the predicate is false are dropped
+	Predicate *Predicate `json:"predicate,omitempty" yaml:"predicate,omitempty"`
+
+	// Enrich: values to set by path. Strings may reference fields, link
+	// metadata and the current time as ${path}, ${link.<key>} and ${now};
+	// a string that is a single reference keeps the referenced value's type.
+	Set map[string]interface{} `json:"set,omitempty" yaml:"set,omitempty"`
+
+	// Convert: formats handed to the transformer, optionally restricted to
+	// transformers of one registered protocol
+	From     string `json:"from,omitempty" yaml:"from,omitempty"`
+	To       string `json:"to,omitempty" yaml:"to,omitempty"`
+	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+}
+
+// FieldMapping copies the value at one path to another
+type FieldMapping struct {
+	From     string      `json:"from" yaml:"from"`
+	To       string      `json:"to" yaml:"to"`
+	Move     bool        `json:"move,omitempty" yaml:"move,omitempty"`         // Remove the source field
+	Default  interface{} `json:"default,omitempty" yaml:"default,omitempty"`   // Used when the source field is missing
+	Required bool        `json:"required,omitempty" yaml:"required,omitempty"` // Fail when the source field is missing
+}
+
+// Predicate tests a message. Either Field and Op are set, or exactly one of
+// All, Any and Not.
+type Predicate struct {
+	Field string      `json:"field,omitempty" yaml:"field,omitempty"`
+	Op    string      `json:"op,omitempty" yaml:"op,omitempty"` // eq, ne, in, gt, gte, lt, lte, contains, matches, exists, missing
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+
+	All []Predicate `json:"all,omitempty" yaml:"all,omitempty"`
+	Any []Predicate `json:"any,omitempty" yaml:"any,omitempty"`
+	Not *Predicate  `json:"not,omitempty" yaml:"not,omitempty"`
+}
+
+// ParsePipelineConfig decodes and validates a JSON pipeline configuration.
+// Conversions are resolved when the pipeline is attached to a link, once the
+// protocols providing transformers are registered.
+func ParsePipelineConfig(data []byte) (*PipelineConfig, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	config := &PipelineConfig{}
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPipeline, err)
+	}
+
+	if _, err := NewPipeline(*config, func(string, string, string) protocols.MessageTransformer {
+		return unresolvedTransformer{}
+	}); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// unresolvedTransformer stands in for transformers while validating a configuration
+type unresolvedTransformer struct{}
+
+func (unresolvedTransformer) Transform(ctx context.Context, message *protocols.Message) (*protocols.Message, error) {
+	return nil, ErrTransformerNotFound
+}
+
+func (unresolvedTransformer) SupportsTransformation(sourceType, targetType string) bool {
+	return false
+}
+
+// TransformerLookup finds a transformer between formats, searching only the
+// named protocol when protocolName is set
+type TransformerLookup func(protocolName, sourceType, targetType string) protocols.MessageTransformer
+
+// StageStats are the counters of one pipeline stage
+type StageStats struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Processed int64  `json:"processed"`
+	Dropped   int64  `json:"dropped"`
+	Errors    int64  `json:"errors"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// StageTrace records what one stage did to a message during a dry run
+type StageTrace struct {
+	Name    string             `json:"name"`
+	Type    string             `json:"type"`
+	Outcome string             `json:"outcome"` // passed, dropped or error
+	Error   string             `json:"error,omitempty"`
+	Message *protocols.Message `json:"message,omitempty"` // The message after the stage
+}
+
+// PipelineResult is the outcome of a dry run
+type PipelineResult struct {
+	Delivered bool         `json:"delivered"`
+	DroppedBy string       `json:"dropped_by,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Output    interface{}  `json:"output,omitempty"` // What would be sent to the target
+	Stages    []StageTrace `json:"stages"`
+}
+
+// Pipeline applies an ordered list of stages to messages
+type Pipeline struct {
+	config PipelineConfig
+	link   map[string]interface{}
+	stages []*pipelineStage
+}
+
+// stageFunc transforms a message, returning false to drop it
+type stageFunc func(ctx context.Context, message *protocols.Message) (bool, *protocols.Message, error)
+
+// pipelineStage is a compiled stage and its counters
+type pipelineStage struct {
+	name      string
+	kind      string
+	apply     stageFunc
+	processed int64
+	dropped   int64
+	errors    int64
+	lastError atomic.Value // string
+}
+
+// NewPipeline compiles a pipeline configuration. Conversion stages are
+// resolved with lookup when the pipeline is created.
+func NewPipeline(config PipelineConfig, lookup TransformerLookup) (*Pipeline, error) {
+	p := &Pipeline{
+		config: config,
+		link:   make(map[string]interface{}, len(config.Metadata)),
+		stages: make([]*pipelineStage, 0, len(config.Stages)),
+	}
+	for key, value := range config.Metadata {
+		p.link[key] = value
+	}
+
+	for i, stageConfig := range config.Stages {
+		name := stageConfig.Name
+		if name == "" {
+			name = fmt.Sprintf("%d-%s", i+1, stageConfig.Type)
+		}
+
+		apply, err := p.compileStage(stageConfig, lookup)
+		if err != nil {
+			return nil, fmt.Errorf("stage '%s': %w", name, err)
+		}
+
+		p.stages = append(p.stages, &pipelineStage{name: name, kind: stageConfig.Type, apply: apply})
+	}
+
+	return p, nil
+}
+
+// forLink makes the link's bridge and endpoints available to enrich stages
+// as ${link.bridge}, ${link.source} and ${link.target}
+func (p *Pipeline) forLink(bridgeID, source, target string) *Pipeline {
+	p.link["bridge"] = bridgeID
+	p.link["source"] = source
+	p.link["target"] = target
+	return p
+}
+
+// Config returns the pipeline configuration
+func (p *Pipeline) Config() PipelineConfig {
+	return p.config
+}
+
+// Process runs a message through the pipeline and records stage counters.
+// It returns the message to deliver, or false if a stage dropped it.
+func (p *Pipeline) Process(ctx context.Context, message interface{}) (interface{}, bool, error) {
+	return p.run(ctx, message, nil)
+}
+
+// DryRun runs a message through the pipeline without recording counters and
+// returns what every stage did
+func (p *Pipeline) DryRun(ctx context.Context, message interface{}) *PipelineResult {
+	result := &PipelineResult{Stages: make([]StageTrace, 0, len(p.stages))}
+
+	output, delivered, err := p.run(ctx, message, result)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Delivered = delivered
+	if delivered {
+		result.Output = output
+	}
+	return result
+}
+
+// Stats returns the counters of every stage, in pipeline order
+func (p *Pipeline) Stats() []StageStats {
+	stats := make([]StageStats, 0, len(p.stages))
+	for _, stage := range p.stages {
+		lastError, _ := stage.lastError.Load().(string)
+		stats = append(stats, StageStats{
+			Name:      stage.name,
+			Type:      stage.kind,
+			Processed: atomic.LoadInt64(&stage.processed),
+			Dropped:   atomic.LoadInt64(&stage.dropped),
+			Errors:    atomic.LoadInt64(&stage.errors),
+			LastError: lastError,
+		})
+	}
+	return stats
+}
+
+// run applies the stages, recording counters unless tracing a dry run
+func (p *Pipeline) run(ctx context.Context, raw interface{}, trace *PipelineResult) (interface{}, bool, error) {
+	message, form, err := decodeLinkMessage(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	if p.config.Envelope && !form.envelope {
+		return nil, false, fmt.Errorf("%w: pipeline expects a message envelope, got a bare payload", ErrStageFailed)
+	}
+
+	for _, stage := range p.stages {
+		keep, next, err := stage.apply(ctx, message)
+
+		if trace != nil {
+			entry := StageTrace{Name: stage.name, Type: stage.kind, Outcome: "passed"}
+			switch {
+			case err != nil:
+				entry.Outcome = "error"
+				entry.Error = err.Error()
+			case !keep:
+				entry.Outcome = "dropped"
+			default:
+				entry.Message = copyMessage(next)
+			}
+			trace.Stages = append(trace.Stages, entry)
+		} else {
+			atomic.AddInt64(&stage.processed, 1)
+			if err != nil {
+				atomic.AddInt64(&stage.errors, 1)
+				stage.lastError.Store(err.Error())
+			} else if !keep {
+				atomic.AddInt64(&stage.dropped, 1)
+			}
+		}
+
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %s: %v", ErrStageFailed, stage.name, err)
+		}
+		if !keep {
+			if trace != nil {
+				trace.DroppedBy = stage.name
+			}
+			return nil, false, nil
+		}
+		message = next
+	}
+
+	output, err := form.encode(message)
+	if err != nil {
+		return nil, false, err
+	}
+	return output, true, nil
+}
+
+// compileStage builds the function applying a stage
+func (p *Pipeline) compileStage(config StageConfig, lookup TransformerLookup) (stageFunc, error) {
+	switch config.Type {
+	case StageMap:
+		return compileMapStage(config.Mappings, p.config.Envelope)
+	case StageFilter:
+		if config.Predicate == nil {
+			return nil, fmt.Errorf("%w: filter stage requires a predicate", ErrInvalidPipeline)
+		}
+		predicate, err := compilePredicate(*config.Predicate)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context, message *protocols.Message) (bool, *protocols.Message, error) {
+			keep, err := predicate(message)
+			return keep, message, err
+		}, nil
+	case StageEnrich:
+		return p.compileEnrichStage(config.Set)
+	case StageConvert:
+		if config.From == "" || config.To == "" {
+			return nil, fmt.Errorf("%w: convert stage requires from and to", ErrInvalidPipeline)
+		}
+		transformer := lookup(config.Protocol, config.From, config.To)
+		if transformer == nil {
+			return nil, fmt.Errorf("%w: %s to %s", ErrTransformerNotFound, config.From, config.To)
+		}
+		return func(ctx context.Context, message *protocols.Message) (bool, *protocols.Message, error) {
+			converted, err := transformer.Transform(ctx, message)
+			if err != nil {
+				return false, nil, err
+			}
+			if converted == nil {
+				return false, nil, fmt.Errorf("transformer returned no message")
+			}
+			return true, converted, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown stage type '%s'", ErrInvalidPipeline, config.Type)
+	}
+}
+
+// compileMapStage builds a field mapping stage
+func compileMapStage(mappings []FieldMapping, envelope bool) (stageFunc, error) {
+	if len(mappings) == 0 {
+		return nil, fmt.Errorf("%w: map stage requires mappings", ErrInvalidPipeline)
+	}
+	for _, mapping := range mappings {
+		if err := validatePath(mapping.From); err != nil {
+			return nil, err
+		}
+		if err := validateTarget(mapping.To, envelope); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(ctx context.Context, message *protocols.Message) (bool, *protocols.Message, error) {
+		for _, mapping := range mappings {
+			value, found := getField(message, mapping.From)
+			if !found {
+				if mapping.Required {
+					return false, nil, fmt.Errorf("required field '%s' is missing", mapping.From)
+				}
+				if mapping.Default == nil {
+					continue
+				}
+				value = mapping.Default
+			}
+
+			if found && mapping.Move {
+				deleteField(message, mapping.From)
+			}
+			if err := setField(message, mapping.To, copyValue(value)); err != nil {
+				return false, nil, err
+			}
+		}
+		return true, message, nil
+	}, nil
+}
+
+// templateReference matches ${path} in enrich values
+var templateReference = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// compileEnrichStage builds a stage setting fields from templates
+func (p *Pipeline) compileEnrichStage(values map[string]interface{}) (stageFunc, error) {
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: enrich stage requires values to set", ErrInvalidPipeline)
+	}
+
+	// Paths are set in a stable order so later values can build on earlier ones
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		if err := validateTarget(path, p.config.Envelope); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	return func(ctx context.Context, message *protocols.Message) (bool, *protocols.Message, error) {
+		for _, path := range paths {
+			value, ok := p.expand(message, values[path])
+			if !ok {
+				continue
+			}
+			if err := setField(message, path, value); err != nil {
+				return false, nil, err
+			}
+		}
+		return true, message, nil
+	}, nil
+}
+
+// expand resolves template references in an enrich value. It returns false
+// when the value is a single reference to a missing field.
+func (p *Pipeline) expand(message *protocols.Message, value interface{}) (interface{}, bool) {
+	template, ok := value.(string)
+	if !ok {
+		return copyValue(value), true
+	}
+
+	if match := templateReference.FindStringSubmatch(template); match != nil && match[0] == template {
+		return p.resolve(message, match[1])
+	}
+
+	return templateReference.ReplaceAllStringFunc(template, func(reference string) string {
+		resolved, ok := p.resolve(message, reference[2:len(reference)-1])
+		if !ok {
+			return ""
+		}
+		return stringValue(resolved)
+	}), true
+}
+
+// resolve looks up a template reference
+func (p *Pipeline) resolve(message *protocols.Message, reference string) (interface{}, bool) {
+	reference = strings.TrimSpace(reference)
+	switch {
+	case reference == "now":
+		return time.Now().UTC().Format(time.RFC3339), true
+	case strings.HasPrefix(reference, "link."):
+		value, ok := p.link[strings.TrimPrefix(reference, "link.")]
+		return copyValue(value), ok
+	default:
+		value, ok := getField(message, reference)
+		return copyValue(value), ok
+	}
+}
+
+// compiledPredicate evaluates a predicate against a message
+type compiledPredicate func(message *protocols.Message) (bool, error)
+
+// compilePredicate validates a predicate and builds its evaluator
+func compilePredicate(predicate Predicate) (compiledPredicate, error) {
+	combinators := 0
+	if predicate.All != nil {
+		combinators++
+	}
+	if predicate.Any != nil {
+		combinators++
+	}
+	if predicate.Not != nil {
+		combinators++
+	}
+
+	if combinators > 0 {
+		if combinators > 1 || predicate.Field != "" {
+			return nil, fmt.Errorf("%w: predicate must set exactly one of field, all, any and not", ErrInvalidPipeline)
+		}
+		return compileCombinator(predicate)
+	}
+
+	if err := validatePath(predicate.Field); err != nil {
+		return nil, err
+	}
+
+	op := predicate.Op
+	if op == "" {
+		op = "eq"
+		if predicate.Value == nil {
+			op = "exists"
+		}
+	}
+
+	field := predicate.Field
+	switch op {
+	case "exists", "missing":
+		want := op == "exists"
+		return func(message *protocols.Message) (bool, error) {
+			_, found := getField(message, field)
+			return found == want, nil
+		}, nil
+	case "eq", "ne":
+		want := op == "eq"
+		return func(message *protocols.Message) (bool, error) {
+			value, _ := getField(message, field)
+			return valuesEqual(value, predicate.Value) == want, nil
+		}, nil
+	case "in":
+		candidates, ok := predicate.Value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: 'in' requires a list value", ErrInvalidPipeline)
+		}
+		return func(message *protocols.Message) (bool, error) {
+			value, _ := getField(message, field)
+			for _, candidate := range candidates {
+				if valuesEqual(value, candidate) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}, nil
+	case "gt", "gte", "lt", "lte":
+		limit, ok := numberValue(predicate.Value)
+		if !ok {
+			return nil, fmt.Errorf("%w: '%s' requires a numeric value", ErrInvalidPipeline, op)
+		}
+		return func(message *protocols.Message) (bool, error) {
+			value, found := getField(message, field)
+			if !found {
+				return false, nil
+			}
+			number, ok := numberValue(value)
+			if !ok {
+				return false, fmt.Errorf("field '%s' is not a number", field)
+			}
+			switch op {
+			case "gt":
+				return number > limit, nil
+			case "gte":
+				return number >= limit, nil
+			case "lt":
+				return number < limit, nil
+			default:
+				return number <= limit, nil
+			}
+		}, nil
+	case "contains":
+		return func(message *protocols.Message) (bool, error) {
+			value, _ := getField(message, field)
+			switch v := value.(type) {
+			case string:
+				return strings.Contains(v, stringValue(predicate.Value)), nil
+			case []interface{}:
+				for _, item := range v {
+					if valuesEqual(item, predicate.Value) {
+						return true, nil
+					}
+				}
+			}
+			return false, nil
+		}, nil
+	case "matches":
+		pattern, ok := predicate.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: 'matches' requires a pattern", ErrInvalidPipeline)
+		}
+		expression, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPipeline, err)
+		}
+		return func(message *protocols.Message) (bool, error) {
+			value, found := getField(message, field)
+			return found && expression.MatchString(stringValue(value)), nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown predicate op '%s'", ErrInvalidPipeline, op)
+	}
+}
+
+// compileCombinator builds an all, any or not predicate
+func compileCombinator(predicate Predicate) (compiledPredicate, error) {
+	if predicate.Not != nil {
+		inner, err := compilePredicate(*predicate.Not)
+		if err != nil {
+			return nil, err
+		}
+		return func(message *protocols.Message) (bool, error) {
+			result, err := inner(message)
+			return !result, err
+		}, nil
+	}
+
+	all := predicate.All != nil
+	children := predicate.All
+	if !all {
+		children = predicate.Any
+	}
+	if len(children) == 0 {
+		return nil, fmt.Errorf("%w: all and any require predicates", ErrInvalidPipeline)
+	}
+
+	compiled := make([]compiledPredicate, 0, len(children))
+	for _, child := range children {
+		inner, err := compilePredicate(child)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, inner)
+	}
+
+	return func(message *protocols.Message) (bool, error) {
+		for _, inner := range compiled {
+			result, err := inner(message)
+			if err != nil {
+				return false, err
+			}
+			if result != all {
+				return result, nil
+			}
+		}
+		return all, nil
+	}, nil
+}
+
+// payloadForm is how a link message was encoded when it entered the pipeline
+type payloadForm struct {
+	envelope bool // The message was a *protocols.Message
+	encoding int
+}
+
+// Payload encodings
+const (
+	encodingValue = iota
+	encodingBytes
+	encodingString
+)
+
+// decodeLinkMessage wraps a message in a protocols.Message with a decoded
+// copy of its payload. Byte and string payloads holding a JSON object or
+// array are decoded; other values are converted to their JSON form.
+func decodeLinkMessage(raw interface{}) (*protocols.Message, payloadForm, error) {
+	var form payloadForm
+	var message *protocols.Message
+
+	switch m := raw.(type) {
+	case *protocols.Message:
+		if m == nil {
+			return nil, form, fmt.Errorf("%w: nil message", ErrStageFailed)
+		}
+		form.envelope = true
+		message = copyMessage(m)
+	case protocols.Message:
+		form.envelope = true
+		message = copyMessage(&m)
+	default:
+		// Stages modify the payload in place, so never share the caller's
+		message = &protocols.Message{Payload: copyValue(raw)}
+	}
+
+	switch payload := message.Payload.(type) {
+	case []byte:
+		form.encoding = encodingBytes
+		message.Payload = decodeJSONPayload(payload)
+	case json.RawMessage:
+		form.encoding = encodingBytes
+		message.Payload = decodeJSONPayload(payload)
+	case string:
+		form.encoding = encodingString
+		message.Payload = decodeJSONPayload([]byte(payload))
+		if _, isBytes := message.Payload.([]byte); isBytes {
+			message.Payload = payload
+		}
+	case nil, map[string]interface{}, []interface{}, bool, float64:
+	default:
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, form, fmt.Errorf("%w: payload is not JSON encodable: %v", ErrStageFailed, err)
+		}
+		decoded := decodeJSONPayload(data)
+		if _, isBytes := decoded.([]byte); !isBytes {
+			message.Payload = decoded
+		}
+	}
+
+	return message, form, nil
+}
+
+// encode returns the message in the form it entered the pipeline
+func (f payloadForm) encode(message *protocols.Message) (interface{}, error) {
+	payload := message.Payload
+	switch f.encoding {
+	case encodingBytes, encodingString:
+		var data []byte
+		switch p := payload.(type) {
+		case []byte:
+			data = p
+		case string:
+			data = []byte(p)
+		default:
+			encoded, err := json.Marshal(p)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrStageFailed, err)
+			}
+			data = encoded
+		}
+		if f.encoding == encodingString {
+			payload = string(data)
+		} else {
+			payload = data
+		}
+	}
+
+	if !f.envelope {
+		return payload, nil
+	}
+	message.Payload = payload
+	return message, nil
+}
+
+// decodeJSONPayload decodes a JSON object or array, returning other data unchanged
+func decodeJSONPayload(data []byte) interface{} {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return data
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(trimmed, &value); err != nil {
+		return data
+	}
+	return value
+}
+
+// validatePath checks that a field path has a known root
+func validatePath(path string) error {
+	root, rest, _ := strings.Cut(path, ".")
+	switch root {
+	case "id", "type", "source", "destination", "priority":
+		if rest == "" {
+			return nil
+		}
+	case "headers":
+		if rest != "" {
+			return nil
+		}
+	case "metadata":
+		if rest != "" {
+			return nil
+		}
+	case "payload":
+		return nil
+	}
+	return fmt.Errorf("%w: invalid field path '%s'", ErrInvalidPipeline, path)
+}
+
+// validateTarget checks a path written by a stage. Bare payloads carry
+// nothing but the payload, so writes anywhere else would be lost.
+func validateTarget(path string, envelope bool) error {
+	if err := validatePath(path); err != nil {
+		return err
+	}
+	if root, _, _ := strings.Cut(path, "."); !envelope && root != "payload" {
+		return fmt.Errorf("%w: '%s' is lost on bare payloads; write payload fields or set envelope", ErrInvalidPipeline, path)
+	}
+	return nil
+}
+
+// getField reads the value at a path
+func getField(message *protocols.Message, path string) (interface{}, bool) {
+	root, rest, _ := strings.Cut(path, ".")
+	switch root {
+	case "id":
+		return message.ID, message.ID != ""
+	case "type":
+		return message.Type, message.Type != ""
+	case "source":
+		return message.Source, message.Source != ""
+	case "destination":
+		return message.Destination, message.Destination != ""
+	case "priority":
+		return float64(message.Priority), true
+	case "headers":
+		value, ok := message.Headers[rest]
+		return value, ok
+	case "metadata":
+		return lookupPath(message.Metadata, strings.Split(rest, "."))
+	case "payload":
+		if rest == "" {
+			return message.Payload, message.Payload != nil
+		}
+		object, ok := message.Payload.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		return lookupPath(object, strings.Split(rest, "."))
+	}
+	return nil, false
+}
+
+// setField writes the value at a path, creating intermediate objects
+func setField(message *protocols.Message, path string, value interface{}) error {
+	root, rest, _ := strings.Cut(path, ".")
+	switch root {
+	case "id":
+		message.ID = stringValue(value)
+	case "type":
+		message.Type = stringValue(value)
+	case "source":
+		message.Source = stringValue(value)
+	case "destination":
+		message.Destination = stringValue(value)
+	case "priority":
+		number, ok := numberValue(value)
+		if !ok {
+			return fmt.Errorf("priority must be a number, got %v", value)
+		}
+		message.Priority = int(number)
+	case "headers":
+		if message.Headers == nil {
+			message.Headers = make(map[string]string)
+		}
+		message.Headers[rest] = stringValue(value)
+	case "metadata":
+		if message.Metadata == nil {
+			message.Metadata = make(map[string]interface{})
+		}
+		return assignPath(message.Metadata, strings.Split(rest, "."), value)
+	case "payload":
+		if rest == "" {
+			message.Payload = value
+			return nil
+		}
+		if message.Payload == nil {
+			message.Payload = make(map[string]interface{})
+		}
+		object, ok := message.Payload.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot set '%s': payload is not an object", path)
+		}
+		return assignPath(object, strings.Split(rest, "."), value)
+	default:
+		return fmt.Errorf("%w: invalid field path '%s'", ErrInvalidPipeline, path)
+	}
+	return nil
+}
+
+// deleteField removes the value at a path
+func deleteField(message *protocols.Message, path string) {
+	root, rest, _ := strings.Cut(path, ".")
+	switch root {
+	case "id":
+		message.ID = ""
+	case "type":
+		message.Type = ""
+	case "source":
+		message.Source = ""
+	case "destination":
+		message.Destination = ""
+	case "headers":
+		delete(message.Headers, rest)
+	case "metadata":
+		removePath(message.Metadata, strings.Split(rest, "."))
+	case "payload":
+		if rest == "" {
+			message.Payload = nil
+			return
+		}
+		if object, ok := message.Payload.(map[string]interface{}); ok {
+			removePath(object, strings.Split(rest, "."))
+		}
+	}
+}
+
+// lookupPath walks nested objects
+func lookupPath(object map[string]interface{}, keys []string) (interface{}, bool) {
+	var current interface{} = object
+	for _, key := range keys {
+		next, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = next[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// assignPath sets a value in nested objects, creating missing levels
+func assignPath(object map[string]interface{}, keys []string, value interface{}) error {
+	for i, key := range keys[:len(keys)-1] {
+		next, exists := object[key]
+		if !exists {
+			created := make(map[string]interface{})
+			object[key] = created
+			object = created
+			continue
+		}
+		nested, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot set '%s': '%s' is not an object", strings.Join(keys, "."), strings.Join(keys[:i+1], "."))
+		}
+		object = nested
+	}
+	object[keys[len(keys)-1]] = value
+	return nil
+}
+
+// removePath deletes a value from nested objects
+func removePath(object map[string]interface{}, keys []string) {
+	parent, ok := lookupPath(object, keys[:len(keys)-1])
+	if !ok {
+		return
+	}
+	if nested, ok := parent.(map[string]interface{}); ok {
+		delete(nested, keys[len(keys)-1])
+	}
+}
+
+// copyMessage copies a message deeply enough that stages cannot modify the original
+func copyMessage(message *protocols.Message) *protocols.Message {
+	copied := *message
+	if message.Headers != nil {
+		copied.Headers = make(map[string]string, len(message.Headers))
+		for key, value := range message.Headers {
+			copied.Headers[key] = value
+		}
+	}
+	if message.Metadata != nil {
+		copied.Metadata = copyValue(message.Metadata).(map[string]interface{})
+	}
+	copied.Payload = copyValue(message.Payload)
+	return &copied
+}
+
+// copyValue deep copies decoded JSON objects and arrays
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	case []byte:
+		return append([]byte(nil), v...)
+	default:
+		return value
+	}
+}
+
+// valuesEqual compares values, treating all numeric types alike
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := numberValue(a); ok {
+		y, ok := numberValue(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// numberValue converts numeric values and numeric strings to float64
+func numberValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	case string:
+		number, err := strconv.ParseFloat(v, 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// stringValue formats a value for headers and templates
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// bridgeLink forwards the messages of one adapter of a bridge to another
+type bridgeLink struct {
+	bridge   string
+	source   string
+	target   string
+	pipeline *Pipeline
+	cancel   context.CancelFunc
+}
+
+// LinkInfo describes a link and the counters of its pipeline stages
+type LinkInfo struct {
+	Bridge string       `json:"bridge"`
+	Source string       `json:"source"`
+	Target string       `json:"target"`
+	Stages []StageStats `json:"stages"`
+}
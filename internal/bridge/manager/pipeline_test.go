@@ -0,0 +1,454 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/protocols"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upperTransformer converts "order" messages to "invoice" messages,
+// upper-casing the payload's name
+type upperTransformer struct{}
+
+func (upperTransformer) Transform(ctx context.Context, message *protocols.Message) (*protocols.Message, error) {
+	payload, ok := message.Payload.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("payload is not an object")
+	}
+	converted := copyMessage(message)
+	converted.Type = "invoice"
+	converted.Payload.(map[string]interface{})["name"] = strings.ToUpper(stringValue(payload["name"]))
+	return converted, nil
+}
+
+func (upperTransformer) SupportsTransformation(sourceType, targetType string) bool {
+	return sourceType == "order" && targetType == "invoice"
+}
+
+// testLookup finds upperTransformer in the "billing" protocol
+func testLookup(protocolName, sourceType, targetType string) protocols.MessageTransformer {
+	if protocolName != "" && protocolName != "billing" {
+		return nil
+	}
+	if (upperTransformer{}).SupportsTransformation(sourceType, targetType) {
+		return upperTransformer{}
+	}
+	return nil
+}
+
+// runPayload processes a bare JSON payload and decodes what is delivered
+func runPayload(t *testing.T, pipeline *Pipeline, payload string) (map[string]interface{}, bool, error) {
+	t.Helper()
+
+	output, delivered, err := pipeline.Process(context.Background(), []byte(payload))
+	if err != nil || !delivered {
+		return nil, delivered, err
+	}
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(output.([]byte), &decoded))
+	return decoded, true, nil
+}
+
+func TestPipelineMapStage(t *testing.T) {
+	tests := []struct {
+		name     string
+		mappings []FieldMapping
+		payload  string
+		want     map[string]interface{}
+		err      bool
+	}{
+		{
+			name:     "copy",
+			mappings: []FieldMapping{{From: "payload.user.id", To: "payload.customer"}},
+			payload:  `{"user":{"id":7}}`,
+			want:     map[string]interface{}{"user": map[string]interface{}{"id": 7.0}, "customer": 7.0},
+		},
+		{
+			name:     "move into a new object",
+			mappings: []FieldMapping{{From: "payload.id", To: "payload.order.id", Move: true}},
+			payload:  `{"id":"o-1"}`,
+			want:     map[string]interface{}{"order": map[string]interface{}{"id": "o-1"}},
+		},
+		{
+			name:     "default for a missing field",
+			mappings: []FieldMapping{{From: "payload.currency", To: "payload.currency", Default: "EUR"}},
+			payload:  `{}`,
+			want:     map[string]interface{}{"currency": "EUR"},
+		},
+		{
+			name:     "missing field without a default is skipped",
+			mappings: []FieldMapping{{From: "payload.currency", To: "payload.unit"}},
+			payload:  `{"id":1}`,
+			want:     map[string]interface{}{"id": 1.0},
+		},
+		{
+			name:     "required field missing",
+			mappings: []FieldMapping{{From: "payload.id", To: "payload.ref", Required: true}},
+			payload:  `{}`,
+			err:      true,
+		},
+		{
+			name:     "target below a scalar",
+			mappings: []FieldMapping{{From: "payload.id", To: "payload.name.first"}},
+			payload:  `{"id":1,"name":"x"}`,
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Type: StageMap, Mappings: tt.mappings}}}, testLookup)
+			require.NoError(t, err)
+
+			got, delivered, err := runPayload(t, pipeline, tt.payload)
+			if tt.err {
+				assert.ErrorIs(t, err, ErrStageFailed)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, delivered)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPipelineFilterStage(t *testing.T) {
+	payload := `{"status":"paid","total":120,"tags":["vip","eu"],"email":"a@example.com"}`
+
+	tests := []struct {
+		name      string
+		predicate Predicate
+		keep      bool
+	}{
+		{"eq", Predicate{Field: "payload.status", Value: "paid"}, true},
+		{"ne", Predicate{Field: "payload.status", Op: "ne", Value: "paid"}, false},
+		{"in", Predicate{Field: "payload.status", Op: "in", Value: []interface{}{"open", "paid"}}, true},
+		{"gt", Predicate{Field: "payload.total", Op: "gt", Value: 100}, true},
+		{"lte", Predicate{Field: "payload.total", Op: "lte", Value: 100}, false},
+		{"contains in a list", Predicate{Field: "payload.tags", Op: "contains", Value: "vip"}, true},
+		{"contains in a string", Predicate{Field: "payload.email", Op: "contains", Value: "@test"}, false},
+		{"matches", Predicate{Field: "payload.email", Op: "matches", Value: `@example\.com$`}, true},
+		{"exists by default", Predicate{Field: "payload.total"}, true},
+		{"missing", Predicate{Field: "payload.refund", Op: "missing"}, true},
+		{"all", Predicate{All: []Predicate{{Field: "payload.status", Value: "paid"}, {Field: "payload.total", Op: "gt", Value: 500}}}, false},
+		{"any", Predicate{Any: []Predicate{{Field: "payload.status", Value: "open"}, {Field: "payload.total", Op: "gt", Value: 100}}}, true},
+		{"not", Predicate{Not: &Predicate{Field: "payload.status", Value: "paid"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Type: StageFilter, Predicate: &tt.predicate}}}, testLookup)
+			require.NoError(t, err)
+
+			_, delivered, err := runPayload(t, pipeline, payload)
+			require.NoError(t, err)
+			assert.Equal(t, tt.keep, delivered)
+		})
+	}
+
+	invalid := []Predicate{
+		{Field: "payload.total", Op: "gt", Value: "many"},
+		{Field: "payload.status", Op: "in", Value: "paid"},
+		{Field: "payload.email", Op: "matches", Value: "("},
+		{Field: "payload.status", Op: "like"},
+		{Field: "payload.status", All: []Predicate{{Field: "payload.total"}}},
+		{Any: []Predicate{}},
+		{Field: "body.status"},
+	}
+	for _, predicate := range invalid {
+		predicate := predicate
+		_, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Type: StageFilter, Predicate: &predicate}}}, testLookup)
+		assert.ErrorIs(t, err, ErrInvalidPipeline, "%+v", predicate)
+	}
+
+	// A comparison against a field that is not a number fails the message
+	pipeline, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Type: StageFilter,
+		Predicate: &Predicate{Field: "payload.status", Op: "gt", Value: 1}}}}, testLookup)
+	require.NoError(t, err)
+	_, _, err = runPayload(t, pipeline, payload)
+	assert.ErrorIs(t, err, ErrStageFailed)
+}
+
+func TestPipelineEnrichStage(t *testing.T) {
+	tests := []struct {
+		name string
+		set  map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "literal values",
+			set:  map[string]interface{}{"payload.version": 2, "payload.flags": []interface{}{"a"}},
+			want: map[string]interface{}{"id": 7.0, "user": "ada", "version": 2, "flags": []interface{}{"a"}},
+		},
+		{
+			name: "single reference keeps its type",
+			set:  map[string]interface{}{"payload.ref": "${payload.id}"},
+			want: map[string]interface{}{"id": 7.0, "user": "ada", "ref": 7.0},
+		},
+		{
+			name: "template over fields and link metadata",
+			set:  map[string]interface{}{"payload.label": "${link.region}:${payload.user}#${payload.id}"},
+			want: map[string]interface{}{"id": 7.0, "user": "ada", "label": "eu:ada#7"},
+		},
+		{
+			name: "link endpoints",
+			set:  map[string]interface{}{"payload.route": "${link.bridge}/${link.source}->${link.target}"},
+			want: map[string]interface{}{"id": 7.0, "user": "ada", "route": "api/orders->billing"},
+		},
+		{
+			name: "missing single reference is not set",
+			set:  map[string]interface{}{"payload.team": "${payload.team}"},
+			want: map[string]interface{}{"id": 7.0, "user": "ada"},
+		},
+		{
+			name: "later paths build on earlier ones",
+			set:  map[string]interface{}{"payload.a": "x", "payload.b": "${payload.a}y"},
+			want: map[string]interface{}{"id": 7.0, "user": "ada", "a": "x", "b": "xy"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := NewPipeline(PipelineConfig{
+				Stages:   []StageConfig{{Type: StageEnrich, Set: tt.set}},
+				Metadata: map[string]interface{}{"region": "eu"},
+			}, testLookup)
+			require.NoError(t, err)
+			pipeline.forLink("api", "orders", "billing")
+
+			output, delivered, err := pipeline.Process(context.Background(), map[string]interface{}{"id": 7.0, "user": "ada"})
+			require.NoError(t, err)
+			require.True(t, delivered)
+			assert.Equal(t, tt.want, output)
+		})
+	}
+
+	pipeline, err := NewPipeline(PipelineConfig{Stages: []StageConfig{{Type: StageEnrich,
+		Set: map[string]interface{}{"payload.seen": "${now}"}}}}, testLookup)
+	require.NoError(t, err)
+	got, _, err := runPayload(t, pipeline, `{}`)
+	require.NoError(t, err)
+	assert.NotEmpty(t, got["seen"])
+}
+
+func TestPipelineConvertStage(t *testing.T) {
+	tests := []struct {
+		name   string
+		stage  StageConfig
+		config error
+		run    error
+	}{
+		{name: "any protocol", stage: StageConfig{Type: StageConvert, From: "order", To: "invoice"}},
+		{name: "named protocol", stage: StageConfig{Type: StageConvert, From: "order", To: "invoice", Protocol: "billing"}},
+		{name: "other protocol", stage: StageConfig{Type: StageConvert, From: "order", To: "invoice", Protocol: "shipping"}, config: ErrTransformerNotFound},
+		{name: "unknown formats", stage: StageConfig{Type: StageConvert, From: "order", To: "receipt"}, config: ErrTransformerNotFound},
+		{name: "formats required", stage: StageConfig{Type: StageConvert, From: "order"}, config: ErrInvalidPipeline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, err := NewPipeline(PipelineConfig{Stages: []StageConfig{tt.stage}}, testLookup)
+			if tt.config != nil {
+				assert.ErrorIs(t, err, tt.config)
+				return
+			}
+			require.NoError(t, err)
+
+			got, delivered, err := runPayload(t, pipeline, `{"name":"ada"}`)
+			require.NoError(t, err)
+			assert.True(t, delivered)
+			assert.Equal(t, map[string]interface{}{"name": "ADA"}, got)
+
+			// Transformer failures fail the message
+			_, _, err = pipeline.Process(context.Background(), []byte(`["not","an","object"]`))
+			assert.ErrorIs(t, err, ErrStageFailed)
+		})
+	}
+
+	// Envelopes keep what the transformer sets outside the payload
+	pipeline, err := NewPipeline(PipelineConfig{Envelope: true,
+		Stages: []StageConfig{{Type: StageConvert, From: "order", To: "invoice"}}}, testLookup)
+	require.NoError(t, err)
+	output, _, err := pipeline.Process(context.Background(), &protocols.Message{Type: "order", Payload: []byte(`{"name":"ada"}`)})
+	require.NoError(t, err)
+	message := output.(*protocols.Message)
+	assert.Equal(t, "invoice", message.Type)
+	assert.JSONEq(t, `{"name":"ADA"}`, string(message.Payload.([]byte)))
+}
+
+func TestPipelineUnknownStage(t *testing.T) {
+	for _, stage := range []StageConfig{
+		{Type: "reverse"},
+		{Type: StageMap},
+		{Type: StageFilter},
+		{Type: StageEnrich},
+		{Type: StageMap, Mappings: []FieldMapping{{From: "payload", To: "body"}}},
+	} {
+		_, err := NewPipeline(PipelineConfig{Stages: []StageConfig{stage}}, testLookup)
+		assert.ErrorIs(t, err, ErrInvalidPipeline, stage.Type)
+	}
+}
+
+func TestPipelineCopiesPayload(t *testing.T) {
+	pipeline, err := NewPipeline(PipelineConfig{Stages: []StageConfig{
+		{Type: StageMap, Mappings: []FieldMapping{{From: "payload.user.id", To: "payload.customer", Move: true}}},
+		{Type: StageEnrich, Set: map[string]interface{}{"payload.user.name": "ada"}},
+	}}, testLookup)
+	require.NoError(t, err)
+
+	payload := map[string]interface{}{"user": map[string]interface{}{"id": 7.0}}
+	output, delivered, err := pipeline.Process(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, delivered)
+
+	assert.Equal(t, map[string]interface{}{"customer": 7.0, "user": map[string]interface{}{"name": "ada"}}, output)
+	assert.Equal(t, map[string]interface{}{"user": map[string]interface{}{"id": 7.0}}, payload, "the caller's payload is untouched")
+
+	// Envelopes and their payloads are copied as well
+	envelope := &protocols.Message{Headers: map[string]string{"trace": "1"}, Payload: map[string]interface{}{"user": map[string]interface{}{"id": 7.0}}}
+	pipeline, err = NewPipeline(PipelineConfig{Envelope: true, Stages: []StageConfig{
+		{Type: StageMap, Mappings: []FieldMapping{{From: "payload.user.id", To: "headers.customer", Move: true}}},
+	}}, testLookup)
+	require.NoError(t, err)
+	output, _, err = pipeline.Process(context.Background(), envelope)
+	require.NoError(t, err)
+
+	assert.Equal(t, "7", output.(*protocols.Message).Headers["customer"])
+	assert.Equal(t, map[string]string{"trace": "1"}, envelope.Headers)
+	assert.Equal(t, map[string]interface{}{"user": map[string]interface{}{"id": 7.0}}, envelope.Payload)
+}
+
+func TestPipelineBarePayloads(t *testing.T) {
+	for _, path := range []string{"id", "type", "source", "destination", "priority", "headers.trace", "metadata.region"} {
+		mapStage := StageConfig{Type: StageMap, Mappings: []FieldMapping{{From: "payload.value", To: path}}}
+		enrichStage := StageConfig{Type: StageEnrich, Set: map[string]interface{}{path: "1"}}
+
+		for _, stage := range []StageConfig{mapStage, enrichStage} {
+			_, err := NewPipeline(PipelineConfig{Stages: []StageConfig{stage}}, testLookup)
+			assert.ErrorIs(t, err, ErrInvalidPipeline, "%s writing %s", stage.Type, path)
+
+			_, err = NewPipeline(PipelineConfig{Envelope: true, Stages: []StageConfig{stage}}, testLookup)
+			assert.NoError(t, err, "%s writing %s", stage.Type, path)
+		}
+	}
+
+	// Other paths may still be read, and envelope pipelines need envelopes
+	pipeline, err := NewPipeline(PipelineConfig{Envelope: true, Stages: []StageConfig{
+		{Type: StageEnrich, Set: map[string]interface{}{"headers.trace": "1"}},
+	}}, testLookup)
+	require.NoError(t, err)
+	_, _, err = pipeline.Process(context.Background(), []byte(`{}`))
+	assert.ErrorIs(t, err, ErrStageFailed)
+
+	pipeline, err = NewPipeline(PipelineConfig{Stages: []StageConfig{
+		{Type: StageMap, Mappings: []FieldMapping{{From: "headers.trace", To: "payload.trace", Default: "none"}}},
+	}}, testLookup)
+	require.NoError(t, err)
+	got, _, err := runPayload(t, pipeline, `{}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"trace": "none"}, got)
+}
+
+func TestPipelineDryRun(t *testing.T) {
+	config := PipelineConfig{Stages: []StageConfig{
+		{Name: "paid-only", Type: StageFilter, Predicate: &Predicate{Field: "payload.status", Value: "paid"}},
+		{Type: StageEnrich, Set: map[string]interface{}{"payload.billed": true}},
+		{Type: StageConvert, From: "order", To: "invoice"},
+	}}
+	pipeline, err := NewPipeline(config, testLookup)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		message   string
+		delivered bool
+		droppedBy string
+		outcomes  []string
+		output    string
+	}{
+		{
+			name:      "delivered",
+			message:   `{"status":"paid","name":"ada"}`,
+			delivered: true,
+			outcomes:  []string{"passed", "passed", "passed"},
+			output:    `{"status":"paid","name":"ADA","billed":true}`,
+		},
+		{
+			name:      "dropped",
+			message:   `{"status":"open","name":"ada"}`,
+			droppedBy: "paid-only",
+			outcomes:  []string{"dropped"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := pipeline.DryRun(ctx, []byte(tt.message))
+			assert.Equal(t, tt.delivered, result.Delivered)
+			assert.Equal(t, tt.droppedBy, result.DroppedBy)
+			assert.Empty(t, result.Error)
+
+			outcomes := make([]string, 0, len(result.Stages))
+			for _, stage := range result.Stages {
+				outcomes = append(outcomes, stage.Outcome)
+			}
+			assert.Equal(t, tt.outcomes, outcomes)
+
+			if tt.output != "" {
+				assert.JSONEq(t, tt.output, string(result.Output.([]byte)))
+				assert.Equal(t, "paid-only", result.Stages[0].Name)
+				assert.Equal(t, "2-enrich", result.Stages[1].Name)
+				assert.Equal(t, true, result.Stages[1].Message.Payload.(map[string]interface{})["billed"])
+				assert.Equal(t, "ada", result.Stages[1].Message.Payload.(map[string]interface{})["name"], "traces are not changed by later stages")
+			}
+		})
+	}
+
+	// A failing stage is reported with the stages before it
+	failing, err := NewPipeline(PipelineConfig{Stages: []StageConfig{
+		{Type: StageEnrich, Set: map[string]interface{}{"payload.seen": true}},
+		{Type: StageMap, Mappings: []FieldMapping{{From: "payload.id", To: "payload.ref", Required: true}}},
+	}}, testLookup)
+	require.NoError(t, err)
+	result := failing.DryRun(ctx, []byte(`{}`))
+	assert.False(t, result.Delivered)
+	assert.Nil(t, result.Output)
+	assert.Contains(t, result.Error, "required field 'payload.id' is missing")
+	require.Len(t, result.Stages, 2)
+	assert.Equal(t, "passed", result.Stages[0].Outcome)
+	assert.Equal(t, "error", result.Stages[1].Outcome)
+
+	// Dry runs leave the counters to real traffic
+	for _, stats := range pipeline.Stats() {
+		assert.Zero(t, stats.Processed, stats.Name)
+	}
+
+	_, _, err = pipeline.Process(ctx, []byte(`{"status":"open"}`))
+	require.NoError(t, err)
+	stats := pipeline.Stats()
+	assert.Equal(t, StageStats{Name: "paid-only", Type: StageFilter, Processed: 1, Dropped: 1}, stats[0])
+	assert.Zero(t, stats[1].Processed)
+}
+
+func TestParsePipelineConfig(t *testing.T) {
+	config, err := ParsePipelineConfig([]byte(`{"stages":[{"type":"convert","from":"order","to":"invoice"}]}`))
+	require.NoError(t, err)
+	assert.Len(t, config.Stages, 1)
+
+	_, err = ParsePipelineConfig([]byte(`{"stages":[{"type":"filter","when":{}}]}`))
+	assert.ErrorIs(t, err, ErrInvalidPipeline)
+
+	_, err = ParsePipelineConfig([]byte(`{"stages":[{"type":"enrich","set":{"headers.trace":"1"}}]}`))
+	assert.ErrorIs(t, err, ErrInvalidPipeline)
+
+	_, err = ParsePipelineConfig([]byte(`{"envelope":true,"stages":[{"type":"enrich","set":{"headers.trace":"1"}}]}`))
+	assert.NoError(t, err)
+}
@@ -158,6 +158,41 @@ func (m *Message) GetMetadata(key string) (string, bool) {
 	return value, exists
 }
 
+// Clone returns a deep copy of the message
+func (m *Message) Clone() *Message {
+	clone := *m
+	clone.Source = cloneTarget(m.Source)
+	clone.Destination = cloneTarget(m.Destination)
+
+	if m.Payload != nil {
+		clone.Payload = append([]byte(nil), m.Payload...)
+	}
+	if m.Metadata != nil {
+		clone.Metadata = make(map[string]string, len(m.Metadata))
+		for key, value := range m.Metadata {
+			clone.Metadata[key] = value
+		}
+	}
+
+	return &clone
+}
+
+// cloneTarget copies a bridge target and its options
+func cloneTarget(target *BridgeTarget) *BridgeTarget {
+	if target == nil {
+		return nil
+	}
+
+	clone := *target
+	if target.Options != nil {
+		clone.Options = make(map[string]interface{}, len(target.Options))
+		for key, value := range target.Options {
+			clone.Options[key] = value
+		}
+	}
+	return &clone
+}
+
 // CreateResponse creates a response message for this message
 func (m *Message) CreateResponse(payload interface{}) (*Message, error) {
 	response, err := NewMessage(TypeResponse, payload)
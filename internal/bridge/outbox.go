@@ -0,0 +1,579 @@
+// outbox.go - Durable outbox providing at-least-once delivery for bridge messages
+
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Outbox bucket names
+var (
+	outboxPendingBucket    = []byte("outbox_pending")
+	outboxDeadLetterBucket = []byte("outbox_dead_letter")
+)
+
+// Common outbox errors
+var (
+	ErrOutboxNotConfigured = errors.New("outbox not configured")
+	ErrOutboxClosed        = errors.New("outbox closed")
+	ErrOutboxEntryNotFound = errors.New("outbox entry not found")
+	ErrInvalidOutboxState  = errors.New("invalid outbox state")
+)
+
+// OutboxState identifies the store an outbox entry lives in
+type OutboxState string
+
+const (
+	OutboxPending    OutboxState = "pending"
+	OutboxDeadLetter OutboxState = "dead_letter"
+)
+
+// DefaultOutboxState is the store used when no state is given: the
+// dead-letter store, whose entries are the ones that need attention
+const DefaultOutboxState = OutboxDeadLetter
+
+// OutboxDeliverFunc delivers a message; a nil error acknowledges it
+type OutboxDeliverFunc func(ctx context.Context, msg *Message) error
+
+// OutboxOptions contains configuration for the outbox
+type OutboxOptions struct {
+	Path            string
+	MaxAttempts     int
+	PollInterval    time.Duration
+	RetryDelay      time.Duration
+	MaxRetryDelay   time.Duration
+	DeliveryTimeout time.Duration
+	BatchSize       int
+	Deliver         OutboxDeliverFunc
+}
+
+// DefaultOutboxOptions returns default outbox options
+func DefaultOutboxOptions() *OutboxOptions {
+	return &OutboxOptions{
+		Path:            "data/bridge-outbox.db",
+		MaxAttempts:     10,
+		PollInterval:    time.Second,
+		RetryDelay:      2 * time.Second,
+		MaxRetryDelay:   5 * time.Minute,
+		DeliveryTimeout: 30 * time.Second,
+		BatchSize:       100,
+	}
+}
+
+// OutboxEntry is a persisted message with its delivery state
+type OutboxEntry struct {
+	Message        *Message    `json:"message"`
+	State          OutboxState `json:"state"`
+	NextAttempt    time.Time   `json:"next_attempt"`
+	LastError      string      `json:"last_error,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	DeadLetteredAt time.Time   `json:"dead_lettered_at,omitempty"`
+}
+
+// Outbox persists messages in bbolt and redelivers them until acknowledged
+type Outbox struct {
+	db       *bolt.DB
+	bridge   *Bridge
+	options  *OutboxOptions
+	logger   BridgeLogger
+	wake     chan struct{}
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	closed   bool
+	mutex    sync.Mutex
+}
+
+// NewOutbox opens the outbox database and attaches the outbox to the bridge
+func NewOutbox(bridge *Bridge, options *OutboxOptions) (*Outbox, error) {
+	// Unset fields are filled from the defaults on a copy so the caller's
+	// options are not modified
+	defaults := DefaultOutboxOptions()
+	if options == nil {
+		options = defaults
+	} else {
+		copied := *options
+		options = &copied
+	}
+	if options.Path == "" {
+		options.Path = defaults.Path
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = defaults.MaxAttempts
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaults.PollInterval
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = defaults.RetryDelay
+	}
+	if options.MaxRetryDelay <= 0 {
+		options.MaxRetryDelay = defaults.MaxRetryDelay
+	}
+	if options.DeliveryTimeout <= 0 {
+		options.DeliveryTimeout = defaults.DeliveryTimeout
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaults.BatchSize
+	}
+
+	db, err := bolt.Open(options.Path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{outboxPendingBucket, outboxDeadLetterBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create outbox buckets: %w", err)
+	}
+
+	outbox := &Outbox{
+		db:       db,
+		bridge:   bridge,
+		options:  options,
+		logger:   bridge.logger,
+		wake:     make(chan struct{}, 1),
+		stopChan: make(chan struct{}),
+	}
+
+	if options.Deliver == nil {
+		options.Deliver = outbox.deliverThroughBridge
+	}
+
+	bridge.outboxMutex.Lock()
+	bridge.outbox = outbox
+	bridge.outboxMutex.Unlock()
+
+	return outbox, nil
+}
+
+// Start begins the background delivery loop; entries left from a previous run are redelivered
+func (o *Outbox) Start() {
+	o.wg.Add(1)
+	go o.run()
+}
+
+// Close stops delivery and closes the database
+func (o *Outbox) Close() error {
+	o.mutex.Lock()
+	if o.closed {
+		o.mutex.Unlock()
+		return nil
+	}
+	o.closed = true
+	close(o.stopChan)
+	o.mutex.Unlock()
+
+	o.wg.Wait()
+	return o.db.Close()
+}
+
+// Submit persists a message for delivery to msg.Destination
+func (o *Outbox) Submit(msg *Message) error {
+	if msg == nil || msg.Destination == nil {
+		return fmt.Errorf("%w: outbox messages require a destination", ErrInvalidMessage)
+	}
+
+	if o.isClosed() {
+		return ErrOutboxClosed
+	}
+
+	now := time.Now()
+	if msg.ID == "" {
+		msg.ID = generateID()
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = now
+	}
+	msg.Status = StatusPending
+
+	entry := &OutboxEntry{
+		Message:     msg,
+		State:       OutboxPending,
+		NextAttempt: now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(outboxPendingBucket)
+		if bucket.Get([]byte(msg.ID)) != nil {
+			return fmt.Errorf("message '%s' already in outbox", msg.ID)
+		}
+		return putEntry(bucket, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	o.signal()
+	return nil
+}
+
+// Ack acknowledges a pending message so it is no longer redelivered
+func (o *Outbox) Ack(id string) error {
+	return o.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(outboxPendingBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return fmt.Errorf("%w: %s", ErrOutboxEntryNotFound, id)
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// List returns the entries in a store, oldest first; an empty state lists
+// the default (dead-letter) store
+func (o *Outbox) List(state OutboxState) ([]*OutboxEntry, error) {
+	name, err := bucketForState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*OutboxEntry, 0)
+	err = o.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(name).ForEach(func(k, v []byte) error {
+			var entry OutboxEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return fmt.Errorf("corrupt outbox entry '%s': %w", k, err)
+			}
+			entries = append(entries, &entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+
+	return entries, nil
+}
+
+// Replay moves a dead-lettered message back to pending with its attempts reset
+func (o *Outbox) Replay(id string) error {
+	err := o.db.Update(func(tx *bolt.Tx) error {
+		deadLetters := tx.Bucket(outboxDeadLetterBucket)
+		data := deadLetters.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("%w: %s", ErrOutboxEntryNotFound, id)
+		}
+
+		var entry OutboxEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return fmt.Errorf("corrupt outbox entry '%s': %w", id, err)
+		}
+
+		now := time.Now()
+		entry.State = OutboxPending
+		entry.NextAttempt = now
+		entry.UpdatedAt = now
+		entry.DeadLetteredAt = time.Time{}
+		entry.Message.Attempts = 0
+		entry.Message.Status = StatusPending
+		entry.Message.Error = ""
+
+		if err := deadLetters.Delete([]byte(id)); err != nil {
+			return err
+		}
+		return putEntry(tx.Bucket(outboxPendingBucket), &entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	o.signal()
+	return nil
+}
+
+// Purge removes an entry from a store, or every entry when id is empty,
+// and returns the number of entries removed; an empty state purges the
+// default (dead-letter) store
+func (o *Outbox) Purge(state OutboxState, id string) (int, error) {
+	name, err := bucketForState(state)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	err = o.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(name)
+
+		if id != "" {
+			if bucket.Get([]byte(id)) == nil {
+				return fmt.Errorf("%w: %s", ErrOutboxEntryNotFound, id)
+			}
+			removed = 1
+			return bucket.Delete([]byte(id))
+		}
+
+		// Collect keys first; deleting while iterating with ForEach is not allowed
+		keys := make([][]byte, 0)
+		if err := bucket.ForEach(func(k, _ []byte) error {
+			keys = append(keys, append([]byte(nil), k...))
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(keys)
+		return nil
+	})
+
+	return removed, err
+}
+
+// run is the delivery loop
+func (o *Outbox) run() {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(o.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		o.processDue()
+
+		select {
+		case <-o.stopChan:
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// processDue attempts delivery of every pending entry whose next attempt is due
+func (o *Outbox) processDue() {
+	now := time.Now()
+	due := make([]*OutboxEntry, 0)
+
+	err := o.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxPendingBucket).ForEach(func(k, v []byte) error {
+			var entry OutboxEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				o.logger.Error(fmt.Sprintf("Skipping corrupt outbox entry '%s': %v", k, err), nil)
+				return nil
+			}
+			if !entry.NextAttempt.After(now) {
+				due = append(due, &entry)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		o.logger.Error(fmt.Sprintf("Failed to scan outbox: %v", err), nil)
+		return
+	}
+
+	// Deliver in submission order
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if len(due) > o.options.BatchSize {
+		due = due[:o.options.BatchSize]
+	}
+
+	for _, entry := range due {
+		select {
+		case <-o.stopChan:
+			return
+		default:
+		}
+		o.attempt(entry)
+	}
+}
+
+// attempt delivers a single entry and records the outcome
+func (o *Outbox) attempt(entry *OutboxEntry) {
+	msg := entry.Message
+
+	if msg.IsExpired() {
+		msg.Status = StatusTimedOut
+		o.logger.Warn(fmt.Sprintf("Outbox message '%s' expired before delivery", msg.ID), map[string]interface{}{
+			"attempts": msg.Attempts,
+		})
+		o.remove(msg.ID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(o.bridge.ctx, o.options.DeliveryTimeout)
+	msg.Attempts++
+	msg.Status = StatusSent
+	err := o.options.Deliver(ctx, msg)
+	cancel()
+
+	if err == nil {
+		msg.Status = StatusDelivered
+		o.remove(msg.ID)
+		return
+	}
+
+	// An attempt interrupted by bridge shutdown doesn't count against the message
+	if o.bridge.ctx.Err() != nil {
+		return
+	}
+
+	now := time.Now()
+	msg.Status = StatusFailed
+	msg.Error = err.Error()
+	entry.LastError = err.Error()
+	entry.UpdatedAt = now
+
+	if msg.Attempts >= o.options.MaxAttempts {
+		entry.State = OutboxDeadLetter
+		entry.DeadLetteredAt = now
+		o.logger.Error(fmt.Sprintf("Outbox message '%s' moved to dead letter after %d attempts", msg.ID, msg.Attempts), map[string]interface{}{
+			"error": err.Error(),
+		})
+		o.moveToDeadLetter(entry)
+		return
+	}
+
+	msg.Status = StatusPending
+	entry.NextAttempt = now.Add(o.retryDelay(msg.Attempts))
+	o.logger.Debug(fmt.Sprintf("Outbox delivery of '%s' failed, will retry", msg.ID), map[string]interface{}{
+		"attempt":      msg.Attempts,
+		"next_attempt": entry.NextAttempt,
+		"error":        err.Error(),
+	})
+
+	if err := o.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(outboxPendingBucket)
+		// The entry may have been acknowledged or purged during delivery
+		if bucket.Get([]byte(msg.ID)) == nil {
+			return nil
+		}
+		return putEntry(bucket, entry)
+	}); err != nil {
+		o.logger.Error(fmt.Sprintf("Failed to update outbox entry '%s': %v", msg.ID, err), nil)
+	}
+}
+
+// deliverThroughBridge is the default delivery function, sending the payload with Bridge.Call
+func (o *Outbox) deliverThroughBridge(ctx context.Context, msg *Message) error {
+	var data interface{} = msg.Payload
+	if json.Valid(msg.Payload) {
+		data = json.RawMessage(msg.Payload)
+	}
+
+	_, err := o.bridge.Call(ctx, *msg.Destination, msg.Destination.Operation, data)
+	return err
+}
+
+// retryDelay returns the exponential delay before the next delivery attempt
+func (o *Outbox) retryDelay(attempts int) time.Duration {
+	delay := o.options.RetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if o.options.MaxRetryDelay > 0 && delay >= o.options.MaxRetryDelay {
+			return o.options.MaxRetryDelay
+		}
+	}
+	return delay
+}
+
+// remove deletes a pending entry
+func (o *Outbox) remove(id string) {
+	if err := o.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxPendingBucket).Delete([]byte(id))
+	}); err != nil {
+		o.logger.Error(fmt.Sprintf("Failed to remove outbox entry '%s': %v", id, err), nil)
+	}
+}
+
+// moveToDeadLetter moves a pending entry to the dead-letter store
+func (o *Outbox) moveToDeadLetter(entry *OutboxEntry) {
+	if err := o.db.Update(func(tx *bolt.Tx) error {
+		pending := tx.Bucket(outboxPendingBucket)
+		if pending.Get([]byte(entry.Message.ID)) == nil {
+			return nil
+		}
+		if err := pending.Delete([]byte(entry.Message.ID)); err != nil {
+			return err
+		}
+		return putEntry(tx.Bucket(outboxDeadLetterBucket), entry)
+	}); err != nil {
+		o.logger.Error(fmt.Sprintf("Failed to dead-letter outbox entry '%s': %v", entry.Message.ID, err), nil)
+	}
+}
+
+// signal wakes the delivery loop
+func (o *Outbox) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// isClosed reports whether the outbox has been closed
+func (o *Outbox) isClosed() bool {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.closed
+}
+
+// putEntry stores an entry keyed by message ID
+func putEntry(bucket *bolt.Bucket, entry *OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox entry: %w", err)
+	}
+	return bucket.Put([]byte(entry.Message.ID), data)
+}
+
+// bucketForState returns the bucket holding entries in the given state
+func bucketForState(state OutboxState) ([]byte, error) {
+	if state == "" {
+		state = DefaultOutboxState
+	}
+
+	switch state {
+	case OutboxPending:
+		return outboxPendingBucket, nil
+	case OutboxDeadLetter:
+		return outboxDeadLetterBucket, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidOutboxState, state)
+	}
+}
+
+// Outbox returns the outbox attached to the bridge, if any
+func (b *Bridge) Outbox() (*Outbox, error) {
+	b.outboxMutex.RLock()
+	defer b.outboxMutex.RUnlock()
+
+	if b.outbox == nil {
+		return nil, ErrOutboxNotConfigured
+	}
+	return b.outbox, nil
+}
+
+// Submit durably queues a message for at-least-once delivery to msg.Destination
+func (b *Bridge) Submit(msg *Message) error {
+	outbox, err := b.Outbox()
+	if err != nil {
+		return err
+	}
+	return outbox.Submit(msg)
+}
@@ -0,0 +1,274 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOutboxDefaults(t *testing.T) {
+	b := NewBridge(&BridgeOptions{}, &defaultLogger{})
+	options := &OutboxOptions{Path: filepath.Join(t.TempDir(), "outbox.db")}
+
+	outbox, err := NewOutbox(b, options)
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	// Unset fields get the same defaults as passing no options at all
+	defaults := DefaultOutboxOptions()
+	assert.Equal(t, defaults.DeliveryTimeout, outbox.options.DeliveryTimeout)
+	assert.Equal(t, defaults.MaxAttempts, outbox.options.MaxAttempts)
+	assert.Equal(t, defaults.RetryDelay, outbox.options.RetryDelay)
+	assert.Equal(t, defaults.MaxRetryDelay, outbox.options.MaxRetryDelay)
+	assert.Equal(t, defaults.BatchSize, outbox.options.BatchSize)
+	assert.Equal(t, defaults.PollInterval, outbox.options.PollInterval)
+	assert.NotNil(t, outbox.options.Deliver)
+
+	// The caller's options are left as given
+	assert.Zero(t, options.DeliveryTimeout)
+	assert.Zero(t, options.MaxAttempts)
+	assert.Nil(t, options.Deliver)
+}
+
+func TestOutboxRetryDelay(t *testing.T) {
+	outbox := &Outbox{options: &OutboxOptions{RetryDelay: 100 * time.Millisecond, MaxRetryDelay: 500 * time.Millisecond}}
+	for attempts, want := range []time.Duration{100, 200, 400, 500, 500} {
+		assert.Equal(t, want*time.Millisecond, outbox.retryDelay(attempts+1), "attempt %d", attempts+1)
+	}
+}
+
+// newTestOutbox opens an outbox at path with fast polling and retries
+func newTestOutbox(t *testing.T, path string, maxAttempts int, deliver OutboxDeliverFunc) *Outbox {
+	t.Helper()
+
+	outbox, err := NewOutbox(NewBridge(&BridgeOptions{}, &defaultLogger{}), &OutboxOptions{
+		Path:          path,
+		MaxAttempts:   maxAttempts,
+		PollInterval:  5 * time.Millisecond,
+		RetryDelay:    20 * time.Millisecond,
+		MaxRetryDelay: 50 * time.Millisecond,
+		Deliver:       deliver,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { outbox.Close() })
+	return outbox
+}
+
+// outboxDeliveries records the deliveries made by an outbox
+type outboxDeliveries struct {
+	ids      []string
+	attempts []int
+	times    []time.Time
+	mutex    sync.Mutex
+}
+
+func (d *outboxDeliveries) record(msg *Message) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.ids = append(d.ids, msg.ID)
+	d.attempts = append(d.attempts, msg.Attempts)
+	d.times = append(d.times, time.Now())
+}
+
+func (d *outboxDeliveries) count() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return len(d.ids)
+}
+
+func TestOutboxRedeliversAfterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.db")
+	target := &BridgeTarget{Adapter: "orders", Protocol: "json", Service: "orders"}
+
+	// Messages submitted before a shutdown are kept on disk
+	first := newTestOutbox(t, path, 3, func(ctx context.Context, msg *Message) error {
+		t.Errorf("message '%s' delivered before the outbox was started", msg.ID)
+		return nil
+	})
+	require.NoError(t, first.Submit(&Message{ID: "a", Payload: []byte(`{"n":1}`), Destination: target}))
+	require.NoError(t, first.Submit(&Message{ID: "b", Payload: []byte(`{"n":2}`), Destination: target}))
+	require.NoError(t, first.Close())
+	assert.ErrorIs(t, first.Submit(&Message{ID: "c", Destination: target}), ErrOutboxClosed)
+
+	deliveries := &outboxDeliveries{}
+	second := newTestOutbox(t, path, 3, func(ctx context.Context, msg *Message) error {
+		deliveries.record(msg)
+		return nil
+	})
+	pending, err := second.List(OutboxPending)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, []byte(`{"n":1}`), pending[0].Message.Payload)
+	assert.Equal(t, *target, *pending[0].Message.Destination)
+
+	second.Start()
+	require.Eventually(t, func() bool { return deliveries.count() == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"a", "b"}, deliveries.ids)
+
+	require.Eventually(t, func() bool {
+		pending, err = second.List(OutboxPending)
+		return err == nil && len(pending) == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestOutboxRetriesWithBackoff(t *testing.T) {
+	deliveries := &outboxDeliveries{}
+	failures := 2
+	outbox := newTestOutbox(t, filepath.Join(t.TempDir(), "outbox.db"), 5, func(ctx context.Context, msg *Message) error {
+		deliveries.record(msg)
+		if msg.Attempts <= failures {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	})
+
+	require.NoError(t, outbox.Submit(&Message{ID: "retry", Payload: []byte(`{}`), Destination: &BridgeTarget{Adapter: "orders"}}))
+	outbox.Start()
+
+	require.Eventually(t, func() bool { return deliveries.count() == 3 }, 2*time.Second, 5*time.Millisecond)
+	require.Eventually(t, func() bool {
+		pending, err := outbox.List(OutboxPending)
+		return err == nil && len(pending) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	deliveries.mutex.Lock()
+	defer deliveries.mutex.Unlock()
+	assert.Equal(t, []int{1, 2, 3}, deliveries.attempts)
+	assert.GreaterOrEqual(t, deliveries.times[1].Sub(deliveries.times[0]), 20*time.Millisecond)
+	assert.GreaterOrEqual(t, deliveries.times[2].Sub(deliveries.times[1]), 40*time.Millisecond)
+
+	deadLetters, err := outbox.List(OutboxDeadLetter)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+func TestOutboxDropsExpiredMessages(t *testing.T) {
+	deliveries := &outboxDeliveries{}
+	outbox := newTestOutbox(t, filepath.Join(t.TempDir(), "outbox.db"), 3, func(ctx context.Context, msg *Message) error {
+		deliveries.record(msg)
+		return nil
+	})
+
+	target := &BridgeTarget{Adapter: "orders"}
+	require.NoError(t, outbox.Submit(&Message{ID: "stale", Destination: target, Expiration: time.Now().Add(-time.Second)}))
+	require.NoError(t, outbox.Submit(&Message{ID: "fresh", Destination: target, Expiration: time.Now().Add(time.Hour)}))
+	outbox.Start()
+
+	require.Eventually(t, func() bool {
+		pending, err := outbox.List(OutboxPending)
+		return err == nil && len(pending) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	deliveries.mutex.Lock()
+	assert.Equal(t, []string{"fresh"}, deliveries.ids)
+	deliveries.mutex.Unlock()
+
+	// Expired messages are dropped, not dead-lettered
+	deadLetters, err := outbox.List(OutboxDeadLetter)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+func TestOutboxAck(t *testing.T) {
+	outbox := newTestOutbox(t, filepath.Join(t.TempDir(), "outbox.db"), 3, nil)
+
+	require.NoError(t, outbox.Submit(&Message{ID: "acked", Destination: &BridgeTarget{Adapter: "orders"}}))
+	assert.Error(t, outbox.Submit(&Message{ID: "acked", Destination: &BridgeTarget{Adapter: "orders"}}), "IDs are unique")
+	assert.ErrorIs(t, outbox.Submit(&Message{ID: "nowhere"}), ErrInvalidMessage)
+
+	require.NoError(t, outbox.Ack("acked"))
+	pending, err := outbox.List(OutboxPending)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	assert.ErrorIs(t, outbox.Ack("acked"), ErrOutboxEntryNotFound)
+}
+
+func TestOutboxReplay(t *testing.T) {
+	deliveries := &outboxDeliveries{}
+	var healthy atomic.Bool
+	outbox := newTestOutbox(t, filepath.Join(t.TempDir(), "outbox.db"), 2, func(ctx context.Context, msg *Message) error {
+		deliveries.record(msg)
+		if !healthy.Load() {
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	})
+
+	require.NoError(t, outbox.Submit(&Message{ID: "replayed", Payload: []byte(`{}`), Destination: &BridgeTarget{Adapter: "orders"}}))
+	outbox.Start()
+
+	var deadLetters []*OutboxEntry
+	var err error
+	require.Eventually(t, func() bool {
+		deadLetters, err = outbox.List(OutboxDeadLetter)
+		return err == nil && len(deadLetters) == 1
+	}, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, deadLetters[0].Message.Attempts)
+	assert.False(t, deadLetters[0].DeadLetteredAt.IsZero())
+
+	// A replayed message starts over with its attempts reset
+	healthy.Store(true)
+	require.NoError(t, outbox.Replay("replayed"))
+	require.Eventually(t, func() bool { return deliveries.count() == 3 }, time.Second, 5*time.Millisecond)
+
+	deliveries.mutex.Lock()
+	assert.Equal(t, []int{1, 2, 1}, deliveries.attempts)
+	deliveries.mutex.Unlock()
+
+	require.Eventually(t, func() bool {
+		pending, err := outbox.List(OutboxPending)
+		return err == nil && len(pending) == 0
+	}, time.Second, 5*time.Millisecond)
+	deadLetters, err = outbox.List(OutboxDeadLetter)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+
+	assert.ErrorIs(t, outbox.Replay("replayed"), ErrOutboxEntryNotFound)
+}
+
+func TestOutboxEmptyStateUsesDeadLetter(t *testing.T) {
+	b := NewBridge(&BridgeOptions{}, &defaultLogger{})
+	failed := errors.New("downstream unavailable")
+	outbox, err := NewOutbox(b, &OutboxOptions{
+		Path:         filepath.Join(t.TempDir(), "outbox.db"),
+		MaxAttempts:  1,
+		PollInterval: 5 * time.Millisecond,
+		Deliver: func(ctx context.Context, msg *Message) error {
+			_, ok := ctx.Deadline()
+			assert.True(t, ok, "deliveries are bounded by the default timeout")
+			return failed
+		},
+	})
+	require.NoError(t, err)
+	defer outbox.Close()
+
+	require.NoError(t, outbox.Submit(&Message{Payload: []byte(`{}`), Destination: &BridgeTarget{Adapter: "orders"}}))
+	outbox.Start()
+
+	var entries []*OutboxEntry
+	require.Eventually(t, func() bool {
+		entries, err = outbox.List("")
+		return err == nil && len(entries) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, OutboxDeadLetter, entries[0].State)
+	assert.Equal(t, failed.Error(), entries[0].LastError)
+
+	pending, err := outbox.List(OutboxPending)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	removed, err := outbox.Purge("", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, err = outbox.List("archived")
+	assert.ErrorIs(t, err, ErrInvalidOutboxState)
+}
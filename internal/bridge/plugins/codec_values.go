@@ -0,0 +1,183 @@
+// codec_values.go - Generic value model shared by the built-in binary and XML codecs
+
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Common codec errors
+var (
+	ErrUnsupportedValue = errors.New("unsupported value")
+	ErrMalformedMessage = errors.New("malformed message")
+)
+
+// maxValueDepth bounds the nesting of encoded and decoded values
+const maxValueDepth = 256
+
+// normalizeValue converts a payload value to the generic form shared by the
+// MessagePack and XML codecs: nil, bool, int64, uint64 (above MaxInt64 only),
+// float64, string, []byte, time.Time, []interface{} and map[string]interface{}.
+// Structs and other types with their own JSON form go through encoding/json.
+func normalizeValue(value interface{}) (interface{}, error) {
+	return normalizeDepth(value, 0)
+}
+
+func normalizeDepth(value interface{}, depth int) (interface{}, error) {
+	if depth > maxValueDepth {
+		return nil, fmt.Errorf("%w: nesting deeper than %d", ErrUnsupportedValue, maxValueDepth)
+	}
+
+	switch x := value.(type) {
+	case nil:
+		return nil, nil
+	case bool, string, int64, float64:
+		return x, nil
+	case []byte:
+		if x == nil {
+			return nil, nil
+		}
+		return x, nil
+	case time.Time:
+		return x, nil
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n, nil
+		}
+		if n, err := strconv.ParseUint(string(x), 10, 64); err == nil {
+			return n, nil
+		}
+		return x.Float64()
+	case json.Marshaler:
+		return normalizeJSON(x, depth)
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := v.Uint(); n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32:
+		// Keep the shortest float32 representation, as encoding/json does
+		return strconv.ParseFloat(strconv.FormatFloat(v.Float(), 'g', -1, 32), 64)
+	case reflect.Float64:
+		return v.Float(), nil
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return normalizeDepth(v.Elem().Interface(), depth+1)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			return data, nil
+		}
+
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			item, err := normalizeDepth(v.Index(i).Interface(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		m := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := mapKeyString(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			item, err := normalizeDepth(iter.Value().Interface(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = item
+		}
+		return m, nil
+
+	case reflect.Struct:
+		return normalizeJSON(value, depth)
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+}
+
+// normalizeJSON converts a value through its JSON form
+func normalizeJSON(value interface{}, depth int) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %T: %v", ErrUnsupportedValue, value, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("%w: %T: %v", ErrUnsupportedValue, value, err)
+	}
+	return normalizeDepth(generic, depth+1)
+}
+
+// mapKeyString formats a map key the way encoding/json does
+func mapKeyString(key reflect.Value) (string, error) {
+	switch key.Kind() {
+	case reflect.String:
+		return key.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("%w: map key of type %s", ErrUnsupportedValue, key.Type())
+}
+
+// sortedKeys returns the keys of a generic map in order, for deterministic output
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedHeaderKeys returns the keys of a header map in order
+func sortedHeaderKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,302 @@
+// config_schema.go - JSON-Schema-style descriptions and validation of plugin configuration
+
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidConfig is matched by every configuration validation error
+var ErrInvalidConfig = errors.New("invalid plugin configuration")
+
+// Schema types
+const (
+	SchemaTypeObject  = "object"
+	SchemaTypeString  = "string"
+	SchemaTypeNumber  = "number"
+	SchemaTypeInteger = "integer"
+	SchemaTypeBoolean = "boolean"
+	SchemaTypeArray   = "array"
+)
+
+// ConfigSchema describes the configuration a plugin accepts, using the
+// JSON Schema keywords of the same names. An empty Type accepts any value.
+type ConfigSchema struct {
+	Type                 string                   `json:"type,omitempty"`
+	Description          string                   `json:"description,omitempty"`
+	Properties           map[string]*ConfigSchema `json:"properties,omitempty"`
+	Required             []string                 `json:"required,omitempty"`
+	AdditionalProperties *bool                    `json:"additionalProperties,omitempty"`
+	Items                *ConfigSchema            `json:"items,omitempty"`
+	Enum                 []interface{}            `json:"enum,omitempty"`
+	Default              interface{}              `json:"default,omitempty"`
+	Minimum              *float64                 `json:"minimum,omitempty"`
+	Maximum              *float64                 `json:"maximum,omitempty"`
+	MinLength            *int                     `json:"minLength,omitempty"`
+	MaxLength            *int                     `json:"maxLength,omitempty"`
+	Pattern              string                   `json:"pattern,omitempty"`
+	MinItems             *int                     `json:"minItems,omitempty"`
+	MaxItems             *int                     `json:"maxItems,omitempty"`
+}
+
+// ConfigViolation is one way a configuration fails its schema
+type ConfigViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ConfigValidationError lists every violation found in a configuration
+type ConfigValidationError struct {
+	Violations []ConfigViolation `json:"violations"`
+}
+
+// Error implements the error interface
+func (e *ConfigValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Path + ": " + v.Message
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidConfig, strings.Join(parts, "; "))
+}
+
+// Unwrap lets errors.Is match ErrInvalidConfig
+func (e *ConfigValidationError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// Validate checks a configuration against the schema, returning a
+// *ConfigValidationError with every violation. A nil schema accepts anything.
+func (s *ConfigSchema) Validate(config map[string]interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	var violations []ConfigViolation
+	s.validateObject("config", config, &violations)
+	if len(violations) > 0 {
+		return &ConfigValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// WithDefaults returns a copy of config with the defaults of missing
+// top-level properties filled in
+func (s *ConfigSchema) WithDefaults(config map[string]interface{}) map[string]interface{} {
+	result := copyConfig(config)
+	if s == nil {
+		return result
+	}
+
+	for name, property := range s.Properties {
+		if _, set := result[name]; !set && property != nil && property.Default != nil {
+			result[name] = property.Default
+		}
+	}
+	return result
+}
+
+// validate checks a single value against the schema
+func (s *ConfigSchema) validate(path string, value interface{}, violations *[]ConfigViolation) {
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, ConfigViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Enum) > 0 && !enumContains(s.Enum, value) {
+		fail("must be one of %s", formatEnum(s.Enum))
+		return
+	}
+
+	switch s.Type {
+	case "":
+	case SchemaTypeObject:
+		object, ok := objectValue(value)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		s.validateObject(path, object, violations)
+
+	case SchemaTypeArray:
+		items, ok := arrayValue(value)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+
+	case SchemaTypeString:
+		str, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		length := utf8.RuneCountInString(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			pattern, err := regexp.Compile(s.Pattern)
+			if err != nil {
+				fail("schema pattern %q is invalid: %v", s.Pattern, err)
+			} else if !pattern.MatchString(str) {
+				fail("must match %q", s.Pattern)
+			}
+		}
+
+	case SchemaTypeNumber, SchemaTypeInteger:
+		number, ok := numericValue(value)
+		if !ok {
+			fail("must be a %s", s.Type)
+			return
+		}
+		if s.Type == SchemaTypeInteger && number != math.Trunc(number) {
+			fail("must be an integer")
+			return
+		}
+		if s.Minimum != nil && number < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+
+	case SchemaTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+
+	default:
+		fail("schema type %q is not supported", s.Type)
+	}
+}
+
+// validateObject checks the properties of an object, in name order
+func (s *ConfigSchema) validateObject(path string, object map[string]interface{}, violations *[]ConfigViolation) {
+	for _, name := range s.Required {
+		if _, set := object[name]; !set {
+			*violations = append(*violations, ConfigViolation{Path: path + "." + name, Message: "is required"})
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, known := s.Properties[name]
+		switch {
+		case known && property != nil:
+			property.validate(path+"."+name, object[name], violations)
+		case !known && s.AdditionalProperties != nil && !*s.AdditionalProperties:
+			*violations = append(*violations, ConfigViolation{Path: path + "." + name, Message: "is not a known setting"})
+		}
+	}
+}
+
+// objectValue returns value as a string-keyed map
+func objectValue(value interface{}) (map[string]interface{}, bool) {
+	if object, ok := value.(map[string]interface{}); ok {
+		return object, true
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	object := make(map[string]interface{}, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		object[iter.Key().String()] = iter.Value().Interface()
+	}
+	return object, true
+}
+
+// arrayValue returns value as a slice of elements
+func arrayValue(value interface{}) ([]interface{}, bool) {
+	if items, ok := value.([]interface{}); ok {
+		return items, true
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+	items := make([]interface{}, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items, true
+}
+
+// numericValue returns value as a float64 when it is any Go or JSON number
+func numericValue(value interface{}) (float64, bool) {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// enumContains reports whether value is one of the allowed values; numbers
+// compare by value so 8080 matches 8080.0 decoded from JSON
+func enumContains(allowed []interface{}, value interface{}) bool {
+	number, isNumber := numericValue(value)
+	for _, candidate := range allowed {
+		if isNumber {
+			if n, ok := numericValue(candidate); ok && n == number {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatEnum renders the allowed values for a violation message
+func formatEnum(allowed []interface{}) string {
+	parts := make([]string, len(allowed))
+	for i, value := range allowed {
+		if encoded, err := json.Marshal(value); err == nil {
+			parts[i] = string(encoded)
+		} else {
+			parts[i] = fmt.Sprint(value)
+		}
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,236 @@
+// config_watcher.go - Reconfigures running plugins when their config file changes
+
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultConfigPollInterval is how often a config file is checked for changes
+const DefaultConfigPollInterval = 5 * time.Second
+
+// ConfigWatcher polls a file holding plugin configuration keyed by plugin
+// ID and applies the sections that changed through Registry.Reconfigure,
+// so a bad edit leaves every plugin on its previous configuration. Files
+// ending in .yaml or .yml are read as YAML, anything else as JSON. Keys
+// removed from a section keep their current value.
+type ConfigWatcher struct {
+	registry *Registry
+	path     string
+	interval time.Duration
+	logger   Logger
+
+	modTime    time.Time
+	size       int64
+	applied    map[string]map[string]interface{}
+	watchMutex sync.Mutex
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+	startOnce  sync.Once
+	stopOnce   sync.Once
+	started    bool
+}
+
+// NewConfigWatcher creates a watcher for a plugin config file
+func NewConfigWatcher(registry *Registry, path string, interval time.Duration, logger Logger) *ConfigWatcher {
+	if interval <= 0 {
+		interval = DefaultConfigPollInterval
+	}
+	if logger == nil {
+		logger = noopLogger{}
+	}
+
+	return &ConfigWatcher{
+		registry: registry,
+		path:     path,
+		interval: interval,
+		logger:   logger,
+		applied:  make(map[string]map[string]interface{}),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Start applies the file once and then watches it until ctx is done or
+// Stop is called. The first load must succeed; later failures are logged
+// and the file is read again on its next change.
+func (w *ConfigWatcher) Start(ctx context.Context) error {
+	if err := w.Reload(); err != nil {
+		return err
+	}
+
+	w.startOnce.Do(func() {
+		w.watchMutex.Lock()
+		w.started = true
+		w.watchMutex.Unlock()
+
+		go w.watch(ctx)
+	})
+	return nil
+}
+
+// Stop stops watching and waits for the watch loop to exit
+func (w *ConfigWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+	})
+
+	w.watchMutex.Lock()
+	started := w.started
+	w.watchMutex.Unlock()
+	if started {
+		<-w.doneCh
+	}
+}
+
+// Reload reads the file and reconfigures the plugins whose section
+// differs from what was last applied
+func (w *ConfigWatcher) Reload() error {
+	w.watchMutex.Lock()
+	defer w.watchMutex.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to read plugin config: %w", err)
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+
+	sections, err := readPluginConfigFile(w.path)
+	if err != nil {
+		return err
+	}
+
+	changed := make(map[string]map[string]interface{})
+	for id, section := range sections {
+		if !reflect.DeepEqual(w.applied[id], section) {
+			changed[id] = section
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if err := w.registry.Reconfigure(changed); err != nil {
+		w.logger.Error("Rejected plugin config file change", map[string]interface{}{
+			"path":  w.path,
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	ids := make([]string, 0, len(changed))
+	for id, section := range changed {
+		w.applied[id] = section
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	w.logger.Info("Applied plugin config file change", map[string]interface{}{
+		"path":    w.path,
+		"plugins": ids,
+	})
+	return nil
+}
+
+// watch reloads the file whenever its modification time or size changes
+func (w *ConfigWatcher) watch(ctx context.Context) {
+	defer close(w.doneCh)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(w.path)
+		if err != nil {
+			w.logger.Warn("Cannot stat plugin config file", map[string]interface{}{
+				"path":  w.path,
+				"error": err.Error(),
+			})
+			continue
+		}
+
+		w.watchMutex.Lock()
+		modified := !info.ModTime().Equal(w.modTime) || info.Size() != w.size
+		w.watchMutex.Unlock()
+
+		if modified {
+			// Errors are logged by Reload; the next change is tried afresh
+			_ = w.Reload()
+		}
+	}
+}
+
+// readPluginConfigFile decodes a file of plugin configuration sections
+func readPluginConfigFile(path string) (map[string]map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin config: %w", err)
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		err = json.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse plugin config %s: %w", path, err)
+	}
+
+	sections := make(map[string]map[string]interface{}, len(raw))
+	for id, value := range raw {
+		section, ok := normalizeYAML(value).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: section for plugin '%s' is not an object", ErrInvalidConfig, id)
+		}
+		sections[id] = section
+	}
+	return sections, nil
+}
+
+// normalizeYAML converts the interface-keyed maps produced by the YAML
+// decoder to string-keyed maps, as the JSON decoder produces
+func normalizeYAML(value interface{}) interface{} {
+	switch x := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(x))
+		for key, item := range x {
+			result[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(x))
+		for key, item := range x {
+			result[key] = normalizeYAML(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(x))
+		for i, item := range x {
+			result[i] = normalizeYAML(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
@@ -0,0 +1,557 @@
+// msgpack_codec.go - MessagePack encoding for protocol messages
+
+package plugins
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// MessagePack message field names
+const (
+	msgpackFieldID        = "id"
+	msgpackFieldType      = "type"
+	msgpackFieldHeaders   = "headers"
+	msgpackFieldPayload   = "payload"
+	msgpackFieldMetadata  = "metadata"
+	msgpackFieldTimestamp = "timestamp"
+)
+
+// msgpackTimestampExt is the extension type reserved for timestamps
+const msgpackTimestampExt = -1
+
+// MessagePackCodec encodes protocol messages as a MessagePack map with the
+// same field names as the JSON encoding. Timestamps use the MessagePack
+// timestamp extension; payload integers decode as int64 (uint64 above
+// MaxInt64), binary data as []byte and nested objects as map[string]interface{}.
+type MessagePackCodec struct{}
+
+// Marshal encodes a message
+func (MessagePackCodec) Marshal(message *ProtocolMessage) ([]byte, error) {
+	payload, err := normalizeValue(message.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("payload: %w", err)
+	}
+
+	var metadata interface{}
+	if message.Metadata != nil {
+		if metadata, err = normalizeValue(message.Metadata); err != nil {
+			return nil, fmt.Errorf("metadata: %w", err)
+		}
+	}
+
+	fields := 4
+	if message.Headers != nil {
+		fields++
+	}
+	if metadata != nil {
+		fields++
+	}
+
+	e := &msgpackEncoder{}
+	e.writeMapHeader(fields)
+
+	e.writeString(msgpackFieldID)
+	e.writeString(message.ID)
+	e.writeString(msgpackFieldType)
+	e.writeString(message.Type)
+
+	if message.Headers != nil {
+		e.writeString(msgpackFieldHeaders)
+		e.writeMapHeader(len(message.Headers))
+		for _, key := range sortedHeaderKeys(message.Headers) {
+			e.writeString(key)
+			e.writeString(message.Headers[key])
+		}
+	}
+
+	e.writeString(msgpackFieldPayload)
+	if err := e.encode(payload, 0); err != nil {
+		return nil, fmt.Errorf("payload: %w", err)
+	}
+
+	if metadata != nil {
+		e.writeString(msgpackFieldMetadata)
+		if err := e.encode(metadata, 0); err != nil {
+			return nil, fmt.Errorf("metadata: %w", err)
+		}
+	}
+
+	e.writeString(msgpackFieldTimestamp)
+	e.writeTime(message.Timestamp)
+
+	return e.buf, nil
+}
+
+// Unmarshal decodes a message
+func (MessagePackCodec) Unmarshal(data []byte, message *ProtocolMessage) error {
+	d := &msgpackDecoder{data: data}
+
+	value, err := d.decode(0)
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return fmt.Errorf("%w: %d trailing bytes", ErrMalformedMessage, len(d.data)-d.pos)
+	}
+
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%w: message is not a map", ErrMalformedMessage)
+	}
+
+	*message = ProtocolMessage{}
+	for key, field := range fields {
+		switch key {
+		case msgpackFieldID:
+			if message.ID, ok = field.(string); !ok {
+				return fmt.Errorf("%w: id is not a string", ErrMalformedMessage)
+			}
+		case msgpackFieldType:
+			if message.Type, ok = field.(string); !ok {
+				return fmt.Errorf("%w: type is not a string", ErrMalformedMessage)
+			}
+		case msgpackFieldHeaders:
+			if field == nil {
+				continue
+			}
+			headers, ok := field.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%w: headers is not a map", ErrMalformedMessage)
+			}
+			message.Headers = make(map[string]string, len(headers))
+			for name, value := range headers {
+				text, ok := value.(string)
+				if !ok {
+					return fmt.Errorf("%w: header %s is not a string", ErrMalformedMessage, name)
+				}
+				message.Headers[name] = text
+			}
+		case msgpackFieldPayload:
+			message.Payload = field
+		case msgpackFieldMetadata:
+			if field == nil {
+				continue
+			}
+			if message.Metadata, ok = field.(map[string]interface{}); !ok {
+				return fmt.Errorf("%w: metadata is not a map", ErrMalformedMessage)
+			}
+		case msgpackFieldTimestamp:
+			if field == nil {
+				continue
+			}
+			if message.Timestamp, ok = field.(time.Time); !ok {
+				return fmt.Errorf("%w: timestamp is not a timestamp", ErrMalformedMessage)
+			}
+		}
+	}
+
+	return nil
+}
+
+// msgpackEncoder appends MessagePack values to a buffer
+type msgpackEncoder struct {
+	buf []byte
+}
+
+// encode writes a normalized value
+func (e *msgpackEncoder) encode(value interface{}, depth int) error {
+	if depth > maxValueDepth {
+		return fmt.Errorf("%w: nesting deeper than %d", ErrUnsupportedValue, maxValueDepth)
+	}
+
+	switch x := value.(type) {
+	case nil:
+		e.buf = append(e.buf, 0xc0)
+	case bool:
+		if x {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case int64:
+		e.writeInt(x)
+	case uint64:
+		e.writeUint(x)
+	case float64:
+		e.buf = append(e.buf, 0xcb)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(x))
+	case string:
+		e.writeString(x)
+	case []byte:
+		e.writeBinary(x)
+	case time.Time:
+		e.writeTime(x)
+	case []interface{}:
+		e.writeArrayHeader(len(x))
+		for _, item := range x {
+			if err := e.encode(item, depth+1); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		e.writeMapHeader(len(x))
+		for _, key := range sortedKeys(x) {
+			e.writeString(key)
+			if err := e.encode(x[key], depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return nil
+}
+
+func (e *msgpackEncoder) writeInt(n int64) {
+	switch {
+	case n >= 0:
+		e.writeUint(uint64(n))
+	case n >= -32:
+		e.buf = append(e.buf, byte(n))
+	case n >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		e.buf = append(e.buf, 0xd1)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	case n >= math.MinInt32:
+		e.buf = append(e.buf, 0xd2)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, 0xd3)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(n))
+	}
+}
+
+func (e *msgpackEncoder) writeUint(n uint64) {
+	switch {
+	case n <= 0x7f:
+		e.buf = append(e.buf, byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xcd)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	case n <= math.MaxUint32:
+		e.buf = append(e.buf, 0xce)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, 0xcf)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, n)
+	}
+}
+
+func (e *msgpackEncoder) writeString(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xda)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdb)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) writeBinary(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xc5)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xc6)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, b...)
+}
+
+func (e *msgpackEncoder) writeArrayHeader(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xdc)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdd)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) writeMapHeader(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xde)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdf)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
+
+// writeTime writes a timestamp extension in its smallest form
+func (e *msgpackEncoder) writeTime(t time.Time) {
+	sec := t.Unix()
+	nsec := uint32(t.Nanosecond())
+
+	switch {
+	case sec >= 0 && sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		e.buf = append(e.buf, 0xd6, byte(0xff))
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(sec))
+	case sec >= 0 && sec>>34 == 0:
+		e.buf = append(e.buf, 0xd7, byte(0xff))
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(nsec)<<34|uint64(sec))
+	default:
+		e.buf = append(e.buf, 0xc7, 12, byte(0xff))
+		e.buf = binary.BigEndian.AppendUint32(e.buf, nsec)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(sec))
+	}
+}
+
+// msgpackDecoder reads MessagePack values from a buffer
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+// decode reads one value into its generic form
+func (d *msgpackDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxValueDepth {
+		return nil, fmt.Errorf("%w: nesting deeper than %d", ErrMalformedMessage, maxValueDepth)
+	}
+
+	b, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return d.readMap(int(b&0x0f), depth)
+	case b&0xf0 == 0x90:
+		return d.readArray(int(b&0x0f), depth)
+	case b&0xe0 == 0xa0:
+		return d.readString(int(b & 0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readLength(b - 0xc4)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.readBytes(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, raw...), nil
+
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readLength(b - 0xc7)
+		if err != nil {
+			return nil, err
+		}
+		return d.readExt(n)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.readExt(1 << (b - 0xd4))
+
+	case 0xca:
+		raw, err := d.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), nil
+	case 0xcb:
+		raw, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), nil
+
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		raw, err := d.readBytes(1 << (b - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		n := readUint(raw)
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		raw, err := d.readBytes(size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend from the encoded width
+		shift := 64 - 8*size
+		return int64(readUint(raw)<<shift) >> shift, nil
+
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readLength(b - 0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return d.readString(n)
+
+	case 0xdc, 0xdd:
+		n, err := d.readLength(b - 0xdc + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.readArray(n, depth)
+
+	case 0xde, 0xdf:
+		n, err := d.readLength(b - 0xde + 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.readMap(n, depth)
+	}
+
+	return nil, fmt.Errorf("%w: unknown type byte 0x%02x", ErrMalformedMessage, b)
+}
+
+func (d *msgpackDecoder) readByte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, fmt.Errorf("%w: unexpected end of data", ErrMalformedMessage)
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *msgpackDecoder) readBytes(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrMalformedMessage)
+	}
+	raw := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return raw, nil
+}
+
+// readLength reads a 1, 2 or 4 byte length selected by width 0, 1 or 2
+func (d *msgpackDecoder) readLength(width byte) (int, error) {
+	raw, err := d.readBytes(1 << width)
+	if err != nil {
+		return 0, err
+	}
+	return int(readUint(raw)), nil
+}
+
+func (d *msgpackDecoder) readString(n int) (string, error) {
+	raw, err := d.readBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func (d *msgpackDecoder) readArray(n int, depth int) (interface{}, error) {
+	// Every element takes at least one byte
+	if n > len(d.data)-d.pos {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrMalformedMessage)
+	}
+
+	list := make([]interface{}, n)
+	for i := range list {
+		item, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = item
+	}
+	return list, nil
+}
+
+func (d *msgpackDecoder) readMap(n int, depth int) (interface{}, error) {
+	// Every entry takes at least two bytes
+	if n > (len(d.data)-d.pos)/2 {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrMalformedMessage)
+	}
+
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		switch k := key.(type) {
+		case string:
+			m[k] = value
+		case int64, uint64:
+			m[fmt.Sprint(k)] = value
+		default:
+			return nil, fmt.Errorf("%w: map key of type %T", ErrMalformedMessage, key)
+		}
+	}
+	return m, nil
+}
+
+// readExt reads an extension value; only timestamps are understood
+func (d *msgpackDecoder) readExt(n int) (interface{}, error) {
+	kind, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := d.readBytes(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if int8(kind) != msgpackTimestampExt {
+		return nil, fmt.Errorf("%w: unknown extension type %d", ErrMalformedMessage, int8(kind))
+	}
+
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(raw)), 0).UTC(), nil
+	case 8:
+		v := binary.BigEndian.Uint64(raw)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)).UTC(), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(raw[:4])
+		sec := int64(binary.BigEndian.Uint64(raw[4:]))
+		return time.Unix(sec, int64(nsec)).UTC(), nil
+	}
+	return nil, fmt.Errorf("%w: timestamp of %d bytes", ErrMalformedMessage, n)
+}
+
+// readUint reads a big-endian unsigned integer of 1, 2, 4 or 8 bytes
+func readUint(raw []byte) uint64 {
+	var n uint64
+	for _, b := range raw {
+		n = n<<8 | uint64(b)
+	}
+	return n
+}
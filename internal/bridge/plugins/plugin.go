@@ -54,6 +54,7 @@ type PluginMetadata struct {
 	Tags         []string          `json:"tags"`
 	Dependencies []string          `json:"dependencies"`
 	Properties   map[string]string `json:"properties"`
+	ConfigSchema *ConfigSchema     `json:"config_schema,omitempty"`
 }
 
 // Plugin defines the interface for all plugins
@@ -228,19 +229,42 @@ func (p *BasePlugin) AddCapability(capability string) {
 	p.capabilities = append(p.capabilities, capability)
 }
 
-// Configure configures the plugin
+// Configure merges configuration changes into the plugin configuration.
+// Changes that would leave the configuration invalid against the plugin's
+// schema are rejected and nothing is applied.
 func (p *BasePlugin) Configure(config map[string]interface{}) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
 	// Merge with existing configuration
-	for key, value := range config {
-		p.config[key] = value
+	merged := mergeConfig(p.config, config)
+	if err := p.metadata.ConfigSchema.Validate(merged); err != nil {
+		return err
 	}
+	p.config = merged
 
 	return nil
 }
 
+// RestoreConfig replaces the whole plugin configuration, undoing earlier
+// changes; it is used to roll back a failed reconfiguration
+func (p *BasePlugin) RestoreConfig(config map[string]interface{}) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.config = copyConfig(config)
+	return nil
+}
+
+// ValidateConfigChange checks that merging changes into the current
+// configuration keeps it valid against the plugin's schema
+func (p *BasePlugin) ValidateConfigChange(changes map[string]interface{}) error {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.metadata.ConfigSchema.Validate(mergeConfig(p.config, changes))
+}
+
 // GetConfig returns the plugin configuration
 func (p *BasePlugin) GetConfig() map[string]interface{} {
 	p.mutex.RLock()
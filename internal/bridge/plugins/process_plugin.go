@@ -0,0 +1,867 @@
+// process_plugin.go - Out-of-process plugins launched as child processes
+
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Process plugin protocol. The host starts the plugin binary with the magic
+// cookie and the protocol versions it speaks in its environment. The plugin
+// answers with a ProcessHandshake as the first line on stdout, then serves
+// newline-delimited JSON requests from stdin, replying on stdout. Anything
+// the plugin writes to stderr is logged by the host.
+const (
+	ProcessProtocolVersion = 1
+	ProcessTransportStdio  = "stdio"
+	ProcessPluginMagic     = "quant-webwork-bridge-plugin"
+
+	ProcessPluginMagicEnv    = "QUANT_PLUGIN_MAGIC_COOKIE"
+	ProcessPluginVersionsEnv = "QUANT_PLUGIN_PROTOCOL_VERSIONS"
+)
+
+// Lifecycle methods every process plugin receives. Other methods name the
+// plugin's capabilities and are invoked with ProcessPlugin.Call.
+const (
+	ProcessMethodInitialize = "plugin.initialize"
+	ProcessMethodStart      = "plugin.start"
+	ProcessMethodStop       = "plugin.stop"
+	ProcessMethodCleanup    = "plugin.cleanup"
+	ProcessMethodConfigure  = "plugin.configure"
+)
+
+// Common process plugin errors
+var (
+	ErrHandshakeFailed   = errors.New("plugin handshake failed")
+	ErrPluginCrashed     = errors.New("plugin process exited")
+	ErrPluginCallFailed  = errors.New("plugin call failed")
+	ErrNotPluginProcess  = errors.New("not launched by a plugin host")
+	ErrPluginTerminating = errors.New("plugin is being cleaned up")
+)
+
+// ProcessHandshake is the first line a plugin process writes to stdout
+type ProcessHandshake struct {
+	Magic           string         `json:"magic"`
+	ProtocolVersion int            `json:"protocol_version"`
+	Transport       string         `json:"transport"`
+	Type            PluginType     `json:"type"`
+	Metadata        PluginMetadata `json:"metadata"`
+	Capabilities    []string       `json:"capabilities"`
+}
+
+// processRequest is a call from the host to the plugin
+type processRequest struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// processResponse is the plugin's reply to a request
+type processResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ProcessPluginConfig configures how a plugin binary is run and supervised
+type ProcessPluginConfig struct {
+	Path              string        // Plugin binary
+	Args              []string      // Arguments passed to the binary
+	Env               []string      // Extra environment, as KEY=value
+	Dir               string        // Working directory
+	HandshakeTimeout  time.Duration // Time allowed for the handshake line
+	CallTimeout       time.Duration // Default timeout of calls without a deadline
+	StopTimeout       time.Duration // Grace period between closing stdin and killing
+	RestartBackoff    time.Duration // Delay before the first restart after a crash
+	MaxRestartBackoff time.Duration // Upper bound of the doubling restart delay
+	MaxRestarts       int           // Consecutive crashes or failed restarts before giving up; 0 is unlimited
+}
+
+// DefaultProcessPluginConfig returns default supervision settings for a binary
+func DefaultProcessPluginConfig(path string, args ...string) ProcessPluginConfig {
+	return ProcessPluginConfig{
+		Path:              path,
+		Args:              args,
+		HandshakeTimeout:  10 * time.Second,
+		CallTimeout:       30 * time.Second,
+		StopTimeout:       5 * time.Second,
+		RestartBackoff:    500 * time.Millisecond,
+		MaxRestartBackoff: 30 * time.Second,
+		MaxRestarts:       10,
+	}
+}
+
+// ProcessPlugin is a Plugin running in a child process. Its type, metadata
+// and capabilities come from the handshake. A crashed process is restarted
+// with backoff and brought back to the state the host last requested.
+type ProcessPlugin struct {
+	*BasePlugin
+	config       ProcessPluginConfig
+	logger       Logger
+	process      *pluginProcess
+	desired      PluginStatus // Lifecycle state replayed after a restart
+	initConfig   map[string]interface{}
+	restarts     int
+	closing      bool
+	stopCh       chan struct{}
+	processMutex sync.Mutex
+}
+
+// LaunchProcessPlugin starts a plugin binary and performs the handshake
+func LaunchProcessPlugin(ctx context.Context, id string, config ProcessPluginConfig, logger Logger) (*ProcessPlugin, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("%w: no plugin binary", ErrHandshakeFailed)
+	}
+	if logger == nil {
+		logger = noopLogger{}
+	}
+
+	p := &ProcessPlugin{
+		BasePlugin: NewBasePlugin(id, "", PluginMetadata{}),
+		config:     config,
+		logger:     logger,
+		desired:    PluginStatusUninitialized,
+		stopCh:     make(chan struct{}),
+	}
+
+	process, handshake, err := p.launch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.pluginType = handshake.Type
+	p.metadata = handshake.Metadata
+	for _, capability := range handshake.Capabilities {
+		p.AddCapability(capability)
+	}
+
+	p.processMutex.Lock()
+	p.process = process
+	p.processMutex.Unlock()
+	go p.supervise(process)
+
+	return p, nil
+}
+
+// ProcessPluginFactory returns a factory launching the plugin binary, for
+// use with Registry.RegisterFactory
+func ProcessPluginFactory(config ProcessPluginConfig, logger Logger) PluginFactory {
+	return func(id string, _ map[string]interface{}) (Plugin, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout(config))
+		defer cancel()
+		return LaunchProcessPlugin(ctx, id, config, logger)
+	}
+}
+
+// Initialize initializes the plugin process with config
+func (p *ProcessPlugin) Initialize(ctx context.Context, config map[string]interface{}) error {
+	if err := p.Call(ctx, ProcessMethodInitialize, config, nil); err != nil {
+		p.setError(err)
+		return err
+	}
+
+	p.processMutex.Lock()
+	p.initConfig = copyConfig(config)
+	p.desired = PluginStatusInitialized
+	p.processMutex.Unlock()
+
+	return p.BasePlugin.Initialize(ctx, copyConfig(config))
+}
+
+// Start starts the plugin process
+func (p *ProcessPlugin) Start(ctx context.Context) error {
+	if status := p.Status(); status != PluginStatusInitialized && status != PluginStatusStopped {
+		return fmt.Errorf("cannot start plugin with status %s", status)
+	}
+
+	if err := p.Call(ctx, ProcessMethodStart, nil, nil); err != nil {
+		p.setError(err)
+		return err
+	}
+
+	p.processMutex.Lock()
+	p.desired = PluginStatusStarted
+	p.processMutex.Unlock()
+
+	return p.BasePlugin.Start(ctx)
+}
+
+// Stop stops the plugin; the process keeps running until Cleanup
+func (p *ProcessPlugin) Stop(ctx context.Context) error {
+	if status := p.Status(); status != PluginStatusStarted {
+		return fmt.Errorf("cannot stop plugin with status %s", status)
+	}
+
+	if err := p.Call(ctx, ProcessMethodStop, nil, nil); err != nil {
+		p.setError(err)
+		return err
+	}
+
+	p.processMutex.Lock()
+	p.desired = PluginStatusStopped
+	p.processMutex.Unlock()
+
+	return p.BasePlugin.Stop(ctx)
+}
+
+// Cleanup asks the plugin to release its resources, waiting at most
+// CallTimeout, then terminates the process: stdin is closed and the process
+// killed if it has not exited within StopTimeout. The process is not
+// restarted afterwards.
+func (p *ProcessPlugin) Cleanup(ctx context.Context) error {
+	p.processMutex.Lock()
+	if p.closing {
+		p.processMutex.Unlock()
+		return nil
+	}
+	p.closing = true
+	close(p.stopCh)
+	process := p.process
+	p.processMutex.Unlock()
+
+	var err error
+	if process != nil && process.alive() {
+		callCtx, cancel := context.WithTimeout(ctx, p.callTimeout())
+		err = process.call(callCtx, ProcessMethodCleanup, nil, nil)
+		cancel()
+	}
+	if process != nil {
+		process.terminate(p.config.StopTimeout)
+	}
+
+	p.BasePlugin.mutex.Lock()
+	p.BasePlugin.config = make(map[string]interface{})
+	p.BasePlugin.status = PluginStatusStopped
+	p.BasePlugin.mutex.Unlock()
+
+	p.logger.Info(fmt.Sprintf("Terminated plugin process: %s", p.ID()), map[string]interface{}{
+		"plugin_id": p.ID(),
+	})
+
+	return err
+}
+
+// Configure sends configuration changes to the plugin process once they
+// have been validated against the schema from its handshake
+func (p *ProcessPlugin) Configure(config map[string]interface{}) error {
+	if err := p.ValidateConfigChange(config); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.callTimeout())
+	defer cancel()
+
+	if err := p.Call(ctx, ProcessMethodConfigure, config, nil); err != nil {
+		return err
+	}
+
+	p.processMutex.Lock()
+	if p.initConfig == nil {
+		p.initConfig = make(map[string]interface{})
+	}
+	for key, value := range config {
+		p.initConfig[key] = value
+	}
+	p.processMutex.Unlock()
+
+	return p.BasePlugin.Configure(config)
+}
+
+// RestoreConfig replaces the configuration kept for restarts and sends the
+// restored values to the plugin process. Keys the process received since
+// are not removed from it until it is next restarted.
+func (p *ProcessPlugin) RestoreConfig(config map[string]interface{}) error {
+	p.processMutex.Lock()
+	p.initConfig = copyConfig(config)
+	p.processMutex.Unlock()
+
+	if err := p.BasePlugin.RestoreConfig(config); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.callTimeout())
+	defer cancel()
+
+	return p.Call(ctx, ProcessMethodConfigure, config, nil)
+}
+
+// Call invokes a method of the plugin process. params is sent as JSON and
+// the reply is decoded into result when it is not nil. Calls without a
+// deadline time out after CallTimeout.
+func (p *ProcessPlugin) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	p.processMutex.Lock()
+	process, closing := p.process, p.closing
+	p.processMutex.Unlock()
+
+	if closing {
+		return ErrPluginTerminating
+	}
+
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.callTimeout())
+		defer cancel()
+	}
+
+	return process.call(ctx, method, params, result)
+}
+
+// PID returns the process ID of the running plugin process
+func (p *ProcessPlugin) PID() int {
+	p.processMutex.Lock()
+	defer p.processMutex.Unlock()
+
+	if p.process == nil || p.process.cmd.Process == nil {
+		return 0
+	}
+	return p.process.cmd.Process.Pid
+}
+
+// Restarts returns the number of times the plugin process was restarted
+func (p *ProcessPlugin) Restarts() int {
+	p.processMutex.Lock()
+	defer p.processMutex.Unlock()
+	return p.restarts
+}
+
+// launch starts the plugin binary and waits for a valid handshake
+func (p *ProcessPlugin) launch(ctx context.Context) (*pluginProcess, *ProcessHandshake, error) {
+	cmd := exec.Command(p.config.Path, p.config.Args...)
+	cmd.Dir = p.config.Dir
+	cmd.Env = append(os.Environ(), p.config.Env...)
+	cmd.Env = append(cmd.Env,
+		ProcessPluginMagicEnv+"="+ProcessPluginMagic,
+		ProcessPluginVersionsEnv+"="+strconv.Itoa(ProcessProtocolVersion),
+	)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrHandshakeFailed, err)
+	}
+
+	process := &pluginProcess{
+		cmd:       cmd,
+		stdin:     stdin,
+		pending:   make(map[uint64]chan processResponse),
+		handshake: make(chan string, 1),
+		exited:    make(chan struct{}),
+		started:   time.Now(),
+	}
+	go process.run(stdout, stderr, p.logger, p.ID())
+
+	timer := time.NewTimer(handshakeTimeout(p.config))
+	defer timer.Stop()
+
+	var line string
+	select {
+	case line = <-process.handshake:
+	case <-process.exited:
+		return nil, nil, fmt.Errorf("%w: process exited: %v", ErrHandshakeFailed, process.exitErr)
+	case <-timer.C:
+		process.terminate(0)
+		return nil, nil, fmt.Errorf("%w: no handshake within %s", ErrHandshakeFailed, handshakeTimeout(p.config))
+	case <-ctx.Done():
+		process.terminate(0)
+		return nil, nil, fmt.Errorf("%w: %v", ErrHandshakeFailed, ctx.Err())
+	}
+
+	handshake, err := parseHandshake(line)
+	if err != nil {
+		process.terminate(0)
+		return nil, nil, err
+	}
+
+	p.logger.Info(fmt.Sprintf("Launched plugin process: %s", p.ID()), map[string]interface{}{
+		"plugin_id":        p.ID(),
+		"pid":              cmd.Process.Pid,
+		"protocol_version": handshake.ProtocolVersion,
+	})
+
+	return process, handshake, nil
+}
+
+// parseHandshake decodes and checks a handshake line
+func parseHandshake(line string) (*ProcessHandshake, error) {
+	handshake := &ProcessHandshake{}
+	if err := json.Unmarshal([]byte(line), handshake); err != nil {
+		return nil, fmt.Errorf("%w: malformed handshake: %v", ErrHandshakeFailed, err)
+	}
+
+	switch {
+	case handshake.Magic != ProcessPluginMagic:
+		return nil, fmt.Errorf("%w: binary is not a bridge plugin", ErrHandshakeFailed)
+	case handshake.ProtocolVersion != ProcessProtocolVersion:
+		return nil, fmt.Errorf("%w: plugin speaks protocol version %d, host supports %d",
+			ErrHandshakeFailed, handshake.ProtocolVersion, ProcessProtocolVersion)
+	case handshake.Transport != "" && handshake.Transport != ProcessTransportStdio:
+		return nil, fmt.Errorf("%w: unsupported transport '%s'", ErrHandshakeFailed, handshake.Transport)
+	case handshake.Type == "":
+		return nil, fmt.Errorf("%w: plugin type missing", ErrHandshakeFailed)
+	}
+
+	return handshake, nil
+}
+
+// supervise restarts the plugin process when it exits unexpectedly
+func (p *ProcessPlugin) supervise(process *pluginProcess) {
+	crashes := 0
+	for {
+		select {
+		case <-process.exited:
+		case <-p.stopCh:
+			return
+		}
+
+		p.processMutex.Lock()
+		closing := p.closing
+		p.processMutex.Unlock()
+		if closing {
+			return
+		}
+
+		crash := fmt.Errorf("%w: %v", ErrPluginCrashed, process.exitErr)
+		p.setError(crash)
+		p.logger.Error(fmt.Sprintf("Plugin process crashed: %s", p.ID()), map[string]interface{}{
+			"plugin_id": p.ID(),
+			"error":     crash.Error(),
+			"uptime":    time.Since(process.started).String(),
+		})
+
+		// A process that stayed up past the longest backoff starts a fresh
+		// series of restarts
+		if time.Since(process.started) >= p.stableAfter() {
+			crashes = 0
+		}
+
+		next, ok := p.restart(&crashes)
+		if !ok {
+			return
+		}
+		process = next
+	}
+}
+
+// restart relaunches the plugin with doubling backoff until it is back in
+// its last requested state. crashes counts consecutive failures; restarting
+// stops after MaxRestarts of them.
+func (p *ProcessPlugin) restart(crashes *int) (*pluginProcess, bool) {
+	for {
+		if p.config.MaxRestarts > 0 && *crashes >= p.config.MaxRestarts {
+			p.setError(fmt.Errorf("%w: gave up after %d restart attempts", ErrPluginCrashed, *crashes))
+			p.logger.Error(fmt.Sprintf("Giving up restarting plugin: %s", p.ID()), map[string]interface{}{
+				"plugin_id": p.ID(),
+				"attempts":  *crashes,
+			})
+			return nil, false
+		}
+
+		backoff := p.config.RestartBackoff
+		for i := 0; i < *crashes && backoff < p.config.MaxRestartBackoff; i++ {
+			backoff *= 2
+		}
+		if p.config.MaxRestartBackoff > 0 && backoff > p.config.MaxRestartBackoff {
+			backoff = p.config.MaxRestartBackoff
+		}
+		*crashes++
+
+		select {
+		case <-time.After(backoff):
+		case <-p.stopCh:
+			return nil, false
+		}
+
+		process, err := p.relaunch()
+		if err != nil {
+			p.logger.Warn(fmt.Sprintf("Failed to restart plugin: %s", p.ID()), map[string]interface{}{
+				"plugin_id": p.ID(),
+				"error":     err.Error(),
+			})
+			continue
+		}
+		return process, true
+	}
+}
+
+// relaunch starts a new process and replays the lifecycle state into it
+func (p *ProcessPlugin) relaunch() (*pluginProcess, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout(p.config)+p.callTimeout())
+	defer cancel()
+
+	process, handshake, err := p.launch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if handshake.Type != p.Type() {
+		process.terminate(0)
+		return nil, fmt.Errorf("%w: plugin type changed from %s to %s", ErrHandshakeFailed, p.Type(), handshake.Type)
+	}
+
+	p.processMutex.Lock()
+	desired, config := p.desired, copyConfig(p.initConfig)
+	p.processMutex.Unlock()
+
+	if desired != PluginStatusUninitialized {
+		if err := process.call(ctx, ProcessMethodInitialize, config, nil); err != nil {
+			process.terminate(0)
+			return nil, err
+		}
+	}
+	if desired == PluginStatusStarted {
+		if err := process.call(ctx, ProcessMethodStart, nil, nil); err != nil {
+			process.terminate(0)
+			return nil, err
+		}
+	}
+
+	p.processMutex.Lock()
+	if p.closing {
+		p.processMutex.Unlock()
+		process.terminate(p.config.StopTimeout)
+		return nil, ErrPluginTerminating
+	}
+	p.process = process
+	p.restarts++
+	restarts := p.restarts
+	p.processMutex.Unlock()
+
+	p.BasePlugin.mutex.Lock()
+	p.BasePlugin.status = desired
+	p.BasePlugin.lastError = nil
+	p.BasePlugin.mutex.Unlock()
+
+	p.logger.Info(fmt.Sprintf("Restarted plugin process: %s", p.ID()), map[string]interface{}{
+		"plugin_id": p.ID(),
+		"pid":       process.cmd.Process.Pid,
+		"restarts":  restarts,
+	})
+
+	return process, nil
+}
+
+// callTimeout returns the default call timeout
+func (p *ProcessPlugin) callTimeout() time.Duration {
+	if p.config.CallTimeout > 0 {
+		return p.config.CallTimeout
+	}
+	return 30 * time.Second
+}
+
+// stableAfter returns how long a process must run before its crash no
+// longer counts towards MaxRestarts
+func (p *ProcessPlugin) stableAfter() time.Duration {
+	if p.config.MaxRestartBackoff > 0 {
+		return p.config.MaxRestartBackoff
+	}
+	return time.Minute
+}
+
+// handshakeTimeout returns the time allowed for a handshake
+func handshakeTimeout(config ProcessPluginConfig) time.Duration {
+	if config.HandshakeTimeout > 0 {
+		return config.HandshakeTimeout
+	}
+	return 10 * time.Second
+}
+
+// pluginProcess is one running instance of a plugin binary
+type pluginProcess struct {
+	cmd          *exec.Cmd
+	stdin        io.WriteCloser
+	pending      map[uint64]chan processResponse
+	nextID       uint64
+	handshake    chan string
+	exited       chan struct{}
+	exitErr      error
+	started      time.Time
+	writeMutex   sync.Mutex
+	pendingMutex sync.Mutex
+}
+
+// run reads the handshake and replies from stdout and logs stderr until the
+// process exits, then fails the calls still waiting for a reply
+func (pp *pluginProcess) run(stdout, stderr io.Reader, logger Logger, id string) {
+	var readers sync.WaitGroup
+	readers.Add(2)
+
+	go func() {
+		defer readers.Done()
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.Debug(fmt.Sprintf("Plugin %s: %s", id, scanner.Text()), map[string]interface{}{
+				"plugin_id": id,
+			})
+		}
+	}()
+
+	go func() {
+		defer readers.Done()
+		reader := bufio.NewReader(stdout)
+
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		pp.handshake <- strings.TrimSpace(line)
+
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				pp.dispatch(line, logger, id)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	readers.Wait()
+	err := pp.cmd.Wait()
+
+	pp.pendingMutex.Lock()
+	pp.exitErr = err
+	if pp.exitErr == nil {
+		pp.exitErr = errors.New("exit status 0")
+	}
+	pending := pp.pending
+	pp.pending = nil
+	pp.pendingMutex.Unlock()
+
+	close(pp.exited)
+	for _, reply := range pending {
+		close(reply)
+	}
+}
+
+// dispatch hands a reply to the call waiting for it
+func (pp *pluginProcess) dispatch(line []byte, logger Logger, id string) {
+	var response processResponse
+	if err := json.Unmarshal(line, &response); err != nil {
+		logger.Warn(fmt.Sprintf("Plugin %s wrote malformed reply: %v", id, err), map[string]interface{}{
+			"plugin_id": id,
+		})
+		return
+	}
+
+	pp.pendingMutex.Lock()
+	reply, exists := pp.pending[response.ID]
+	delete(pp.pending, response.ID)
+	pp.pendingMutex.Unlock()
+
+	if exists {
+		reply <- response
+	}
+}
+
+// call sends a request and waits for its reply
+func (pp *pluginProcess) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	request := processRequest{Method: method}
+	if params != nil {
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrPluginCallFailed, method, err)
+		}
+		request.Params = encoded
+	}
+
+	reply := make(chan processResponse, 1)
+	pp.pendingMutex.Lock()
+	if pp.pending == nil {
+		pp.pendingMutex.Unlock()
+		return fmt.Errorf("%w: %v", ErrPluginCrashed, pp.exitErr)
+	}
+	pp.nextID++
+	request.ID = pp.nextID
+	pp.pending[request.ID] = reply
+	pp.pendingMutex.Unlock()
+
+	line, err := json.Marshal(request)
+	if err != nil {
+		pp.forget(request.ID)
+		return fmt.Errorf("%w: %s: %v", ErrPluginCallFailed, method, err)
+	}
+
+	pp.writeMutex.Lock()
+	_, err = pp.stdin.Write(append(line, '\n'))
+	pp.writeMutex.Unlock()
+	if err != nil {
+		pp.forget(request.ID)
+		return fmt.Errorf("%w: %s: %v", ErrPluginCallFailed, method, err)
+	}
+
+	select {
+	case response, ok := <-reply:
+		if !ok {
+			return fmt.Errorf("%w: %s: %v", ErrPluginCrashed, method, pp.exitErr)
+		}
+		if response.Error != "" {
+			return fmt.Errorf("%w: %s: %s", ErrPluginCallFailed, method, response.Error)
+		}
+		if result != nil && len(response.Result) > 0 {
+			if err := json.Unmarshal(response.Result, result); err != nil {
+				return fmt.Errorf("%w: %s: bad result: %v", ErrPluginCallFailed, method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		pp.forget(request.ID)
+		return fmt.Errorf("%w: %s: %v", ErrPluginCallFailed, method, ctx.Err())
+	}
+}
+
+// forget stops waiting for a reply
+func (pp *pluginProcess) forget(id uint64) {
+	pp.pendingMutex.Lock()
+	defer pp.pendingMutex.Unlock()
+	delete(pp.pending, id)
+}
+
+// alive reports whether the process is still running
+func (pp *pluginProcess) alive() bool {
+	select {
+	case <-pp.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// terminate closes stdin so the plugin can exit on its own and kills it
+// once grace has passed
+func (pp *pluginProcess) terminate(grace time.Duration) {
+	pp.writeMutex.Lock()
+	pp.stdin.Close()
+	pp.writeMutex.Unlock()
+
+	if grace > 0 {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-pp.exited:
+			return
+		case <-timer.C:
+		}
+	}
+
+	if pp.cmd.Process != nil {
+		pp.cmd.Process.Kill()
+	}
+	<-pp.exited
+}
+
+// copyConfig returns a shallow copy of a configuration map
+func copyConfig(config map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		copied[key] = value
+	}
+	return copied
+}
+
+// noopLogger discards log messages
+type noopLogger struct{}
+
+func (noopLogger) Debug(msg string, fields map[string]interface{}) {}
+func (noopLogger) Info(msg string, fields map[string]interface{})  {}
+func (noopLogger) Warn(msg string, fields map[string]interface{})  {}
+func (noopLogger) Error(msg string, fields map[string]interface{}) {}
+
+// ProcessHandler serves one call from the host inside a plugin process.
+// Lifecycle calls use the ProcessMethod names; the returned value is sent
+// back as JSON.
+type ProcessHandler func(ctx context.Context, method string, params json.RawMessage) (interface{}, error)
+
+// ServeProcessPlugin runs inside a plugin binary: it answers the host's
+// handshake on stdout and serves calls from stdin until the host closes it.
+// Magic, ProtocolVersion and Transport of handshake are filled in.
+func ServeProcessPlugin(handshake ProcessHandshake, handler ProcessHandler) error {
+	if os.Getenv(ProcessPluginMagicEnv) != ProcessPluginMagic {
+		return ErrNotPluginProcess
+	}
+
+	supported := false
+	for _, version := range strings.Split(os.Getenv(ProcessPluginVersionsEnv), ",") {
+		if strings.TrimSpace(version) == strconv.Itoa(ProcessProtocolVersion) {
+			supported = true
+		}
+	}
+	if !supported {
+		return fmt.Errorf("%w: host does not support protocol version %d", ErrHandshakeFailed, ProcessProtocolVersion)
+	}
+
+	return serveProcessPlugin(context.Background(), os.Stdin, os.Stdout, handshake, handler)
+}
+
+// serveProcessPlugin writes the handshake to w and serves requests read from r
+func serveProcessPlugin(ctx context.Context, r io.Reader, w io.Writer, handshake ProcessHandshake, handler ProcessHandler) error {
+	handshake.Magic = ProcessPluginMagic
+	handshake.ProtocolVersion = ProcessProtocolVersion
+	handshake.Transport = ProcessTransportStdio
+
+	var writeMutex sync.Mutex
+	write := func(v interface{}) error {
+		line, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		_, err = w.Write(append(line, '\n'))
+		return err
+	}
+
+	if err := write(handshake); err != nil {
+		return err
+	}
+
+	var calls sync.WaitGroup
+	defer calls.Wait()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var request processRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			continue
+		}
+
+		calls.Add(1)
+		go func() {
+			defer calls.Done()
+
+			response := processResponse{ID: request.ID}
+			result, err := handler(ctx, request.Method, request.Params)
+			if err == nil && result != nil {
+				response.Result, err = json.Marshal(result)
+			}
+			if err != nil {
+				response.Error = err.Error()
+			}
+			write(response)
+		}()
+	}
+
+	return scanner.Err()
+}
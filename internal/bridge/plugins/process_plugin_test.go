@@ -0,0 +1,172 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helperPluginEnv makes the test binary act as a plugin process
+const helperPluginEnv = "PLUGINS_TEST_HELPER_PLUGIN"
+
+// TestHelperPlugin is the plugin process launched by the process plugin tests
+func TestHelperPlugin(t *testing.T) {
+	mode := os.Getenv(helperPluginEnv)
+	if mode == "" {
+		t.Skip("only runs as a plugin process")
+	}
+
+	if mode == "old-version" {
+		fmt.Printf(`{"magic":%q,"protocol_version":99,"type":"protocol"}`+"\n", ProcessPluginMagic)
+		os.Exit(0)
+	}
+
+	// Each start is recorded so tests can see the lifecycle replayed after a crash
+	marker := os.Getenv("PLUGINS_TEST_MARKER")
+	err := ServeProcessPlugin(ProcessHandshake{
+		Type:         PluginTypeProtocol,
+		Metadata:     PluginMetadata{Name: "echo", Version: "1.0.0"},
+		Capabilities: []string{CapabilityEncode},
+	}, func(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+		switch method {
+		case ProcessMethodStart:
+			f, _ := os.OpenFile(marker, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+			f.WriteString("start\n")
+			f.Close()
+			return nil, nil
+		case ProcessMethodCleanup:
+			if mode == "hang-cleanup" {
+				time.Sleep(time.Hour)
+			}
+			return nil, nil
+		case ProcessMethodInitialize, ProcessMethodStop, ProcessMethodConfigure:
+			return nil, nil
+		case "echo":
+			return params, nil
+		case "crash":
+			os.Exit(3)
+		}
+		return nil, fmt.Errorf("unknown method %s", method)
+	})
+	if err != nil {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+func helperPluginConfig(t *testing.T, mode string) (ProcessPluginConfig, string) {
+	marker := filepath.Join(t.TempDir(), "starts")
+	config := DefaultProcessPluginConfig(os.Args[0], "-test.run=^TestHelperPlugin$")
+	config.Env = []string{helperPluginEnv + "=" + mode, "PLUGINS_TEST_MARKER=" + marker}
+	config.RestartBackoff = 10 * time.Millisecond
+	config.MaxRestartBackoff = 100 * time.Millisecond
+	config.StopTimeout = time.Second
+	return config, marker
+}
+
+func TestProcessPluginLifecycle(t *testing.T) {
+	config, _ := helperPluginConfig(t, "serve")
+	registry := NewRegistry(noopLogger{})
+	require.NoError(t, registry.RegisterFactory("echo", ProcessPluginFactory(config, nil)))
+
+	created, err := registry.CreatePlugin("echo", "echo-1", nil)
+	require.NoError(t, err)
+	plugin := created.(*ProcessPlugin)
+
+	// Type, metadata and capabilities come from the handshake
+	assert.Equal(t, PluginTypeProtocol, plugin.Type())
+	assert.Equal(t, "echo", plugin.Metadata().Name)
+	assert.True(t, plugin.SupportsCapability(CapabilityEncode))
+
+	ctx := context.Background()
+	require.NoError(t, registry.InitializePlugin("echo-1", ctx, map[string]interface{}{"level": "debug"}))
+	require.NoError(t, registry.StartPlugin("echo-1", ctx))
+	assert.Equal(t, PluginStatusStarted, plugin.Status())
+
+	var reply map[string]string
+	require.NoError(t, plugin.Call(ctx, "echo", map[string]string{"hello": "world"}, &reply))
+	assert.Equal(t, "world", reply["hello"])
+
+	err = plugin.Call(ctx, "missing", nil, nil)
+	assert.ErrorIs(t, err, ErrPluginCallFailed)
+
+	// Cleanup terminates the process
+	require.NoError(t, registry.StopPlugin("echo-1", ctx))
+	process := plugin.process
+	require.NoError(t, registry.CleanupPlugin("echo-1", ctx))
+	assert.False(t, process.alive())
+	assert.ErrorIs(t, plugin.Call(ctx, "echo", nil, nil), ErrPluginTerminating)
+}
+
+func TestProcessPluginCleanupTimesOut(t *testing.T) {
+	config, _ := helperPluginConfig(t, "hang-cleanup")
+	config.CallTimeout = 100 * time.Millisecond
+	config.StopTimeout = 100 * time.Millisecond
+	ctx := context.Background()
+
+	plugin, err := LaunchProcessPlugin(ctx, "echo-3", config, nil)
+	require.NoError(t, err)
+	process := plugin.process
+
+	// A plugin that never answers the cleanup call is still terminated
+	start := time.Now()
+	err = plugin.Cleanup(ctx)
+	assert.ErrorIs(t, err, ErrPluginCallFailed)
+	assert.Contains(t, err.Error(), "deadline exceeded")
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.False(t, process.alive())
+	assert.Equal(t, PluginStatusStopped, plugin.Status())
+}
+
+func TestProcessPluginRestart(t *testing.T) {
+	config, marker := helperPluginConfig(t, "serve")
+	ctx := context.Background()
+
+	plugin, err := LaunchProcessPlugin(ctx, "echo-2", config, nil)
+	require.NoError(t, err)
+	defer plugin.Cleanup(ctx)
+
+	require.NoError(t, plugin.Initialize(ctx, nil))
+	require.NoError(t, plugin.Start(ctx))
+	pid := plugin.PID()
+
+	err = plugin.Call(ctx, "crash", nil, nil)
+	assert.True(t, errors.Is(err, ErrPluginCrashed), "call fails when the process exits: %v", err)
+
+	// The process comes back started
+	assert.Eventually(t, func() bool {
+		return plugin.Restarts() == 1 && plugin.Status() == PluginStatusStarted
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotEqual(t, pid, plugin.PID())
+	assert.NoError(t, plugin.LastError())
+
+	starts, err := os.ReadFile(marker)
+	require.NoError(t, err)
+	assert.Equal(t, "start\nstart\n", string(starts))
+
+	var reply string
+	require.NoError(t, plugin.Call(ctx, "echo", "again", &reply))
+	assert.Equal(t, "again", reply)
+}
+
+func TestProcessPluginHandshakeVersion(t *testing.T) {
+	config, _ := helperPluginConfig(t, "old-version")
+
+	_, err := LaunchProcessPlugin(context.Background(), "old", config, nil)
+	assert.ErrorIs(t, err, ErrHandshakeFailed)
+	assert.Contains(t, err.Error(), "version 99")
+}
+
+func TestServeProcessPluginRequiresHost(t *testing.T) {
+	t.Setenv(ProcessPluginMagicEnv, "")
+	err := ServeProcessPlugin(ProcessHandshake{Type: PluginTypeUtility}, nil)
+	assert.ErrorIs(t, err, ErrNotPluginProcess)
+}
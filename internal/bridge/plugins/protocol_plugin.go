@@ -38,6 +38,16 @@ type MessageValidationResult struct {
 	Details  map[string]interface{} `json:"details,omitempty"`
 }
 
+// MessageValidationError is returned when a validator rejects a message
+type MessageValidationError struct {
+	Result *MessageValidationResult
+}
+
+// Error implements the error interface
+func (e *MessageValidationError) Error() string {
+	return fmt.Sprintf("invalid message: %v", e.Result.Errors)
+}
+
 // ProtocolMessage represents a message handled by the protocol
 type ProtocolMessage struct {
 	ID        string                 `json:"id"`
@@ -56,6 +66,7 @@ type ProtocolPlugin struct {
 	contentType     string
 	messageHandlers map[string]MessageHandler
 	validator       MessageValidator
+	codec           MessageCodec
 	encoderOptions  map[string]interface{}
 	decoderOptions  map[string]interface{}
 	handlersMutex   sync.RWMutex
@@ -68,6 +79,12 @@ type MessageHandler func(ctx context.Context, message *ProtocolMessage) (*Protoc
 // MessageValidator validates messages
 type MessageValidator func(ctx context.Context, message *ProtocolMessage) (*MessageValidationResult, error)
 
+// MessageCodec converts protocol messages to and from a wire format
+type MessageCodec interface {
+	Marshal(message *ProtocolMessage) ([]byte, error)
+	Unmarshal(data []byte, message *ProtocolMessage) error
+}
+
 // ProtocolStats tracks protocol statistics
 type ProtocolStats struct {
 	MessagesEncoded  int64
@@ -206,6 +223,49 @@ func (p *ProtocolPlugin) SetValidator(validator MessageValidator) {
 	p.validator = validator
 }
 
+// AddValidator adds a validator that runs after the one already set
+func (p *ProtocolPlugin) AddValidator(validator MessageValidator) {
+	if p.validator == nil {
+		p.validator = validator
+		return
+	}
+	p.validator = ChainValidators(p.validator, validator)
+}
+
+// ChainValidators combines validators into one that runs each in turn and merges their results
+func ChainValidators(validators ...MessageValidator) MessageValidator {
+	return func(ctx context.Context, message *ProtocolMessage) (*MessageValidationResult, error) {
+		merged := &MessageValidationResult{Valid: true}
+
+		for _, validator := range validators {
+			result, err := validator(ctx, message)
+			if err != nil {
+				return nil, err
+			}
+			if result == nil {
+				continue
+			}
+
+			merged.Valid = merged.Valid && result.Valid
+			merged.Errors = append(merged.Errors, result.Errors...)
+			merged.Warnings = append(merged.Warnings, result.Warnings...)
+			for key, value := range result.Details {
+				if merged.Details == nil {
+					merged.Details = make(map[string]interface{})
+				}
+				merged.Details[key] = value
+			}
+		}
+
+		return merged, nil
+	}
+}
+
+// SetCodec sets the codec used in place of the built-in encoding for the protocol
+func (p *ProtocolPlugin) SetCodec(codec MessageCodec) {
+	p.codec = codec
+}
+
 // Encode encodes a message
 func (p *ProtocolPlugin) Encode(ctx context.Context, message *ProtocolMessage) ([]byte, error) {
 	startTime := time.Now()
@@ -220,7 +280,7 @@ func (p *ProtocolPlugin) Encode(ctx context.Context, message *ProtocolMessage) (
 
 		if !result.Valid {
 			p.stats.ValidationErrors++
-			return nil, fmt.Errorf("invalid message: %v", result.Errors)
+			return nil, &MessageValidationError{Result: result}
 		}
 	}
 
@@ -232,18 +292,18 @@ func (p *ProtocolPlugin) Encode(ctx context.Context, message *ProtocolMessage) (
 	// Encode the message
 	var data []byte
 	var err error
-	switch p.protocolName {
-	case ProtocolJSON:
+	switch {
+	case p.codec != nil:
+		data, err = p.codec.Marshal(message)
+	case p.protocolName == ProtocolJSON:
 		data, err = json.Marshal(message)
-	case ProtocolMessagePack:
-		// MessagePack encoding would go here
-		err = fmt.Errorf("MessagePack encoding not implemented")
-	case ProtocolProtobuf:
-		// Protobuf encoding would go here
-		err = fmt.Errorf("Protobuf encoding not implemented")
-	case ProtocolXML:
-		// XML encoding would go here
-		err = fmt.Errorf("XML encoding not implemented")
+	case p.protocolName == ProtocolMessagePack:
+		data, err = MessagePackCodec{}.Marshal(message)
+	case p.protocolName == ProtocolProtobuf:
+		// Protobuf needs service definitions, supplied through a codec
+		err = fmt.Errorf("Protobuf encoding requires a message codec")
+	case p.protocolName == ProtocolXML:
+		data, err = XMLCodec{}.Marshal(message)
 	default:
 		// Default to JSON
 		data, err = json.Marshal(message)
@@ -273,18 +333,18 @@ func (p *ProtocolPlugin) Decode(ctx context.Context, data []byte) (*ProtocolMess
 	// Decode the message
 	var message ProtocolMessage
 	var err error
-	switch p.protocolName {
-	case ProtocolJSON:
+	switch {
+	case p.codec != nil:
+		err = p.codec.Unmarshal(data, &message)
+	case p.protocolName == ProtocolJSON:
 		err = json.Unmarshal(data, &message)
-	case ProtocolMessagePack:
-		// MessagePack decoding would go here
-		err = fmt.Errorf("MessagePack decoding not implemented")
-	case ProtocolProtobuf:
-		// Protobuf decoding would go here
-		err = fmt.Errorf("Protobuf decoding not implemented")
-	case ProtocolXML:
-		// XML decoding would go here
-		err = fmt.Errorf("XML decoding not implemented")
+	case p.protocolName == ProtocolMessagePack:
+		err = MessagePackCodec{}.Unmarshal(data, &message)
+	case p.protocolName == ProtocolProtobuf:
+		// Protobuf needs service definitions, supplied through a codec
+		err = fmt.Errorf("Protobuf decoding requires a message codec")
+	case p.protocolName == ProtocolXML:
+		err = XMLCodec{}.Unmarshal(data, &message)
 	default:
 		// Default to JSON
 		err = json.Unmarshal(data, &message)
@@ -305,7 +365,7 @@ func (p *ProtocolPlugin) Decode(ctx context.Context, data []byte) (*ProtocolMess
 
 		if !result.Valid {
 			p.stats.ValidationErrors++
-			return nil, fmt.Errorf("invalid message: %v", result.Errors)
+			return nil, &MessageValidationError{Result: result}
 		}
 	}
 
@@ -365,29 +425,50 @@ func CreateJSONProtocolPlugin(id string) *ProtocolPlugin {
 	plugin := NewProtocolPlugin(id, ProtocolJSON, "1.0", "application/json",
 		WithCapabilities(CapabilityEncode, CapabilityDecode, CapabilityValidate))
 
-	// Add JSON-specific validator
-	plugin.SetValidator(func(ctx context.Context, message *ProtocolMessage) (*MessageValidationResult, error) {
-		// Perform basic validation
-		if message.ID == "" {
-			return &MessageValidationResult{
-				Valid:  false,
-				Errors: []string{"message ID cannot be empty"},
-			}, nil
-		}
+	plugin.SetValidator(validateMessageIdentity)
 
-		if message.Type == "" {
-			return &MessageValidationResult{
-				Valid:  false,
-				Errors: []string{"message type cannot be empty"},
-			}, nil
-		}
+	return plugin
+}
+
+// CreateMessagePackProtocolPlugin creates a new MessagePack protocol plugin
+func CreateMessagePackProtocolPlugin(id string) *ProtocolPlugin {
+	plugin := NewProtocolPlugin(id, ProtocolMessagePack, "1.0", "application/msgpack",
+		WithCapabilities(CapabilityEncode, CapabilityDecode, CapabilityValidate))
+
+	plugin.SetValidator(validateMessageIdentity)
+
+	return plugin
+}
+
+// CreateXMLProtocolPlugin creates a new XML protocol plugin
+func CreateXMLProtocolPlugin(id string) *ProtocolPlugin {
+	plugin := NewProtocolPlugin(id, ProtocolXML, "1.0", "application/xml",
+		WithCapabilities(CapabilityEncode, CapabilityDecode, CapabilityValidate))
+
+	plugin.SetValidator(validateMessageIdentity)
+
+	return plugin
+}
 
+// validateMessageIdentity requires messages to carry an ID and a type
+func validateMessageIdentity(ctx context.Context, message *ProtocolMessage) (*MessageValidationResult, error) {
+	if message.ID == "" {
 		return &MessageValidationResult{
-			Valid: true,
+			Valid:  false,
+			Errors: []string{"message ID cannot be empty"},
 		}, nil
-	})
+	}
 
-	return plugin
+	if message.Type == "" {
+		return &MessageValidationResult{
+			Valid:  false,
+			Errors: []string{"message type cannot be empty"},
+		}, nil
+	}
+
+	return &MessageValidationResult{
+		Valid: true,
+	}, nil
 }
 
 // PluginFactory implementations
@@ -398,9 +479,19 @@ func init() {
 		return plugin, nil
 	}
 
+	// Register MessagePack and XML protocol plugin factories
+	msgpackProtocolFactory := func(id string, config map[string]interface{}) (Plugin, error) {
+		return CreateMessagePackProtocolPlugin(id), nil
+	}
+	xmlProtocolFactory := func(id string, config map[string]interface{}) (Plugin, error) {
+		return CreateXMLProtocolPlugin(id), nil
+	}
+
 	// Add to registry when available
 	if registry, ok := globalRegistry.(*Registry); ok {
 		registry.RegisterFactory("json-protocol", jsonProtocolFactory)
+		registry.RegisterFactory("msgpack-protocol", msgpackProtocolFactory)
+		registry.RegisterFactory("xml-protocol", xmlProtocolFactory)
 	}
 }
 
@@ -258,3 +258,173 @@ func TestMessageValidation(t *testing.T) {
 	assert.Contains(t, err.Error(), "Type is required")
 	assert.Contains(t, err.Error(), "Payload is required")
 }
+
+// normalizeThroughJSON gives values the shape encoding/json decodes them to
+func normalizeThroughJSON(t *testing.T, value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+
+	var generic interface{}
+	require.NoError(t, json.Unmarshal(data, &generic))
+	return generic
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	timestamp := time.Date(2024, 3, 15, 10, 30, 45, 123456789, time.UTC)
+	message := &ProtocolMessage{
+		ID:   "msg-42",
+		Type: "token.analyze",
+		Headers: map[string]string{
+			"trace-id": "abc-123",
+			"control":  "line\nbreak\x01bell",
+		},
+		Payload: map[string]interface{}{
+			"address":  "0xdeadbeef",
+			"count":    int64(-7),
+			"big":      uint64(1<<63 + 11),
+			"ratio":    3.25,
+			"enabled":  true,
+			"missing":  nil,
+			"raw":      []byte{0, 1, 2, 255},
+			"seen":     timestamp.Add(-time.Hour),
+			"escaped":  "<tag attr=\"x\"> & 'y'",
+			"invalid":  "nul\x00byte",
+			"tags":     []interface{}{"a", int64(1), 2.5, false, nil},
+			"empty":    []interface{}{},
+			"nested":   map[string]interface{}{"deeper": map[string]interface{}{"level": int64(3)}},
+			"nothing":  map[string]interface{}{},
+			"unicode":  "héllo, 世界 🚀",
+			"negative": int64(-1 << 40),
+		},
+		Metadata: map[string]interface{}{
+			"priority": int64(5),
+			"source":   "api",
+		},
+		Timestamp: timestamp,
+	}
+
+	codecs := map[string]MessageCodec{
+		ProtocolMessagePack: MessagePackCodec{},
+		ProtocolXML:         XMLCodec{},
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			data, err := codec.Marshal(message)
+			require.NoError(t, err)
+
+			var decoded ProtocolMessage
+			require.NoError(t, codec.Unmarshal(data, &decoded))
+
+			assert.Equal(t, message.ID, decoded.ID)
+			assert.Equal(t, message.Type, decoded.Type)
+			assert.Equal(t, message.Headers, decoded.Headers)
+			assert.Equal(t, message.Metadata, decoded.Metadata)
+			assert.True(t, message.Timestamp.Equal(decoded.Timestamp))
+
+			payload, ok := decoded.Payload.(map[string]interface{})
+			require.True(t, ok)
+			seen, ok := payload["seen"].(time.Time)
+			require.True(t, ok)
+			assert.True(t, timestamp.Add(-time.Hour).Equal(seen))
+
+			delete(payload, "seen")
+			expected := make(map[string]interface{})
+			for key, value := range message.Payload.(map[string]interface{}) {
+				expected[key] = value
+			}
+			delete(expected, "seen")
+			assert.Equal(t, expected, payload)
+		})
+	}
+}
+
+func TestCrossCodecEquivalence(t *testing.T) {
+	type tokenInfo struct {
+		Address  string   `json:"address"`
+		Decimals uint8    `json:"decimals"`
+		Supply   float64  `json:"supply"`
+		Holders  []string `json:"holders,omitempty"`
+	}
+
+	message := &ProtocolMessage{
+		ID:      "cross-1",
+		Type:    "token.info",
+		Headers: map[string]string{"source": "test"},
+		Payload: map[string]interface{}{
+			"token":  tokenInfo{Address: "0xabc", Decimals: 18, Supply: 1e6, Holders: []string{"a", "b"}},
+			"counts": []int{1, 2, 3},
+			"scores": map[string]float32{"risk": 0.1},
+			"label":  "plain",
+		},
+		Metadata:  map[string]interface{}{"attempt": 2},
+		Timestamp: time.Date(2024, 1, 2, 15, 4, 5, 6000, time.UTC),
+	}
+
+	ctx := context.Background()
+	protocolPlugins := []*ProtocolPlugin{
+		CreateJSONProtocolPlugin("json"),
+		CreateMessagePackProtocolPlugin("msgpack"),
+		CreateXMLProtocolPlugin("xml"),
+	}
+
+	var reference *ProtocolMessage
+	for _, plugin := range protocolPlugins {
+		require.NoError(t, plugin.Initialize(ctx, nil))
+
+		encoded, err := plugin.Encode(ctx, message)
+		require.NoError(t, err, plugin.protocolName)
+
+		decoded, err := plugin.Decode(ctx, encoded)
+		require.NoError(t, err, plugin.protocolName)
+
+		if reference == nil {
+			reference = decoded
+			continue
+		}
+
+		assert.Equal(t, reference.ID, decoded.ID, plugin.protocolName)
+		assert.Equal(t, reference.Type, decoded.Type, plugin.protocolName)
+		assert.Equal(t, reference.Headers, decoded.Headers, plugin.protocolName)
+		assert.True(t, reference.Timestamp.Equal(decoded.Timestamp), plugin.protocolName)
+		assert.Equal(t, normalizeThroughJSON(t, reference.Payload), normalizeThroughJSON(t, decoded.Payload), plugin.protocolName)
+		assert.Equal(t, normalizeThroughJSON(t, reference.Metadata), normalizeThroughJSON(t, decoded.Metadata), plugin.protocolName)
+	}
+
+	// Every plugin validates the same way
+	for _, plugin := range protocolPlugins {
+		_, err := plugin.Encode(ctx, &ProtocolMessage{Type: "token.info"})
+		assert.Error(t, err, plugin.protocolName)
+		assert.Contains(t, err.Error(), "message ID cannot be empty")
+	}
+}
+
+func TestCodecRejectsMalformedInput(t *testing.T) {
+	var message ProtocolMessage
+
+	msgpackInputs := [][]byte{
+		{},
+		{0x81},             // map missing its entry
+		{0xdb, 0xff, 0xff}, // truncated str32 length
+		{0xc1},             // reserved type byte
+		{0x80, 0x00},       // trailing data
+		{0x92, 0x01, 0x02}, // not a map
+	}
+	for _, input := range msgpackInputs {
+		assert.ErrorIs(t, MessagePackCodec{}.Unmarshal(input, &message), ErrMalformedMessage, "%x", input)
+	}
+
+	xmlInputs := []string{
+		"",
+		"<other/>",
+		"<message><payload><int>x</int></payload></message>",
+		"<message><payload><int>1</int><int>2</int></payload></message>",
+		"<message><payload><unknown/></payload></message>",
+		"<message><metadata><item key=\"a\"><null/></item></metadata></message>",
+		"<message></message><message></message>",
+		"<message><payload>",
+	}
+	for _, input := range xmlInputs {
+		assert.ErrorIs(t, XMLCodec{}.Unmarshal([]byte(input), &message), ErrMalformedMessage, input)
+	}
+}
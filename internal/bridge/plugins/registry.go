@@ -14,6 +14,7 @@ type Registry struct {
 	factories    map[string]PluginFactory
 	dependencies map[string][]string
 	mutex        sync.RWMutex
+	configMutex  sync.Mutex
 	logger       Logger
 }
 
@@ -188,6 +189,17 @@ func (r *Registry) InitializePlugin(id string, ctx context.Context, config map[s
 		return ErrPluginNotFound
 	}
 
+	// Fill in defaults and validate against the plugin's schema
+	schema := plugin.Metadata().ConfigSchema
+	config = schema.WithDefaults(config)
+	if err := schema.Validate(config); err != nil {
+		r.logger.Error(fmt.Sprintf("Invalid configuration for plugin '%s': %v", id, err), map[string]interface{}{
+			"plugin_id": id,
+			"error":     err.Error(),
+		})
+		return fmt.Errorf("failed to initialize plugin '%s': %w", id, err)
+	}
+
 	// Initialize the plugin
 	if err := plugin.Initialize(ctx, config); err != nil {
 		r.logger.Error(fmt.Sprintf("Failed to initialize plugin '%s': %v", id, err), map[string]interface{}{
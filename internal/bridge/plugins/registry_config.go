@@ -0,0 +1,113 @@
+// registry_config.go - Validated live reconfiguration of registered plugins
+
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ConfigRestorer is implemented by plugins whose whole configuration can be
+// replaced, so a failed reconfiguration is rolled back exactly instead of
+// by re-applying the previous values
+type ConfigRestorer interface {
+	RestoreConfig(config map[string]interface{}) error
+}
+
+// PluginConfig returns the current configuration of a plugin and the
+// schema it is validated against, which is nil if the plugin has none
+func (r *Registry) PluginConfig(id string) (map[string]interface{}, *ConfigSchema, error) {
+	plugin, err := r.GetPlugin(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plugin.GetConfig(), plugin.Metadata().ConfigSchema, nil
+}
+
+// ConfigurePlugin merges configuration changes into a plugin's
+// configuration. The changes are validated against the plugin's schema
+// first, and rolled back if the plugin fails to apply them.
+func (r *Registry) ConfigurePlugin(id string, changes map[string]interface{}) error {
+	return r.Reconfigure(map[string]map[string]interface{}{id: changes})
+}
+
+// Reconfigure applies configuration changes to several plugins as one
+// unit, keyed by plugin ID. Nothing is applied unless every plugin's
+// merged configuration is valid, and if any plugin fails to apply its
+// changes the plugins already reconfigured are restored.
+func (r *Registry) Reconfigure(changes map[string]map[string]interface{}) error {
+	r.configMutex.Lock()
+	defer r.configMutex.Unlock()
+
+	ids := make([]string, 0, len(changes))
+	for id := range changes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Validate everything before touching any plugin
+	targets := make([]Plugin, len(ids))
+	previous := make([]map[string]interface{}, len(ids))
+	var errs []error
+	for i, id := range ids {
+		plugin, err := r.GetPlugin(id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: '%s'", err, id))
+			continue
+		}
+		targets[i] = plugin
+		previous[i] = plugin.GetConfig()
+
+		merged := mergeConfig(previous[i], changes[id])
+		if err := plugin.Metadata().ConfigSchema.Validate(merged); err != nil {
+			errs = append(errs, fmt.Errorf("plugin '%s': %w", id, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for i, id := range ids {
+		if err := targets[i].Configure(changes[id]); err != nil {
+			r.logger.Error(fmt.Sprintf("Failed to reconfigure plugin '%s': %v", id, err), map[string]interface{}{
+				"plugin_id": id,
+				"error":     err.Error(),
+			})
+
+			// The failed plugin may have applied part of its changes
+			errs = append(errs, fmt.Errorf("failed to reconfigure plugin '%s': %w", id, err))
+			for j := i; j >= 0; j-- {
+				if restoreErr := restoreConfig(targets[j], previous[j]); restoreErr != nil {
+					errs = append(errs, fmt.Errorf("rollback of plugin '%s': %w", ids[j], restoreErr))
+				}
+			}
+			return errors.Join(errs...)
+		}
+	}
+
+	r.logger.Info("Reconfigured plugins", map[string]interface{}{
+		"plugins": ids,
+	})
+	return nil
+}
+
+// restoreConfig puts back a plugin's previous configuration
+func restoreConfig(plugin Plugin, config map[string]interface{}) error {
+	if restorer, ok := plugin.(ConfigRestorer); ok {
+		return restorer.RestoreConfig(config)
+	}
+	return plugin.Configure(config)
+}
+
+// mergeConfig returns a copy of config with changes applied on top
+func mergeConfig(config, changes map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(config)+len(changes))
+	for key, value := range config {
+		merged[key] = value
+	}
+	for key, value := range changes {
+		merged[key] = value
+	}
+	return merged
+}
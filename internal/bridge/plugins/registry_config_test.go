@@ -0,0 +1,168 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(f float64) *float64 { return &f }
+
+// testConfigSchema accepts a port, a mode and a list of upstream hosts
+func testConfigSchema() *ConfigSchema {
+	closed := false
+	return &ConfigSchema{
+		Type:     SchemaTypeObject,
+		Required: []string{"port"},
+		Properties: map[string]*ConfigSchema{
+			"port": {Type: SchemaTypeInteger, Minimum: floatPtr(1), Maximum: floatPtr(65535)},
+			"mode": {Type: SchemaTypeString, Enum: []interface{}{"fast", "safe"}, Default: "safe"},
+			"upstreams": {
+				Type:  SchemaTypeArray,
+				Items: &ConfigSchema{Type: SchemaTypeString, Pattern: `^[a-z.]+:\d+$`},
+			},
+		},
+		AdditionalProperties: &closed,
+	}
+}
+
+// configPlugin applies changes before rejecting fast mode on port 1, like a
+// plugin that fails partway through reconfiguring
+type configPlugin struct {
+	*BasePlugin
+}
+
+func (p *configPlugin) Configure(config map[string]interface{}) error {
+	if err := p.BasePlugin.Configure(config); err != nil {
+		return err
+	}
+	if config["mode"] == "fast" && p.GetConfig()["port"] == 1 {
+		return errors.New("fast mode needs a privileged port")
+	}
+	return nil
+}
+
+func newConfigPlugin(id string, schema *ConfigSchema) *configPlugin {
+	return &configPlugin{NewBasePlugin(id, PluginTypeUtility, PluginMetadata{Name: id, ConfigSchema: schema})}
+}
+
+func TestConfigSchemaValidate(t *testing.T) {
+	schema := testConfigSchema()
+
+	assert.NoError(t, schema.Validate(map[string]interface{}{
+		"port":      8080,
+		"mode":      "fast",
+		"upstreams": []interface{}{"a.example:80"},
+	}))
+	assert.NoError(t, schema.Validate(map[string]interface{}{"port": 8080.0}), "JSON numbers are integers when whole")
+
+	err := schema.Validate(map[string]interface{}{
+		"port":      70000,
+		"mode":      "slow",
+		"upstreams": []string{"ok.example:1", "Bad Host"},
+		"extra":     true,
+	})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+
+	var validationErr *ConfigValidationError
+	require.True(t, errors.As(err, &validationErr))
+	paths := make([]string, 0, len(validationErr.Violations))
+	for _, v := range validationErr.Violations {
+		paths = append(paths, v.Path)
+	}
+	assert.Equal(t, []string{"config.extra", "config.mode", "config.port", "config.upstreams[1]"}, paths)
+
+	err = schema.Validate(map[string]interface{}{"port": 1.5})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "config.port: must be an integer")
+	assert.Contains(t, schema.Validate(map[string]interface{}{}).Error(), "config.port: is required")
+
+	var nilSchema *ConfigSchema
+	assert.NoError(t, nilSchema.Validate(map[string]interface{}{"anything": 1}))
+}
+
+func TestRegistryInitializeValidatesConfig(t *testing.T) {
+	registry := NewRegistry(noopLogger{})
+	plugin := newConfigPlugin("proxy", testConfigSchema())
+	require.NoError(t, registry.RegisterPlugin(plugin))
+
+	err := registry.InitializePlugin("proxy", context.Background(), map[string]interface{}{"port": "80"})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+	assert.Equal(t, PluginStatusUninitialized, plugin.Status())
+
+	require.NoError(t, registry.InitializePlugin("proxy", context.Background(), map[string]interface{}{"port": 80}))
+	assert.Equal(t, map[string]interface{}{"port": 80, "mode": "safe"}, plugin.GetConfig(), "defaults are filled in")
+
+	err = plugin.Configure(map[string]interface{}{"port": 0})
+	assert.True(t, errors.Is(err, ErrInvalidConfig), "Configure validates on its own")
+	assert.Equal(t, 80, plugin.GetConfig()["port"])
+}
+
+func TestRegistryReconfigureRollsBack(t *testing.T) {
+	registry := NewRegistry(noopLogger{})
+	proxy := newConfigPlugin("proxy", testConfigSchema())
+	edge := newConfigPlugin("edge", testConfigSchema())
+	require.NoError(t, registry.RegisterPlugin(proxy))
+	require.NoError(t, registry.RegisterPlugin(edge))
+	require.NoError(t, registry.InitializePlugin("proxy", context.Background(), map[string]interface{}{"port": 1}))
+	require.NoError(t, registry.InitializePlugin("edge", context.Background(), map[string]interface{}{"port": 443}))
+
+	// An invalid change to one plugin leaves both untouched
+	err := registry.Reconfigure(map[string]map[string]interface{}{
+		"edge":  {"port": 8443},
+		"proxy": {"port": -1},
+	})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+	assert.Equal(t, 443, edge.GetConfig()["port"])
+
+	// A change the plugin fails to apply rolls back the plugins before it
+	err = registry.Reconfigure(map[string]map[string]interface{}{
+		"edge":  {"port": 8443, "upstreams": []interface{}{"a.example:80"}},
+		"proxy": {"mode": "fast"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "privileged port")
+	assert.Equal(t, map[string]interface{}{"port": 443, "mode": "safe"}, edge.GetConfig())
+	assert.Equal(t, map[string]interface{}{"port": 1, "mode": "safe"}, proxy.GetConfig())
+
+	require.NoError(t, registry.ConfigurePlugin("edge", map[string]interface{}{"mode": "fast"}))
+	assert.Equal(t, "fast", edge.GetConfig()["mode"])
+
+	err = registry.ConfigurePlugin("missing", map[string]interface{}{"port": 1})
+	assert.True(t, errors.Is(err, ErrPluginNotFound))
+}
+
+func TestConfigWatcherAppliesFileChanges(t *testing.T) {
+	registry := NewRegistry(noopLogger{})
+	plugin := newConfigPlugin("proxy", testConfigSchema())
+	require.NoError(t, registry.RegisterPlugin(plugin))
+	require.NoError(t, registry.InitializePlugin("proxy", context.Background(), map[string]interface{}{"port": 80}))
+	require.NoError(t, registry.StartPlugin("proxy", context.Background()))
+
+	path := filepath.Join(t.TempDir(), "plugins.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("proxy:\n  port: 8080\n"), 0o600))
+
+	watcher := NewConfigWatcher(registry, path, 10*time.Millisecond, noopLogger{})
+	require.NoError(t, watcher.Start(context.Background()))
+	defer watcher.Stop()
+	assert.Equal(t, 8080, plugin.GetConfig()["port"])
+
+	// An invalid edit is rejected and the running configuration kept
+	require.NoError(t, os.WriteFile(path, []byte("proxy:\n  port: 99999\n"), 0o600))
+	assert.True(t, errors.Is(watcher.Reload(), ErrInvalidConfig))
+	assert.Equal(t, 8080, plugin.GetConfig()["port"])
+	assert.Equal(t, PluginStatusStarted, plugin.Status())
+
+	require.NoError(t, os.WriteFile(path, []byte("proxy:\n  port: 9090\n  mode: fast\n"), 0o600))
+	assert.Eventually(t, func() bool {
+		return plugin.GetConfig()["port"] == 9090
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "fast", plugin.GetConfig()["mode"])
+}
@@ -0,0 +1,294 @@
+// registry_lifecycle.go - Dependency-ordered start and stop of all registered plugins
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Common lifecycle errors
+var (
+	ErrDependencyCycle   = errors.New("plugin dependency cycle")
+	ErrDependencyMissing = errors.New("plugin dependency not registered")
+	ErrPluginStopTimeout = errors.New("plugin stop timed out")
+)
+
+// DependencyOrder groups the registered plugins into waves: every plugin's
+// dependencies are in earlier waves, so the plugins of one wave can be
+// started together. A cycle is reported with its path.
+func (r *Registry) DependencyOrder() ([][]string, error) {
+	r.mutex.RLock()
+	ids := make([]string, 0, len(r.plugins))
+	registered := make(map[string]bool, len(r.plugins))
+	for id := range r.plugins {
+		ids = append(ids, id)
+		registered[id] = true
+	}
+	deps := make(map[string][]string, len(r.dependencies))
+	for id, list := range r.dependencies {
+		deps[id] = append([]string(nil), list...)
+	}
+	r.mutex.RUnlock()
+
+	sort.Strings(ids)
+	for _, id := range ids {
+		for _, dep := range deps[id] {
+			if !registered[dep] {
+				return nil, fmt.Errorf("%w: '%s' depends on '%s'", ErrDependencyMissing, id, dep)
+			}
+		}
+	}
+
+	if cycle := findCycle(ids, deps); cycle != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+	}
+
+	// Each plugin goes one wave after its latest dependency
+	wave := make(map[string]int, len(ids))
+	var depth func(id string) int
+	depth = func(id string) int {
+		if w, done := wave[id]; done {
+			return w
+		}
+		w := 0
+		for _, dep := range deps[id] {
+			if d := depth(dep) + 1; d > w {
+				w = d
+			}
+		}
+		wave[id] = w
+		return w
+	}
+
+	var waves [][]string
+	for _, id := range ids {
+		w := depth(id)
+		for len(waves) <= w {
+			waves = append(waves, nil)
+		}
+		waves[w] = append(waves[w], id)
+	}
+
+	return waves, nil
+}
+
+// findCycle returns a dependency cycle as a path that starts and ends with
+// the same plugin, or nil when the graph is acyclic
+func findCycle(ids []string, deps map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(ids))
+	var path []string
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = visiting
+		path = append(path, id)
+
+		sorted := append([]string(nil), deps[id]...)
+		sort.Strings(sorted)
+		for _, dep := range sorted {
+			switch state[dep] {
+			case visiting:
+				for i, p := range path {
+					if p == dep {
+						return append(append([]string(nil), path[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[id] = visited
+		return nil
+	}
+
+	for _, id := range ids {
+		if state[id] == unvisited {
+			if cycle := visit(id); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// StartAll starts every registered plugin that is not already started, in
+// dependency order; plugins must be initialized first. The plugins of a wave
+// are started in parallel. If any plugin fails, the plugins started by this
+// call are stopped again in reverse order, each within stopTimeout even when
+// ctx is done, and the start errors returned.
+func (r *Registry) StartAll(ctx context.Context, stopTimeout time.Duration) error {
+	waves, err := r.DependencyOrder()
+	if err != nil {
+		return err
+	}
+
+	var started [][]string
+	for _, wave := range waves {
+		pending := make([]string, 0, len(wave))
+		for _, id := range wave {
+			if plugin, err := r.GetPlugin(id); err == nil && plugin.Status() != PluginStatusStarted {
+				pending = append(pending, id)
+			}
+		}
+
+		succeeded, errs := r.runWave(pending, func(id string) error {
+			return r.StartPlugin(id, ctx)
+		})
+		started = append(started, succeeded)
+
+		if len(errs) > 0 {
+			r.logger.Warn("Rolling back plugin start", map[string]interface{}{
+				"failed": len(errs),
+			})
+			if rollbackErr := r.stopWaves(context.WithoutCancel(ctx), started, stopTimeout); rollbackErr != nil {
+				errs = append(errs, fmt.Errorf("rollback: %w", rollbackErr))
+			}
+			return errors.Join(errs...)
+		}
+	}
+
+	r.logger.Info("Started all plugins", map[string]interface{}{
+		"waves": len(waves),
+	})
+	return nil
+}
+
+// StopAll stops every started plugin in reverse dependency order, giving
+// each plugin timeout to stop. A plugin whose dependent failed to stop is
+// left running. All stop errors are returned.
+func (r *Registry) StopAll(ctx context.Context, timeout time.Duration) error {
+	waves, err := r.DependencyOrder()
+	if err != nil {
+		return err
+	}
+
+	if err := r.stopWaves(ctx, waves, timeout); err != nil {
+		return err
+	}
+
+	r.logger.Info("Stopped all plugins", map[string]interface{}{
+		"waves": len(waves),
+	})
+	return nil
+}
+
+// stopWaves stops the started plugins of waves, last wave first. The
+// dependencies of a plugin that failed to stop, or was left running, are
+// left running too.
+func (r *Registry) stopWaves(ctx context.Context, waves [][]string, timeout time.Duration) error {
+	r.mutex.RLock()
+	deps := make(map[string][]string, len(r.dependencies))
+	for id, list := range r.dependencies {
+		deps[id] = append([]string(nil), list...)
+	}
+	r.mutex.RUnlock()
+
+	held := make(map[string]bool)
+	var errs []error
+	for i := len(waves) - 1; i >= 0; i-- {
+		pending := make([]string, 0, len(waves[i]))
+		for _, id := range waves[i] {
+			plugin, err := r.GetPlugin(id)
+			if err != nil || plugin.Status() != PluginStatusStarted {
+				continue
+			}
+			if held[id] {
+				r.logger.Warn(fmt.Sprintf("Leaving plugin '%s' running for its dependents", id), map[string]interface{}{
+					"plugin_id": id,
+				})
+				for _, dep := range deps[id] {
+					held[dep] = true
+				}
+				continue
+			}
+			pending = append(pending, id)
+		}
+
+		stopped, waveErrs := r.runWave(pending, func(id string) error {
+			return r.stopWithTimeout(ctx, id, timeout)
+		})
+		errs = append(errs, waveErrs...)
+
+		if len(stopped) < len(pending) {
+			done := make(map[string]bool, len(stopped))
+			for _, id := range stopped {
+				done[id] = true
+			}
+			for _, id := range pending {
+				if !done[id] {
+					for _, dep := range deps[id] {
+						held[dep] = true
+					}
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopWithTimeout stops a plugin, giving up waiting once timeout has passed
+func (r *Registry) stopWithTimeout(ctx context.Context, id string, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.StopPlugin(id, ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		r.logger.Error(fmt.Sprintf("Timed out stopping plugin '%s'", id), map[string]interface{}{
+			"plugin_id": id,
+			"timeout":   timeout.String(),
+		})
+		return fmt.Errorf("%w: '%s' after %s", ErrPluginStopTimeout, id, timeout)
+	}
+}
+
+// runWave applies action to the plugins of a wave in parallel and returns
+// the plugins it succeeded for, in wave order, and the errors
+func (r *Registry) runWave(ids []string, action func(id string) error) ([]string, []error) {
+	results := make([]error, len(ids))
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			results[i] = action(id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	succeeded := make([]string, 0, len(ids))
+	var errs []error
+	for i, err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			succeeded = append(succeeded, ids[i])
+		}
+	}
+	return succeeded, errs
+}
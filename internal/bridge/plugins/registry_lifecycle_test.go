@@ -0,0 +1,208 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lifecycleEvents records plugin starts and stops in order
+type lifecycleEvents struct {
+	events []string
+	mutex  sync.Mutex
+}
+
+func (e *lifecycleEvents) add(event string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *lifecycleEvents) index(event string) int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for i, recorded := range e.events {
+		if recorded == event {
+			return i
+		}
+	}
+	return -1
+}
+
+// lifecyclePlugin records its lifecycle and can fail or stall
+type lifecyclePlugin struct {
+	*BasePlugin
+	events    *lifecycleEvents
+	startErr  error
+	startWait time.Duration
+	stopErr   error
+	stopWait  time.Duration
+}
+
+func (p *lifecyclePlugin) Start(ctx context.Context) error {
+	time.Sleep(p.startWait)
+	if p.startErr != nil {
+		return p.startErr
+	}
+	p.events.add("start " + p.ID())
+	return p.BasePlugin.Start(ctx)
+}
+
+func (p *lifecyclePlugin) Stop(ctx context.Context) error {
+	time.Sleep(p.stopWait)
+	if p.stopErr != nil {
+		return p.stopErr
+	}
+	p.events.add("stop " + p.ID())
+	return p.BasePlugin.Stop(ctx)
+}
+
+// newLifecycleRegistry registers initialized plugins with dependencies
+func newLifecycleRegistry(t *testing.T, events *lifecycleEvents, deps map[string][]string, plugins ...*lifecyclePlugin) *Registry {
+	registry := NewRegistry(noopLogger{})
+	for _, plugin := range plugins {
+		plugin.events = events
+		require.NoError(t, registry.RegisterPlugin(plugin))
+		require.NoError(t, plugin.Initialize(context.Background(), nil))
+	}
+	for id, list := range deps {
+		for _, dep := range list {
+			require.NoError(t, registry.AddDependency(id, dep))
+		}
+	}
+	return registry
+}
+
+func newLifecyclePlugin(id string) *lifecyclePlugin {
+	return &lifecyclePlugin{BasePlugin: NewBasePlugin(id, PluginTypeUtility, PluginMetadata{Name: id})}
+}
+
+func TestRegistryStartAllOrder(t *testing.T) {
+	events := &lifecycleEvents{}
+	store, cache, api, metrics := newLifecyclePlugin("store"), newLifecyclePlugin("cache"), newLifecyclePlugin("api"), newLifecyclePlugin("metrics")
+	store.startWait = 50 * time.Millisecond
+	metrics.startWait = 50 * time.Millisecond
+	registry := newLifecycleRegistry(t, events, map[string][]string{
+		"cache": {"store"},
+		"api":   {"cache", "store"},
+	}, store, cache, api, metrics)
+
+	waves, err := registry.DependencyOrder()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"metrics", "store"}, {"cache"}, {"api"}}, waves)
+
+	// Independent plugins start together
+	began := time.Now()
+	require.NoError(t, registry.StartAll(context.Background(), time.Second))
+	assert.Less(t, time.Since(began), 90*time.Millisecond)
+	assert.Less(t, events.index("start store"), events.index("start cache"))
+	assert.Less(t, events.index("start cache"), events.index("start api"))
+
+	require.NoError(t, registry.StopAll(context.Background(), time.Second))
+	assert.Less(t, events.index("stop api"), events.index("stop cache"))
+	assert.Less(t, events.index("stop cache"), events.index("stop store"))
+	for _, plugin := range registry.ListPlugins() {
+		assert.Equal(t, PluginStatusStopped, plugin.Status())
+	}
+}
+
+func TestRegistryDependencyCycle(t *testing.T) {
+	events := &lifecycleEvents{}
+	registry := newLifecycleRegistry(t, events, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+	}, newLifecyclePlugin("a"), newLifecyclePlugin("b"), newLifecyclePlugin("c"), newLifecyclePlugin("d"))
+
+	err := registry.StartAll(context.Background(), time.Second)
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+	assert.Contains(t, err.Error(), "a -> b -> c -> a")
+	assert.Empty(t, events.events)
+}
+
+func TestRegistryStartAllRollback(t *testing.T) {
+	events := &lifecycleEvents{}
+	store, cache, api := newLifecyclePlugin("store"), newLifecyclePlugin("cache"), newLifecyclePlugin("api")
+	failure := errors.New("port in use")
+	api.startErr = failure
+	registry := newLifecycleRegistry(t, events, map[string][]string{
+		"cache": {"store"},
+		"api":   {"cache"},
+	}, store, cache, api)
+
+	err := registry.StartAll(context.Background(), time.Second)
+	assert.ErrorIs(t, err, failure)
+
+	// Started plugins are stopped again, dependents first
+	assert.Less(t, events.index("stop cache"), events.index("stop store"))
+	assert.Equal(t, PluginStatusStopped, store.Status())
+	assert.Equal(t, PluginStatusStopped, cache.Status())
+}
+
+func TestRegistryStopAllTimeout(t *testing.T) {
+	events := &lifecycleEvents{}
+	store, api := newLifecyclePlugin("store"), newLifecyclePlugin("api")
+	api.stopWait = 200 * time.Millisecond
+	registry := newLifecycleRegistry(t, events, map[string][]string{
+		"api": {"store"},
+	}, store, api)
+	require.NoError(t, registry.StartAll(context.Background(), time.Second))
+
+	// The stalled dependent times out, so its dependency is left running
+	err := registry.StopAll(context.Background(), 20*time.Millisecond)
+	assert.ErrorIs(t, err, ErrPluginStopTimeout)
+	assert.Equal(t, PluginStatusStarted, store.Status())
+}
+
+func TestRegistryStopAllKeepsDependenciesOfFailedStop(t *testing.T) {
+	events := &lifecycleEvents{}
+	store, cache, api, metrics := newLifecyclePlugin("store"), newLifecyclePlugin("cache"), newLifecyclePlugin("api"), newLifecyclePlugin("metrics")
+	failure := errors.New("connections still open")
+	api.stopErr = failure
+	registry := newLifecycleRegistry(t, events, map[string][]string{
+		"cache":   {"store"},
+		"api":     {"cache"},
+		"metrics": {"store"},
+	}, store, cache, api, metrics)
+	require.NoError(t, registry.StartAll(context.Background(), time.Second))
+
+	// Only the failed stop is reported; what api needs, directly or not, is
+	// left running without being asked to stop
+	err := registry.StopAll(context.Background(), time.Second)
+	assert.ErrorIs(t, err, failure)
+	assert.NotContains(t, err.Error(), "cache")
+
+	assert.Equal(t, PluginStatusStarted, api.Status())
+	assert.Equal(t, PluginStatusStarted, cache.Status())
+	assert.Equal(t, PluginStatusStarted, store.Status())
+	assert.Equal(t, PluginStatusStopped, metrics.Status())
+	assert.Equal(t, -1, events.index("stop cache"))
+	assert.Equal(t, -1, events.index("stop store"))
+}
+
+func TestRegistryStartAllRollbackAfterCancel(t *testing.T) {
+	events := &lifecycleEvents{}
+	store, api := newLifecyclePlugin("store"), newLifecyclePlugin("api")
+	api.startErr = context.Canceled
+	registry := newLifecycleRegistry(t, events, map[string][]string{
+		"api": {"store"},
+	}, store, api)
+
+	// The start is cancelled while api starts; the rollback still stops store
+	ctx, cancel := context.WithCancel(context.Background())
+	api.startWait = 20 * time.Millisecond
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	err := registry.StartAll(ctx, time.Second)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrPluginStopTimeout)
+	assert.Equal(t, PluginStatusStopped, store.Status())
+}
@@ -0,0 +1,558 @@
+// xml_codec.go - XML encoding for protocol messages
+
+package plugins
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// XML element names
+const (
+	xmlMessage  = "message"
+	xmlHeaders  = "headers"
+	xmlHeader   = "header"
+	xmlPayload  = "payload"
+	xmlMetadata = "metadata"
+	xmlEntry    = "entry"
+
+	xmlNull   = "null"
+	xmlBool   = "bool"
+	xmlInt    = "int"
+	xmlUint   = "uint"
+	xmlFloat  = "float"
+	xmlString = "string"
+	xmlBytes  = "bytes"
+	xmlTime   = "time"
+	xmlList   = "list"
+	xmlMap    = "map"
+)
+
+// xmlBase64Suffix marks attributes holding base64 text that XML cannot carry as-is
+const xmlBase64Suffix = "-base64"
+
+// XMLCodec encodes protocol messages as XML. Payload and metadata values are
+// written as typed elements so they decode to the same generic values as the
+// MessagePack codec:
+//
+//	<message id="m-1" type="request" timestamp="2024-01-02T15:04:05.123Z">
+//	  <headers><header name="trace-id">abc</header></headers>
+//	  <payload><map><entry key="count"><int>3</int></entry></map></payload>
+//	  <metadata><entry key="source"><string>api</string></entry></metadata>
+//	</message>
+//
+// Strings that XML 1.0 cannot represent are base64 encoded, with an
+// encoding="base64" attribute on elements or a "-base64" suffix on attributes.
+type XMLCodec struct{}
+
+// Marshal encodes a message
+func (XMLCodec) Marshal(message *ProtocolMessage) ([]byte, error) {
+	payload, err := normalizeValue(message.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("payload: %w", err)
+	}
+
+	var metadata map[string]interface{}
+	if message.Metadata != nil {
+		normalized, err := normalizeValue(message.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("metadata: %w", err)
+		}
+		metadata = normalized.(map[string]interface{})
+	}
+
+	var buf bytes.Buffer
+	e := &xmlEncoder{enc: xml.NewEncoder(&buf)}
+
+	start := xml.StartElement{Name: xml.Name{Local: xmlMessage}}
+	start.Attr = appendTextAttr(start.Attr, "id", message.ID)
+	start.Attr = appendTextAttr(start.Attr, "type", message.Type)
+	if !message.Timestamp.IsZero() {
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: "timestamp"},
+			Value: message.Timestamp.Format(time.RFC3339Nano),
+		})
+	}
+	e.start(start)
+
+	if message.Headers != nil {
+		e.start(xml.StartElement{Name: xml.Name{Local: xmlHeaders}})
+		for _, name := range sortedHeaderKeys(message.Headers) {
+			e.text(xml.StartElement{
+				Name: xml.Name{Local: xmlHeader},
+				Attr: appendTextAttr(nil, "name", name),
+			}, message.Headers[name])
+		}
+		e.end(xmlHeaders)
+	}
+
+	e.start(xml.StartElement{Name: xml.Name{Local: xmlPayload}})
+	if err := e.value(payload, 0); err != nil {
+		return nil, fmt.Errorf("payload: %w", err)
+	}
+	e.end(xmlPayload)
+
+	if metadata != nil {
+		e.start(xml.StartElement{Name: xml.Name{Local: xmlMetadata}})
+		if err := e.entries(metadata, 0); err != nil {
+			return nil, fmt.Errorf("metadata: %w", err)
+		}
+		e.end(xmlMetadata)
+	}
+
+	e.end(xmlMessage)
+
+	if e.err == nil {
+		e.err = e.enc.Flush()
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a message
+func (XMLCodec) Unmarshal(data []byte, message *ProtocolMessage) error {
+	d := &xmlDecoder{dec: xml.NewDecoder(bytes.NewReader(data))}
+
+	root, err := d.next()
+	if err == io.EOF {
+		return fmt.Errorf("%w: empty document", ErrMalformedMessage)
+	}
+	if err != nil {
+		return err
+	}
+	if root == nil || root.Name.Local != xmlMessage {
+		return fmt.Errorf("%w: expected <%s> root element", ErrMalformedMessage, xmlMessage)
+	}
+
+	*message = ProtocolMessage{}
+	if message.ID, err = textAttr(root, "id"); err != nil {
+		return err
+	}
+	if message.Type, err = textAttr(root, "type"); err != nil {
+		return err
+	}
+	if stamp, ok := attr(root, "timestamp"); ok {
+		if message.Timestamp, err = time.Parse(time.RFC3339Nano, stamp); err != nil {
+			return fmt.Errorf("%w: timestamp: %v", ErrMalformedMessage, err)
+		}
+	}
+
+	for {
+		child, err := d.next()
+		if err != nil {
+			return err
+		}
+		if child == nil {
+			break
+		}
+
+		switch child.Name.Local {
+		case xmlHeaders:
+			if message.Headers, err = d.headers(); err != nil {
+				return err
+			}
+		case xmlPayload:
+			if message.Payload, err = d.single(0); err != nil {
+				return err
+			}
+		case xmlMetadata:
+			if message.Metadata, err = d.entries(0); err != nil {
+				return err
+			}
+		default:
+			if err := d.dec.Skip(); err != nil {
+				return fmt.Errorf("%w: %v", ErrMalformedMessage, err)
+			}
+		}
+	}
+
+	// Nothing but whitespace, comments and processing instructions may follow
+	extra, err := d.next()
+	switch {
+	case err == io.EOF:
+		return nil
+	case err != nil:
+		return err
+	case extra != nil:
+		return fmt.Errorf("%w: content after </%s>", ErrMalformedMessage, xmlMessage)
+	}
+	return nil
+}
+
+// xmlEncoder writes typed value elements, keeping the first error
+type xmlEncoder struct {
+	enc *xml.Encoder
+	err error
+}
+
+func (e *xmlEncoder) start(start xml.StartElement) {
+	if e.err == nil {
+		e.err = e.enc.EncodeToken(start)
+	}
+}
+
+func (e *xmlEncoder) end(name string) {
+	if e.err == nil {
+		e.err = e.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+	}
+}
+
+// text writes an element holding a string, base64 encoding it when XML cannot carry it
+func (e *xmlEncoder) text(start xml.StartElement, s string) {
+	if !xmlSafe(s) {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "encoding"}, Value: "base64"})
+		s = base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	e.start(start)
+	if e.err == nil && s != "" {
+		e.err = e.enc.EncodeToken(xml.CharData(s))
+	}
+	e.end(start.Name.Local)
+}
+
+// scalar writes an element holding already formatted text
+func (e *xmlEncoder) scalar(name, text string) {
+	e.start(xml.StartElement{Name: xml.Name{Local: name}})
+	if e.err == nil {
+		e.err = e.enc.EncodeToken(xml.CharData(text))
+	}
+	e.end(name)
+}
+
+// value writes a normalized value as a typed element
+func (e *xmlEncoder) value(value interface{}, depth int) error {
+	if depth > maxValueDepth {
+		return fmt.Errorf("%w: nesting deeper than %d", ErrUnsupportedValue, maxValueDepth)
+	}
+
+	switch x := value.(type) {
+	case nil:
+		e.start(xml.StartElement{Name: xml.Name{Local: xmlNull}})
+		e.end(xmlNull)
+	case bool:
+		e.scalar(xmlBool, strconv.FormatBool(x))
+	case int64:
+		e.scalar(xmlInt, strconv.FormatInt(x, 10))
+	case uint64:
+		e.scalar(xmlUint, strconv.FormatUint(x, 10))
+	case float64:
+		e.scalar(xmlFloat, strconv.FormatFloat(x, 'g', -1, 64))
+	case string:
+		e.text(xml.StartElement{Name: xml.Name{Local: xmlString}}, x)
+	case []byte:
+		e.scalar(xmlBytes, base64.StdEncoding.EncodeToString(x))
+	case time.Time:
+		e.scalar(xmlTime, x.Format(time.RFC3339Nano))
+	case []interface{}:
+		e.start(xml.StartElement{Name: xml.Name{Local: xmlList}})
+		for _, item := range x {
+			if err := e.value(item, depth+1); err != nil {
+				return err
+			}
+		}
+		e.end(xmlList)
+	case map[string]interface{}:
+		e.start(xml.StartElement{Name: xml.Name{Local: xmlMap}})
+		if err := e.entries(x, depth); err != nil {
+			return err
+		}
+		e.end(xmlMap)
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedValue, value)
+	}
+	return e.err
+}
+
+// entries writes map entries in key order
+func (e *xmlEncoder) entries(m map[string]interface{}, depth int) error {
+	for _, key := range sortedKeys(m) {
+		e.start(xml.StartElement{
+			Name: xml.Name{Local: xmlEntry},
+			Attr: appendTextAttr(nil, "key", key),
+		})
+		if err := e.value(m[key], depth+1); err != nil {
+			return err
+		}
+		e.end(xmlEntry)
+	}
+	return e.err
+}
+
+// xmlDecoder reads typed value elements
+type xmlDecoder struct {
+	dec *xml.Decoder
+}
+
+// next returns the next child element, or nil at the end of the current element.
+// Whitespace, comments and processing instructions are skipped.
+func (d *xmlDecoder) next() (*xml.StartElement, error) {
+	for {
+		token, err := d.dec.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedMessage, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			return &t, nil
+		case xml.EndElement:
+			return nil, nil
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return nil, fmt.Errorf("%w: unexpected text %q", ErrMalformedMessage, string(t))
+			}
+		}
+	}
+}
+
+// text reads the character data of the current element up to its end
+func (d *xmlDecoder) text() (string, error) {
+	var sb strings.Builder
+	for {
+		token, err := d.dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrMalformedMessage, err)
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.EndElement:
+			return sb.String(), nil
+		case xml.StartElement:
+			return "", fmt.Errorf("%w: unexpected <%s> in text", ErrMalformedMessage, t.Name.Local)
+		}
+	}
+}
+
+// single reads the one value element inside the current element
+func (d *xmlDecoder) single(depth int) (interface{}, error) {
+	child, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	if child == nil {
+		return nil, fmt.Errorf("%w: missing value", ErrMalformedMessage)
+	}
+
+	value, err := d.value(child, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	if extra, err := d.next(); err != nil || extra != nil {
+		if err == nil {
+			err = fmt.Errorf("%w: more than one value", ErrMalformedMessage)
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// value reads a typed value element whose start has been consumed
+func (d *xmlDecoder) value(start *xml.StartElement, depth int) (interface{}, error) {
+	if depth > maxValueDepth {
+		return nil, fmt.Errorf("%w: nesting deeper than %d", ErrMalformedMessage, maxValueDepth)
+	}
+
+	switch start.Name.Local {
+	case xmlNull:
+		if _, err := d.text(); err != nil {
+			return nil, err
+		}
+		return nil, nil
+
+	case xmlList:
+		list := make([]interface{}, 0)
+		for {
+			child, err := d.next()
+			if err != nil {
+				return nil, err
+			}
+			if child == nil {
+				return list, nil
+			}
+			item, err := d.value(child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+
+	case xmlMap:
+		return d.entries(depth)
+
+	case xmlString:
+		return d.decodedText(start)
+	}
+
+	text, err := d.text()
+	if err != nil {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+
+	var value interface{}
+	switch start.Name.Local {
+	case xmlBool:
+		value, err = strconv.ParseBool(text)
+	case xmlInt:
+		value, err = strconv.ParseInt(text, 10, 64)
+	case xmlUint:
+		var n uint64
+		if n, err = strconv.ParseUint(text, 10, 64); err == nil && n <= math.MaxInt64 {
+			value = int64(n)
+		} else {
+			value = n
+		}
+	case xmlFloat:
+		value, err = strconv.ParseFloat(text, 64)
+	case xmlBytes:
+		value, err = base64.StdEncoding.DecodeString(text)
+	case xmlTime:
+		value, err = time.Parse(time.RFC3339Nano, text)
+	default:
+		return nil, fmt.Errorf("%w: unknown value element <%s>", ErrMalformedMessage, start.Name.Local)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: <%s>: %v", ErrMalformedMessage, start.Name.Local, err)
+	}
+	return value, nil
+}
+
+// decodedText reads element text, undoing base64 encoding when marked
+func (d *xmlDecoder) decodedText(start *xml.StartElement) (string, error) {
+	text, err := d.text()
+	if err != nil {
+		return "", err
+	}
+
+	if encoding, ok := attr(start, "encoding"); ok {
+		if encoding != "base64" {
+			return "", fmt.Errorf("%w: unknown encoding %q", ErrMalformedMessage, encoding)
+		}
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+		if err != nil {
+			return "", fmt.Errorf("%w: <%s>: %v", ErrMalformedMessage, start.Name.Local, err)
+		}
+		return string(raw), nil
+	}
+	return text, nil
+}
+
+// entries reads <entry> elements up to the end of the current element
+func (d *xmlDecoder) entries(depth int) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for {
+		child, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			return m, nil
+		}
+		if child.Name.Local != xmlEntry {
+			return nil, fmt.Errorf("%w: expected <%s>, got <%s>", ErrMalformedMessage, xmlEntry, child.Name.Local)
+		}
+
+		key, err := textAttr(child, "key")
+		if err != nil {
+			return nil, err
+		}
+		if m[key], err = d.single(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// headers reads <header> elements up to the end of the current element
+func (d *xmlDecoder) headers() (map[string]string, error) {
+	headers := make(map[string]string)
+	for {
+		child, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			return headers, nil
+		}
+		if child.Name.Local != xmlHeader {
+			return nil, fmt.Errorf("%w: expected <%s>, got <%s>", ErrMalformedMessage, xmlHeader, child.Name.Local)
+		}
+
+		name, err := textAttr(child, "name")
+		if err != nil {
+			return nil, err
+		}
+		if headers[name], err = d.decodedText(child); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attr returns an attribute value
+func attr(start *xml.StartElement, name string) (string, bool) {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// textAttr returns a string attribute written by appendTextAttr
+func textAttr(start *xml.StartElement, name string) (string, error) {
+	if encoded, ok := attr(start, name+xmlBase64Suffix); ok {
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %v", ErrMalformedMessage, name, err)
+		}
+		return string(raw), nil
+	}
+	value, _ := attr(start, name)
+	return value, nil
+}
+
+// appendTextAttr adds a string attribute, base64 encoding it when XML cannot carry it
+func appendTextAttr(attrs []xml.Attr, name, value string) []xml.Attr {
+	if !xmlSafe(value) {
+		return append(attrs, xml.Attr{
+			Name:  xml.Name{Local: name + xmlBase64Suffix},
+			Value: base64.StdEncoding.EncodeToString([]byte(value)),
+		})
+	}
+	return append(attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+// xmlSafe reports whether a string survives an XML 1.0 round trip unchanged
+func xmlSafe(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r == '\t', r == '\n', r == '\r':
+		case r >= 0x20 && r <= 0xD7FF:
+		case r >= 0xE000 && r <= 0xFFFD:
+		case r >= 0x10000 && r <= 0x10FFFF:
+		default:
+			return false
+		}
+	}
+	return true
+}
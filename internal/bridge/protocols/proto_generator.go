@@ -0,0 +1,460 @@
+// proto_generator.go - proto3 file generation from service definitions
+
+package protocols
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Generated proto file layout
+const (
+	ProtoCommonPackage = "common"
+	ProtoCommonFile    = "common.proto"
+	protoGoPackageBase = "github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/protocols/"
+	protoStructImport  = "google/protobuf/struct.proto"
+)
+
+// errorCodes lists error codes in protobuf enum order; number 0 is reserved for unspecified
+var errorCodes = []ErrorCode{
+	ErrorCodeInvalidRequest,
+	ErrorCodeInternalError,
+	ErrorCodeServiceUnavailable,
+	ErrorCodeResourceNotFound,
+	ErrorCodePermissionDenied,
+	ErrorCodeUnauthenticated,
+	ErrorCodeResourceExhausted,
+	ErrorCodeDeadlineExceeded,
+	ErrorCodeAlreadyExists,
+	ErrorCodeFailedPrecondition,
+	ErrorCodeAborted,
+	ErrorCodeOutOfRange,
+	ErrorCodeUnimplemented,
+	ErrorCodeDataLoss,
+	ErrorCodeUnavailable,
+}
+
+// serviceTypes lists service types in protobuf enum order; number 0 is reserved for unspecified
+var serviceTypes = []ServiceType{
+	ServiceTypeAnalyzer,
+	ServiceTypeRisk,
+	ServiceTypeStorage,
+	ServiceTypeMonitor,
+	ServiceTypeAPI,
+	ServiceTypeAuth,
+	ServiceTypeDiscovery,
+}
+
+// protoEnumNumber returns the protobuf enum number of an error code
+func (c ErrorCode) protoEnumNumber() (int32, bool) {
+	if c == "" {
+		return 0, true
+	}
+	for i, code := range errorCodes {
+		if code == c {
+			return int32(i + 1), true
+		}
+	}
+	return 0, false
+}
+
+// setProtoEnumNumber sets an error code from its protobuf enum number
+func (c *ErrorCode) setProtoEnumNumber(n int32) bool {
+	if n < 0 || int(n) > len(errorCodes) {
+		return false
+	}
+	if n == 0 {
+		*c = ""
+	} else {
+		*c = errorCodes[n-1]
+	}
+	return true
+}
+
+// protoEnumNumber returns the protobuf enum number of a service type
+func (t ServiceType) protoEnumNumber() (int32, bool) {
+	if t == "" {
+		return 0, true
+	}
+	for i, serviceType := range serviceTypes {
+		if serviceType == t {
+			return int32(i + 1), true
+		}
+	}
+	return 0, false
+}
+
+// setProtoEnumNumber sets a service type from its protobuf enum number
+func (t *ServiceType) setProtoEnumNumber(n int32) bool {
+	if n < 0 || int(n) > len(serviceTypes) {
+		return false
+	}
+	if n == 0 {
+		*t = ""
+	} else {
+		*t = serviceTypes[n-1]
+	}
+	return true
+}
+
+// protoEnumTypes maps Go enum types to their generated enum names
+var protoEnumTypes = map[reflect.Type]string{
+	reflect.TypeOf(ErrorCode("")):   "ErrorCode",
+	reflect.TypeOf(ServiceType("")): "ServiceType",
+}
+
+// protoGenerator accumulates the message definitions of one proto file
+type protoGenerator struct {
+	pkg      string
+	imports  map[string]bool
+	defined  map[string]bool
+	messages []string
+}
+
+// newProtoGenerator creates a generator for a proto package
+func newProtoGenerator(pkg string) *protoGenerator {
+	return &protoGenerator{
+		pkg:     pkg,
+		imports: make(map[string]bool),
+		defined: make(map[string]bool),
+	}
+}
+
+// wellKnown returns a google.protobuf type name, importing its file
+func (g *protoGenerator) wellKnown(name string) string {
+	g.imports[protoStructImport] = true
+	return "google.protobuf." + name
+}
+
+// common returns a type from the common package, qualified when needed
+func (g *protoGenerator) common(name string) string {
+	if g.pkg == ProtoCommonPackage {
+		return name
+	}
+	g.imports[ProtoCommonFile] = true
+	return ProtoCommonPackage + "." + name
+}
+
+// addMessage records a message definition once
+func (g *protoGenerator) addMessage(name, definition string) {
+	if g.defined[name] {
+		return
+	}
+	g.defined[name] = true
+	g.messages = append(g.messages, definition)
+}
+
+// paramProtoType returns the proto field type for a parameter type, with
+// "repeated " for lists; containers nested in containers fall back to Value
+func (g *protoGenerator) paramProtoType(t paramType) string {
+	switch t.name {
+	case "list":
+		if t.elem.name == "list" || t.elem.name == "map" {
+			return "repeated " + g.wellKnown("Value")
+		}
+		return "repeated " + g.paramProtoType(*t.elem)
+	case "map":
+		if t.elem.name == "list" || t.elem.name == "map" {
+			return "map<string, " + g.wellKnown("Value") + ">"
+		}
+		return "map<string, " + g.paramProtoType(*t.elem) + ">"
+	case "any":
+		return g.wellKnown("Value")
+	}
+
+	if t.isMsg {
+		messageType, _ := LookupMessageType(t.name)
+		g.addGoMessage(t.name, messageType)
+		return t.name
+	}
+
+	return t.name
+}
+
+// addRequestMessage defines a method's input message from its parameters
+func (g *protoGenerator) addRequestMessage(method MethodDefinition) {
+	if messageType, ok := LookupMessageType(method.InputType); ok {
+		g.addGoMessage(method.InputType, messageType)
+		return
+	}
+	if g.defined[method.InputType] {
+		return
+	}
+	g.defined[method.InputType] = true
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// %s is the request for %s\n", method.InputType, method.Name)
+	fmt.Fprintf(&sb, "message %s {\n", method.InputType)
+	for i, param := range method.Params {
+		comment := param.Description
+		if param.Required {
+			comment = strings.TrimSpace(comment + " (required)")
+		}
+		if comment != "" {
+			fmt.Fprintf(&sb, "  // %s\n", comment)
+		}
+		fmt.Fprintf(&sb, "  %s %s = %d;\n", g.paramProtoType(parseParamType(param.Type)), param.Name, i+1)
+	}
+	sb.WriteString("}\n")
+
+	g.messages = append(g.messages, sb.String())
+}
+
+// addResponseMessage defines a method's output message. Outputs without a
+// registered Go type carry free-form fields, encoded like google.protobuf.Struct.
+func (g *protoGenerator) addResponseMessage(method MethodDefinition) {
+	if messageType, ok := LookupMessageType(method.OutputType); ok {
+		g.addGoMessage(method.OutputType, messageType)
+		return
+	}
+
+	g.addMessage(method.OutputType, fmt.Sprintf(
+		"// %s is the response for %s; wire-compatible with google.protobuf.Struct\nmessage %s {\n  map<string, %s> fields = 1;\n}\n",
+		method.OutputType, method.Name, method.OutputType, g.wellKnown("Value")))
+}
+
+// addGoMessage defines a message from a protobuf-tagged Go struct and the
+// messages it references
+func (g *protoGenerator) addGoMessage(name string, t reflect.Type) {
+	if g.defined[name] {
+		return
+	}
+	g.defined[name] = true
+
+	fields, err := protoFields(t)
+	if err != nil {
+		g.messages = append(g.messages, fmt.Sprintf("// %s could not be generated: %v\n", name, err))
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "message %s {\n", name)
+	for _, field := range fields {
+		structField := t.Field(field.index)
+		fmt.Fprintf(&sb, "  %s %s = %d;\n", g.goProtoType(structField.Type), protoFieldName(structField), field.number)
+	}
+	sb.WriteString("}\n")
+
+	g.messages = append(g.messages, sb.String())
+}
+
+// goProtoType returns the proto field type matching how MarshalProto encodes a Go type
+func (g *protoGenerator) goProtoType(t reflect.Type) string {
+	if name, ok := protoEnumTypes[t]; ok {
+		return g.common(name)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.goProtoType(t.Elem())
+	case reflect.Interface:
+		return g.wellKnown("Value")
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int64:
+		return "int64"
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return "int32"
+	case reflect.Uint, reflect.Uint64:
+		return "uint64"
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "uint32"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+		elem := t.Elem()
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		if elem.Kind() == reflect.Slice || elem.Kind() == reflect.Map {
+			return "repeated " + g.wellKnown("Value")
+		}
+		return "repeated " + g.goProtoType(elem)
+
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return g.wellKnown("Struct")
+		}
+		return "map<string, " + g.goProtoType(t.Elem()) + ">"
+
+	case reflect.Struct:
+		name := g.messageName(t)
+		g.addGoMessage(name, t)
+		return name
+	}
+
+	return g.wellKnown("Value")
+}
+
+// messageName returns the registered name of a Go struct type, or its Go name
+func (g *protoGenerator) messageName(t reflect.Type) string {
+	messageTypesMutex.RLock()
+	defer messageTypesMutex.RUnlock()
+
+	for name, registered := range messageTypes {
+		if registered == t {
+			return name
+		}
+	}
+	return t.Name()
+}
+
+// importList returns the files the generated definitions import, sorted
+func (g *protoGenerator) importList() []string {
+	imports := make([]string, 0, len(g.imports))
+	for file := range g.imports {
+		imports = append(imports, file)
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// render writes a complete proto file around the given service block
+func (g *protoGenerator) render(description, service string) string {
+	var sb strings.Builder
+
+	sb.WriteString("// Code generated from the bridge service manifest. DO NOT EDIT.\n\n")
+	sb.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&sb, "package %s;\n\n", g.pkg)
+
+	if imports := g.importList(); len(imports) > 0 {
+		for _, file := range imports {
+			fmt.Fprintf(&sb, "import \"%s\";\n", file)
+		}
+		sb.WriteString("\n")
+	}
+
+	fmt.Fprintf(&sb, "option go_package = \"%s%s\";\n\n", protoGoPackageBase, g.pkg)
+
+	if service != "" {
+		if description != "" {
+			fmt.Fprintf(&sb, "// %s\n", description)
+		}
+		sb.WriteString(service)
+		sb.WriteString("\n")
+	}
+
+	for i, message := range g.messages {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(message)
+	}
+
+	return sb.String()
+}
+
+// renderProtoEnum renders an enum whose values are prefixed with the enum name
+func renderProtoEnum(name string, values []string) string {
+	prefix := strings.ToUpper(toSnakeCase(name)) + "_"
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "enum %s {\n", name)
+	fmt.Fprintf(&sb, "  %sUNSPECIFIED = 0;\n", prefix)
+	for i, value := range values {
+		fmt.Fprintf(&sb, "  %s%s = %d;\n", prefix, strings.ToUpper(value), i+1)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// GenerateCommonProto generates the proto file shared by all services: the
+// ErrorCode and ServiceType enums and the Message envelope
+func GenerateCommonProto() string {
+	g := newProtoGenerator(ProtoCommonPackage)
+
+	codes := make([]string, len(errorCodes))
+	for i, code := range errorCodes {
+		codes[i] = string(code)
+	}
+	g.addMessage("ErrorCode", "// ErrorCode is a standardized error code\n"+renderProtoEnum("ErrorCode", codes))
+
+	types := make([]string, len(serviceTypes))
+	for i, serviceType := range serviceTypes {
+		types[i] = string(serviceType)
+	}
+	g.addMessage("ServiceType", "// ServiceType is the type of a service\n"+renderProtoEnum("ServiceType", types))
+
+	g.addGoMessage("MessageHeader", reflect.TypeOf(MessageHeader{}))
+	g.addGoMessage("ErrorMessage", reflect.TypeOf(ErrorMessage{}))
+
+	g.addMessage("Message", fmt.Sprintf(`// Message is the envelope for every bridge message. The payload holds the
+// method's request message for requests to known methods, and an encoded
+// google.protobuf.Struct otherwise.
+message Message {
+  MessageHeader header = 1;
+  bytes payload = 2;
+  ErrorMessage error = 3;
+  %s meta = 4;
+}
+`, g.wellKnown("Struct")))
+
+	return g.render("", "")
+}
+
+// ProtoPackage returns the proto package of a service
+func (s *ServiceDefinition) ProtoPackage() string {
+	return strings.ToLower(string(s.Type))
+}
+
+// ProtoFileName returns the path of a service's proto file, relative to the proto root
+func (s *ServiceDefinition) ProtoFileName() string {
+	return path.Join(s.ProtoPackage(), toSnakeCase(s.Name)+".proto")
+}
+
+// GenerateProtoFiles generates proto files for every service in the manifest,
+// keyed by path relative to the proto root
+func (m *ServiceManifest) GenerateProtoFiles() map[string]string {
+	files := map[string]string{
+		ProtoCommonFile: GenerateCommonProto(),
+	}
+
+	for i := range m.Services {
+		service := &m.Services[i]
+		files[service.ProtoFileName()] = service.GenerateProto()
+	}
+
+	return files
+}
+
+// protoFieldName returns the proto field name of a struct field, taken from its JSON tag
+func protoFieldName(field reflect.StructField) string {
+	if tag := field.Tag.Get("json"); tag != "" {
+		if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return toSnakeCase(field.Name)
+}
+
+// toSnakeCase converts a CamelCase name to snake_case
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word at a lower-to-upper change or at the end of an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,200 @@
+package protocols
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// updateGolden rewrites the golden files from the generator's output
+var updateGolden = flag.Bool("update", false, "update the golden .proto files in testdata")
+
+// goldenProtoDir holds the expected generator output, one file per generated path
+const goldenProtoDir = "testdata/proto"
+
+func TestGenerateProtoFilesGolden(t *testing.T) {
+	manifest := DefaultServiceManifest()
+	files := manifest.GenerateProtoFiles()
+
+	if *updateGolden {
+		require.NoError(t, os.RemoveAll(goldenProtoDir))
+		for path, content := range files {
+			target := filepath.Join(goldenProtoDir, filepath.FromSlash(path)+".golden")
+			require.NoError(t, os.MkdirAll(filepath.Dir(target), 0755))
+			require.NoError(t, os.WriteFile(target, []byte(content), 0644))
+		}
+	}
+
+	var golden []string
+	require.NoError(t, filepath.Walk(goldenProtoDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(goldenProtoDir, path)
+			golden = append(golden, strings.TrimSuffix(filepath.ToSlash(rel), ".golden"))
+		}
+		return err
+	}))
+
+	generated := make([]string, 0, len(files))
+	for path := range files {
+		generated = append(generated, path)
+	}
+	sort.Strings(generated)
+	require.Equal(t, golden, generated, "generated files differ from %s; rerun with -update", goldenProtoDir)
+
+	for _, path := range generated {
+		want, err := os.ReadFile(filepath.Join(goldenProtoDir, filepath.FromSlash(path)+".golden"))
+		require.NoError(t, err)
+		assert.Equal(t, string(want), files[path], "%s differs from its golden file; rerun with -update", path)
+	}
+
+	// The golden files keep the declarations clients depend on
+	common := files[ProtoCommonFile]
+	for _, declaration := range []string{
+		"enum ErrorCode {\n  ERROR_CODE_UNSPECIFIED = 0;",
+		"enum ServiceType {\n  SERVICE_TYPE_UNSPECIFIED = 0;",
+		"message MessageHeader {",
+		"message ErrorMessage {\n  ErrorCode code = 1;",
+		"message Message {\n  MessageHeader header = 1;",
+	} {
+		assert.Contains(t, common, declaration)
+	}
+
+	analyzer := files["analyzer/token_analyzer.proto"]
+	for _, declaration := range []string{
+		"rpc AnalyzeToken(AnalyzeTokenRequest) returns (AnalyzeTokenResponse);",
+		"message AnalyzeTokenResponse {\n  TokenAnalysisResult result = 1;",
+		"repeated TokenAnalysisResult results = 1;",
+		"repeated TokenFinding findings = 6;",
+		"TokenRiskProfile risk_profile = 8;",
+		"map<string, RiskCategory> risk_categories = 4;",
+		"TokenTransactionHistory transaction_history = 10;",
+		"repeated DailyTransactions transactions_per_day = 3;",
+	} {
+		assert.Contains(t, analyzer, declaration)
+	}
+
+	assert.Contains(t, files["discovery/discovery.proto"], "common.ServiceType type = 4;")
+}
+
+var (
+	protoImportLine = regexp.MustCompile(`^import "([^"]+)";$`)
+	protoBlockLine  = regexp.MustCompile(`^(message|enum|service) (\w+) \{$`)
+	protoFieldLine  = regexp.MustCompile(`^(?:repeated )?(map<\w+, ([\w.]+)>|[\w.]+) (\w+) = (\d+);$`)
+	protoValueLine  = regexp.MustCompile(`^(\w+) = (\d+);$`)
+	protoRPCLine    = regexp.MustCompile(`^rpc \w+\((\w+)\) returns \((?:stream )?(\w+)\);$`)
+)
+
+// protoScalars are the proto3 scalar field types
+var protoScalars = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true, "sfixed32": true, "sfixed64": true,
+	"bool": true, "string": true, "bytes": true,
+}
+
+// TestGenerateProtoFilesWellFormed checks the generated files with a small line
+// scanner, as no .proto parser is available to the build: blocks balance, every
+// import is generated or well known, field and enum numbers are unique, enums
+// start at zero, and every referenced type is declared
+func TestGenerateProtoFilesWellFormed(t *testing.T) {
+	manifest := DefaultServiceManifest()
+	files := manifest.GenerateProtoFiles()
+
+	// Types declared per package, for references qualified with a package name
+	declared := make(map[string]map[string]bool)
+	for _, content := range files {
+		pkg := ""
+		types := make(map[string]bool)
+		for _, line := range strings.Split(content, "\n") {
+			if strings.HasPrefix(line, "package ") {
+				pkg = strings.TrimSuffix(strings.TrimPrefix(line, "package "), ";")
+			}
+			if match := protoBlockLine.FindStringSubmatch(line); match != nil && match[1] != "service" {
+				types[match[2]] = true
+			}
+		}
+		declared[pkg] = types
+	}
+
+	resolves := func(pkg, typeName string) bool {
+		if protoScalars[typeName] || strings.HasPrefix(typeName, "google.protobuf.") {
+			return true
+		}
+		if dot := strings.LastIndex(typeName, "."); dot >= 0 {
+			return declared[typeName[:dot]][typeName[dot+1:]]
+		}
+		return declared[pkg][typeName]
+	}
+
+	for path, content := range files {
+		t.Run(path, func(t *testing.T) {
+			lines := strings.Split(content, "\n")
+			require.Contains(t, lines, `syntax = "proto3";`)
+
+			pkg := ""
+			var block, name string
+			var numbers map[string]bool
+			depth := 0
+
+			for i, raw := range lines {
+				line := strings.TrimSpace(raw)
+				if line == "" || strings.HasPrefix(line, "//") {
+					continue
+				}
+				where := fmt.Sprintf("%s:%d", path, i+1)
+
+				switch {
+				case strings.HasPrefix(line, "package "):
+					pkg = strings.TrimSuffix(strings.TrimPrefix(line, "package "), ";")
+				case protoImportLine.MatchString(line):
+					imported := protoImportLine.FindStringSubmatch(line)[1]
+					_, generated := files[imported]
+					assert.True(t, generated || strings.HasPrefix(imported, "google/protobuf/"), "%s: unknown import %q", where, imported)
+				case protoBlockLine.MatchString(line):
+					require.Zero(t, depth, "%s: nested block", where)
+					match := protoBlockLine.FindStringSubmatch(line)
+					block, name, numbers = match[1], match[2], make(map[string]bool)
+					depth++
+				case line == "}":
+					depth--
+					require.GreaterOrEqual(t, depth, 0, "%s: unbalanced block", where)
+				case depth == 0:
+					assert.Regexp(t, `^(syntax|option) `, line, "%s: unexpected top-level statement", where)
+				case block == "message":
+					match := protoFieldLine.FindStringSubmatch(line)
+					require.NotNil(t, match, "%s: malformed field in %s: %q", where, name, line)
+					typeName := match[1]
+					if match[2] != "" {
+						typeName = match[2]
+					}
+					assert.True(t, resolves(pkg, typeName), "%s: %s references undeclared type %q", where, name, typeName)
+					assert.False(t, numbers[match[4]], "%s: %s reuses field number %s", where, name, match[4])
+					assert.NotEqual(t, "0", match[4], "%s: %s uses field number 0", where, name)
+					numbers[match[4]] = true
+				case block == "enum":
+					match := protoValueLine.FindStringSubmatch(line)
+					require.NotNil(t, match, "%s: malformed enum value in %s: %q", where, name, line)
+					if len(numbers) == 0 {
+						assert.Equal(t, "0", match[2], "%s: %s does not start at zero", where, name)
+					}
+					assert.False(t, numbers[match[2]], "%s: %s reuses value %s", where, name, match[2])
+					numbers[match[2]] = true
+				case block == "service":
+					match := protoRPCLine.FindStringSubmatch(line)
+					require.NotNil(t, match, "%s: malformed rpc in %s: %q", where, name, line)
+					assert.True(t, resolves(pkg, match[1]), "%s: undeclared request type %q", where, match[1])
+					assert.True(t, resolves(pkg, match[2]), "%s: undeclared response type %q", where, match[2])
+				}
+			}
+			assert.Zero(t, depth, "unbalanced blocks")
+			assert.NotEmpty(t, pkg, "no package")
+		})
+	}
+}
@@ -0,0 +1,935 @@
+// protobuf_codec.go - Binary protobuf protocol plugin for bridge messages
+
+package protocols
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Message field numbers, matching the protobuf tags on ProtobufMessage
+const (
+	messageHeaderField  protowire.Number = 1
+	messagePayloadField protowire.Number = 2
+	messageErrorField   protowire.Number = 3
+	messageMetaField    protowire.Number = 4
+)
+
+// Protocol message headers carried in MessageHeader fields
+const (
+	HeaderMessageType   = "message_type"
+	HeaderVersion       = "version"
+	HeaderCorrelationID = "correlation_id"
+	HeaderSource        = "source"
+	HeaderDestination   = "destination"
+	HeaderTraceID       = "trace_id"
+	HeaderServiceName   = "service_name"
+	HeaderServiceType   = "service_type"
+)
+
+// Meta keys for protocol message fields without a MessageHeader equivalent
+const (
+	metaType     = "type"
+	metaHeaders  = "headers"
+	metaMetadata = "metadata"
+	metaScalar   = "scalar" // The payload was wrapped as {"value": x}
+)
+
+// messageTypes maps message type names used in parameter definitions to Go types
+var (
+	messageTypes      = make(map[string]reflect.Type)
+	messageTypesMutex sync.RWMutex
+)
+
+func init() {
+	RegisterMessageType("ServiceInfo", ServiceInfo{})
+	RegisterMessageType("TokenAnalysisResult", TokenAnalysisResult{})
+	RegisterMessageType("AnalyzeTokenResponse", AnalyzeTokenResponse{})
+	RegisterMessageType("BatchAnalyzeTokensResponse", BatchAnalyzeTokensResponse{})
+	RegisterMessageType("DiscoverServicesResponse", DiscoverServicesResponse{})
+	RegisterMessageType("TokenFinding", TokenFinding{})
+	RegisterMessageType("TokenRecommendation", TokenRecommendation{})
+	RegisterMessageType("TokenRecommendationAction", TokenRecommendationAction{})
+	RegisterMessageType("TokenRiskProfile", TokenRiskProfile{})
+	RegisterMessageType("RiskCategory", RiskCategory{})
+	RegisterMessageType("HistoricalRiskEntry", HistoricalRiskEntry{})
+	RegisterMessageType("TokenContractInfo", TokenContractInfo{})
+	RegisterMessageType("TokenTransactionHistory", TokenTransactionHistory{})
+	RegisterMessageType("DailyTransactions", DailyTransactions{})
+	RegisterMessageType("TokenTransaction", TokenTransaction{})
+	RegisterMessageType("TokenVolumeStats", TokenVolumeStats{})
+	RegisterMessageType("HolderBucket", HolderBucket{})
+	RegisterMessageType("ErrorMessage", ErrorMessage{})
+	RegisterMessageType("MessageHeader", MessageHeader{})
+}
+
+// RegisterMessageType makes a protobuf-tagged struct usable as a parameter type by name
+func RegisterMessageType(name string, prototype interface{}) {
+	t := reflect.TypeOf(prototype)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	messageTypesMutex.Lock()
+	defer messageTypesMutex.Unlock()
+	messageTypes[name] = t
+}
+
+// LookupMessageType returns the Go type registered for a message type name
+func LookupMessageType(name string) (reflect.Type, bool) {
+	messageTypesMutex.RLock()
+	defer messageTypesMutex.RUnlock()
+	t, ok := messageTypes[name]
+	return t, ok
+}
+
+// paramType is a parsed ParameterDefinition type such as "string",
+// "list<string>" or "map<string, any>"
+type paramType struct {
+	name  string // scalar or message type name; "list" or "map" for containers
+	elem  *paramType
+	isMsg bool
+}
+
+// parseParamType parses a parameter type string
+func parseParamType(s string) paramType {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(s)
+
+	switch {
+	case strings.HasPrefix(lower, "list<") && strings.HasSuffix(lower, ">"):
+		elem := parseParamType(s[len("list<") : len(s)-1])
+		return paramType{name: "list", elem: &elem}
+
+	case strings.HasPrefix(lower, "repeated "):
+		elem := parseParamType(s[len("repeated "):])
+		return paramType{name: "list", elem: &elem}
+
+	case strings.HasPrefix(lower, "map<") && strings.HasSuffix(lower, ">"):
+		inner := s[len("map<") : len(s)-1]
+		value := "any"
+		if comma := strings.Index(inner, ","); comma >= 0 {
+			value = inner[comma+1:]
+		}
+		elem := parseParamType(value)
+		return paramType{name: "map", elem: &elem}
+	}
+
+	switch lower {
+	case "string", "bool", "bytes", "any":
+		return paramType{name: lower}
+	case "int", "int32", "sint32", "sfixed32":
+		return paramType{name: "int32"}
+	case "int64", "sint64", "sfixed64", "long":
+		return paramType{name: "int64"}
+	case "uint", "uint32", "fixed32":
+		return paramType{name: "uint32"}
+	case "uint64", "fixed64":
+		return paramType{name: "uint64"}
+	case "float":
+		return paramType{name: "float"}
+	case "double", "number":
+		return paramType{name: "double"}
+	}
+
+	if _, ok := LookupMessageType(s); ok {
+		return paramType{name: s, isMsg: true}
+	}
+
+	// Unknown message types travel as google.protobuf.Value
+	return paramType{name: "any"}
+}
+
+// EncodeParams encodes a payload as the message described by a method's
+// parameters. Field numbers follow parameter order, starting at 1.
+func EncodeParams(params []ParameterDefinition, payload map[string]any) ([]byte, error) {
+	known := make(map[string]bool, len(params))
+	var b []byte
+
+	for i, param := range params {
+		known[param.Name] = true
+
+		value, present := payload[param.Name]
+		if !present || value == nil {
+			continue
+		}
+
+		var err error
+		b, err = appendParam(b, protowire.Number(i+1), parseParamType(param.Type), value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s': %w", param.Name, err)
+		}
+	}
+
+	for name := range payload {
+		if !known[name] {
+			return nil, fmt.Errorf("payload field '%s' is not a defined parameter", name)
+		}
+	}
+
+	return b, nil
+}
+
+// DecodeParams decodes a message described by a method's parameters into a payload map
+func DecodeParams(params []ParameterDefinition, data []byte) (map[string]any, error) {
+	payload := make(map[string]any)
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		index := int(num) - 1
+		if index < 0 || index >= len(params) {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+
+		param := params[index]
+		n, err := consumeParam(data, typ, parseParamType(param.Type), param.Name, payload)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s': %w", param.Name, err)
+		}
+		data = data[n:]
+	}
+
+	return payload, nil
+}
+
+// appendParam appends a parameter value of the given type
+func appendParam(b []byte, num protowire.Number, t paramType, value interface{}) ([]byte, error) {
+	switch t.name {
+	case "list":
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, fmt.Errorf("expected a list, got %T", value)
+		}
+
+		if wire, packable := paramWireType(*t.elem); packable {
+			var packed []byte
+			for i := 0; i < v.Len(); i++ {
+				var err error
+				packed, err = appendParamScalar(packed, *t.elem, wire, v.Index(i).Interface())
+				if err != nil {
+					return nil, fmt.Errorf("element %d: %w", i, err)
+				}
+			}
+			return appendEmbedded(b, num, packed), nil
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			b, err = appendParam(b, num, *t.elem, v.Index(i).Interface())
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return b, nil
+
+	case "map":
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("expected a string-keyed map, got %T", value)
+		}
+
+		for _, key := range sortedMapKeys(v) {
+			entry := protowire.AppendTag(nil, 1, protowire.BytesType)
+			entry = protowire.AppendString(entry, key.String())
+			entry, err := appendParam(entry, 2, *t.elem, v.MapIndex(key).Interface())
+			if err != nil {
+				return nil, fmt.Errorf("key '%s': %w", key.String(), err)
+			}
+			b = appendEmbedded(b, num, entry)
+		}
+		return b, nil
+
+	case "any":
+		message, err := appendValue(nil, reflect.ValueOf(&value).Elem())
+		if err != nil {
+			return nil, err
+		}
+		return appendEmbedded(b, num, message), nil
+	}
+
+	if t.isMsg {
+		message, err := marshalNamedMessage(t.name, value)
+		if err != nil {
+			return nil, err
+		}
+		return appendEmbedded(b, num, message), nil
+	}
+
+	wire, _ := paramWireType(t)
+	b = protowire.AppendTag(b, num, wire)
+	return appendParamScalar(b, t, wire, value)
+}
+
+// paramWireType returns the wire type of a scalar parameter type and whether it can be packed
+func paramWireType(t paramType) (protowire.Type, bool) {
+	switch t.name {
+	case "bool", "int32", "int64", "uint32", "uint64":
+		return protowire.VarintType, true
+	case "float":
+		return protowire.Fixed32Type, true
+	case "double":
+		return protowire.Fixed64Type, true
+	case "string", "bytes":
+		return protowire.BytesType, false
+	default:
+		return protowire.BytesType, false
+	}
+}
+
+// appendParamScalar appends a scalar value without its tag, converting JSON-decoded values
+func appendParamScalar(b []byte, t paramType, wire protowire.Type, value interface{}) ([]byte, error) {
+	switch t.name {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			v := reflect.ValueOf(value)
+			if v.Kind() != reflect.String {
+				return nil, fmt.Errorf("expected a string, got %T", value)
+			}
+			s = v.String()
+		}
+		return protowire.AppendString(b, s), nil
+
+	case "bytes":
+		switch x := value.(type) {
+		case []byte:
+			return protowire.AppendBytes(b, x), nil
+		case string:
+			data, err := base64.StdEncoding.DecodeString(x)
+			if err != nil {
+				return nil, fmt.Errorf("expected base64 bytes: %w", err)
+			}
+			return protowire.AppendBytes(b, data), nil
+		default:
+			return nil, fmt.Errorf("expected bytes, got %T", value)
+		}
+
+	case "bool":
+		x, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a bool, got %T", value)
+		}
+		return protowire.AppendVarint(b, protowire.EncodeBool(x)), nil
+
+	case "int32", "int64":
+		x, err := toInt64(value)
+		if err != nil {
+			return nil, err
+		}
+		if t.name == "int32" && (x < math.MinInt32 || x > math.MaxInt32) {
+			return nil, fmt.Errorf("value %d out of range for int32", x)
+		}
+		return protowire.AppendVarint(b, uint64(x)), nil
+
+	case "uint32", "uint64":
+		x, err := toInt64(value)
+		if err != nil {
+			return nil, err
+		}
+		if x < 0 || (t.name == "uint32" && x > math.MaxUint32) {
+			return nil, fmt.Errorf("value %d out of range for %s", x, t.name)
+		}
+		return protowire.AppendVarint(b, uint64(x)), nil
+
+	case "float", "double":
+		x, err := toFloat64(value)
+		if err != nil {
+			return nil, err
+		}
+		if wire == protowire.Fixed32Type {
+			return protowire.AppendFixed32(b, math.Float32bits(float32(x))), nil
+		}
+		return protowire.AppendFixed64(b, math.Float64bits(x)), nil
+	}
+
+	return nil, fmt.Errorf("%w: parameter type %s", ErrProtoUnsupported, t.name)
+}
+
+// consumeParam decodes one occurrence of a parameter field into payload[name]
+func consumeParam(b []byte, typ protowire.Type, t paramType, name string, payload map[string]any) (int, error) {
+	switch t.name {
+	case "list":
+		list, _ := payload[name].([]interface{})
+
+		if wire, packable := paramWireType(*t.elem); packable && typ == protowire.BytesType {
+			data, n, err := consumeEmbedded(b, typ)
+			if err != nil {
+				return 0, err
+			}
+			for len(data) > 0 {
+				value, m, err := consumeParamScalar(data, wire, *t.elem)
+				if err != nil {
+					return 0, err
+				}
+				list = append(list, value)
+				data = data[m:]
+			}
+			payload[name] = list
+			return n, nil
+		}
+
+		element := make(map[string]any)
+		n, err := consumeParam(b, typ, *t.elem, "", element)
+		if err != nil {
+			return 0, err
+		}
+		payload[name] = append(list, element[""])
+		return n, nil
+
+	case "map":
+		data, n, err := consumeEmbedded(b, typ)
+		if err != nil {
+			return 0, err
+		}
+
+		fields, _ := payload[name].(map[string]interface{})
+		if fields == nil {
+			fields = make(map[string]interface{})
+		}
+
+		entry := make(map[string]any)
+		var key string
+		for len(data) > 0 {
+			num, entryType, m := protowire.ConsumeTag(data)
+			if m < 0 {
+				return 0, protowire.ParseError(m)
+			}
+			data = data[m:]
+
+			switch num {
+			case 1:
+				key, m = protowire.ConsumeString(data)
+				if m < 0 {
+					return 0, protowire.ParseError(m)
+				}
+			case 2:
+				m, err = consumeParam(data, entryType, *t.elem, "value", entry)
+				if err != nil {
+					return 0, err
+				}
+			default:
+				m = protowire.ConsumeFieldValue(num, entryType, data)
+				if m < 0 {
+					return 0, protowire.ParseError(m)
+				}
+			}
+			data = data[m:]
+		}
+
+		fields[key] = entry["value"]
+		payload[name] = fields
+		return n, nil
+
+	case "any":
+		data, n, err := consumeEmbedded(b, typ)
+		if err != nil {
+			return 0, err
+		}
+		value, err := consumeValue(data)
+		if err != nil {
+			return 0, err
+		}
+		payload[name] = value
+		return n, nil
+	}
+
+	if t.isMsg {
+		data, n, err := consumeEmbedded(b, typ)
+		if err != nil {
+			return 0, err
+		}
+		value, err := unmarshalNamedMessage(t.name, data)
+		if err != nil {
+			return 0, err
+		}
+		payload[name] = value
+		return n, nil
+	}
+
+	value, n, err := consumeParamScalar(b, typ, t)
+	if err != nil {
+		return 0, err
+	}
+	payload[name] = value
+	return n, nil
+}
+
+// consumeParamScalar decodes a scalar parameter value
+func consumeParamScalar(b []byte, typ protowire.Type, t paramType) (interface{}, int, error) {
+	want, _ := paramWireType(t)
+	if typ != want {
+		return nil, 0, fmt.Errorf("%w: %d for %s", ErrProtoWireType, typ, t.name)
+	}
+
+	switch t.name {
+	case "string":
+		s, n := protowire.ConsumeString(b)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+		return s, n, nil
+
+	case "bytes":
+		data, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+		return append([]byte(nil), data...), n, nil
+
+	case "float":
+		x, n := protowire.ConsumeFixed32(b)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+		return float64(math.Float32frombits(x)), n, nil
+
+	case "double":
+		x, n := protowire.ConsumeFixed64(b)
+		if n < 0 {
+			return nil, 0, protowire.ParseError(n)
+		}
+		return math.Float64frombits(x), n, nil
+	}
+
+	x, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return nil, 0, protowire.ParseError(n)
+	}
+
+	switch t.name {
+	case "bool":
+		return protowire.DecodeBool(x), n, nil
+	case "int32":
+		return int64(int32(x)), n, nil
+	case "int64":
+		return int64(x), n, nil
+	default:
+		return x, n, nil
+	}
+}
+
+// marshalNamedMessage encodes a value as the registered message type with the given name
+func marshalNamedMessage(name string, value interface{}) ([]byte, error) {
+	t, _ := LookupMessageType(name)
+
+	message := reflect.New(t)
+	if reflect.TypeOf(value) == t || reflect.TypeOf(value) == message.Type() {
+		return MarshalProto(value)
+	}
+
+	// Payloads decoded from JSON arrive as maps; convert through the JSON shape
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, message.Interface()); err != nil {
+		return nil, fmt.Errorf("value does not match message %s: %w", name, err)
+	}
+	return MarshalProto(message.Interface())
+}
+
+// unmarshalNamedMessage decodes a registered message type into its JSON-shaped map
+func unmarshalNamedMessage(name string, data []byte) (interface{}, error) {
+	t, _ := LookupMessageType(name)
+
+	message := reflect.New(t)
+	if err := UnmarshalProto(data, message.Interface()); err != nil {
+		return nil, err
+	}
+	return toGeneric(message.Interface())
+}
+
+// toInt64 converts JSON-decoded and native numbers to an integer
+func toInt64(value interface{}) (int64, error) {
+	switch x := value.(type) {
+	case json.Number:
+		return x.Int64()
+	case float64:
+		if x != math.Trunc(x) {
+			return 0, fmt.Errorf("expected an integer, got %v", x)
+		}
+		return int64(x), nil
+	case float32:
+		if float64(x) != math.Trunc(float64(x)) {
+			return 0, fmt.Errorf("expected an integer, got %v", x)
+		}
+		return int64(x), nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), nil
+	}
+	return 0, fmt.Errorf("expected an integer, got %T", value)
+}
+
+// toFloat64 converts JSON-decoded and native numbers to a float
+func toFloat64(value interface{}) (float64, error) {
+	if number, ok := value.(json.Number); ok {
+		return number.Float64()
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	}
+	return 0, fmt.Errorf("expected a number, got %T", value)
+}
+
+// ProtobufCodec encodes bridge protocol messages as Message in the protobuf
+// wire format. Request payloads for methods in the manifest are encoded as
+// the method's parameter message; other payloads as google.protobuf.Struct.
+type ProtobufCodec struct {
+	methods map[string]*MethodDefinition
+}
+
+// NewProtobufCodec creates a codec for the services in a manifest
+func NewProtobufCodec(manifest *ServiceManifest) *ProtobufCodec {
+	codec := &ProtobufCodec{
+		methods: make(map[string]*MethodDefinition),
+	}
+
+	if manifest != nil {
+		for i := range manifest.Services {
+			service := &manifest.Services[i]
+			for j := range service.Methods {
+				method := &service.Methods[j]
+				codec.methods[method.Name] = method
+				codec.methods[service.Name+"."+method.Name] = method
+			}
+		}
+	}
+
+	return codec
+}
+
+// method returns the method a message type refers to, accepting "Method",
+// "Service.Method" and gRPC-style "/package.Service/Method" names
+func (c *ProtobufCodec) method(messageType string) *MethodDefinition {
+	if method, ok := c.methods[messageType]; ok {
+		return method
+	}
+	if slash := strings.LastIndex(messageType, "/"); slash >= 0 {
+		return c.methods[messageType[slash+1:]]
+	}
+	return nil
+}
+
+// paramsFor returns the parameters describing a request payload, if any
+func (c *ProtobufCodec) paramsFor(messageType string, kind MessageType) []ParameterDefinition {
+	if kind != MessageTypeRequest {
+		return nil
+	}
+	if method := c.method(messageType); method != nil {
+		return method.Params
+	}
+	return nil
+}
+
+// namedMessageFor returns the registered message type of a payload, if any:
+// the method's input type for requests and its output type for responses
+func (c *ProtobufCodec) namedMessageFor(messageType string, kind MessageType) string {
+	method := c.method(messageType)
+	if method == nil {
+		return ""
+	}
+
+	var name string
+	switch kind {
+	case MessageTypeRequest:
+		name = method.InputType
+	case MessageTypeResponse:
+		name = method.OutputType
+	default:
+		return ""
+	}
+
+	if _, ok := LookupMessageType(name); ok {
+		return name
+	}
+	return ""
+}
+
+// Marshal encodes a protocol message
+func (c *ProtobufCodec) Marshal(message *plugins.ProtocolMessage) ([]byte, error) {
+	header := MessageHeader{
+		MessageID:     message.ID,
+		Version:       APIVersion(message.Headers[HeaderVersion]),
+		Type:          MessageType(message.Headers[HeaderMessageType]),
+		Timestamp:     message.Timestamp.UnixNano() / int64(time.Millisecond),
+		CorrelationID: message.Headers[HeaderCorrelationID],
+		Source:        message.Headers[HeaderSource],
+		Destination:   message.Headers[HeaderDestination],
+		TraceID:       message.Headers[HeaderTraceID],
+		ServiceName:   message.Headers[HeaderServiceName],
+		ServiceType:   ServiceType(message.Headers[HeaderServiceType]),
+	}
+	if header.Version == "" {
+		header.Version = APIVersionV1
+	}
+
+	var errorMessage *ErrorMessage
+	switch payload := message.Payload.(type) {
+	case *ErrorMessage:
+		errorMessage = payload
+	case ErrorMessage:
+		errorMessage = &payload
+	}
+	if header.Type == "" {
+		header.Type = MessageTypeRequest
+		if errorMessage != nil {
+			header.Type = MessageTypeError
+		}
+	}
+
+	headerBytes, err := MarshalProto(&header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode header: %w", err)
+	}
+	b := appendEmbedded(nil, messageHeaderField, headerBytes)
+
+	if errorMessage != nil {
+		errorBytes, err := MarshalProto(errorMessage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode error: %w", err)
+		}
+		b = appendEmbedded(b, messageErrorField, errorBytes)
+	}
+
+	meta := c.meta(message)
+	if errorMessage == nil && message.Payload != nil {
+		payload, wrapped, err := c.encodePayload(message.Type, header.Type, message.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode payload: %w", err)
+		}
+		b = appendEmbedded(b, messagePayloadField, payload)
+		if wrapped {
+			meta[metaScalar] = true
+		}
+	}
+
+	if len(meta) > 0 {
+		metaBytes, err := appendStructValue(nil, reflect.ValueOf(meta))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode meta: %w", err)
+		}
+		b = appendEmbedded(b, messageMetaField, metaBytes)
+	}
+
+	return b, nil
+}
+
+// encodePayload encodes a payload as the method's parameter message or a
+// Struct. Non-object payloads are wrapped as {"value": x} to fit; the second
+// result reports that, so Unmarshal can unwrap them.
+func (c *ProtobufCodec) encodePayload(messageType string, kind MessageType, payload interface{}) ([]byte, bool, error) {
+	if name := c.namedMessageFor(messageType, kind); name != "" {
+		data, err := marshalNamedMessage(name, payload)
+		return data, false, err
+	}
+
+	wrapped := false
+	fields, ok := payload.(map[string]interface{})
+	if !ok {
+		generic, err := toGeneric(payload)
+		if err != nil {
+			return nil, false, err
+		}
+		if fields, ok = generic.(map[string]interface{}); !ok {
+			fields = map[string]interface{}{"value": generic}
+			wrapped = true
+		}
+	}
+
+	if params := c.paramsFor(messageType, kind); params != nil {
+		data, err := EncodeParams(params, fields)
+		return data, wrapped, err
+	}
+	data, err := appendStructValue(nil, reflect.ValueOf(fields))
+	return data, wrapped, err
+}
+
+// meta collects the protocol message fields that MessageHeader cannot carry
+func (c *ProtobufCodec) meta(message *plugins.ProtocolMessage) map[string]interface{} {
+	meta := make(map[string]interface{})
+
+	if message.Type != "" {
+		meta[metaType] = message.Type
+	}
+
+	headers := make(map[string]interface{})
+	for key, value := range message.Headers {
+		switch key {
+		case HeaderVersion, HeaderMessageType, HeaderCorrelationID, HeaderSource,
+			HeaderDestination, HeaderTraceID, HeaderServiceName, HeaderServiceType:
+			continue
+		}
+		headers[key] = value
+	}
+	if len(headers) > 0 {
+		meta[metaHeaders] = headers
+	}
+
+	if len(message.Metadata) > 0 {
+		meta[metaMetadata] = message.Metadata
+	}
+
+	return meta
+}
+
+// Unmarshal decodes a protocol message
+func (c *ProtobufCodec) Unmarshal(data []byte, message *plugins.ProtocolMessage) error {
+	var header MessageHeader
+	var payload, errorBytes []byte
+	var meta map[string]interface{}
+	hasPayload := false
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+
+		field, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch num {
+		case messageHeaderField:
+			if err := UnmarshalProto(field, &header); err != nil {
+				return fmt.Errorf("failed to decode header: %w", err)
+			}
+		case messagePayloadField:
+			payload = field
+			hasPayload = true
+		case messageErrorField:
+			errorBytes = field
+		case messageMetaField:
+			var err error
+			if meta, err = consumeStructValue(field); err != nil {
+				return fmt.Errorf("failed to decode meta: %w", err)
+			}
+		}
+	}
+
+	message.ID = header.MessageID
+	message.Timestamp = time.Unix(0, header.Timestamp*int64(time.Millisecond))
+	message.Headers = make(map[string]string)
+	setHeader(message.Headers, HeaderVersion, string(header.Version))
+	setHeader(message.Headers, HeaderMessageType, string(header.Type))
+	setHeader(message.Headers, HeaderCorrelationID, header.CorrelationID)
+	setHeader(message.Headers, HeaderSource, header.Source)
+	setHeader(message.Headers, HeaderDestination, header.Destination)
+	setHeader(message.Headers, HeaderTraceID, header.TraceID)
+	setHeader(message.Headers, HeaderServiceName, header.ServiceName)
+	setHeader(message.Headers, HeaderServiceType, string(header.ServiceType))
+
+	if messageType, ok := meta[metaType].(string); ok {
+		message.Type = messageType
+	}
+	if headers, ok := meta[metaHeaders].(map[string]interface{}); ok {
+		for key, value := range headers {
+			if s, ok := value.(string); ok {
+				message.Headers[key] = s
+			}
+		}
+	}
+	if metadata, ok := meta[metaMetadata].(map[string]interface{}); ok {
+		message.Metadata = metadata
+	}
+
+	switch {
+	case errorBytes != nil:
+		errorMessage := &ErrorMessage{}
+		if err := UnmarshalProto(errorBytes, errorMessage); err != nil {
+			return fmt.Errorf("failed to decode error: %w", err)
+		}
+		message.Payload = errorMessage
+
+	case hasPayload:
+		if name := c.namedMessageFor(message.Type, header.Type); name != "" {
+			value, err := unmarshalNamedMessage(name, payload)
+			if err != nil {
+				return fmt.Errorf("failed to decode payload: %w", err)
+			}
+			message.Payload = value
+		} else if params := c.paramsFor(message.Type, header.Type); params != nil {
+			fields, err := DecodeParams(params, payload)
+			if err != nil {
+				return fmt.Errorf("failed to decode payload: %w", err)
+			}
+			message.Payload = fields
+		} else {
+			fields, err := consumeStructValue(payload)
+			if err != nil {
+				return fmt.Errorf("failed to decode payload: %w", err)
+			}
+			message.Payload = fields
+		}
+
+		if scalar, _ := meta[metaScalar].(bool); scalar {
+			if fields, ok := message.Payload.(map[string]interface{}); ok {
+				message.Payload = fields["value"]
+			}
+		}
+	}
+
+	return nil
+}
+
+// setHeader sets a header when the value is not empty
+func setHeader(headers map[string]string, key, value string) {
+	if value != "" {
+		headers[key] = value
+	}
+}
+
+// CreateProtobufProtocolPlugin creates a protocol plugin that speaks the
+// protobuf wire format for the services in a manifest
+func CreateProtobufProtocolPlugin(id string, manifest *ServiceManifest) *plugins.ProtocolPlugin {
+	plugin := plugins.NewProtocolPlugin(id, plugins.ProtocolProtobuf, "1.0", "application/x-protobuf",
+		plugins.WithCapabilities(plugins.CapabilityEncode, plugins.CapabilityDecode, plugins.CapabilityStreaming))
+
+	plugin.SetCodec(NewProtobufCodec(manifest))
+	NewValidator(manifest).Attach(plugin)
+	return plugin
+}
@@ -0,0 +1,112 @@
+package protocols
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTrip encodes and decodes a message with the codec
+func roundTrip(t *testing.T, codec *ProtobufCodec, message *plugins.ProtocolMessage) *plugins.ProtocolMessage {
+	t.Helper()
+
+	data, err := codec.Marshal(message)
+	require.NoError(t, err)
+
+	decoded := &plugins.ProtocolMessage{}
+	require.NoError(t, codec.Unmarshal(data, decoded))
+	return decoded
+}
+
+func TestProtobufCodecPayloadRoundTrip(t *testing.T) {
+	codec := NewProtobufCodec(nil)
+
+	tests := []struct {
+		name    string
+		payload interface{}
+		want    interface{}
+	}{
+		{"string", "hello", "hello"},
+		{"number", 42.5, 42.5},
+		{"integer", 7, float64(7)},
+		{"boolean", true, true},
+		{"list", []interface{}{"a", 1.0, false}, []interface{}{"a", 1.0, false}},
+		{"object", map[string]interface{}{"name": "quant", "count": 3.0}, map[string]interface{}{"name": "quant", "count": 3.0}},
+		{"object with value key", map[string]interface{}{"value": 1.0}, map[string]interface{}{"value": 1.0}},
+		{"struct", struct {
+			Name string `json:"name"`
+		}{"quant"}, map[string]interface{}{"name": "quant"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded := roundTrip(t, codec, &plugins.ProtocolMessage{
+				ID:      "msg-1",
+				Type:    "Echo",
+				Payload: tt.payload,
+			})
+			assert.Equal(t, tt.want, decoded.Payload)
+			assert.NotContains(t, decoded.Metadata, metaScalar)
+		})
+	}
+}
+
+func TestProtobufCodecEnvelopeRoundTrip(t *testing.T) {
+	codec := NewProtobufCodec(nil)
+	timestamp := time.UnixMilli(1700000000123)
+
+	decoded := roundTrip(t, codec, &plugins.ProtocolMessage{
+		ID:   "msg-2",
+		Type: "Echo",
+		Headers: map[string]string{
+			HeaderCorrelationID: "corr-1",
+			HeaderTraceID:       "trace-1",
+			"x-tenant":          "acme",
+		},
+		Metadata:  map[string]interface{}{"attempt": 2.0},
+		Payload:   "ping",
+		Timestamp: timestamp,
+	})
+
+	assert.Equal(t, "msg-2", decoded.ID)
+	assert.Equal(t, "Echo", decoded.Type)
+	assert.True(t, timestamp.Equal(decoded.Timestamp))
+	assert.Equal(t, "corr-1", decoded.Headers[HeaderCorrelationID])
+	assert.Equal(t, "trace-1", decoded.Headers[HeaderTraceID])
+	assert.Equal(t, "acme", decoded.Headers["x-tenant"])
+	assert.Equal(t, string(MessageTypeRequest), decoded.Headers[HeaderMessageType])
+	assert.Equal(t, map[string]interface{}{"attempt": 2.0}, decoded.Metadata)
+	assert.Equal(t, "ping", decoded.Payload)
+}
+
+func TestProtobufCodecErrorRoundTrip(t *testing.T) {
+	codec := NewProtobufCodec(nil)
+
+	decoded := roundTrip(t, codec, &plugins.ProtocolMessage{
+		ID:      "msg-3",
+		Payload: &ErrorMessage{Code: ErrorCodeInvalidRequest, Message: "bad input"},
+	})
+
+	assert.Equal(t, string(MessageTypeError), decoded.Headers[HeaderMessageType])
+	require.IsType(t, &ErrorMessage{}, decoded.Payload)
+	assert.Equal(t, ErrorCodeInvalidRequest, decoded.Payload.(*ErrorMessage).Code)
+	assert.Equal(t, "bad input", decoded.Payload.(*ErrorMessage).Message)
+}
+
+func TestProtobufCodecMethodParamsRoundTrip(t *testing.T) {
+	manifest := DefaultServiceManifest()
+	codec := NewProtobufCodec(&manifest)
+
+	decoded := roundTrip(t, codec, &plugins.ProtocolMessage{
+		ID:      "msg-4",
+		Type:    "AnalyzeToken",
+		Payload: map[string]interface{}{"token_address": "0xabc"},
+	})
+
+	payload, ok := decoded.Payload.(map[string]interface{})
+	require.True(t, ok, "payload decoded as %T", decoded.Payload)
+	assert.Equal(t, "0xabc", payload["token_address"])
+}
@@ -0,0 +1,819 @@
+// protobuf_wire.go - Protocol Buffers wire encoding driven by protobuf struct tags
+
+package protocols
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Common protobuf encoding errors
+var (
+	ErrProtoUnsupported = errors.New("type cannot be encoded as protobuf")
+	ErrProtoWireType    = errors.New("unexpected protobuf wire type")
+)
+
+// google.protobuf.Value field numbers
+const (
+	valueNullField   protowire.Number = 1
+	valueNumberField protowire.Number = 2
+	valueStringField protowire.Number = 3
+	valueBoolField   protowire.Number = 4
+	valueStructField protowire.Number = 5
+	valueListField   protowire.Number = 6
+)
+
+// protoEnum is implemented by string types encoded as protobuf enums
+type protoEnum interface {
+	protoEnumNumber() (int32, bool)
+}
+
+// protoEnumSetter is implemented by pointers to protobuf enum types
+type protoEnumSetter interface {
+	setProtoEnumNumber(n int32) bool
+}
+
+// protoField is a struct field carrying a protobuf tag
+type protoField struct {
+	number protowire.Number
+	index  int
+}
+
+// protoFieldsCache caches the tagged fields of each struct type
+var protoFieldsCache sync.Map
+
+// protoFields returns the protobuf-tagged fields of a struct type
+func protoFields(t reflect.Type) ([]protoField, error) {
+	if cached, ok := protoFieldsCache.Load(t); ok {
+		return cached.([]protoField), nil
+	}
+
+	fields := make([]protoField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("protobuf")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+
+		number, err := strconv.Atoi(strings.Split(tag, ",")[0])
+		if err != nil || !protowire.Number(number).IsValid() {
+			return nil, fmt.Errorf("invalid protobuf tag %q on %s.%s", tag, t.Name(), field.Name)
+		}
+		fields = append(fields, protoField{number: protowire.Number(number), index: i})
+	}
+
+	protoFieldsCache.Store(t, fields)
+	return fields, nil
+}
+
+// MarshalProto encodes a struct in the protobuf wire format, using the field
+// numbers from its protobuf tags. Fields of type any are encoded as
+// google.protobuf.Value and map[string]any as google.protobuf.Struct.
+func MarshalProto(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T", ErrProtoUnsupported, v)
+	}
+
+	return appendStruct(nil, rv)
+}
+
+// UnmarshalProto decodes protobuf wire data into the struct pointed to by v.
+// Unknown fields are skipped.
+func UnmarshalProto(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: unmarshal target must be a non-nil struct pointer, got %T", ErrProtoUnsupported, v)
+	}
+
+	return consumeStruct(data, rv.Elem())
+}
+
+// appendStruct appends the fields of a struct value
+func appendStruct(b []byte, rv reflect.Value) ([]byte, error) {
+	fields, err := protoFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields {
+		b, err = appendField(b, field.number, rv.Field(field.index), false)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", rv.Type().Name(), rv.Type().Field(field.index).Name, err)
+		}
+	}
+	return b, nil
+}
+
+// appendField appends one field. Zero values are omitted as in proto3 unless
+// always is set, which repeated elements and map values require.
+func appendField(b []byte, num protowire.Number, v reflect.Value, always bool) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() && !always {
+			return b, nil
+		}
+		value, err := appendValue(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendEmbedded(b, num, value), nil
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			if always {
+				return appendEmbedded(b, num, nil), nil
+			}
+			return b, nil
+		}
+		// A set pointer marks presence, so its message is always written
+		return appendField(b, num, v.Elem(), true)
+
+	case reflect.Struct:
+		if v.IsZero() && !always {
+			return b, nil
+		}
+		message, err := appendStruct(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendEmbedded(b, num, message), nil
+
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if v.Len() == 0 && !always {
+				return b, nil
+			}
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			return protowire.AppendBytes(b, bytesOf(v)), nil
+		}
+		if v.Len() == 0 {
+			return b, nil
+		}
+
+		if _, packable := scalarWireType(v.Type().Elem().Kind()); packable {
+			var packed []byte
+			for i := 0; i < v.Len(); i++ {
+				packed = appendScalar(packed, v.Index(i))
+			}
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			return protowire.AppendBytes(b, packed), nil
+		}
+
+		var err error
+		for i := 0; i < v.Len(); i++ {
+			b, err = appendField(b, num, v.Index(i), true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+
+	case reflect.Map:
+		if v.Len() == 0 && !always {
+			return b, nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: map key %s", ErrProtoUnsupported, v.Type().Key())
+		}
+
+		// map[string]any is google.protobuf.Struct
+		if v.Type().Elem().Kind() == reflect.Interface {
+			message, err := appendStructValue(nil, v)
+			if err != nil {
+				return nil, err
+			}
+			return appendEmbedded(b, num, message), nil
+		}
+
+		for _, key := range sortedMapKeys(v) {
+			entry := protowire.AppendTag(nil, 1, protowire.BytesType)
+			entry = protowire.AppendString(entry, key.String())
+			entry, err := appendField(entry, 2, v.MapIndex(key), true)
+			if err != nil {
+				return nil, err
+			}
+			b = appendEmbedded(b, num, entry)
+		}
+		return b, nil
+
+	default:
+		if enum, ok := v.Interface().(protoEnum); ok {
+			number, valid := enum.protoEnumNumber()
+			if !valid {
+				return nil, fmt.Errorf("%w: %q is not a valid %s", ErrProtoUnsupported, v.String(), v.Type().Name())
+			}
+			if number == 0 && !always {
+				return b, nil
+			}
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			return protowire.AppendVarint(b, uint64(number)), nil
+		}
+
+		typ, ok := scalarWireType(v.Kind())
+		if !ok && v.Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: %s", ErrProtoUnsupported, v.Type())
+		}
+		if v.IsZero() && !always {
+			return b, nil
+		}
+		if v.Kind() == reflect.String {
+			typ = protowire.BytesType
+		}
+		b = protowire.AppendTag(b, num, typ)
+		return appendScalar(b, v), nil
+	}
+}
+
+// appendEmbedded appends a length-delimited embedded message
+func appendEmbedded(b []byte, num protowire.Number, message []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, message)
+}
+
+// scalarWireType returns the wire type of a numeric or boolean kind, which may be packed
+func scalarWireType(kind reflect.Kind) (protowire.Type, bool) {
+	switch kind {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return protowire.VarintType, true
+	case reflect.Float32:
+		return protowire.Fixed32Type, true
+	case reflect.Float64:
+		return protowire.Fixed64Type, true
+	default:
+		return 0, false
+	}
+}
+
+// appendScalar appends a scalar value without its tag
+func appendScalar(b []byte, v reflect.Value) []byte {
+	switch v.Kind() {
+	case reflect.Bool:
+		return protowire.AppendVarint(b, protowire.EncodeBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return protowire.AppendVarint(b, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return protowire.AppendVarint(b, v.Uint())
+	case reflect.Float32:
+		return protowire.AppendFixed32(b, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		return protowire.AppendFixed64(b, math.Float64bits(v.Float()))
+	case reflect.String:
+		return protowire.AppendString(b, v.String())
+	}
+	return b
+}
+
+// consumeStruct decodes message fields into a struct value
+func consumeStruct(b []byte, rv reflect.Value) error {
+	fields, err := protoFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		index := -1
+		for _, field := range fields {
+			if field.number == num {
+				index = field.index
+				break
+			}
+		}
+
+		if index < 0 {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		n, err := consumeField(b, typ, rv.Field(index))
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", rv.Type().Name(), rv.Type().Field(index).Name, err)
+		}
+		b = b[n:]
+	}
+
+	return nil
+}
+
+// consumeField decodes one occurrence of a field into v, returning the bytes consumed
+func consumeField(b []byte, typ protowire.Type, v reflect.Value) (int, error) {
+	switch v.Kind() {
+	case reflect.Interface:
+		data, n, err := consumeEmbedded(b, typ)
+		if err != nil {
+			return 0, err
+		}
+		if v.NumMethod() != 0 {
+			return 0, fmt.Errorf("%w: %s", ErrProtoUnsupported, v.Type())
+		}
+		value, err := consumeValue(data)
+		if err != nil {
+			return 0, err
+		}
+		if value == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return n, nil
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return consumeField(b, typ, v.Elem())
+
+	case reflect.Struct:
+		data, n, err := consumeEmbedded(b, typ)
+		if err != nil {
+			return 0, err
+		}
+		return n, consumeStruct(data, v)
+
+	case reflect.Slice:
+		elemType := v.Type().Elem()
+		if elemType.Kind() == reflect.Uint8 {
+			data, n, err := consumeEmbedded(b, typ)
+			if err != nil {
+				return 0, err
+			}
+			v.SetBytes(append([]byte(nil), data...))
+			return n, nil
+		}
+
+		// Packed repeated scalars
+		if elemWire, packable := scalarWireType(elemType.Kind()); packable && typ == protowire.BytesType {
+			data, n, err := consumeEmbedded(b, typ)
+			if err != nil {
+				return 0, err
+			}
+			for len(data) > 0 {
+				elem := reflect.New(elemType).Elem()
+				m, err := consumeScalar(data, elemWire, elem)
+				if err != nil {
+					return 0, err
+				}
+				v.Set(reflect.Append(v, elem))
+				data = data[m:]
+			}
+			return n, nil
+		}
+
+		elem := reflect.New(elemType).Elem()
+		n, err := consumeField(b, typ, elem)
+		if err != nil {
+			return 0, err
+		}
+		v.Set(reflect.Append(v, elem))
+		return n, nil
+
+	case reflect.Map:
+		data, n, err := consumeEmbedded(b, typ)
+		if err != nil {
+			return 0, err
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return 0, fmt.Errorf("%w: map key %s", ErrProtoUnsupported, v.Type().Key())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+
+		if v.Type().Elem().Kind() == reflect.Interface {
+			fields, err := consumeStructValue(data)
+			if err != nil {
+				return 0, err
+			}
+			for key, value := range fields {
+				elem := reflect.Zero(v.Type().Elem())
+				if value != nil {
+					elem = reflect.ValueOf(value)
+				}
+				v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+			}
+			return n, nil
+		}
+
+		key := reflect.New(v.Type().Key()).Elem()
+		value := reflect.New(v.Type().Elem()).Elem()
+		for len(data) > 0 {
+			num, entryType, m := protowire.ConsumeTag(data)
+			if m < 0 {
+				return 0, protowire.ParseError(m)
+			}
+			data = data[m:]
+
+			switch num {
+			case 1:
+				m, err = consumeScalar(data, entryType, key)
+			case 2:
+				m, err = consumeField(data, entryType, value)
+			default:
+				m = protowire.ConsumeFieldValue(num, entryType, data)
+				if m < 0 {
+					err = protowire.ParseError(m)
+				}
+			}
+			if err != nil {
+				return 0, err
+			}
+			data = data[m:]
+		}
+		v.SetMapIndex(key, value)
+		return n, nil
+
+	default:
+		if setter, ok := v.Addr().Interface().(protoEnumSetter); ok && typ == protowire.VarintType {
+			x, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+			if !setter.setProtoEnumNumber(int32(x)) {
+				return 0, fmt.Errorf("unknown %s enum number %d", v.Type().Name(), x)
+			}
+			return n, nil
+		}
+		return consumeScalar(b, typ, v)
+	}
+}
+
+// consumeEmbedded reads a length-delimited field
+func consumeEmbedded(b []byte, typ protowire.Type) ([]byte, int, error) {
+	if typ != protowire.BytesType {
+		return nil, 0, fmt.Errorf("%w: %d, want length-delimited", ErrProtoWireType, typ)
+	}
+	data, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return nil, 0, protowire.ParseError(n)
+	}
+	return data, n, nil
+}
+
+// consumeScalar decodes a scalar into v
+func consumeScalar(b []byte, typ protowire.Type, v reflect.Value) (int, error) {
+	if v.Kind() == reflect.String {
+		data, n, err := consumeEmbedded(b, typ)
+		if err != nil {
+			return 0, err
+		}
+		v.SetString(string(data))
+		return n, nil
+	}
+
+	want, ok := scalarWireType(v.Kind())
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrProtoUnsupported, v.Type())
+	}
+	if typ != want {
+		return 0, fmt.Errorf("%w: %d for %s", ErrProtoWireType, typ, v.Type())
+	}
+
+	switch typ {
+	case protowire.VarintType:
+		x, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		switch v.Kind() {
+		case reflect.Bool:
+			v.SetBool(protowire.DecodeBool(x))
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(int64(x))
+		default:
+			v.SetUint(x)
+		}
+		return n, nil
+
+	case protowire.Fixed32Type:
+		x, n := protowire.ConsumeFixed32(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetFloat(float64(math.Float32frombits(x)))
+		return n, nil
+
+	default:
+		x, n := protowire.ConsumeFixed64(b)
+		if n < 0 {
+			return 0, protowire.ParseError(n)
+		}
+		v.SetFloat(math.Float64frombits(x))
+		return n, nil
+	}
+}
+
+// appendValue encodes a dynamic value as a google.protobuf.Value message body
+func appendValue(b []byte, v reflect.Value) ([]byte, error) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			b = protowire.AppendTag(b, valueNullField, protowire.VarintType)
+			return protowire.AppendVarint(b, 0), nil
+		}
+		v = v.Elem()
+	}
+
+	if number, ok := v.Interface().(json.Number); ok {
+		f, err := number.Float64()
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, valueNumberField, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(f)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b = protowire.AppendTag(b, valueBoolField, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(v.Bool())), nil
+
+	case reflect.String:
+		b = protowire.AppendTag(b, valueStringField, protowire.BytesType)
+		return protowire.AppendString(b, v.String()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b = protowire.AppendTag(b, valueNumberField, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(float64(v.Int()))), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b = protowire.AppendTag(b, valueNumberField, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(float64(v.Uint()))), nil
+
+	case reflect.Float32, reflect.Float64:
+		b = protowire.AppendTag(b, valueNumberField, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(v.Float())), nil
+
+	case reflect.Slice, reflect.Array:
+		// Value has no bytes kind; follow the JSON mapping and use base64
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b = protowire.AppendTag(b, valueStringField, protowire.BytesType)
+			return protowire.AppendString(b, base64.StdEncoding.EncodeToString(bytesOf(v))), nil
+		}
+
+		var list []byte
+		for i := 0; i < v.Len(); i++ {
+			element, err := appendValue(nil, v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list = appendEmbedded(list, 1, element)
+		}
+		return appendEmbedded(b, valueListField, list), nil
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%w: map key %s", ErrProtoUnsupported, v.Type().Key())
+		}
+		message, err := appendStructValue(nil, v)
+		if err != nil {
+			return nil, err
+		}
+		return appendEmbedded(b, valueStructField, message), nil
+
+	case reflect.Struct:
+		// Structs travel in their JSON shape
+		generic, err := toGeneric(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return appendValue(b, reflect.ValueOf(&generic).Elem())
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrProtoUnsupported, v.Type())
+	}
+}
+
+// appendStructValue encodes a string-keyed map as a google.protobuf.Struct message body
+func appendStructValue(b []byte, v reflect.Value) ([]byte, error) {
+	for _, key := range sortedMapKeys(v) {
+		value, err := appendValue(nil, v.MapIndex(key))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key.String(), err)
+		}
+
+		entry := protowire.AppendTag(nil, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, key.String())
+		entry = appendEmbedded(entry, 2, value)
+		b = appendEmbedded(b, 1, entry)
+	}
+	return b, nil
+}
+
+// consumeValue decodes a google.protobuf.Value into the types encoding/json produces
+func consumeValue(b []byte) (interface{}, error) {
+	var value interface{}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch {
+		case num == valueNullField && typ == protowire.VarintType:
+			_, n = protowire.ConsumeVarint(b)
+			value = nil
+		case num == valueNumberField && typ == protowire.Fixed64Type:
+			var x uint64
+			x, n = protowire.ConsumeFixed64(b)
+			value = math.Float64frombits(x)
+		case num == valueStringField && typ == protowire.BytesType:
+			var s string
+			s, n = protowire.ConsumeString(b)
+			value = s
+		case num == valueBoolField && typ == protowire.VarintType:
+			var x uint64
+			x, n = protowire.ConsumeVarint(b)
+			value = protowire.DecodeBool(x)
+		case num == valueStructField && typ == protowire.BytesType:
+			var data []byte
+			data, n = protowire.ConsumeBytes(b)
+			if n >= 0 {
+				fields, err := consumeStructValue(data)
+				if err != nil {
+					return nil, err
+				}
+				value = fields
+			}
+		case num == valueListField && typ == protowire.BytesType:
+			var data []byte
+			data, n = protowire.ConsumeBytes(b)
+			if n >= 0 {
+				list, err := consumeListValue(data)
+				if err != nil {
+					return nil, err
+				}
+				value = list
+			}
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+
+	return value, nil
+}
+
+// consumeStructValue decodes a google.protobuf.Struct message body
+func consumeStructValue(b []byte) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if num != 1 || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		entry, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var key string
+		var value interface{}
+		for len(entry) > 0 {
+			entryNum, entryType, m := protowire.ConsumeTag(entry)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			entry = entry[m:]
+
+			switch {
+			case entryNum == 1 && entryType == protowire.BytesType:
+				key, m = protowire.ConsumeString(entry)
+			case entryNum == 2 && entryType == protowire.BytesType:
+				var data []byte
+				data, m = protowire.ConsumeBytes(entry)
+				if m >= 0 {
+					var err error
+					if value, err = consumeValue(data); err != nil {
+						return nil, fmt.Errorf("%s: %w", key, err)
+					}
+				}
+			default:
+				m = protowire.ConsumeFieldValue(entryNum, entryType, entry)
+			}
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			entry = entry[m:]
+		}
+		fields[key] = value
+	}
+
+	return fields, nil
+}
+
+// consumeListValue decodes a google.protobuf.ListValue message body
+func consumeListValue(b []byte) ([]interface{}, error) {
+	list := make([]interface{}, 0)
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if num != 1 || typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+
+		data, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		value, err := consumeValue(data)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+
+	return list, nil
+}
+
+// sortedMapKeys returns map keys in a stable order so encoding is deterministic
+func sortedMapKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
+// bytesOf returns the contents of a byte slice or array value
+func bytesOf(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+	data := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(data), v)
+	return data
+}
+
+// toGeneric converts a value to the maps, slices and scalars encoding/json produces
+func toGeneric(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
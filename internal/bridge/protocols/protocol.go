@@ -1,6 +1,6 @@
 // protocol.go - Protocol definitions for bridge communication
 
-package protocol
+package protocols
 
 import (
 	"context"
@@ -47,21 +47,21 @@ type ErrorCode string
 
 // Standard error codes
 const (
-	ErrorCodeInvalidRequest      ErrorCode = "INVALID_REQUEST"
-	ErrorCodeInternalError       ErrorCode = "INTERNAL_ERROR"
-	ErrorCodeServiceUnavailable  ErrorCode = "SERVICE_UNAVAILABLE"
-	ErrorCodeResourceNotFound    ErrorCode = "RESOURCE_NOT_FOUND"
-	ErrorCodePermissionDenied    ErrorCode = "PERMISSION_DENIED"
-	ErrorCodeUnauthenticated     ErrorCode = "UNAUTHENTICATED"
-	ErrorCodeResourceExhausted   ErrorCode = "RESOURCE_EXHAUSTED"
-	ErrorCodeDeadlineExceeded    ErrorCode = "DEADLINE_EXCEEDED"
-	ErrorCodeAlreadyExists       ErrorCode = "ALREADY_EXISTS"
-	ErrorCodeFailedPrecondition  ErrorCode = "FAILED_PRECONDITION"
-	ErrorCodeAborted             ErrorCode = "ABORTED"
-	ErrorCodeOutOfRange          ErrorCode = "OUT_OF_RANGE"
-	ErrorCodeUnimplemented       ErrorCode = "UNIMPLEMENTED"
-	ErrorCodeDataLoss            ErrorCode = "DATA_LOSS"
-	ErrorCodeUnavailable         ErrorCode = "UNAVAILABLE"
+	ErrorCodeInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	ErrorCodeInternalError      ErrorCode = "INTERNAL_ERROR"
+	ErrorCodeServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrorCodeResourceNotFound   ErrorCode = "RESOURCE_NOT_FOUND"
+	ErrorCodePermissionDenied   ErrorCode = "PERMISSION_DENIED"
+	ErrorCodeUnauthenticated    ErrorCode = "UNAUTHENTICATED"
+	ErrorCodeResourceExhausted  ErrorCode = "RESOURCE_EXHAUSTED"
+	ErrorCodeDeadlineExceeded   ErrorCode = "DEADLINE_EXCEEDED"
+	ErrorCodeAlreadyExists      ErrorCode = "ALREADY_EXISTS"
+	ErrorCodeFailedPrecondition ErrorCode = "FAILED_PRECONDITION"
+	ErrorCodeAborted            ErrorCode = "ABORTED"
+	ErrorCodeOutOfRange         ErrorCode = "OUT_OF_RANGE"
+	ErrorCodeUnimplemented      ErrorCode = "UNIMPLEMENTED"
+	ErrorCodeDataLoss           ErrorCode = "DATA_LOSS"
+	ErrorCodeUnavailable        ErrorCode = "UNAVAILABLE"
 )
 
 // MessageHeader contains common fields for all messages
@@ -99,12 +99,13 @@ func NewUUID() string {
 	return fmt.Sprintf("msg-%d", time.Now().UnixNano())
 }
 
-// Message represents a generic protocol buffer message
-type Message struct {
-	Header  MessageHeader   `json:"header" protobuf:"1"`
-	Payload map[string]any  `json:"payload" protobuf:"2"`
-	Error   *ErrorMessage   `json:"error,omitempty" protobuf:"3"`
-	Meta    map[string]any  `json:"meta,omitempty" protobuf:"4"`
+// ProtobufMessage represents a generic protocol buffer message, the
+// envelope named Message in generated proto files
+type ProtobufMessage struct {
+	Header  MessageHeader  `json:"header" protobuf:"1"`
+	Payload map[string]any `json:"payload" protobuf:"2"`
+	Error   *ErrorMessage  `json:"error,omitempty" protobuf:"3"`
+	Meta    map[string]any `json:"meta,omitempty" protobuf:"4"`
 }
 
 // ErrorMessage represents an error in a protocol buffer message
@@ -125,14 +126,14 @@ type ServiceDefinition struct {
 
 // MethodDefinition defines a service method
 type MethodDefinition struct {
-	Name        string               `json:"name" protobuf:"1"`
-	Description string               `json:"description,omitempty" protobuf:"2"`
-	InputType   string               `json:"input_type" protobuf:"3"`
-	OutputType  string               `json:"output_type" protobuf:"4"`
-	Type        MethodType           `json:"type" protobuf:"5"`
-	Options     map[string]string    `json:"options,omitempty" protobuf:"6"`
-	Deprecated  bool                 `json:"deprecated,omitempty" protobuf:"7"`
-	Since       APIVersion           `json:"since,omitempty" protobuf:"8"`
+	Name        string                `json:"name" protobuf:"1"`
+	Description string                `json:"description,omitempty" protobuf:"2"`
+	InputType   string                `json:"input_type" protobuf:"3"`
+	OutputType  string                `json:"output_type" protobuf:"4"`
+	Type        MethodType            `json:"type" protobuf:"5"`
+	Options     map[string]string     `json:"options,omitempty" protobuf:"6"`
+	Deprecated  bool                  `json:"deprecated,omitempty" protobuf:"7"`
+	Since       APIVersion            `json:"since,omitempty" protobuf:"8"`
 	Params      []ParameterDefinition `json:"params,omitempty" protobuf:"9"`
 }
 
@@ -154,6 +155,14 @@ type ParameterDefinition struct {
 	Description string `json:"description,omitempty" protobuf:"3"`
 	Required    bool   `json:"required,omitempty" protobuf:"4"`
 	Default     any    `json:"default,omitempty" protobuf:"5"`
+
+	// Constraints checked by Validator; zero values mean unconstrained
+	Enum      []string `json:"enum,omitempty" protobuf:"6"`
+	Minimum   *float64 `json:"minimum,omitempty" protobuf:"7"`
+	Maximum   *float64 `json:"maximum,omitempty" protobuf:"8"`
+	MinLength int      `json:"min_length,omitempty" protobuf:"9"`
+	MaxLength int      `json:"max_length,omitempty" protobuf:"10"`
+	Pattern   string   `json:"pattern,omitempty" protobuf:"11"`
 }
 
 // TokenAnalysisService defines the token analyzer service
@@ -175,6 +184,7 @@ var TokenAnalysisService = ServiceDefinition{
 					Type:        "string",
 					Description: "The token's contract address",
 					Required:    true,
+					MinLength:   1,
 				},
 				{
 					Name:        "options",
@@ -196,6 +206,7 @@ var TokenAnalysisService = ServiceDefinition{
 					Type:        "list<string>",
 					Description: "List of token contract addresses",
 					Required:    true,
+					MinLength:   1,
 				},
 				{
 					Name:        "options",
@@ -217,6 +228,7 @@ var TokenAnalysisService = ServiceDefinition{
 					Type:        "string",
 					Description: "The token's contract address",
 					Required:    true,
+					MinLength:   1,
 				},
 				{
 					Name:        "update_types",
@@ -248,6 +260,7 @@ var RiskAnalysisService = ServiceDefinition{
 					Type:        "string",
 					Description: "The token's contract address",
 					Required:    true,
+					MinLength:   1,
 				},
 				{
 					Name:        "risk_factors",
@@ -269,6 +282,7 @@ var RiskAnalysisService = ServiceDefinition{
 					Type:        "list<string>",
 					Description: "List of token contract addresses",
 					Required:    true,
+					MinLength:   1,
 				},
 				{
 					Name:        "comparison_factors",
@@ -330,6 +344,7 @@ var DiscoveryService = ServiceDefinition{
 					Type:        "string",
 					Description: "Type of service",
 					Required:    false,
+					Enum:        serviceTypeNames(),
 				},
 				{
 					Name:        "version",
@@ -351,6 +366,7 @@ var DiscoveryService = ServiceDefinition{
 					Type:        "string",
 					Description: "Type of service",
 					Required:    false,
+					Enum:        serviceTypeNames(),
 				},
 			},
 		},
@@ -374,75 +390,91 @@ type ServiceInfo struct {
 
 // TokenAnalysisResult represents the result of a token analysis
 type TokenAnalysisResult struct {
-	TokenAddress      string                  `json:"token_address" protobuf:"1"`
-	TokenName         string                  `json:"token_name,omitempty" protobuf:"2"`
-	TokenSymbol       string                  `json:"token_symbol,omitempty" protobuf:"3"`
-	ContractType      string                  `json:"contract_type,omitempty" protobuf:"4"`
-	StandardCompliance []string               `json:"standard_compliance,omitempty" protobuf:"5"`
-	Findings          []TokenFinding          `json:"findings,omitempty" protobuf:"6"`
-	Recommendations   []TokenRecommendation   `json:"recommendations,omitempty" protobuf:"7"`
-	RiskProfile       *TokenRiskProfile       `json:"risk_profile,omitempty" protobuf:"8"`
-	ContractInfo      *TokenContractInfo      `json:"contract_info,omitempty" protobuf:"9"`
+	TokenAddress       string                   `json:"token_address" protobuf:"1"`
+	TokenName          string                   `json:"token_name,omitempty" protobuf:"2"`
+	TokenSymbol        string                   `json:"token_symbol,omitempty" protobuf:"3"`
+	ContractType       string                   `json:"contract_type,omitempty" protobuf:"4"`
+	StandardCompliance []string                 `json:"standard_compliance,omitempty" protobuf:"5"`
+	Findings           []TokenFinding           `json:"findings,omitempty" protobuf:"6"`
+	Recommendations    []TokenRecommendation    `json:"recommendations,omitempty" protobuf:"7"`
+	RiskProfile        *TokenRiskProfile        `json:"risk_profile,omitempty" protobuf:"8"`
+	ContractInfo       *TokenContractInfo       `json:"contract_info,omitempty" protobuf:"9"`
 	TransactionHistory *TokenTransactionHistory `json:"transaction_history,omitempty" protobuf:"10"`
-	Analytics         map[string]any          `json:"analytics,omitempty" protobuf:"11"`
-	Timestamp         int64                   `json:"timestamp" protobuf:"12"`
-	AnalysisVersion   string                  `json:"analysis_version" protobuf:"13"`
+	Analytics          map[string]any           `json:"analytics,omitempty" protobuf:"11"`
+	Timestamp          int64                    `json:"timestamp" protobuf:"12"`
+	AnalysisVersion    string                   `json:"analysis_version" protobuf:"13"`
+}
+
+// AnalyzeTokenResponse is the response of TokenAnalyzer.AnalyzeToken
+type AnalyzeTokenResponse struct {
+	Result *TokenAnalysisResult `json:"result" protobuf:"1"`
+}
+
+// BatchAnalyzeTokensResponse is the response of TokenAnalyzer.BatchAnalyzeTokens
+type BatchAnalyzeTokensResponse struct {
+	Results []TokenAnalysisResult `json:"results" protobuf:"1"`
+	Errors  map[string]string     `json:"errors,omitempty" protobuf:"2"`
+}
+
+// DiscoverServicesResponse is the response of Discovery.DiscoverServices
+type DiscoverServicesResponse struct {
+	Services []ServiceInfo `json:"services" protobuf:"1"`
 }
 
 // TokenFinding represents a finding from token analysis
 type TokenFinding struct {
-	ID          string      `json:"id" protobuf:"1"`
-	Title       string      `json:"title" protobuf:"2"`
-	Description string      `json:"description" protobuf:"3"`
-	Severity    string      `json:"severity" protobuf:"4"`
-	Category    string      `json:"category" protobuf:"5"`
-	Location    string      `json:"location,omitempty" protobuf:"6"`
-	Evidence    any         `json:"evidence,omitempty" protobuf:"7"`
-	Confidence  float64     `json:"confidence,omitempty" protobuf:"8"`
-	FirstFound  int64       `json:"first_found,omitempty" protobuf:"9"`
-	LastFound   int64       `json:"last_found" protobuf:"10"`
-	References  []string    `json:"references,omitempty" protobuf:"11"`
-	IsFixed     bool        `json:"is_fixed,omitempty" protobuf:"12"`
+	ID          string   `json:"id" protobuf:"1"`
+	Title       string   `json:"title" protobuf:"2"`
+	Description string   `json:"description" protobuf:"3"`
+	Severity    string   `json:"severity" protobuf:"4"`
+	Category    string   `json:"category" protobuf:"5"`
+	Location    string   `json:"location,omitempty" protobuf:"6"`
+	Evidence    any      `json:"evidence,omitempty" protobuf:"7"`
+	Confidence  float64  `json:"confidence,omitempty" protobuf:"8"`
+	FirstFound  int64    `json:"first_found,omitempty" protobuf:"9"`
+	LastFound   int64    `json:"last_found" protobuf:"10"`
+	References  []string `json:"references,omitempty" protobuf:"11"`
+	IsFixed     bool     `json:"is_fixed,omitempty" protobuf:"12"`
 }
 
 // TokenRecommendation represents a recommendation for addressing findings
 type TokenRecommendation struct {
-	ID          string                   `json:"id" protobuf:"1"`
-	Title       string                   `json:"title" protobuf:"2"`
-	Description string                   `json:"description" protobuf:"3"`
-	Priority    string                   `json:"priority" protobuf:"4"`
-	RelatedFindingIDs []string           `json:"related_finding_ids,omitempty" protobuf:"5"`
-	Actions     []TokenRecommendationAction `json:"actions,omitempty" protobuf:"6"`
+	ID                string                      `json:"id" protobuf:"1"`
+	Title             string                      `json:"title" protobuf:"2"`
+	Description       string                      `json:"description" protobuf:"3"`
+	Priority          string                      `json:"priority" protobuf:"4"`
+	RelatedFindingIDs []string                    `json:"related_finding_ids,omitempty" protobuf:"5"`
+	Actions           []TokenRecommendationAction `json:"actions,omitempty" protobuf:"6"`
 }
 
 // TokenRecommendationAction represents an action to address a recommendation
 type TokenRecommendationAction struct {
-	ID          string      `json:"id" protobuf:"1"`
-	Title       string      `json:"title" protobuf:"2"`
-	Description string      `json:"description" protobuf:"3"`
-	ActionType  string      `json:"action_type" protobuf:"4"`
-	Priority    string      `json:"priority" protobuf:"5"`
-	CodeSnippet string      `json:"code_snippet,omitempty" protobuf:"6"`
+	ID          string `json:"id" protobuf:"1"`
+	Title       string `json:"title" protobuf:"2"`
+	Description string `json:"description" protobuf:"3"`
+	ActionType  string `json:"action_type" protobuf:"4"`
+	Priority    string `json:"priority" protobuf:"5"`
+	CodeSnippet string `json:"code_snippet,omitempty" protobuf:"6"`
 }
 
 // TokenRiskProfile represents a token's risk assessment
 type TokenRiskProfile struct {
-	OverallRiskScore   float64              `json:"overall_risk_score" protobuf:"1"`
-	RiskLevel          string               `json:"risk_level" protobuf:"2"`
-	RiskFactors        map[string]float64   `json:"risk_factors" protobuf:"3"`
-	RiskCategories     map[string]RiskCategory `json:"risk_categories" protobuf:"4"`
-	HistoricalRisk     []HistoricalRiskEntry `json:"historical_risk,omitempty" protobuf:"5"`
-	ConfidenceScore    float64              `json:"confidence_score" protobuf:"6"`
-	AnalysisTimestamp  int64                `json:"analysis_timestamp" protobuf:"7"`
+	OverallRiskScore  float64                 `json:"overall_risk_score" protobuf:"1"`
+	RiskLevel         string                  `json:"risk_level" protobuf:"2"`
+	RiskFactors       map[string]float64      `json:"risk_factors" protobuf:"3"`
+	RiskCategories    map[string]RiskCategory `json:"risk_categories" protobuf:"4"`
+	HistoricalRisk    []HistoricalRiskEntry   `json:"historical_risk,omitempty" protobuf:"5"`
+	ConfidenceScore   float64                 `json:"confidence_score" protobuf:"6"`
+	AnalysisTimestamp int64                   `json:"analysis_timestamp" protobuf:"7"`
 }
 
 // RiskCategory represents a category of risk assessment
 type RiskCategory struct {
-	Name        string              `json:"name" protobuf:"1"`
-	Score       float64             `json:"score" protobuf:"2"`
-	Level       string              `json:"level" protobuf:"3"`
-	Factors     map[string]float64  `json:"factors" protobuf:"4"`
-	Description string              `json:"description,omitempty" protobuf:"5"`
+	Name        string             `json:"name" protobuf:"1"`
+	Score       float64            `json:"score" protobuf:"2"`
+	Level       string             `json:"level" protobuf:"3"`
+	Factors     map[string]float64 `json:"factors" protobuf:"4"`
+	Description string             `json:"description,omitempty" protobuf:"5"`
 }
 
 // HistoricalRiskEntry represents a historical risk assessment entry
@@ -455,69 +487,69 @@ type HistoricalRiskEntry struct {
 
 // TokenContractInfo represents information about the token contract
 type TokenContractInfo struct {
-	Address           string            `json:"address" protobuf:"1"`
-	Name              string            `json:"name,omitempty" protobuf:"2"`
-	Symbol            string            `json:"symbol,omitempty" protobuf:"3"`
-	Decimals          int               `json:"decimals,omitempty" protobuf:"4"`
-	TotalSupply       string            `json:"total_supply,omitempty" protobuf:"5"`
-	CreationTimestamp int64             `json:"creation_timestamp,omitempty" protobuf:"6"`
-	Creator           string            `json:"creator,omitempty" protobuf:"7"`
-	BlockNumber       int64             `json:"block_number,omitempty" protobuf:"8"`
-	BytecodeHash      string            `json:"bytecode_hash,omitempty" protobuf:"9"`
-	SourceCodeVerified bool              `json:"source_code_verified,omitempty" protobuf:"10"`
-	CompilerVersion   string            `json:"compiler_version,omitempty" protobuf:"11"`
-	OptimizationUsed  bool              `json:"optimization_used,omitempty" protobuf:"12"`
-	Runs              int               `json:"runs,omitempty" protobuf:"13"`
-	ConstructorArgs   string            `json:"constructor_args,omitempty" protobuf:"14"`
-	EVMVersion        string            `json:"evm_version,omitempty" protobuf:"15"`
-	Library           string            `json:"library,omitempty" protobuf:"16"`
-	LicenseType       string            `json:"license_type,omitempty" protobuf:"17"`
-	SwarmSource       string            `json:"swarm_source,omitempty" protobuf:"18"`
-	ABI               string            `json:"abi,omitempty" protobuf:"19"`
-	Implementation    string            `json:"implementation,omitempty" protobuf:"20"`
+	Address            string `json:"address" protobuf:"1"`
+	Name               string `json:"name,omitempty" protobuf:"2"`
+	Symbol             string `json:"symbol,omitempty" protobuf:"3"`
+	Decimals           int    `json:"decimals,omitempty" protobuf:"4"`
+	TotalSupply        string `json:"total_supply,omitempty" protobuf:"5"`
+	CreationTimestamp  int64  `json:"creation_timestamp,omitempty" protobuf:"6"`
+	Creator            string `json:"creator,omitempty" protobuf:"7"`
+	BlockNumber        int64  `json:"block_number,omitempty" protobuf:"8"`
+	BytecodeHash       string `json:"bytecode_hash,omitempty" protobuf:"9"`
+	SourceCodeVerified bool   `json:"source_code_verified,omitempty" protobuf:"10"`
+	CompilerVersion    string `json:"compiler_version,omitempty" protobuf:"11"`
+	OptimizationUsed   bool   `json:"optimization_used,omitempty" protobuf:"12"`
+	Runs               int    `json:"runs,omitempty" protobuf:"13"`
+	ConstructorArgs    string `json:"constructor_args,omitempty" protobuf:"14"`
+	EVMVersion         string `json:"evm_version,omitempty" protobuf:"15"`
+	Library            string `json:"library,omitempty" protobuf:"16"`
+	LicenseType        string `json:"license_type,omitempty" protobuf:"17"`
+	SwarmSource        string `json:"swarm_source,omitempty" protobuf:"18"`
+	ABI                string `json:"abi,omitempty" protobuf:"19"`
+	Implementation     string `json:"implementation,omitempty" protobuf:"20"`
 }
 
 // TokenTransactionHistory represents transaction history data for a token
 type TokenTransactionHistory struct {
-	TotalTransactions   int64                `json:"total_transactions" protobuf:"1"`
-	UniqueAddresses     int64                `json:"unique_addresses" protobuf:"2"`
-	TransactionsPerDay  []DailyTransactions  `json:"transactions_per_day,omitempty" protobuf:"3"`
-	TopTransactions     []TokenTransaction   `json:"top_transactions,omitempty" protobuf:"4"`
-	VolumeStats         TokenVolumeStats     `json:"volume_stats,omitempty" protobuf:"5"`
-	HolderDistribution  []HolderBucket       `json:"holder_distribution,omitempty" protobuf:"6"`
+	TotalTransactions  int64               `json:"total_transactions" protobuf:"1"`
+	UniqueAddresses    int64               `json:"unique_addresses" protobuf:"2"`
+	TransactionsPerDay []DailyTransactions `json:"transactions_per_day,omitempty" protobuf:"3"`
+	TopTransactions    []TokenTransaction  `json:"top_transactions,omitempty" protobuf:"4"`
+	VolumeStats        TokenVolumeStats    `json:"volume_stats,omitempty" protobuf:"5"`
+	HolderDistribution []HolderBucket      `json:"holder_distribution,omitempty" protobuf:"6"`
 }
 
 // DailyTransactions represents daily transaction data
 type DailyTransactions struct {
-	Date         string  `json:"date" protobuf:"1"`
-	Count        int64   `json:"count" protobuf:"2"`
-	Volume       string  `json:"volume" protobuf:"3"`
-	AvgPrice     string  `json:"avg_price,omitempty" protobuf:"4"`
-	UniqueWallets int64   `json:"unique_wallets,omitempty" protobuf:"5"`
+	Date          string `json:"date" protobuf:"1"`
+	Count         int64  `json:"count" protobuf:"2"`
+	Volume        string `json:"volume" protobuf:"3"`
+	AvgPrice      string `json:"avg_price,omitempty" protobuf:"4"`
+	UniqueWallets int64  `json:"unique_wallets,omitempty" protobuf:"5"`
 }
 
 // TokenTransaction represents a significant token transaction
 type TokenTransaction struct {
-	TransactionHash  string  `json:"transaction_hash" protobuf:"1"`
-	Timestamp        int64   `json:"timestamp" protobuf:"2"`
-	From             string  `json:"from" protobuf:"3"`
-	To               string  `json:"to" protobuf:"4"`
-	Value            string  `json:"value" protobuf:"5"`
-	BlockNumber      int64   `json:"block_number" protobuf:"6"`
-	TransactionIndex int     `json:"transaction_index" protobuf:"7"`
-	Type             string  `json:"type,omitempty" protobuf:"8"`
-	GasUsed          int64   `json:"gas_used,omitempty" protobuf:"9"`
-	GasPrice         string  `json:"gas_price,omitempty" protobuf:"10"`
+	TransactionHash  string `json:"transaction_hash" protobuf:"1"`
+	Timestamp        int64  `json:"timestamp" protobuf:"2"`
+	From             string `json:"from" protobuf:"3"`
+	To               string `json:"to" protobuf:"4"`
+	Value            string `json:"value" protobuf:"5"`
+	BlockNumber      int64  `json:"block_number" protobuf:"6"`
+	TransactionIndex int    `json:"transaction_index" protobuf:"7"`
+	Type             string `json:"type,omitempty" protobuf:"8"`
+	GasUsed          int64  `json:"gas_used,omitempty" protobuf:"9"`
+	GasPrice         string `json:"gas_price,omitempty" protobuf:"10"`
 }
 
 // TokenVolumeStats represents volume statistics for a token
 type TokenVolumeStats struct {
-	TotalVolume     string  `json:"total_volume" protobuf:"1"`
-	AverageVolume   string  `json:"average_volume" protobuf:"2"`
-	HighestVolume   string  `json:"highest_volume" protobuf:"3"`
+	TotalVolume       string `json:"total_volume" protobuf:"1"`
+	AverageVolume     string `json:"average_volume" protobuf:"2"`
+	HighestVolume     string `json:"highest_volume" protobuf:"3"`
 	HighestVolumeDate string `json:"highest_volume_date" protobuf:"4"`
-	LowestVolume    string  `json:"lowest_volume" protobuf:"5"`
-	LowestVolumeDate string `json:"lowest_volume_date" protobuf:"6"`
+	LowestVolume      string `json:"lowest_volume" protobuf:"5"`
+	LowestVolumeDate  string `json:"lowest_volume_date" protobuf:"6"`
 }
 
 // HolderBucket represents a distribution bucket for token holders
@@ -530,9 +562,9 @@ type HolderBucket struct {
 
 // ServiceManifest represents a collection of service definitions
 type ServiceManifest struct {
-	Services []ServiceDefinition `json:"services" protobuf:"1"`
-	Version  string              `json:"version" protobuf:"2"`
-	Generated int64              `json:"generated" protobuf:"3"`
+	Services  []ServiceDefinition `json:"services" protobuf:"1"`
+	Version   string              `json:"version" protobuf:"2"`
+	Generated int64               `json:"generated" protobuf:"3"`
 }
 
 // DefaultServiceManifest returns the default service manifest
@@ -578,32 +610,59 @@ func (m *ServiceManifest) GetServiceByType(serviceType ServiceType) *ServiceDefi
 	return nil
 }
 
-// ValidateMessage validates a message against its schema
+// ValidateMessage validates a message against its schema. The schema is a
+// method name from DefaultServiceManifest, a MethodDefinition or a list of
+// ParameterDefinitions; failures are returned as *ValidationError.
 func ValidateMessage(message interface{}, schema interface{}) error {
-	// This is a placeholder for a real validation implementation
-	// In a real implementation, you would use reflection or a schema validation library
-	return nil
+	validator := DefaultValidator()
+
+	switch s := schema.(type) {
+	case string:
+		method, ok := validator.Method(s)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownMethod, s)
+		}
+		return validator.ValidateMethod(method, message)
+	case *MethodDefinition:
+		return validator.ValidateMethod(s, message)
+	case MethodDefinition:
+		return validator.ValidateMethod(&s, message)
+	case []ParameterDefinition:
+		if fields := validator.validateParams(s, message); len(fields) > 0 {
+			return &ValidationError{Fields: fields}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %T", ErrInvalidSchema, schema)
+	}
 }
 
-// GenerateProto generates a .proto file definition from a service definition
+// serviceTypeNames returns the known service types as strings
+func serviceTypeNames() []string {
+	names := make([]string, 0, len(serviceTypes))
+	for _, serviceType := range serviceTypes {
+		names = append(names, string(serviceType))
+	}
+	return names
+}
+
+// GenerateProto generates a proto3 file for a service definition, including
+// request messages built from method parameters, response messages and the
+// nested message types they reference
 func (s *ServiceDefinition) GenerateProto() string {
-	// This is a simplified implementation
+	g := newProtoGenerator(s.ProtoPackage())
+
 	sb := strings.Builder{}
-	
-	sb.WriteString(fmt.Sprintf("syntax = \"proto3\";\n\n"))
-	sb.WriteString(fmt.Sprintf("package %s;\n\n", strings.ToLower(string(s.Type))))
-	sb.WriteString(fmt.Sprintf("option go_package = \"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/protocols/%s\";\n\n", strings.ToLower(string(s.Type))))
-	
-	sb.WriteString(fmt.Sprintf("// %s\n", s.Description))
 	sb.WriteString(fmt.Sprintf("service %s {\n", s.Name))
-	
-	for _, method := range s.Methods {
-		sb.WriteString(fmt.Sprintf("  // %s\n", method.Description))
-		
-		if method.Deprecated {
-			sb.WriteString("  option deprecated = true;\n")
+
+	for i, method := range s.Methods {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if method.Description != "" {
+			sb.WriteString(fmt.Sprintf("  // %s\n", method.Description))
 		}
-		
+
 		var requestStream, responseStream string
 		if method.Type == MethodTypeClientStream || method.Type == MethodTypeBidiStream {
 			requestStream = "stream "
@@ -611,34 +670,47 @@ func (s *ServiceDefinition) GenerateProto() string {
 		if method.Type == MethodTypeServerStream || method.Type == MethodTypeBidiStream {
 			responseStream = "stream "
 		}
-		
-		sb.WriteString(fmt.Sprintf("  rpc %s(%s%s) returns (%s%s);\n\n", 
+
+		sb.WriteString(fmt.Sprintf("  rpc %s(%s%s) returns (%s%s)",
 			method.Name, requestStream, method.InputType, responseStream, method.OutputType))
+		if method.Deprecated {
+			sb.WriteString(" {\n    option deprecated = true;\n  }\n")
+		} else {
+			sb.WriteString(";\n")
+		}
+
+		g.addRequestMessage(method)
+		g.addResponseMessage(method)
+	}
+
+	sb.WriteString("}\n")
+
+	return g.render(s.Description, sb.String())
+}
+
+// ConvertToProtobufMessage converts a Go value to a protocol buffer message.
+// Structs and maps become the payload fields; other values are wrapped in a
+// "data" field.
+func ConvertToProtobufMessage(data interface{}) (*ProtobufMessage, error) {
+	generic, err := toGeneric(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert value: %w", err)
 	}
-	
-	sb.WriteString("}\n\n")
-	
-	// Add message definitions
-	// This would be more complex in a real implementation,
-	// generating message types based on the parameters and return types
-	
-	return sb.String()
-}
-
-// ConvertToProtobufMessage converts a Go struct to a protocol buffer message
-func ConvertToProtobufMessage(data interface{}) (*Message, error) {
-	// This is a placeholder for a real implementation
-	// In a real implementation, you would use reflection or a protocol buffer library
-	return &Message{
+
+	payload, ok := generic.(map[string]any)
+	if !ok {
+		payload = map[string]any{
+			"data": generic,
+		}
+	}
+
+	return &ProtobufMessage{
 		Header: MessageHeader{
 			MessageID: NewUUID(),
 			Version:   APIVersionV1,
 			Type:      MessageTypeResponse,
 			Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
 		},
-		Payload: map[string]any{
-			"data": data,
-		},
+		Payload: payload,
 	}, nil
 }
-
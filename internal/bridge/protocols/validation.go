@@ -0,0 +1,541 @@
+// validation.go - Validation of message payloads against service definitions
+
+package protocols
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
+)
+
+// Common validation errors
+var (
+	ErrUnknownMethod = errors.New("unknown service method")
+	ErrInvalidSchema = errors.New("unsupported validation schema")
+)
+
+// FieldError describes one invalid field in a payload
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError reports every field of a payload that does not match its method definition
+type ValidationError struct {
+	Method string       `json:"method,omitempty"`
+	Fields []FieldError `json:"fields"`
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		problems[i] = field.Path + ": " + field.Message
+	}
+
+	if e.Method != "" {
+		return fmt.Sprintf("invalid request for %s: %s", e.Method, strings.Join(problems, "; "))
+	}
+	return "invalid request: " + strings.Join(problems, "; ")
+}
+
+// ErrorMessage converts the error to a structured INVALID_REQUEST error
+func (e *ValidationError) ErrorMessage() *ErrorMessage {
+	fields := make([]map[string]any, len(e.Fields))
+	for i, field := range e.Fields {
+		fields[i] = map[string]any{
+			"path":    field.Path,
+			"message": field.Message,
+		}
+	}
+
+	details := map[string]any{
+		"fields": fields,
+	}
+	if e.Method != "" {
+		details["method"] = e.Method
+	}
+
+	return &ErrorMessage{
+		Code:    ErrorCodeInvalidRequest,
+		Message: e.Error(),
+		Details: details,
+	}
+}
+
+// Validator checks request payloads against the methods of a service manifest
+type Validator struct {
+	methods  map[string]*MethodDefinition
+	services map[*MethodDefinition]string
+	patterns sync.Map
+}
+
+// NewValidator creates a validator for the services in a manifest
+func NewValidator(manifest *ServiceManifest) *Validator {
+	v := &Validator{
+		methods:  make(map[string]*MethodDefinition),
+		services: make(map[*MethodDefinition]string),
+	}
+
+	if manifest != nil {
+		for i := range manifest.Services {
+			service := &manifest.Services[i]
+			for j := range service.Methods {
+				method := &service.Methods[j]
+				v.methods[method.Name] = method
+				v.methods[service.Name+"."+method.Name] = method
+				v.services[method] = service.Name
+			}
+		}
+	}
+
+	return v
+}
+
+var (
+	defaultValidator     *Validator
+	defaultValidatorOnce sync.Once
+)
+
+// DefaultValidator returns a validator for DefaultServiceManifest
+func DefaultValidator() *Validator {
+	defaultValidatorOnce.Do(func() {
+		manifest := DefaultServiceManifest()
+		defaultValidator = NewValidator(&manifest)
+	})
+	return defaultValidator
+}
+
+// Method returns the method a name refers to, accepting "Method",
+// "Service.Method" and gRPC-style "/package.Service/Method" names
+func (v *Validator) Method(name string) (*MethodDefinition, bool) {
+	if method, ok := v.methods[name]; ok {
+		return method, true
+	}
+	if slash := strings.LastIndex(name, "/"); slash >= 0 {
+		method, ok := v.methods[name[slash+1:]]
+		return method, ok
+	}
+	return nil, false
+}
+
+// Validate checks a request payload for the named method. Payloads for
+// methods outside the manifest are not checked.
+func (v *Validator) Validate(methodName string, payload interface{}) error {
+	method, ok := v.Method(methodName)
+	if !ok {
+		return nil
+	}
+	return v.ValidateMethod(method, payload)
+}
+
+// ValidateMethod checks a request payload against a method definition
+func (v *Validator) ValidateMethod(method *MethodDefinition, payload interface{}) error {
+	fields := v.validateParams(method.Params, payload)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	name := method.Name
+	if service, ok := v.services[method]; ok {
+		name = service + "." + method.Name
+	}
+	return &ValidationError{Method: name, Fields: fields}
+}
+
+// MessageValidator returns a protocol plugin validator that checks request
+// messages by their type; the structured error is in the result's details
+func (v *Validator) MessageValidator() plugins.MessageValidator {
+	return func(ctx context.Context, message *plugins.ProtocolMessage) (*plugins.MessageValidationResult, error) {
+		if kind := message.Headers[HeaderMessageType]; kind != "" && kind != string(MessageTypeRequest) {
+			return &plugins.MessageValidationResult{Valid: true}, nil
+		}
+
+		err := v.Validate(message.Type, message.Payload)
+		if err == nil {
+			return &plugins.MessageValidationResult{Valid: true}, nil
+		}
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			return nil, err
+		}
+
+		problems := make([]string, len(validationErr.Fields))
+		for i, field := range validationErr.Fields {
+			problems[i] = field.Path + ": " + field.Message
+		}
+
+		return &plugins.MessageValidationResult{
+			Valid:  false,
+			Errors: problems,
+			Details: map[string]interface{}{
+				"error": validationErr.ErrorMessage(),
+			},
+		}, nil
+	}
+}
+
+// Attach adds the validator to a protocol plugin, after any validator it already has
+func (v *Validator) Attach(plugin *plugins.ProtocolPlugin) {
+	plugin.AddValidator(v.MessageValidator())
+}
+
+// validateParams checks a payload object against parameter definitions
+func (v *Validator) validateParams(params []ParameterDefinition, payload interface{}) []FieldError {
+	var fields map[string]interface{}
+
+	switch p := payload.(type) {
+	case nil:
+		fields = map[string]interface{}{}
+	case map[string]interface{}:
+		fields = p
+	default:
+		generic, err := toGeneric(payload)
+		if err != nil {
+			return []FieldError{{Path: "$", Message: fmt.Sprintf("payload cannot be read: %v", err)}}
+		}
+		var ok bool
+		if fields, ok = generic.(map[string]interface{}); !ok {
+			return []FieldError{{Path: "$", Message: "payload must be an object"}}
+		}
+	}
+
+	var problems []FieldError
+	known := make(map[string]bool, len(params))
+
+	for _, param := range params {
+		known[param.Name] = true
+
+		value, present := fields[param.Name]
+		if !present || value == nil {
+			if param.Required {
+				problems = append(problems, FieldError{Path: param.Name, Message: "is required"})
+			}
+			continue
+		}
+
+		problems = append(problems, v.validateValue(param.Name, parseParamType(param.Type), &param, value)...)
+	}
+
+	unknown := make([]string, 0)
+	for name := range fields {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, FieldError{Path: name, Message: "is not a defined parameter"})
+	}
+
+	return problems
+}
+
+// validateValue checks a value against a parameter type and, for scalars, the parameter's constraints
+func (v *Validator) validateValue(path string, t paramType, param *ParameterDefinition, value interface{}) []FieldError {
+	switch t.name {
+	case "list":
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return []FieldError{{Path: path, Message: "must be a list"}}
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return []FieldError{{Path: path, Message: "must be a list"}}
+		}
+
+		var problems []FieldError
+		problems = append(problems, checkLength(path, param, rv.Len(), "items")...)
+		for i := 0; i < rv.Len(); i++ {
+			element := rv.Index(i).Interface()
+			elementPath := fmt.Sprintf("%s[%d]", path, i)
+			if element == nil {
+				problems = append(problems, FieldError{Path: elementPath, Message: "must not be null"})
+				continue
+			}
+			// Value constraints apply to each element; length constraints to the list
+			problems = append(problems, v.validateValue(elementPath, *t.elem, elementConstraints(param), element)...)
+		}
+		return problems
+
+	case "map":
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			return []FieldError{{Path: path, Message: "must be an object"}}
+		}
+
+		var problems []FieldError
+		for _, key := range sortedMapKeys(rv) {
+			entry := rv.MapIndex(key).Interface()
+			if entry == nil && t.elem.name == "any" {
+				continue
+			}
+			problems = append(problems, v.validateValue(path+"."+key.String(), *t.elem, nil, entry)...)
+		}
+		return problems
+
+	case "any":
+		return nil
+	}
+
+	if t.isMsg {
+		return v.validateMessage(path, t.name, value)
+	}
+
+	if problem := checkScalarType(t, value); problem != "" {
+		return []FieldError{{Path: path, Message: problem}}
+	}
+	return v.checkConstraints(path, t, param, value)
+}
+
+// elementConstraints returns the constraints that apply to the elements of a list parameter
+func elementConstraints(param *ParameterDefinition) *ParameterDefinition {
+	if param == nil {
+		return nil
+	}
+	return &ParameterDefinition{
+		Name:    param.Name,
+		Enum:    param.Enum,
+		Minimum: param.Minimum,
+		Maximum: param.Maximum,
+		Pattern: param.Pattern,
+	}
+}
+
+// checkScalarType returns a problem description when a value does not match a scalar type
+func checkScalarType(t paramType, value interface{}) string {
+	switch t.name {
+	case "string":
+		if reflect.ValueOf(value).Kind() != reflect.String {
+			return "must be a string"
+		}
+
+	case "bool":
+		if _, ok := value.(bool); !ok {
+			return "must be a boolean"
+		}
+
+	case "bytes":
+		switch x := value.(type) {
+		case []byte:
+		case string:
+			if _, err := base64.StdEncoding.DecodeString(x); err != nil {
+				return "must be base64-encoded bytes"
+			}
+		default:
+			return "must be bytes"
+		}
+
+	case "int32", "int64", "uint32", "uint64":
+		n, err := toInt64(value)
+		if err != nil {
+			return "must be an integer"
+		}
+		if rv := reflect.ValueOf(value); rv.Kind() >= reflect.Uint && rv.Kind() <= reflect.Uintptr {
+			switch {
+			case t.name == "int32" && rv.Uint() > math.MaxInt32, t.name == "int64" && rv.Uint() > math.MaxInt64:
+				return "is out of range"
+			case t.name == "uint32" && rv.Uint() > math.MaxUint32:
+				return "must fit in an unsigned 32-bit integer"
+			}
+			return ""
+		}
+		switch {
+		case t.name == "int32" && (n < math.MinInt32 || n > math.MaxInt32):
+			return "must fit in a 32-bit integer"
+		case (t.name == "uint32" || t.name == "uint64") && n < 0:
+			return "must not be negative"
+		case t.name == "uint32" && n > math.MaxUint32:
+			return "must fit in an unsigned 32-bit integer"
+		}
+
+	case "float", "double":
+		if _, err := toFloat64(value); err != nil {
+			return "must be a number"
+		}
+	}
+
+	return ""
+}
+
+// checkConstraints checks a scalar value against a parameter's enum, range, length and pattern
+func (v *Validator) checkConstraints(path string, t paramType, param *ParameterDefinition, value interface{}) []FieldError {
+	if param == nil {
+		return nil
+	}
+
+	var problems []FieldError
+
+	if len(param.Enum) > 0 {
+		text := fmt.Sprint(value)
+		allowed := false
+		for _, option := range param.Enum {
+			if option == text {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			problems = append(problems, FieldError{
+				Path:    path,
+				Message: fmt.Sprintf("must be one of [%s]", strings.Join(param.Enum, ", ")),
+			})
+		}
+	}
+
+	if param.Minimum != nil || param.Maximum != nil {
+		if number, err := toFloat64(value); err == nil {
+			if param.Minimum != nil && number < *param.Minimum {
+				problems = append(problems, FieldError{Path: path, Message: fmt.Sprintf("must be at least %v", *param.Minimum)})
+			}
+			if param.Maximum != nil && number > *param.Maximum {
+				problems = append(problems, FieldError{Path: path, Message: fmt.Sprintf("must be at most %v", *param.Maximum)})
+			}
+		}
+	}
+
+	if s, ok := value.(string); ok && t.name == "string" {
+		problems = append(problems, checkLength(path, param, len([]rune(s)), "characters")...)
+
+		if param.Pattern != "" {
+			pattern, err := v.compilePattern(param.Pattern)
+			if err != nil {
+				problems = append(problems, FieldError{Path: path, Message: fmt.Sprintf("has an invalid pattern in its definition: %v", err)})
+			} else if !pattern.MatchString(s) {
+				problems = append(problems, FieldError{Path: path, Message: fmt.Sprintf("must match pattern %s", param.Pattern)})
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkLength checks a string or list length against a parameter's length bounds
+func checkLength(path string, param *ParameterDefinition, length int, unit string) []FieldError {
+	if param == nil {
+		return nil
+	}
+
+	var problems []FieldError
+	if param.MinLength > 0 && length < param.MinLength {
+		problems = append(problems, FieldError{Path: path, Message: fmt.Sprintf("must have at least %d %s", param.MinLength, unit)})
+	}
+	if param.MaxLength > 0 && length > param.MaxLength {
+		problems = append(problems, FieldError{Path: path, Message: fmt.Sprintf("must have at most %d %s", param.MaxLength, unit)})
+	}
+	return problems
+}
+
+// compilePattern compiles and caches a parameter pattern
+func (v *Validator) compilePattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := v.patterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	v.patterns.Store(pattern, compiled)
+	return compiled, nil
+}
+
+// validateMessage checks a value against a registered message type, including its enum fields
+func (v *Validator) validateMessage(path, name string, value interface{}) []FieldError {
+	t, _ := LookupMessageType(name)
+
+	message := reflect.New(t)
+	if reflect.TypeOf(value) == t {
+		message.Elem().Set(reflect.ValueOf(value))
+	} else if reflect.TypeOf(value) == message.Type() {
+		message = reflect.ValueOf(value)
+	} else {
+		if reflect.ValueOf(value).Kind() != reflect.Map {
+			return []FieldError{{Path: path, Message: fmt.Sprintf("must be a %s object", name)}}
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return []FieldError{{Path: path, Message: err.Error()}}
+		}
+		if err := json.Unmarshal(data, message.Interface()); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				return []FieldError{{Path: path + "." + typeErr.Field, Message: "must be " + jsonTypeName(typeErr.Type)}}
+			}
+			return []FieldError{{Path: path, Message: fmt.Sprintf("does not match %s: %v", name, err)}}
+		}
+	}
+
+	return checkEnumFields(path, message.Elem())
+}
+
+// checkEnumFields reports enum fields holding values outside their enum
+func checkEnumFields(path string, rv reflect.Value) []FieldError {
+	var problems []FieldError
+
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := rv.Field(i)
+		fieldPath := path + "." + protoFieldName(field)
+
+		if enum, ok := value.Interface().(protoEnum); ok {
+			if _, valid := enum.protoEnumNumber(); !valid {
+				problems = append(problems, FieldError{
+					Path:    fieldPath,
+					Message: fmt.Sprintf("%q is not a valid %s", value.String(), value.Type().Name()),
+				})
+			}
+			continue
+		}
+
+		switch value.Kind() {
+		case reflect.Struct:
+			problems = append(problems, checkEnumFields(fieldPath, value)...)
+		case reflect.Ptr:
+			if !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+				problems = append(problems, checkEnumFields(fieldPath, value.Elem())...)
+			}
+		case reflect.Slice:
+			if value.Type().Elem().Kind() == reflect.Struct {
+				for j := 0; j < value.Len(); j++ {
+					problems = append(problems, checkEnumFields(fmt.Sprintf("%s[%d]", fieldPath, j), value.Index(j))...)
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// jsonTypeName describes a Go type in JSON terms, with its article
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,52 @@
+package protocols
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatorValidate(t *testing.T) {
+	validator := DefaultValidator()
+
+	tests := []struct {
+		name    string
+		method  string
+		payload interface{}
+		paths   []string
+	}{
+		{"valid", "AnalyzeToken", map[string]interface{}{"token_address": "0xabc"}, nil},
+		{"service qualified", "TokenAnalyzer.AnalyzeToken", map[string]interface{}{"token_address": "0xabc"}, nil},
+		{"grpc style", "/quant.TokenAnalyzer/AnalyzeToken", map[string]interface{}{"token_address": "0xabc"}, nil},
+		{"missing required", "AnalyzeToken", map[string]interface{}{}, []string{"token_address"}},
+		{"too short", "AnalyzeToken", map[string]interface{}{"token_address": ""}, []string{"token_address"}},
+		{"unknown field", "AnalyzeToken", map[string]interface{}{"token_address": "0xabc", "extra": 1}, []string{"extra"}},
+		{"not an object", "AnalyzeToken", "0xabc", []string{"$"}},
+		{"struct payload", "AnalyzeToken", struct {
+			TokenAddress string `json:"token_address"`
+		}{"0xabc"}, nil},
+		{"unknown method", "Echo", "anything", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate(tt.method, tt.payload)
+			if tt.paths == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.True(t, errors.As(err, &validationErr), "got %v", err)
+			paths := make([]string, len(validationErr.Fields))
+			for i, field := range validationErr.Fields {
+				paths[i] = field.Path
+			}
+			assert.Equal(t, tt.paths, paths)
+			assert.Equal(t, "TokenAnalyzer.AnalyzeToken", validationErr.Method)
+			assert.Equal(t, ErrorCodeInvalidRequest, validationErr.ErrorMessage().Code)
+		})
+	}
+}
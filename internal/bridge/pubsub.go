@@ -0,0 +1,536 @@
+// pubsub.go - Asynchronous publish/subscribe messaging for the bridge
+
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/adapters"
+	"github.com/google/uuid"
+)
+
+// Metadata keys used by publish/subscribe messages
+const (
+	MetadataTopic  = "topic"
+	MetadataOrigin = "origin"
+)
+
+// Topic wildcards. Topics are dot-separated segments; "*" matches exactly one
+// segment and "#" (only valid as the last segment) matches zero or more.
+const (
+	topicSeparator      = "."
+	topicSingleWildcard = "*"
+	topicMultiWildcard  = "#"
+)
+
+// Common publish/subscribe errors
+var (
+	ErrInvalidTopic         = errors.New("invalid topic")
+	ErrSubscriberBufferFull = errors.New("subscriber buffer full")
+	ErrSubscriptionClosed   = errors.New("subscription closed")
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+)
+
+// BackpressurePolicy defines what happens when a subscriber's buffer is full
+type BackpressurePolicy string
+
+const (
+	// BackpressureDropOldest discards the oldest buffered message to make room
+	BackpressureDropOldest BackpressurePolicy = "drop_oldest"
+
+	// BackpressureBlock makes Publish wait for buffer space or context expiry
+	BackpressureBlock BackpressurePolicy = "block"
+
+	// BackpressureReject discards the new message and reports it to the publisher
+	BackpressureReject BackpressurePolicy = "reject"
+)
+
+// EventHandler processes a published message
+type EventHandler func(ctx context.Context, msg *Message) error
+
+// SubscriptionOptions configures delivery to a subscriber
+type SubscriptionOptions struct {
+	BufferSize     int
+	Backpressure   BackpressurePolicy
+	HandlerTimeout time.Duration
+}
+
+// DefaultSubscriptionOptions returns default subscription options
+func DefaultSubscriptionOptions() *SubscriptionOptions {
+	return &SubscriptionOptions{
+		BufferSize:     256,
+		Backpressure:   BackpressureDropOldest,
+		HandlerTimeout: 30 * time.Second,
+	}
+}
+
+// SubscriptionStats contains delivery statistics for a subscription
+type SubscriptionStats struct {
+	ID        string `json:"id"`
+	Pattern   string `json:"pattern"`
+	Remote    string `json:"remote,omitempty"`
+	Buffered  int    `json:"buffered"`
+	Delivered int64  `json:"delivered"`
+	Dropped   int64  `json:"dropped"`
+	Rejected  int64  `json:"rejected"`
+	Failed    int64  `json:"failed"`
+}
+
+// TopicSubscription is a registered interest in a topic pattern; it implements Subscription
+type TopicSubscription struct {
+	ID      string
+	Pattern string
+
+	remoteAdapter string
+	options       *SubscriptionOptions
+	handler       EventHandler
+	buffer        chan *Message
+	bufferMutex   sync.Mutex
+	done          chan struct{}
+	closeOnce     sync.Once
+	bridge        *Bridge
+	delivered     int64
+	dropped       int64
+	rejected      int64
+	failed        int64
+}
+
+// Unsubscribe removes the subscription from its bridge
+func (s *TopicSubscription) Unsubscribe() error {
+	return s.bridge.Unsubscribe(s.ID)
+}
+
+// GetID returns the subscription ID
+func (s *TopicSubscription) GetID() string {
+	return s.ID
+}
+
+// Stats returns delivery statistics for the subscription
+func (s *TopicSubscription) Stats() SubscriptionStats {
+	return SubscriptionStats{
+		ID:        s.ID,
+		Pattern:   s.Pattern,
+		Remote:    s.remoteAdapter,
+		Buffered:  len(s.buffer),
+		Delivered: atomic.LoadInt64(&s.delivered),
+		Dropped:   atomic.LoadInt64(&s.dropped),
+		Rejected:  atomic.LoadInt64(&s.rejected),
+		Failed:    atomic.LoadInt64(&s.failed),
+	}
+}
+
+// enqueue buffers a message according to the subscription's backpressure policy
+func (s *TopicSubscription) enqueue(ctx context.Context, msg *Message) error {
+	select {
+	case <-s.done:
+		return ErrSubscriptionClosed
+	default:
+	}
+
+	switch s.options.Backpressure {
+	case BackpressureBlock:
+		select {
+		case s.buffer <- msg:
+			return nil
+		case <-s.done:
+			return ErrSubscriptionClosed
+		case <-ctx.Done():
+			atomic.AddInt64(&s.rejected, 1)
+			return fmt.Errorf("%w: %s: %v", ErrSubscriberBufferFull, s.ID, ctx.Err())
+		}
+
+	case BackpressureReject:
+		select {
+		case s.buffer <- msg:
+			return nil
+		default:
+			atomic.AddInt64(&s.rejected, 1)
+			return fmt.Errorf("%w: %s", ErrSubscriberBufferFull, s.ID)
+		}
+
+	default:
+		// Serialize producers so evicting and inserting happen together
+		s.bufferMutex.Lock()
+		defer s.bufferMutex.Unlock()
+
+		select {
+		case s.buffer <- msg:
+			return nil
+		default:
+		}
+
+		select {
+		case <-s.buffer:
+			atomic.AddInt64(&s.dropped, 1)
+		default:
+		}
+
+		// Producers hold the lock and the dispatcher only frees space, so this
+		// insert succeeds; were it to fail, the new message is dropped rather than retried
+		select {
+		case s.buffer <- msg:
+		default:
+			atomic.AddInt64(&s.dropped, 1)
+		}
+		return nil
+	}
+}
+
+// dispatch delivers buffered messages to the handler until the subscription closes
+func (s *TopicSubscription) dispatch() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case msg := <-s.buffer:
+			if msg.IsExpired() {
+				atomic.AddInt64(&s.dropped, 1)
+				continue
+			}
+			s.deliver(msg)
+		}
+	}
+}
+
+// deliver invokes the handler for a single message
+func (s *TopicSubscription) deliver(msg *Message) {
+	ctx := s.bridge.ctx
+	if s.options.HandlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.options.HandlerTimeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			atomic.AddInt64(&s.failed, 1)
+			s.bridge.logger.Error(fmt.Sprintf("Subscriber '%s' panicked: %v", s.ID, r), nil)
+		}
+	}()
+
+	if err := s.handler(ctx, msg); err != nil {
+		atomic.AddInt64(&s.failed, 1)
+		s.bridge.logger.Warn(fmt.Sprintf("Subscriber '%s' failed to handle message", s.ID), map[string]interface{}{
+			"topic": msg.Metadata[MetadataTopic],
+			"error": err.Error(),
+		})
+		return
+	}
+
+	atomic.AddInt64(&s.delivered, 1)
+}
+
+// close stops delivery to the subscription
+func (s *TopicSubscription) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// ValidateTopicPattern checks that a subscription pattern is well formed
+func ValidateTopicPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("%w: empty pattern", ErrInvalidTopic)
+	}
+
+	segments := strings.Split(pattern, topicSeparator)
+	for i, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("%w: empty segment in '%s'", ErrInvalidTopic, pattern)
+		}
+		if segment == topicMultiWildcard && i != len(segments)-1 {
+			return fmt.Errorf("%w: '%s' must be the last segment in '%s'", ErrInvalidTopic, topicMultiWildcard, pattern)
+		}
+	}
+
+	return nil
+}
+
+// validateTopic checks that a publish topic is concrete
+func validateTopic(topic string) error {
+	if err := ValidateTopicPattern(topic); err != nil {
+		return err
+	}
+
+	for _, segment := range strings.Split(topic, topicSeparator) {
+		if segment == topicSingleWildcard || segment == topicMultiWildcard {
+			return fmt.Errorf("%w: wildcards are not allowed when publishing to '%s'", ErrInvalidTopic, topic)
+		}
+	}
+
+	return nil
+}
+
+// MatchTopic reports whether a topic matches a subscription pattern
+func MatchTopic(pattern, topic string) bool {
+	patternSegments := strings.Split(pattern, topicSeparator)
+	topicSegments := strings.Split(topic, topicSeparator)
+
+	for i, segment := range patternSegments {
+		if segment == topicMultiWildcard {
+			return true
+		}
+		if i >= len(topicSegments) {
+			return false
+		}
+		if segment != topicSingleWildcard && segment != topicSegments[i] {
+			return false
+		}
+	}
+
+	return len(patternSegments) == len(topicSegments)
+}
+
+// Subscribe registers a handler for messages published to topics matching the pattern
+func (b *Bridge) Subscribe(pattern string, handler EventHandler, options *SubscriptionOptions) (*TopicSubscription, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("subscription handler cannot be nil")
+	}
+
+	return b.addSubscription(pattern, "", handler, options)
+}
+
+// SubscribeRemote forwards messages published to topics matching the pattern to
+// the peer on the named adapter, typically a WebSocket adapter
+func (b *Bridge) SubscribeRemote(pattern, adapterName string, options *SubscriptionOptions) (*TopicSubscription, error) {
+	adapter, err := b.GetAdapter(adapterName)
+	if err != nil {
+		return nil, err
+	}
+
+	handler := func(ctx context.Context, msg *Message) error {
+		// Don't echo events back to the peer they came from
+		if origin, _ := msg.GetMetadata(MetadataOrigin); origin == adapterName {
+			return nil
+		}
+
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+
+		// Push adapters deliver events without waiting for the peer to reply
+		if push, err := adapters.Push(adapter); err == nil {
+			return push.Notify(ctx, data)
+		}
+
+		_, err = adapter.Send(ctx, data)
+		return err
+	}
+
+	return b.addSubscription(pattern, adapterName, handler, options)
+}
+
+// addSubscription validates and registers a subscription and starts its dispatcher
+func (b *Bridge) addSubscription(pattern, remoteAdapter string, handler EventHandler, options *SubscriptionOptions) (*TopicSubscription, error) {
+	if b.Status() == StatusShuttingDown {
+		return nil, ErrBridgeShuttingDown
+	}
+
+	if err := ValidateTopicPattern(pattern); err != nil {
+		return nil, err
+	}
+
+	// Defaults are filled in on a copy so the caller's options are not modified
+	if options == nil {
+		options = DefaultSubscriptionOptions()
+	} else {
+		copied := *options
+		options = &copied
+	}
+	if options.BufferSize <= 0 {
+		options.BufferSize = b.options.BufferSize
+	}
+	if options.BufferSize <= 0 {
+		// An unbuffered channel would leave drop_oldest nothing to evict
+		options.BufferSize = DefaultBridgeOptions().BufferSize
+	}
+	if options.Backpressure == "" {
+		options.Backpressure = BackpressureDropOldest
+	}
+
+	subscription := &TopicSubscription{
+		ID:            uuid.New().String(),
+		Pattern:       pattern,
+		remoteAdapter: remoteAdapter,
+		options:       options,
+		handler:       handler,
+		buffer:        make(chan *Message, options.BufferSize),
+		done:          make(chan struct{}),
+		bridge:        b,
+	}
+
+	b.subscriptionsMutex.Lock()
+	b.subscriptions[subscription.ID] = subscription
+	b.subscriptionsMutex.Unlock()
+
+	go subscription.dispatch()
+
+	b.logger.Debug(fmt.Sprintf("Added subscription '%s' for '%s'", subscription.ID, pattern), map[string]interface{}{
+		"backpressure": string(options.Backpressure),
+		"remote":       remoteAdapter,
+	})
+
+	return subscription, nil
+}
+
+// Unsubscribe removes a subscription and stops its delivery
+func (b *Bridge) Unsubscribe(id string) error {
+	b.subscriptionsMutex.Lock()
+	subscription, exists := b.subscriptions[id]
+	delete(b.subscriptions, id)
+	b.subscriptionsMutex.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, id)
+	}
+
+	subscription.close()
+	return nil
+}
+
+// Subscriptions returns statistics for all active subscriptions
+func (b *Bridge) Subscriptions() []SubscriptionStats {
+	b.subscriptionsMutex.RLock()
+	defer b.subscriptionsMutex.RUnlock()
+
+	stats := make([]SubscriptionStats, 0, len(b.subscriptions))
+	for _, subscription := range b.subscriptions {
+		stats = append(stats, subscription.Stats())
+	}
+	return stats
+}
+
+// Publish delivers a copy of a message to every subscriber whose pattern
+// matches the topic. Delivery is asynchronous; Publish returns once the
+// message has been buffered for each subscriber, or an error if any
+// subscriber rejected it.
+func (b *Bridge) Publish(ctx context.Context, topic string, msg *Message) error {
+	if b.Status() == StatusShuttingDown {
+		return ErrBridgeShuttingDown
+	}
+
+	if err := validateTopic(topic); err != nil {
+		return err
+	}
+
+	if msg == nil {
+		return ErrInvalidMessage
+	}
+
+	// The caller's message is left untouched
+	event := msg.Clone()
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	event.Type = TypeEvent
+	event.AddMetadata(MetadataTopic, topic)
+
+	b.subscriptionsMutex.RLock()
+	matches := make([]*TopicSubscription, 0)
+	for _, subscription := range b.subscriptions {
+		if MatchTopic(subscription.Pattern, topic) {
+			matches = append(matches, subscription)
+		}
+	}
+	b.subscriptionsMutex.RUnlock()
+
+	rejected := 0
+	var lastErr error
+	for _, subscription := range matches {
+		// Each subscriber gets its own copy, so handlers cannot race on it
+		if err := subscription.enqueue(ctx, event.Clone()); err != nil && !errors.Is(err, ErrSubscriptionClosed) {
+			rejected++
+			lastErr = err
+		}
+	}
+
+	if rejected > 0 {
+		return fmt.Errorf("message on '%s' rejected by %d of %d subscribers: %w", topic, rejected, len(matches), lastErr)
+	}
+
+	return nil
+}
+
+// AttachEventAdapter accepts publish/subscribe traffic from the peer on the named
+// adapter: events it sends are published locally, and its subscribe and
+// unsubscribe messages manage remote subscriptions that forward events back to it
+func (b *Bridge) AttachEventAdapter(adapterName string) error {
+	adapter, err := b.GetAdapter(adapterName)
+	if err != nil {
+		return err
+	}
+
+	receiver, ok := adapter.(interface {
+		SetMessageHandler(handler adapters.MessageHandler)
+	})
+	if !ok {
+		return fmt.Errorf("adapter '%s' does not support pushed messages", adapterName)
+	}
+
+	var remoteMutex sync.Mutex
+	remoteSubscriptions := make(map[string]string) // pattern -> subscription ID
+
+	receiver.SetMessageHandler(func(data []byte) error {
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+
+		topic, _ := msg.GetMetadata(MetadataTopic)
+
+		switch msg.Type {
+		case TypeEvent:
+			msg.AddMetadata(MetadataOrigin, adapterName)
+			return b.Publish(b.ctx, topic, &msg)
+
+		case TypeSubscribe:
+			remoteMutex.Lock()
+			defer remoteMutex.Unlock()
+			if _, exists := remoteSubscriptions[topic]; exists {
+				return nil
+			}
+			subscription, err := b.SubscribeRemote(topic, adapterName, nil)
+			if err != nil {
+				return err
+			}
+			remoteSubscriptions[topic] = subscription.ID
+			return nil
+
+		case TypeUnsubscribe:
+			remoteMutex.Lock()
+			defer remoteMutex.Unlock()
+			id, exists := remoteSubscriptions[topic]
+			if !exists {
+				return nil
+			}
+			delete(remoteSubscriptions, topic)
+			return b.Unsubscribe(id)
+		}
+
+		return nil
+	})
+
+	return nil
+}
+
+// closeSubscriptions stops delivery to every subscription
+func (b *Bridge) closeSubscriptions() {
+	b.subscriptionsMutex.Lock()
+	defer b.subscriptionsMutex.Unlock()
+
+	for id, subscription := range b.subscriptions {
+		subscription.close()
+		delete(b.subscriptions, id)
+	}
+}
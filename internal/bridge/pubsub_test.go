@@ -0,0 +1,153 @@
+package bridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"orders.created", "orders.created", true},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders.created.eu", false},
+		{"orders.#", "orders", true},
+		{"orders.#", "orders.created.eu", true},
+		{"*.created", "trades.created", true},
+		{"orders.created", "orders.updated", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MatchTopic(tt.pattern, tt.topic), "%s ~ %s", tt.pattern, tt.topic)
+	}
+
+	assert.ErrorIs(t, ValidateTopicPattern("orders.#.eu"), ErrInvalidTopic)
+	assert.ErrorIs(t, ValidateTopicPattern("orders..created"), ErrInvalidTopic)
+}
+
+func TestPublishGivesEachSubscriberItsOwnCopy(t *testing.T) {
+	b := NewBridge(&BridgeOptions{BufferSize: 8}, &defaultLogger{})
+	received := make(chan *Message, 2)
+
+	// Both handlers modify the message they are given
+	handler := func(ctx context.Context, msg *Message) error {
+		msg.AddMetadata("seen", "yes")
+		msg.Payload[0] = 'X'
+		received <- msg
+		return nil
+	}
+	_, err := b.Subscribe("orders.*", handler, nil)
+	require.NoError(t, err)
+	_, err = b.Subscribe("orders.#", handler, nil)
+	require.NoError(t, err)
+
+	msg := &Message{Payload: []byte(`{"id":1}`), Metadata: map[string]string{"tenant": "acme"}}
+	require.NoError(t, b.Publish(context.Background(), "orders.created", msg))
+
+	first, second := <-received, <-received
+	assert.NotSame(t, first, second)
+	for _, delivered := range []*Message{first, second} {
+		assert.Equal(t, TypeEvent, delivered.Type)
+		assert.NotEmpty(t, delivered.ID)
+		assert.Equal(t, "orders.created", delivered.Metadata[MetadataTopic])
+		assert.Equal(t, "acme", delivered.Metadata["tenant"])
+	}
+	assert.Equal(t, first.ID, second.ID)
+
+	// The published message is left as the caller built it
+	assert.Empty(t, msg.ID)
+	assert.True(t, msg.Timestamp.IsZero())
+	assert.Equal(t, `{"id":1}`, string(msg.Payload))
+	assert.Equal(t, map[string]string{"tenant": "acme"}, msg.Metadata)
+}
+
+func TestSubscribeDoesNotModifyOptions(t *testing.T) {
+	b := NewBridge(&BridgeOptions{BufferSize: 16}, &defaultLogger{})
+	options := &SubscriptionOptions{HandlerTimeout: time.Second}
+
+	subscription, err := b.Subscribe("orders.*", func(ctx context.Context, msg *Message) error { return nil }, options)
+	require.NoError(t, err)
+	defer subscription.Unsubscribe()
+
+	assert.Equal(t, &SubscriptionOptions{HandlerTimeout: time.Second}, options)
+	assert.Equal(t, 16, cap(subscription.buffer))
+	assert.Equal(t, BackpressureDropOldest, subscription.options.Backpressure)
+}
+
+func TestPublishBackpressure(t *testing.T) {
+	b := NewBridge(&BridgeOptions{BufferSize: 1}, &defaultLogger{})
+	release := make(chan struct{})
+	handler := func(ctx context.Context, msg *Message) error {
+		<-release
+		return nil
+	}
+	defer close(release)
+
+	rejecting, err := b.Subscribe("prices.*", handler, &SubscriptionOptions{Backpressure: BackpressureReject})
+	require.NoError(t, err)
+
+	// One message is held by the handler and one fills the buffer
+	require.NoError(t, b.Publish(context.Background(), "prices.btc", &Message{}))
+	require.Eventually(t, func() bool { return rejecting.Stats().Buffered == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, b.Publish(context.Background(), "prices.btc", &Message{}))
+
+	err = b.Publish(context.Background(), "prices.btc", &Message{})
+	assert.ErrorIs(t, err, ErrSubscriberBufferFull)
+	assert.Equal(t, int64(1), rejecting.Stats().Rejected)
+
+	assert.ErrorIs(t, b.Publish(context.Background(), "prices.*", &Message{}), ErrInvalidTopic)
+	assert.ErrorIs(t, b.Publish(context.Background(), "prices.btc", nil), ErrInvalidMessage)
+}
+
+func TestSubscribeBufferFallsBackToDefault(t *testing.T) {
+	b := NewBridge(&BridgeOptions{}, &defaultLogger{})
+
+	subscription, err := b.Subscribe("orders.*", func(ctx context.Context, msg *Message) error { return nil }, &SubscriptionOptions{})
+	require.NoError(t, err)
+	defer subscription.Unsubscribe()
+
+	assert.Equal(t, DefaultBridgeOptions().BufferSize, cap(subscription.buffer))
+	assert.Equal(t, DefaultBridgeOptions().BufferSize, subscription.options.BufferSize)
+}
+
+func TestPublishDropOldest(t *testing.T) {
+	b := NewBridge(&BridgeOptions{BufferSize: 1}, &defaultLogger{})
+	held := make(chan *Message, 1)
+	release := make(chan struct{})
+	handler := func(ctx context.Context, msg *Message) error {
+		held <- msg
+		<-release
+		return nil
+	}
+
+	subscription, err := b.Subscribe("prices.*", handler, &SubscriptionOptions{Backpressure: BackpressureDropOldest})
+	require.NoError(t, err)
+	defer subscription.Unsubscribe()
+
+	// The first message is held by the handler, later ones replace each other
+	require.NoError(t, b.Publish(context.Background(), "prices.btc", &Message{Payload: []byte("1")}))
+	<-held
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, payload := range []string{"2", "3", "4"} {
+			assert.NoError(t, b.Publish(context.Background(), "prices.btc", &Message{Payload: []byte(payload)}))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publishing to a full drop_oldest subscriber blocked")
+	}
+
+	assert.Equal(t, int64(2), subscription.Stats().Dropped)
+	close(release)
+	assert.Equal(t, "4", string((<-held).Payload))
+}
@@ -0,0 +1,275 @@
+// stream.go - Streaming calls through the bridge
+
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/adapters"
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
+)
+
+// Stream message headers
+const (
+	HeaderStreamID  = "stream-id"
+	HeaderStreamSeq = "stream-seq"
+)
+
+// BridgeStream is an open stream to a target. Every message is encoded
+// individually with the target's protocol plugin.
+type BridgeStream struct {
+	id        string
+	bridge    *Bridge
+	target    BridgeTarget
+	kind      adapters.StreamKind
+	protocol  *plugins.ProtocolPlugin
+	stream    adapters.AdapterStream
+	ctx       context.Context
+	cancel    context.CancelFunc
+	release   func()
+	done      func()
+	sendSeq   int64
+	sendMutex sync.Mutex
+	closeOnce sync.Once
+	opened    time.Time
+}
+
+// Stream opens a server, client or bidirectional stream to a target. The
+// target's Operation names the method (a gRPC full method or a REST endpoint).
+// The stream holds a concurrency slot until it is closed, and is closed when
+// ctx is canceled.
+func (b *Bridge) Stream(ctx context.Context, target BridgeTarget, kind adapters.StreamKind) (*BridgeStream, error) {
+	if b.Status() != StatusReady {
+		return nil, ErrBridgeNotInitialized
+	}
+
+	if target.Adapter == "" || target.Protocol == "" {
+		return nil, ErrInvalidTarget
+	}
+
+	switch kind {
+	case adapters.StreamServer, adapters.StreamClient, adapters.StreamBidi:
+	default:
+		return nil, fmt.Errorf("unknown stream kind '%s'", kind)
+	}
+
+	b.protocolsMutex.RLock()
+	protocol, exists := b.protocols[target.Protocol]
+	b.protocolsMutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrProtocolNotFound, target.Protocol)
+	}
+
+	b.adaptersMutex.RLock()
+	adapter, exists := b.adapters[target.Adapter]
+	b.adaptersMutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrAdapterNotFound, target.Adapter)
+	}
+
+	streaming, err := adapters.Streaming(adapter)
+	if err != nil {
+		return nil, err
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+
+	// Resolve the target service to a healthy instance
+	streamCtx, done, err := b.routeToService(streamCtx, target)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Wait for a concurrency slot
+	release, err := b.admit(streamCtx, target)
+	if err != nil {
+		done()
+		cancel()
+		return nil, err
+	}
+
+	id := generateID()
+	stream, err := b.openAdapterStream(streamCtx, streaming, target, adapters.StreamOptions{
+		Kind:     kind,
+		Target:   target.Service,
+		Method:   target.Operation,
+		Headers:  map[string]string{HeaderStreamID: id},
+		Window:   b.options.StreamWindow,
+		StreamID: id,
+		Encoding: target.Protocol,
+	})
+	if err != nil {
+		release()
+		done()
+		cancel()
+		return nil, err
+	}
+
+	s := &BridgeStream{
+		id:       id,
+		bridge:   b,
+		target:   target,
+		kind:     kind,
+		protocol: protocol,
+		stream:   stream,
+		ctx:      streamCtx,
+		cancel:   cancel,
+		release:  release,
+		done:     done,
+		opened:   time.Now(),
+	}
+
+	// Close the stream when the caller or the bridge goes away
+	go func() {
+		select {
+		case <-streamCtx.Done():
+		case <-b.ctx.Done():
+		}
+		s.Close()
+	}()
+
+	b.logger.Debug("Opened bridge stream", map[string]interface{}{
+		"stream":    id,
+		"adapter":   target.Adapter,
+		"operation": target.Operation,
+		"kind":      string(kind),
+	})
+
+	return s, nil
+}
+
+// openAdapterStream opens the adapter stream, consulting the target's circuit breaker
+func (b *Bridge) openAdapterStream(ctx context.Context, adapter adapters.StreamingAdapter, target BridgeTarget, options adapters.StreamOptions) (adapters.AdapterStream, error) {
+	breaker := b.getCircuitBreaker(target)
+	if breaker != nil {
+		if err := breaker.allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	stream, err := adapter.OpenStream(ctx, options)
+	b.recordCallMetrics(target, time.Since(start), err)
+
+	if breaker != nil {
+		switch {
+		case err == nil:
+			breaker.recordSuccess()
+		case ctx.Err() == nil && isRetryableError(err):
+			breaker.recordFailure()
+		default:
+			breaker.release()
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	return stream, nil
+}
+
+// ID returns the stream identifier
+func (s *BridgeStream) ID() string {
+	return s.id
+}
+
+// Kind returns the stream kind
+func (s *BridgeStream) Kind() adapters.StreamKind {
+	return s.kind
+}
+
+// Context returns the stream context, which is done once the stream is closed
+func (s *BridgeStream) Context() context.Context {
+	return s.ctx
+}
+
+// Send encodes and sends one message. It blocks while the peer's flow
+// control window is full.
+func (s *BridgeStream) Send(data interface{}) error {
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
+
+	seq := atomic.AddInt64(&s.sendSeq, 1)
+	message := &plugins.ProtocolMessage{
+		ID:   generateID(),
+		Type: s.target.Operation,
+		Headers: map[string]string{
+			HeaderStreamID:  s.id,
+			HeaderStreamSeq: strconv.FormatInt(seq, 10),
+		},
+		Payload:   data,
+		Timestamp: time.Now(),
+	}
+
+	encoded, err := s.protocol.Encode(s.ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	if err := s.stream.Send(encoded); err != nil {
+		if s.ctx.Err() != nil {
+			return s.ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// Recv receives and decodes one message. It returns io.EOF once the peer has
+// finished sending.
+func (s *BridgeStream) Recv() (interface{}, error) {
+	message, err := s.RecvMessage()
+	if err != nil {
+		return nil, err
+	}
+	return message.Payload, nil
+}
+
+// RecvMessage receives one message including its headers
+func (s *BridgeStream) RecvMessage() (*plugins.ProtocolMessage, error) {
+	data, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	data, _, err = DecompressPayload(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress message: %w", err)
+	}
+
+	message, err := s.protocol.Decode(s.ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+	return message, nil
+}
+
+// CloseSend signals that the caller has finished sending
+func (s *BridgeStream) CloseSend() error {
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
+	return s.stream.CloseSend()
+}
+
+// Close closes the stream and releases its concurrency slot
+func (s *BridgeStream) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.stream.Close()
+		s.cancel()
+		s.release()
+		s.done()
+
+		s.bridge.logger.Debug("Closed bridge stream", map[string]interface{}{
+			"stream":   s.id,
+			"duration": time.Since(s.opened).String(),
+		})
+	})
+	return err
+}
@@ -0,0 +1,556 @@
+// token_budget.go - Token limits, payload chunking and usage accounting for bridge calls
+
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/tokens"
+)
+
+// Chunk headers carried by every message of a chunked call. Overlap is the
+// number of bytes at the start of a chunk repeated from the previous chunk
+// to preserve context.
+//
+// Replies are joined in chunk order. A responder whose reply begins with the
+// repeated bytes must echo the chunk-overlap header, and chunk-index when it
+// may reply out of order, so the repeat is stripped; a reply without the
+// header is joined whole.
+const (
+	HeaderChunkID      = "chunk-id"
+	HeaderChunkIndex   = "chunk-index"
+	HeaderChunkCount   = "chunk-count"
+	HeaderChunkOverlap = "chunk-overlap"
+)
+
+// tokenInfoMetadataKey is the protocol message metadata key holding TokenInfo
+const tokenInfoMetadataKey = "token_info"
+
+// anonymousCaller is the caller token usage is accounted to when none is set
+const anonymousCaller = "anonymous"
+
+// Common token budget errors
+var (
+	ErrTokenBudgetExceeded = errors.New("token budget exceeded")
+	ErrChunkSequence       = errors.New("invalid chunk sequence")
+)
+
+// callerContextKey is the context key for the caller accounted for token usage
+type callerContextKey struct{}
+
+// WithCaller returns a context naming the caller that bridge calls are accounted to
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, caller)
+}
+
+// CallerFromContext returns the caller stored in the context, if any
+func CallerFromContext(ctx context.Context) (string, bool) {
+	caller, ok := ctx.Value(callerContextKey{}).(string)
+	return caller, ok && caller != ""
+}
+
+// callCaller determines the caller of a call from the context or target options
+func callCaller(ctx context.Context, target BridgeTarget) string {
+	if caller, ok := CallerFromContext(ctx); ok {
+		return caller
+	}
+	if caller, ok := target.Options["caller"].(string); ok && caller != "" {
+		return caller
+	}
+	return anonymousCaller
+}
+
+// TokenUsage is the token usage accounted to one caller
+type TokenUsage struct {
+	Caller          string    `json:"caller"`
+	Requests        int64     `json:"requests"`
+	ChunkedRequests int64     `json:"chunked_requests"`
+	Chunks          int64     `json:"chunks"`
+	Rejected        int64     `json:"rejected"`
+	InputTokens     int64     `json:"input_tokens"`
+	OutputTokens    int64     `json:"output_tokens"`
+	TotalTokens     int64     `json:"total_tokens"`
+	LastRequest     time.Time `json:"last_request"`
+}
+
+// tokenBudget enforces TokenLimits on call payloads and accounts usage per caller
+type tokenBudget struct {
+	limits     TokenLimits
+	model      string
+	usage      map[string]*TokenUsage
+	usageMutex sync.Mutex
+}
+
+// tokenPlan describes how a call payload is sent
+type tokenPlan struct {
+	caller  string
+	tokens  int      // Tokens in the whole payload
+	chunks  []string // Payload pieces, nil when sent whole
+	overlap []int    // Bytes each chunk repeats from the previous one
+	binary  bool     // The payload was a byte slice
+}
+
+// newTokenBudget creates a token budget counting tokens for model
+func newTokenBudget(limits TokenLimits, model string) *tokenBudget {
+	return &tokenBudget{
+		limits: limits,
+		model:  model,
+		usage:  make(map[string]*TokenUsage),
+	}
+}
+
+// count estimates the tokens in text from the model's average rate
+func (t *tokenBudget) count(text string) int {
+	return tokens.CalculateTextTokens(text, t.model)
+}
+
+// inputLimit returns the most input tokens a single message may carry. Under
+// a total limit, room is left for the reply: MaxOutputTokens when it is set
+// and below the total, otherwise half the total.
+func (t *tokenBudget) inputLimit() int {
+	limit := t.limits.MaxInputTokens
+	if total := t.limits.MaxTotalTokens; total > 0 {
+		reserved := total / 2
+		if output := t.limits.MaxOutputTokens; output > 0 && output < total {
+			reserved = output
+		}
+		if room := total - reserved; limit <= 0 || room < limit {
+			limit = room
+		}
+	}
+	return limit
+}
+
+// plan checks a payload against the input limit. Oversized payloads are
+// split into overlapping chunks when chunking is enabled and rejected
+// otherwise.
+func (t *tokenBudget) plan(caller string, data interface{}) (*tokenPlan, error) {
+	text, binary, err := payloadText(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	plan := &tokenPlan{caller: caller, tokens: t.count(text), binary: binary}
+
+	limit := t.inputLimit()
+	if limit <= 0 || plan.tokens <= limit {
+		return plan, nil
+	}
+
+	if !t.limits.EnableChunking {
+		t.reject(caller)
+		return nil, fmt.Errorf("%w: payload of %d tokens exceeds the input limit of %d", ErrTokenBudgetExceeded, plan.tokens, limit)
+	}
+
+	chunkTokens, overlapTokens, err := t.chunkSizes(limit)
+	if err != nil {
+		t.reject(caller)
+		return nil, err
+	}
+
+	plan.chunks, plan.overlap = splitText(text, plan.tokens, chunkTokens, overlapTokens)
+	return plan, nil
+}
+
+// chunkSizes returns the chunk size and overlap in tokens for an input
+// limit. Unset values come from the model's allocation of the limit.
+func (t *tokenBudget) chunkSizes(limit int) (int, int, error) {
+	size := t.limits.ChunkSize
+	overlap := t.limits.PreserveContextSize
+
+	if size <= 0 {
+		allocation := tokens.Allocate(t.model, limit)
+		size = allocation.ChunkSize
+		if overlap <= 0 {
+			overlap = allocation.ChunkOverlap
+		}
+	}
+	if size > limit {
+		size = limit
+	}
+	if size <= 0 {
+		return 0, 0, fmt.Errorf("%w: input limit of %d tokens is too small to chunk", ErrTokenBudgetExceeded, limit)
+	}
+
+	// Every chunk must carry at least as much new content as repeated context
+	if overlap > size/2 {
+		overlap = size / 2
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+
+	return size, overlap, nil
+}
+
+// checkExchange enforces the output and total limits on one request and reply
+func (t *tokenBudget) checkExchange(input, output int) error {
+	if limit := t.limits.MaxOutputTokens; limit > 0 && output > limit {
+		return fmt.Errorf("%w: response of %d tokens exceeds the output limit of %d", ErrTokenBudgetExceeded, output, limit)
+	}
+	if limit := t.limits.MaxTotalTokens; limit > 0 && input+output > limit {
+		return fmt.Errorf("%w: %d tokens exceed the total limit of %d", ErrTokenBudgetExceeded, input+output, limit)
+	}
+	return nil
+}
+
+// record accounts the tokens of a call to its caller
+func (t *tokenBudget) record(caller string, info *TokenInfo) {
+	t.usageMutex.Lock()
+	defer t.usageMutex.Unlock()
+
+	usage := t.callerUsage(caller)
+	usage.Requests++
+	if info.IsChunked {
+		usage.ChunkedRequests++
+		usage.Chunks += int64(info.TotalChunks)
+	}
+	usage.InputTokens += int64(info.InputTokens)
+	usage.OutputTokens += int64(info.OutputTokens)
+	usage.TotalTokens += int64(info.TotalTokens)
+	usage.LastRequest = time.Now()
+}
+
+// reject accounts a call refused for exceeding the budget
+func (t *tokenBudget) reject(caller string) {
+	t.usageMutex.Lock()
+	defer t.usageMutex.Unlock()
+
+	usage := t.callerUsage(caller)
+	usage.Rejected++
+	usage.LastRequest = time.Now()
+}
+
+// callerUsage returns the usage of a caller; the caller holds usageMutex
+func (t *tokenBudget) callerUsage(caller string) *TokenUsage {
+	usage, exists := t.usage[caller]
+	if !exists {
+		usage = &TokenUsage{Caller: caller}
+		t.usage[caller] = usage
+	}
+	return usage
+}
+
+// snapshot returns the usage of every caller, ordered by caller
+func (t *tokenBudget) snapshot() []TokenUsage {
+	t.usageMutex.Lock()
+	defer t.usageMutex.Unlock()
+
+	usage := make([]TokenUsage, 0, len(t.usage))
+	for _, u := range t.usage {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Caller < usage[j].Caller })
+	return usage
+}
+
+// TokenUsage returns the token usage accounted to every caller
+func (b *Bridge) TokenUsage() []TokenUsage {
+	return b.tokens.snapshot()
+}
+
+// payloadText returns the text whose tokens are counted and chunked
+func payloadText(data interface{}) (string, bool, error) {
+	switch p := data.(type) {
+	case nil:
+		return "", false, nil
+	case []byte:
+		return string(p), true, nil
+	case string:
+		return p, false, nil
+	default:
+		encoded, err := json.Marshal(p)
+		if err != nil {
+			return "", false, err
+		}
+		return string(encoded), false, nil
+	}
+}
+
+// splitText splits text into chunks of about chunkTokens tokens, each
+// starting with about overlapTokens tokens of the previous chunk. Tokens are
+// mapped to bytes at the text's average rate, rounded so a chunk never
+// counts more than chunkTokens; cuts fall on rune boundaries and, where
+// possible, after whitespace.
+func splitText(text string, tokens, chunkTokens, overlapTokens int) ([]string, []int) {
+	bytesPerToken := float64(len(text)) / float64(tokens+1)
+	chunkBytes := int(float64(chunkTokens) * bytesPerToken)
+	overlapBytes := int(float64(overlapTokens) * bytesPerToken)
+	if chunkBytes < utf8.UTFMax {
+		chunkBytes = utf8.UTFMax
+	}
+	if overlapBytes > chunkBytes/2 {
+		overlapBytes = chunkBytes / 2
+	}
+
+	var chunks []string
+	var overlaps []int
+	start, repeated := 0, 0
+	for {
+		end := start + chunkBytes
+		if end >= len(text) {
+			chunks = append(chunks, text[start:])
+			overlaps = append(overlaps, repeated)
+			return chunks, overlaps
+		}
+		end = cutPoint(text, start+repeated, end)
+
+		chunks = append(chunks, text[start:end])
+		overlaps = append(overlaps, repeated)
+
+		next := runeStart(text, end-overlapBytes)
+		if next <= start {
+			next = end
+		}
+		start, repeated = next, end-next
+	}
+}
+
+// cutPoint moves end back to just after whitespace in the last quarter of
+// the chunk, or else to a rune boundary, never at or before min
+func cutPoint(text string, min, end int) int {
+	for i := end; i > end-(end-min)/4 && i > min; i-- {
+		r, _ := utf8.DecodeLastRuneInString(text[:i])
+		if unicode.IsSpace(r) {
+			return i
+		}
+	}
+	if aligned := runeStart(text, end); aligned > min {
+		return aligned
+	}
+	return end
+}
+
+// runeStart moves i back to the start of the rune containing it
+func runeStart(text string, i int) int {
+	if i <= 0 {
+		return 0
+	}
+	for back := 0; back < utf8.UTFMax && i > 0 && !utf8.RuneStart(text[i]); back++ {
+		i--
+	}
+	return i
+}
+
+// chunkMessages builds the correlated messages of a chunked call
+func (p *tokenPlan) chunkMessages(budget *tokenBudget, operation string) []*plugins.ProtocolMessage {
+	sequenceID := generateID()
+	messages := make([]*plugins.ProtocolMessage, 0, len(p.chunks))
+
+	for i, chunk := range p.chunks {
+		var payload interface{} = chunk
+		if p.binary {
+			payload = []byte(chunk)
+		}
+
+		tokens := budget.count(chunk)
+		messages = append(messages, &plugins.ProtocolMessage{
+			ID:   fmt.Sprintf("%s-%d", sequenceID, i),
+			Type: operation,
+			Headers: map[string]string{
+				HeaderChunkID:      sequenceID,
+				HeaderChunkIndex:   strconv.Itoa(i),
+				HeaderChunkCount:   strconv.Itoa(len(p.chunks)),
+				HeaderChunkOverlap: strconv.Itoa(p.overlap[i]),
+			},
+			Payload: payload,
+			Metadata: map[string]interface{}{
+				tokenInfoMetadataKey: &TokenInfo{
+					InputTokens:  tokens,
+					TotalTokens:  tokens,
+					ModelContext: budget.model,
+					IsChunked:    true,
+					ChunkIndex:   i,
+					TotalChunks:  len(p.chunks),
+				},
+			},
+			Timestamp: time.Now(),
+		})
+	}
+
+	return messages
+}
+
+// reassembleResponses combines the replies to a chunked call. Text replies
+// are joined in chunk order, dropping the overlap a reply echoes in its
+// chunk-overlap header; other replies are returned as a list in chunk order.
+func reassembleResponses(responses []*plugins.ProtocolMessage, binary bool) (interface{}, error) {
+	ordered := make([]*plugins.ProtocolMessage, len(responses))
+	copy(ordered, responses)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return chunkIndex(ordered[i], i) < chunkIndex(ordered[j], j)
+	})
+
+	var joined []byte
+	payloads := make([]interface{}, 0, len(ordered))
+	text := true
+	for _, response := range ordered {
+		payloads = append(payloads, response.Payload)
+
+		var piece []byte
+		switch p := response.Payload.(type) {
+		case []byte:
+			piece = p
+		case string:
+			piece = []byte(p)
+		default:
+			text = false
+			continue
+		}
+
+		overlap, err := chunkOverlap(response.Headers)
+		if err != nil {
+			return nil, err
+		}
+		if overlap > len(piece) {
+			return nil, fmt.Errorf("%w: overlap of %d bytes exceeds the chunk", ErrChunkSequence, overlap)
+		}
+		joined = append(joined, piece[overlap:]...)
+	}
+
+	if !text {
+		return payloads, nil
+	}
+	if binary {
+		return joined, nil
+	}
+	return string(joined), nil
+}
+
+// chunkIndex returns the index a reply declares, or its position
+func chunkIndex(message *plugins.ProtocolMessage, position int) int {
+	if index, err := strconv.Atoi(message.Headers[HeaderChunkIndex]); err == nil {
+		return index
+	}
+	return position
+}
+
+// chunkOverlap returns the overlap declared in chunk headers
+func chunkOverlap(headers map[string]string) (int, error) {
+	value, ok := headers[HeaderChunkOverlap]
+	if !ok {
+		return 0, nil
+	}
+	overlap, err := strconv.Atoi(value)
+	if err != nil || overlap < 0 {
+		return 0, fmt.Errorf("%w: bad %s header '%s'", ErrChunkSequence, HeaderChunkOverlap, value)
+	}
+	return overlap, nil
+}
+
+// ChunkAssembler reassembles the chunked payloads of bridge calls on the
+// receiving side. Sequences not completed within maxAge are discarded.
+type ChunkAssembler struct {
+	sequences     map[string]*chunkSequence
+	maxAge        time.Duration
+	sequenceMutex sync.Mutex
+}
+
+// chunkSequence holds the chunks received for one call
+type chunkSequence struct {
+	chunks   [][]byte
+	received int
+	started  time.Time
+}
+
+// NewChunkAssembler creates a chunk assembler
+func NewChunkAssembler(maxAge time.Duration) *ChunkAssembler {
+	return &ChunkAssembler{
+		sequences: make(map[string]*chunkSequence),
+		maxAge:    maxAge,
+	}
+}
+
+// Add records a received message. It returns the payload of the whole call
+// and true once every chunk has arrived; a message that is not a chunk is
+// returned at once.
+func (a *ChunkAssembler) Add(message *plugins.ProtocolMessage) ([]byte, bool, error) {
+	var payload []byte
+	switch p := message.Payload.(type) {
+	case []byte:
+		payload = p
+	case string:
+		payload = []byte(p)
+	default:
+		return nil, false, fmt.Errorf("%w: chunk payload must be text", ErrChunkSequence)
+	}
+
+	id, chunked := message.Headers[HeaderChunkID]
+	if !chunked {
+		return payload, true, nil
+	}
+
+	index, err := strconv.Atoi(message.Headers[HeaderChunkIndex])
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: bad %s header", ErrChunkSequence, HeaderChunkIndex)
+	}
+	count, err := strconv.Atoi(message.Headers[HeaderChunkCount])
+	if err != nil || count <= 0 || index < 0 || index >= count {
+		return nil, false, fmt.Errorf("%w: chunk %d of %s", ErrChunkSequence, index, message.Headers[HeaderChunkCount])
+	}
+	overlap, err := chunkOverlap(message.Headers)
+	if err != nil {
+		return nil, false, err
+	}
+	if overlap > len(payload) {
+		return nil, false, fmt.Errorf("%w: overlap of %d bytes exceeds the chunk", ErrChunkSequence, overlap)
+	}
+
+	a.sequenceMutex.Lock()
+	defer a.sequenceMutex.Unlock()
+
+	a.expire()
+
+	sequence, exists := a.sequences[id]
+	if !exists {
+		sequence = &chunkSequence{chunks: make([][]byte, count), started: time.Now()}
+		a.sequences[id] = sequence
+	}
+	if len(sequence.chunks) != count {
+		return nil, false, fmt.Errorf("%w: sequence %s changed chunk count", ErrChunkSequence, id)
+	}
+	if sequence.chunks[index] == nil {
+		sequence.received++
+	}
+	sequence.chunks[index] = append([]byte{}, payload[overlap:]...)
+
+	if sequence.received < count {
+		return nil, false, nil
+	}
+
+	delete(a.sequences, id)
+	var joined []byte
+	for _, chunk := range sequence.chunks {
+		joined = append(joined, chunk...)
+	}
+	return joined, true, nil
+}
+
+// Pending returns the number of incomplete sequences
+func (a *ChunkAssembler) Pending() int {
+	a.sequenceMutex.Lock()
+	defer a.sequenceMutex.Unlock()
+	return len(a.sequences)
+}
+
+// expire discards stale sequences; the caller holds sequenceMutex
+func (a *ChunkAssembler) expire() {
+	if a.maxAge <= 0 {
+		return
+	}
+	for id, sequence := range a.sequences {
+		if time.Since(sequence.started) > a.maxAge {
+			delete(a.sequences, id)
+		}
+	}
+}
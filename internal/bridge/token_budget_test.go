@@ -0,0 +1,376 @@
+package bridge
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/bridge/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// joinChunks rebuilds the text of a split by dropping every chunk's overlap
+func joinChunks(chunks []string, overlaps []int) string {
+	var joined strings.Builder
+	for i, chunk := range chunks {
+		joined.WriteString(chunk[overlaps[i]:])
+	}
+	return joined.String()
+}
+
+// echoReplies answers chunk messages with their own payloads and headers
+func echoReplies(messages []*plugins.ProtocolMessage) []*plugins.ProtocolMessage {
+	replies := make([]*plugins.ProtocolMessage, 0, len(messages))
+	for _, message := range messages {
+		replies = append(replies, &plugins.ProtocolMessage{Headers: message.Headers, Payload: message.Payload})
+	}
+	return replies
+}
+
+func TestTokenBudgetInputLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits TokenLimits
+		want   int
+	}{
+		{"no limits", TokenLimits{}, 0},
+		{"input only", TokenLimits{MaxInputTokens: 100}, 100},
+		{"total leaves half for the reply", TokenLimits{MaxTotalTokens: 100}, 50},
+		{"total leaves the output limit", TokenLimits{MaxTotalTokens: 100, MaxOutputTokens: 20}, 80},
+		{"output limit above the total", TokenLimits{MaxTotalTokens: 100, MaxOutputTokens: 150}, 50},
+		{"input below the room", TokenLimits{MaxInputTokens: 60, MaxTotalTokens: 100, MaxOutputTokens: 20}, 60},
+		{"room below the input", TokenLimits{MaxInputTokens: 90, MaxTotalTokens: 100, MaxOutputTokens: 20}, 80},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, newTokenBudget(tt.limits, "").inputLimit(), tt.name)
+	}
+}
+
+func TestTokenBudgetCount(t *testing.T) {
+	assert.Equal(t, 25, newTokenBudget(TokenLimits{}, "").count(strings.Repeat("a", 100)))
+	assert.Equal(t, 25, newTokenBudget(TokenLimits{}, "gpt-4").count(strings.Repeat("a", 100)))
+	assert.Equal(t, 22, newTokenBudget(TokenLimits{}, "claude-3-opus").count(strings.Repeat("a", 100)))
+
+	// Rates are per byte, so multi-byte text counts more per character
+	assert.Equal(t, 75, newTokenBudget(TokenLimits{}, "").count(strings.Repeat("語", 100)))
+}
+
+func TestTokenBudgetPlan(t *testing.T) {
+	text := strings.Repeat("lorem ipsum dolor sit amet ", 40) // 1080 bytes, 270 tokens
+
+	t.Run("payload within the limit is sent whole", func(t *testing.T) {
+		budget := newTokenBudget(TokenLimits{MaxInputTokens: 300}, "")
+		plan, err := budget.plan("alice", text)
+		require.NoError(t, err)
+		assert.Nil(t, plan.chunks)
+		assert.Equal(t, 270, plan.tokens)
+		assert.False(t, plan.binary)
+	})
+
+	t.Run("oversized payload is rejected without chunking", func(t *testing.T) {
+		budget := newTokenBudget(TokenLimits{MaxInputTokens: 100}, "")
+		_, err := budget.plan("alice", text)
+		assert.ErrorIs(t, err, ErrTokenBudgetExceeded)
+
+		usage := budget.snapshot()
+		require.Len(t, usage, 1)
+		assert.Equal(t, "alice", usage[0].Caller)
+		assert.Equal(t, int64(1), usage[0].Rejected)
+		assert.Zero(t, usage[0].Requests)
+	})
+
+	t.Run("chunks leave room for the reply", func(t *testing.T) {
+		limits := TokenLimits{MaxTotalTokens: 100, MaxOutputTokens: 40, EnableChunking: true}
+		budget := newTokenBudget(limits, "")
+		plan, err := budget.plan("alice", []byte(text))
+		require.NoError(t, err)
+		require.NotNil(t, plan.chunks)
+		assert.True(t, plan.binary)
+
+		for _, chunk := range plan.chunks {
+			assert.LessOrEqual(t, budget.count(chunk)+limits.MaxOutputTokens, limits.MaxTotalTokens)
+		}
+		assert.Equal(t, text, joinChunks(plan.chunks, plan.overlap))
+	})
+
+	t.Run("limit too small to chunk is rejected", func(t *testing.T) {
+		budget := newTokenBudget(TokenLimits{MaxInputTokens: 1, EnableChunking: true}, "")
+		_, err := budget.plan("bob", text)
+		assert.ErrorIs(t, err, ErrTokenBudgetExceeded)
+		assert.Equal(t, int64(1), budget.snapshot()[0].Rejected)
+	})
+}
+
+func TestTokenBudgetChunkSizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  TokenLimits
+		size    int
+		overlap int
+	}{
+		{"defaults from the limit", TokenLimits{}, 700, 70},
+		{"configured", TokenLimits{ChunkSize: 200, PreserveContextSize: 20}, 200, 20},
+		{"size capped by the limit", TokenLimits{ChunkSize: 5000}, 1000, 0},
+		{"overlap at most half the chunk", TokenLimits{ChunkSize: 100, PreserveContextSize: 80}, 100, 50},
+	}
+
+	for _, tt := range tests {
+		size, overlap, err := newTokenBudget(tt.limits, "").chunkSizes(1000)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.size, size, tt.name)
+		assert.Equal(t, tt.overlap, overlap, tt.name)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	budget := newTokenBudget(TokenLimits{}, "")
+	random := rand.New(rand.NewSource(1))
+	words := []string{"alpha", "beta", "γάμμα", "δέλτα", "日本語", "テキスト", "🙂", "x"}
+	var mixed strings.Builder
+	for mixed.Len() < 4000 {
+		mixed.WriteString(words[random.Intn(len(words))])
+		if random.Intn(3) > 0 {
+			mixed.WriteByte(' ')
+		}
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		chunk   int
+		overlap int
+	}{
+		{"ascii with spaces", strings.Repeat("the quick brown fox ", 100), 50, 10},
+		{"no whitespace", strings.Repeat("abcdefghij", 100), 40, 5},
+		{"multi-byte text", mixed.String(), 60, 12},
+		{"multi-byte without whitespace", strings.Repeat("日本語テキスト", 80), 30, 6},
+		{"overlap larger than the chunk", strings.Repeat("the quick brown fox ", 100), 20, 50},
+		{"single rune chunks", strings.Repeat("語", 50), 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := budget.count(tt.text)
+			chunks, overlaps := splitText(tt.text, tokens, tt.chunk, tt.overlap)
+			require.Len(t, overlaps, len(chunks))
+			require.Greater(t, len(chunks), 1)
+
+			assert.Equal(t, tt.text, joinChunks(chunks, overlaps))
+			assert.Zero(t, overlaps[0])
+			for i, chunk := range chunks {
+				assert.True(t, utf8.ValidString(chunk), "chunk %d is cut inside a rune", i)
+				assert.Less(t, overlaps[i], len(chunk), "chunk %d carries no new content", i)
+				if tt.chunk > 0 {
+					assert.LessOrEqual(t, budget.count(chunk), tt.chunk, "chunk %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCutPoint(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		min  int
+		end  int
+		want int
+	}{
+		{"after whitespace in the last quarter", "aaaaaa bbbbbbbbb", 0, 8, 7},
+		{"whitespace too early is ignored", "a bbbbbbbbbbbbbbbbbbb", 0, 16, 16},
+		{"back to a rune boundary", "aaa語bbb", 0, 4, 3},
+		{"never at or before min", "語語", 3, 4, 4},
+		{"already on a boundary", "abcdef", 0, 4, 4},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, cutPoint(tt.text, tt.min, tt.end), tt.name)
+	}
+}
+
+func TestChunkedCallReassembly(t *testing.T) {
+	text := strings.Repeat("Ünïcödé payload with spaces, ", 60)
+	budget := newTokenBudget(TokenLimits{MaxInputTokens: 80, EnableChunking: true, PreserveContextSize: 10}, "gpt-4")
+
+	for _, binary := range []bool{false, true} {
+		var data interface{} = text
+		if binary {
+			data = []byte(text)
+		}
+		plan, err := budget.plan("alice", data)
+		require.NoError(t, err)
+		messages := plan.chunkMessages(budget, "summarize")
+		require.Greater(t, len(messages), 2)
+
+		sequenceID := messages[0].Headers[HeaderChunkID]
+		for i, message := range messages {
+			assert.Equal(t, "summarize", message.Type)
+			assert.Equal(t, sequenceID, message.Headers[HeaderChunkID])
+			assert.Equal(t, len(messages), message.Metadata[tokenInfoMetadataKey].(*TokenInfo).TotalChunks)
+			assert.Equal(t, i, message.Metadata[tokenInfoMetadataKey].(*TokenInfo).ChunkIndex)
+		}
+
+		// Echoed replies in any order are stripped of their overlap
+		replies := echoReplies(messages)
+		rand.New(rand.NewSource(2)).Shuffle(len(replies), func(i, j int) { replies[i], replies[j] = replies[j], replies[i] })
+		result, err := reassembleResponses(replies, binary)
+		require.NoError(t, err)
+		if binary {
+			assert.Equal(t, []byte(text), result)
+		} else {
+			assert.Equal(t, text, result)
+		}
+	}
+
+	// Replies without chunk headers are joined whole, in order
+	result, err := reassembleResponses([]*plugins.ProtocolMessage{{Payload: "a"}, {Payload: []byte("b")}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "ab", result)
+
+	// Replies that are not text are listed in chunk order
+	result, err = reassembleResponses([]*plugins.ProtocolMessage{
+		{Headers: map[string]string{HeaderChunkIndex: "1"}, Payload: map[string]interface{}{"n": 1}},
+		{Headers: map[string]string{HeaderChunkIndex: "0"}, Payload: map[string]interface{}{"n": 0}},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"n": 0}, map[string]interface{}{"n": 1}}, result)
+
+	_, err = reassembleResponses([]*plugins.ProtocolMessage{
+		{Headers: map[string]string{HeaderChunkOverlap: "5"}, Payload: "abc"},
+	}, false)
+	assert.ErrorIs(t, err, ErrChunkSequence)
+	_, err = reassembleResponses([]*plugins.ProtocolMessage{
+		{Headers: map[string]string{HeaderChunkOverlap: "-1"}, Payload: "abc"},
+	}, false)
+	assert.ErrorIs(t, err, ErrChunkSequence)
+}
+
+func TestChunkAssembler(t *testing.T) {
+	text := strings.Repeat("chunked call payload, ", 50)
+	budget := newTokenBudget(TokenLimits{MaxInputTokens: 40, EnableChunking: true, PreserveContextSize: 8}, "")
+	plan, err := budget.plan("alice", text)
+	require.NoError(t, err)
+	messages := plan.chunkMessages(budget, "ingest")
+	require.Greater(t, len(messages), 2)
+
+	t.Run("out of order with duplicates", func(t *testing.T) {
+		assembler := NewChunkAssembler(time.Minute)
+		last := len(messages) - 1
+
+		// Every chunk but the first arrives twice, last to first
+		for i := last; i > 0; i-- {
+			_, done, err := assembler.Add(messages[i])
+			require.NoError(t, err)
+			assert.False(t, done)
+			_, done, err = assembler.Add(messages[i])
+			require.NoError(t, err)
+			assert.False(t, done, "a duplicate does not complete the sequence")
+		}
+		assert.Equal(t, 1, assembler.Pending())
+
+		payload, done, err := assembler.Add(messages[0])
+		require.NoError(t, err)
+		require.True(t, done)
+		assert.Equal(t, text, string(payload))
+		assert.Zero(t, assembler.Pending())
+	})
+
+	t.Run("plain message is returned at once", func(t *testing.T) {
+		payload, done, err := NewChunkAssembler(0).Add(&plugins.ProtocolMessage{Payload: "whole"})
+		require.NoError(t, err)
+		assert.True(t, done)
+		assert.Equal(t, "whole", string(payload))
+	})
+
+	t.Run("invalid chunks", func(t *testing.T) {
+		chunk := func(headers map[string]string, payload interface{}) *plugins.ProtocolMessage {
+			all := map[string]string{HeaderChunkID: "seq", HeaderChunkIndex: "0", HeaderChunkCount: "2"}
+			for key, value := range headers {
+				all[key] = value
+			}
+			return &plugins.ProtocolMessage{Headers: all, Payload: payload}
+		}
+
+		tests := []struct {
+			name    string
+			message *plugins.ProtocolMessage
+		}{
+			{"payload is not text", chunk(nil, 42)},
+			{"bad index", chunk(map[string]string{HeaderChunkIndex: "first"}, "a")},
+			{"index beyond the count", chunk(map[string]string{HeaderChunkIndex: "2"}, "a")},
+			{"negative index", chunk(map[string]string{HeaderChunkIndex: "-1"}, "a")},
+			{"no count", chunk(map[string]string{HeaderChunkCount: "0"}, "a")},
+			{"overlap beyond the payload", chunk(map[string]string{HeaderChunkOverlap: "4"}, "abc")},
+			{"bad overlap", chunk(map[string]string{HeaderChunkOverlap: "x"}, "abc")},
+		}
+		for _, tt := range tests {
+			_, done, err := NewChunkAssembler(0).Add(tt.message)
+			assert.ErrorIs(t, err, ErrChunkSequence, tt.name)
+			assert.False(t, done, tt.name)
+		}
+
+		assembler := NewChunkAssembler(0)
+		_, _, err := assembler.Add(chunk(nil, "a"))
+		require.NoError(t, err)
+		_, _, err = assembler.Add(chunk(map[string]string{HeaderChunkIndex: "1", HeaderChunkCount: "3"}, "b"))
+		assert.ErrorIs(t, err, ErrChunkSequence, "count changed within a sequence")
+	})
+
+	t.Run("stale sequences expire", func(t *testing.T) {
+		assembler := NewChunkAssembler(10 * time.Millisecond)
+		_, _, err := assembler.Add(messages[0])
+		require.NoError(t, err)
+		assert.Equal(t, 1, assembler.Pending())
+
+		time.Sleep(20 * time.Millisecond)
+		_, _, err = assembler.Add(&plugins.ProtocolMessage{
+			Headers: map[string]string{HeaderChunkID: "other", HeaderChunkIndex: "0", HeaderChunkCount: "2"},
+			Payload: "x",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, assembler.Pending(), "only the new sequence is left")
+	})
+}
+
+func TestTokenUsagePerCaller(t *testing.T) {
+	budget := newTokenBudget(TokenLimits{}, "")
+	budget.record("bob", &TokenInfo{InputTokens: 10, OutputTokens: 5, TotalTokens: 15})
+	budget.record("alice", &TokenInfo{InputTokens: 30, OutputTokens: 10, TotalTokens: 40, IsChunked: true, TotalChunks: 3})
+	budget.record("alice", &TokenInfo{InputTokens: 2, OutputTokens: 1, TotalTokens: 3})
+	budget.reject("bob")
+
+	usage := budget.snapshot()
+	require.Len(t, usage, 2)
+
+	alice, bob := usage[0], usage[1]
+	assert.Equal(t, "alice", alice.Caller)
+	assert.Equal(t, int64(2), alice.Requests)
+	assert.Equal(t, int64(1), alice.ChunkedRequests)
+	assert.Equal(t, int64(3), alice.Chunks)
+	assert.Equal(t, int64(32), alice.InputTokens)
+	assert.Equal(t, int64(11), alice.OutputTokens)
+	assert.Equal(t, int64(43), alice.TotalTokens)
+	assert.Zero(t, alice.Rejected)
+
+	assert.Equal(t, "bob", bob.Caller)
+	assert.Equal(t, int64(1), bob.Requests)
+	assert.Equal(t, int64(1), bob.Rejected)
+	assert.False(t, bob.LastRequest.IsZero())
+
+	// Snapshots are copies
+	usage[0].Requests = 100
+	assert.Equal(t, int64(2), budget.snapshot()[0].Requests)
+
+	// The context names the caller before the target options do
+	target := BridgeTarget{Options: map[string]interface{}{"caller": "from-options"}}
+	ctx := context.Background()
+	assert.Equal(t, "from-options", callCaller(ctx, target))
+	assert.Equal(t, "from-context", callCaller(WithCaller(ctx, "from-context"), target))
+	assert.Equal(t, anonymousCaller, callCaller(ctx, BridgeTarget{}))
+	assert.Equal(t, anonymousCaller, callCaller(WithCaller(ctx, ""), BridgeTarget{}))
+}
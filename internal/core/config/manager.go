@@ -59,6 +59,7 @@ type IPMaskingConfig struct {
 type BridgeConfig struct {
 	Protocols []string        `mapstructure:"protocols"`
 	Discovery DiscoveryConfig `mapstructure:"discovery"`
+	Plugins   PluginsConfig   `mapstructure:"plugins"`
 }
 
 // DiscoveryConfig represents service discovery configuration
@@ -67,6 +68,12 @@ type DiscoveryConfig struct {
 	RefreshInterval time.Duration `mapstructure:"refreshInterval"`
 }
 
+// PluginsConfig represents plugin configuration
+type PluginsConfig struct {
+	ConfigPath   string        `mapstructure:"configPath"`   // File watched for plugin config changes; empty disables watching
+	PollInterval time.Duration `mapstructure:"pollInterval"` // How often the file is checked for changes
+}
+
 // MonitoringConfig represents monitoring-specific configuration
 type MonitoringConfig struct {
 	Metrics    MetricsConfig    `mapstructure:"metrics"`
@@ -149,6 +156,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("bridge.protocols", []string{"grpc", "rest", "websocket"})
 	v.SetDefault("bridge.discovery.enabled", true)
 	v.SetDefault("bridge.discovery.refreshInterval", "30s")
+	v.SetDefault("bridge.plugins.configPath", "")
+	v.SetDefault("bridge.plugins.pollInterval", "5s")
 
 	// Monitoring defaults
 	v.SetDefault("monitoring.metrics.enabled", true)
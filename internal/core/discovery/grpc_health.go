@@ -0,0 +1,199 @@
+// grpc_health.go - gRPC health protocol probing for service instances
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Instance metadata keys that select gRPC health checking
+const (
+	// MetadataProtocol names the instance protocol; "grpc" instances are
+	// checked over grpc.health.v1 at their Address
+	MetadataProtocol = "protocol"
+
+	// MetadataGRPCHealthService is the service name sent in health requests;
+	// empty asks about the server as a whole
+	MetadataGRPCHealthService = "grpc_health_service"
+
+	// ProtocolGRPC is the MetadataProtocol value of gRPC instances
+	ProtocolGRPC = "grpc"
+)
+
+// isGRPCInstance reports whether an instance is checked over the gRPC health protocol
+func isGRPCInstance(instance *ServiceInstance) bool {
+	return instance.Metadata[MetadataProtocol] == ProtocolGRPC
+}
+
+// SetGRPCDialOptions sets the options used to connect to gRPC instances.
+// By default connections are made without transport security.
+func (h *HealthCheckerImpl) SetGRPCDialOptions(opts ...grpc.DialOption) {
+	h.grpcMutex.Lock()
+	defer h.grpcMutex.Unlock()
+
+	h.grpcDialOptions = opts
+}
+
+// grpcConn returns the shared connection to an instance address
+func (h *HealthCheckerImpl) grpcConn(address string) (*grpc.ClientConn, error) {
+	h.grpcMutex.Lock()
+	defer h.grpcMutex.Unlock()
+
+	if conn, exists := h.grpcConns[address]; exists {
+		return conn, nil
+	}
+
+	opts := h.grpcDialOptions
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+
+	conn, err := grpc.NewClient(address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+
+	h.grpcConns[address] = conn
+	return conn, nil
+}
+
+// releaseGRPCConn closes the connection to an address no monitor uses any
+// more. The caller must hold h.mutex.
+func (h *HealthCheckerImpl) releaseGRPCConn(address string) {
+	for _, monitor := range h.monitors {
+		if monitor.instance.Address == address {
+			return
+		}
+	}
+
+	h.grpcMutex.Lock()
+	defer h.grpcMutex.Unlock()
+
+	if conn, exists := h.grpcConns[address]; exists {
+		conn.Close()
+		delete(h.grpcConns, address)
+	}
+}
+
+// closeGRPCConns closes every gRPC connection
+func (h *HealthCheckerImpl) closeGRPCConns() {
+	h.grpcMutex.Lock()
+	defer h.grpcMutex.Unlock()
+
+	for address, conn := range h.grpcConns {
+		conn.Close()
+		delete(h.grpcConns, address)
+	}
+}
+
+// checkGRPCHealth performs a single grpc.health.v1 Check
+func (h *HealthCheckerImpl) checkGRPCHealth(instance *ServiceInstance) (ServiceStatus, error) {
+	conn, err := h.grpcConn(instance.Address)
+	if err != nil {
+		return StatusDown, err
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, h.defaultTimeout)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: instance.Metadata[MetadataGRPCHealthService],
+	})
+	if err != nil {
+		return grpcErrorStatus(err)
+	}
+
+	return grpcServingStatus(resp.Status), nil
+}
+
+// watchGRPCHealth follows an instance's health over a Watch stream, reopening
+// it after failures. It returns true if monitoring should continue by
+// polling: the server does not implement Watch, or a custom handler replaced
+// gRPC checking.
+func (h *HealthCheckerImpl) watchGRPCHealth(ctx context.Context, monitor *healthCheckMonitor) bool {
+	for {
+		err := h.watchGRPCStream(ctx, monitor)
+
+		select {
+		case <-h.ctx.Done():
+			return false
+		case <-monitor.stopChan:
+			return false
+		default:
+		}
+
+		if ctx.Err() != nil || status.Code(err) == codes.Unimplemented {
+			return true
+		}
+
+		// The stream broke, so the instance can't be vouched for until it reopens
+		h.recordStatus(monitor, StatusDown, err)
+
+		timer := time.NewTimer(monitor.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return true
+		case <-monitor.stopChan:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// watchGRPCStream records every status sent on one Watch stream until it ends
+func (h *HealthCheckerImpl) watchGRPCStream(ctx context.Context, monitor *healthCheckMonitor) error {
+	conn, err := h.grpcConn(monitor.instance.Address)
+	if err != nil {
+		return err
+	}
+
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{
+		Service: monitor.instance.Metadata[MetadataGRPCHealthService],
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		h.recordStatus(monitor, grpcServingStatus(resp.Status), nil)
+	}
+}
+
+// grpcServingStatus maps a health protocol status to a service status
+func grpcServingStatus(servingStatus healthpb.HealthCheckResponse_ServingStatus) ServiceStatus {
+	switch servingStatus {
+	case healthpb.HealthCheckResponse_SERVING:
+		return StatusUp
+	case healthpb.HealthCheckResponse_NOT_SERVING, healthpb.HealthCheckResponse_SERVICE_UNKNOWN:
+		return StatusDown
+	default:
+		return StatusUnknown
+	}
+}
+
+// grpcErrorStatus maps a failed health Check to a service status
+func grpcErrorStatus(err error) (ServiceStatus, error) {
+	switch status.Code(err) {
+	case codes.NotFound:
+		// The server does not know the requested service
+		return StatusDown, nil
+	case codes.Unimplemented:
+		return StatusUnknown, fmt.Errorf("instance does not implement grpc.health.v1: %w", err)
+	default:
+		return StatusDown, err
+	}
+}
@@ -0,0 +1,190 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// statusRecorder is a Registry that reports every status update
+type statusRecorder struct {
+	Registry
+	updates chan ServiceStatus
+}
+
+func newStatusRecorder() *statusRecorder {
+	return &statusRecorder{updates: make(chan ServiceStatus, 16)}
+}
+
+// UpdateStatus implements Registry
+func (r *statusRecorder) UpdateStatus(serviceID string, status ServiceStatus) error {
+	r.updates <- status
+	return nil
+}
+
+// next returns the next status update
+func (r *statusRecorder) next(t *testing.T) ServiceStatus {
+	t.Helper()
+
+	select {
+	case status := <-r.updates:
+		return status
+	case <-time.After(2 * time.Second):
+		t.Fatal("no status update")
+		return ""
+	}
+}
+
+// checkOnlyHealthServer implements Check but not Watch, like older servers
+type checkOnlyHealthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (s *checkOnlyHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// startHealthServer serves the given health implementation, or none when nil
+func startHealthServer(t *testing.T, server healthpb.HealthServer) string {
+	t.Helper()
+
+	grpcServer := grpc.NewServer()
+	if server != nil {
+		healthpb.RegisterHealthServer(grpcServer, server)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().String()
+}
+
+// grpcInstance describes a gRPC instance checked for the given service
+func grpcInstance(address, service string) *ServiceInstance {
+	return &ServiceInstance{
+		ID:      "orders-1",
+		Name:    "orders",
+		Address: address,
+		Metadata: ServiceMetadata{
+			MetadataProtocol:          ProtocolGRPC,
+			MetadataGRPCHealthService: service,
+		},
+	}
+}
+
+func TestGRPCStatusMapping(t *testing.T) {
+	assert.Equal(t, StatusUp, grpcServingStatus(healthpb.HealthCheckResponse_SERVING))
+	assert.Equal(t, StatusDown, grpcServingStatus(healthpb.HealthCheckResponse_NOT_SERVING))
+	assert.Equal(t, StatusDown, grpcServingStatus(healthpb.HealthCheckResponse_SERVICE_UNKNOWN))
+	assert.Equal(t, StatusUnknown, grpcServingStatus(healthpb.HealthCheckResponse_UNKNOWN))
+
+	tests := []struct {
+		err     error
+		want    ServiceStatus
+		failure bool
+	}{
+		{status.Error(codes.NotFound, "unknown service"), StatusDown, false},
+		{status.Error(codes.Unimplemented, "unknown service grpc.health.v1.Health"), StatusUnknown, true},
+		{status.Error(codes.Unavailable, "connection refused"), StatusDown, true},
+		{errors.New("dial failed"), StatusDown, true},
+	}
+
+	for _, tt := range tests {
+		got, err := grpcErrorStatus(tt.err)
+		assert.Equal(t, tt.want, got, tt.err.Error())
+		assert.Equal(t, tt.failure, err != nil, tt.err.Error())
+	}
+}
+
+func TestCheckHealthOverGRPC(t *testing.T) {
+	server := health.NewServer()
+	server.SetServingStatus("acme.Orders", healthpb.HealthCheckResponse_SERVING)
+	address := startHealthServer(t, server)
+
+	checker := NewHealthChecker(time.Second)
+	defer checker.Stop()
+
+	status, err := checker.CheckHealth(grpcInstance(address, "acme.Orders"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusUp, status)
+
+	server.SetServingStatus("acme.Orders", healthpb.HealthCheckResponse_NOT_SERVING)
+	status, err = checker.CheckHealth(grpcInstance(address, "acme.Orders"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusDown, status)
+
+	// A service the server does not know is down rather than failing the check
+	status, err = checker.CheckHealth(grpcInstance(address, "acme.Payments"))
+	require.NoError(t, err)
+	assert.Equal(t, StatusDown, status)
+
+	// The server as a whole
+	status, err = checker.CheckHealth(grpcInstance(address, ""))
+	require.NoError(t, err)
+	assert.Equal(t, StatusUp, status)
+
+	status, err = checker.CheckHealth(grpcInstance(startHealthServer(t, nil), ""))
+	assert.Error(t, err)
+	assert.Equal(t, StatusUnknown, status)
+}
+
+func TestMonitorGRPCInstanceByWatch(t *testing.T) {
+	server := health.NewServer()
+	server.SetServingStatus("acme.Orders", healthpb.HealthCheckResponse_SERVING)
+	address := startHealthServer(t, server)
+
+	checker := NewHealthChecker(time.Second)
+	defer checker.Stop()
+	recorder := newStatusRecorder()
+	checker.SetRegistry(recorder)
+
+	// The interval is too long to poll, so updates arrive over Watch
+	require.NoError(t, checker.StartMonitoring(grpcInstance(address, "acme.Orders"), time.Hour))
+	assert.Equal(t, StatusUp, recorder.next(t))
+
+	server.SetServingStatus("acme.Orders", healthpb.HealthCheckResponse_NOT_SERVING)
+	assert.Equal(t, StatusDown, recorder.next(t))
+
+	last, ok := checker.LastStatus("orders-1")
+	require.True(t, ok)
+	assert.Equal(t, StatusDown, last)
+
+	// The connection goes with the last monitor of the address
+	require.NoError(t, checker.StopMonitoring("orders-1"))
+	checker.grpcMutex.Lock()
+	assert.Empty(t, checker.grpcConns)
+	checker.grpcMutex.Unlock()
+}
+
+func TestMonitorGRPCInstanceFallsBackToPolling(t *testing.T) {
+	address := startHealthServer(t, &checkOnlyHealthServer{})
+
+	checker := NewHealthChecker(time.Second)
+	defer checker.Stop()
+	recorder := newStatusRecorder()
+	checker.SetRegistry(recorder)
+
+	require.NoError(t, checker.StartMonitoring(grpcInstance(address, ""), 10*time.Millisecond))
+	assert.Equal(t, StatusUp, recorder.next(t))
+
+	// A custom handler replaces gRPC checks
+	require.NoError(t, checker.SetHealthCheckHandler("orders-1", func() (ServiceStatus, error) {
+		return StatusMaintenance, nil
+	}))
+	require.Eventually(t, func() bool {
+		last, _ := checker.LastStatus("orders-1")
+		return last == StatusMaintenance
+	}, 2*time.Second, 5*time.Millisecond)
+}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"sync"
 	"time"
+	
+	"google.golang.org/grpc"
 )
 
 // HealthCheckMode defines how health checks are performed
@@ -19,6 +21,9 @@ const (
 	
 	// ModeCustom uses a custom health check function
 	ModeCustom
+	
+	// ModeGRPC performs health checks via the grpc.health.v1 protocol
+	ModeGRPC
 )
 
 // healthCheckMonitor tracks a single service's health
@@ -31,6 +36,7 @@ type healthCheckMonitor struct {
 	lastStatus  ServiceStatus
 	stopChan    chan struct{}
 	registry    Registry
+	watchCancel context.CancelFunc // ends the gRPC Watch stream
 }
 
 // HealthCheckerImpl implements the HealthChecker interface
@@ -42,6 +48,11 @@ type HealthCheckerImpl struct {
 	defaultTimeout time.Duration
 	ctx            context.Context
 	cancel         context.CancelFunc
+	
+	// Connections to gRPC instances, shared by checks of the same address
+	grpcConns       map[string]*grpc.ClientConn
+	grpcDialOptions []grpc.DialOption
+	grpcMutex       sync.Mutex
 }
 
 // NewHealthChecker creates a new health checker
@@ -54,6 +65,7 @@ func NewHealthChecker(timeout time.Duration) *HealthCheckerImpl {
 		defaultTimeout: timeout,
 		ctx:            ctx,
 		cancel:         cancel,
+		grpcConns:      make(map[string]*grpc.ClientConn),
 	}
 	
 	return checker
@@ -78,6 +90,10 @@ func (h *HealthCheckerImpl) CheckHealth(instance *ServiceInstance) (ServiceStatu
 		return monitor.handler()
 	}
 	
+	if isGRPCInstance(instance) {
+		return h.checkGRPCHealth(instance)
+	}
+	
 	// Default to HTTP health check
 	if instance.HealthCheckURL == nil {
 		return StatusUnknown, fmt.Errorf("health check URL not defined")
@@ -128,10 +144,17 @@ func (h *HealthCheckerImpl) StartMonitoring(instance *ServiceInstance, interval
 		lastCheck: time.Time{},
 	}
 	
+	// gRPC instances push status changes over a Watch stream
+	watchCtx := h.ctx
+	if isGRPCInstance(instance) {
+		monitor.mode = ModeGRPC
+		watchCtx, monitor.watchCancel = context.WithCancel(h.ctx)
+	}
+	
 	h.monitors[instance.ID] = monitor
 	
 	// Start monitoring goroutine
-	go h.monitorService(monitor)
+	go h.monitorService(watchCtx, monitor)
 	
 	return nil
 }
@@ -148,8 +171,15 @@ func (h *HealthCheckerImpl) StopMonitoring(serviceID string) error {
 	
 	// Signal the monitoring goroutine to stop
 	close(monitor.stopChan)
+	if monitor.watchCancel != nil {
+		monitor.watchCancel()
+	}
 	delete(h.monitors, serviceID)
 	
+	if isGRPCInstance(monitor.instance) {
+		h.releaseGRPCConn(monitor.instance.Address)
+	}
+	
 	return nil
 }
 
@@ -166,11 +196,38 @@ func (h *HealthCheckerImpl) SetHealthCheckHandler(serviceID string, handler func
 	monitor.mode = ModeCustom
 	monitor.handler = handler
 	
+	// A running Watch stream gives way to polling the handler
+	if monitor.watchCancel != nil {
+		monitor.watchCancel()
+	}
+	
 	return nil
 }
 
-// monitorService periodically checks service health
-func (h *HealthCheckerImpl) monitorService(monitor *healthCheckMonitor) {
+// LastStatus returns the most recent health check result for a monitored service
+func (h *HealthCheckerImpl) LastStatus(serviceID string) (ServiceStatus, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	
+	monitor, exists := h.monitors[serviceID]
+	if !exists || monitor.lastCheck.IsZero() {
+		return StatusUnknown, false
+	}
+	
+	return monitor.lastStatus, true
+}
+
+// monitorService periodically checks service health. gRPC instances are
+// followed over a Watch stream until the server turns out not to support it.
+func (h *HealthCheckerImpl) monitorService(watchCtx context.Context, monitor *healthCheckMonitor) {
+	h.mutex.RLock()
+	mode := monitor.mode
+	h.mutex.RUnlock()
+	
+	if mode == ModeGRPC && !h.watchGRPCHealth(watchCtx, monitor) {
+		return
+	}
+	
 	ticker := time.NewTicker(monitor.interval)
 	defer ticker.Stop()
 	
@@ -182,25 +239,31 @@ func (h *HealthCheckerImpl) monitorService(monitor *healthCheckMonitor) {
 			return
 		case <-ticker.C:
 			status, err := h.CheckHealth(monitor.instance)
-			
-			// Only update if status changed or there was an error
-			if err != nil || monitor.lastStatus != status {
-				if h.registry != nil {
-					h.registry.UpdateStatus(monitor.instance.ID, status)
-				}
-				
-				// Update monitoring state
-				h.mutex.Lock()
-				if mon, exists := h.monitors[monitor.instance.ID]; exists {
-					mon.lastStatus = status
-					mon.lastCheck = time.Now()
-				}
-				h.mutex.Unlock()
-			}
+			h.recordStatus(monitor, status, err)
 		}
 	}
 }
 
+// recordStatus publishes a health check result to the registry
+func (h *HealthCheckerImpl) recordStatus(monitor *healthCheckMonitor, status ServiceStatus, err error) {
+	h.mutex.Lock()
+	mon, exists := h.monitors[monitor.instance.ID]
+	if !exists || mon != monitor {
+		h.mutex.Unlock()
+		return
+	}
+	
+	// Only update if status changed or there was an error
+	changed := err != nil || monitor.lastStatus != status || monitor.lastCheck.IsZero()
+	monitor.lastStatus = status
+	monitor.lastCheck = time.Now()
+	h.mutex.Unlock()
+	
+	if changed && h.registry != nil {
+		h.registry.UpdateStatus(monitor.instance.ID, status)
+	}
+}
+
 // Stop stops all health checkers
 func (h *HealthCheckerImpl) Stop() {
 	h.cancel()
@@ -213,4 +276,6 @@ func (h *HealthCheckerImpl) Stop() {
 		close(monitor.stopChan)
 		delete(h.monitors, id)
 	}
+	
+	h.closeGRPCConns()
 }
@@ -34,6 +34,11 @@ const (
 	StatusUnknown ServiceStatus = "UNKNOWN"
 )
 
+// IsAvailable reports whether a service in this status can accept traffic
+func (s ServiceStatus) IsAvailable() bool {
+	return s == StatusUp || s == StatusDegraded
+}
+
 // ServiceMetadata stores additional information about a service
 type ServiceMetadata map[string]string
 
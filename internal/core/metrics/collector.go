@@ -28,6 +28,12 @@ type Collector struct {
 	// Network metrics
 	networkIn  prometheus.Counter
 	networkOut prometheus.Counter
+
+	// Bridge metrics
+	circuitBreakerState       *prometheus.GaugeVec
+	circuitBreakerTransitions *prometheus.CounterVec
+	compressionRatio          *prometheus.HistogramVec
+	compressionBytes          *prometheus.CounterVec
 }
 
 // NewCollector creates a new metrics collector
@@ -95,6 +101,37 @@ func NewCollector(config config.MetricsConfig, logger *zap.SugaredLogger) *Colle
 				Help: "Total number of bytes sent",
 			},
 		),
+
+		// Bridge metrics
+		circuitBreakerState: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "bridge_circuit_breaker_state",
+				Help: "Current circuit breaker state per bridge target (1 for the active state)",
+			},
+			[]string{"target", "state"},
+		),
+		circuitBreakerTransitions: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "bridge_circuit_breaker_transitions_total",
+				Help: "Total number of circuit breaker state transitions",
+			},
+			[]string{"target", "from", "to"},
+		),
+		compressionRatio: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "bridge_compression_ratio",
+				Help:    "Compressed size divided by original size for bridge payloads",
+				Buckets: []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1.0, 1.2},
+			},
+			[]string{"algorithm"},
+		),
+		compressionBytes: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "bridge_compression_bytes_total",
+				Help: "Total bytes passed through bridge compression, before and after",
+			},
+			[]string{"algorithm", "stage"},
+		),
 	}
 
 	// Register the metrics
@@ -107,6 +144,10 @@ func NewCollector(config config.MetricsConfig, logger *zap.SugaredLogger) *Colle
 		collector.diskUsage,
 		collector.networkIn,
 		collector.networkOut,
+		collector.circuitBreakerState,
+		collector.circuitBreakerTransitions,
+		collector.compressionRatio,
+		collector.compressionBytes,
 	)
 
 	// Start the resource metrics collection
@@ -135,6 +176,25 @@ func (c *Collector) RecordNetworkActivity(bytesIn, bytesOut float64) {
 	c.networkOut.Add(bytesOut)
 }
 
+// RecordCircuitBreakerTransition records a circuit breaker state change for a bridge target
+func (c *Collector) RecordCircuitBreakerTransition(target, from, to string) {
+	if from != "" {
+		c.circuitBreakerState.WithLabelValues(target, from).Set(0)
+	}
+	c.circuitBreakerState.WithLabelValues(target, to).Set(1)
+	c.circuitBreakerTransitions.WithLabelValues(target, from, to).Inc()
+}
+
+// RecordCompression records the effect of compressing a bridge payload
+func (c *Collector) RecordCompression(algorithm string, originalSize, compressedSize int) {
+	if originalSize <= 0 {
+		return
+	}
+	c.compressionRatio.WithLabelValues(algorithm).Observe(float64(compressedSize) / float64(originalSize))
+	c.compressionBytes.WithLabelValues(algorithm, "original").Add(float64(originalSize))
+	c.compressionBytes.WithLabelValues(algorithm, "compressed").Add(float64(compressedSize))
+}
+
 // Collect provides backwards compatibility with existing code that uses the Collect method
 func (c *Collector) Collect(source string, name string, value float64, tags map[string]string) {
 	// Convert tags to a slice of string values for Prometheus labels
@@ -0,0 +1,73 @@
+// tokens.go - Per-model token estimates and budget allocation shared by the bridge and risk analysis
+
+package tokens
+
+// DefaultTokensPerByte estimates tokens for models without a known rate,
+// about four bytes of text per token
+const DefaultTokensPerByte = 0.25
+
+// modelTokensPerByte are the estimated token rates of known models; these
+// are rough averages, a real tokenizer would be model specific
+var modelTokensPerByte = map[string]float64{
+	"gpt-3.5-turbo":   0.25, // 4 chars per token
+	"gpt-4":           0.25,
+	"claude-3-sonnet": 0.22, // ~4.5 chars per token
+	"claude-3-opus":   0.22,
+	"llama-2-70b":     0.23, // ~4.3 chars per token
+	"gemini-pro":      0.24, // ~4.2 chars per token
+}
+
+// TokensPerByte returns the estimated token rate of a model, falling back to
+// DefaultTokensPerByte, and whether the model has a known rate
+func TokensPerByte(modelID string) (float64, bool) {
+	rate, known := modelTokensPerByte[modelID]
+	if !known {
+		return DefaultTokensPerByte, false
+	}
+	return rate, true
+}
+
+// CalculateTextTokens estimates the number of tokens in a text string for a specific model
+func CalculateTextTokens(text string, modelID string) int {
+	rate, _ := TokensPerByte(modelID)
+	return int(float64(len(text)) * rate)
+}
+
+// AllocationStrategy defines how tokens should be allocated to a model
+type AllocationStrategy struct {
+	// ModelID is the target model
+	ModelID string
+
+	// MaxBudget is the maximum token budget to allocate
+	MaxBudget int
+
+	// InputOutputRatio is the recommended ratio between input and output tokens
+	// (e.g., 0.75 means 75% for input, 25% for output)
+	InputOutputRatio float64
+
+	// ReservedSystemTokens is tokens reserved for system messages/overhead
+	ReservedSystemTokens int
+
+	// ChunkingThreshold is when to start chunking content
+	ChunkingThreshold int
+
+	// ChunkSize is the recommended chunk size when chunking
+	ChunkSize int
+
+	// ChunkOverlap is the recommended overlap between chunks
+	ChunkOverlap int
+}
+
+// Allocate returns the allocation strategy for a budget given to one model:
+// chunking starts at 80% of the budget, with chunks of 70% overlapping by 7%
+func Allocate(modelID string, budget int) AllocationStrategy {
+	return AllocationStrategy{
+		ModelID:              modelID,
+		MaxBudget:            budget,
+		InputOutputRatio:     0.8,
+		ReservedSystemTokens: 100,
+		ChunkingThreshold:    budget * 8 / 10,
+		ChunkSize:            budget * 7 / 10,
+		ChunkOverlap:         budget * 7 / 100,
+	}
+}
@@ -0,0 +1,42 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateTextTokens(t *testing.T) {
+	text := strings.Repeat("a", 100)
+
+	tests := []struct {
+		model string
+		want  int
+		known bool
+	}{
+		{"gpt-4", 25, true},
+		{"claude-3-opus", 22, true},
+		{"llama-2-70b", 23, true},
+		{"gemini-pro", 24, true},
+		{"", 25, false},
+		{"unknown-model", 25, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CalculateTextTokens(text, tt.model), tt.model)
+		_, known := TokensPerByte(tt.model)
+		assert.Equal(t, tt.known, known, tt.model)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	strategy := Allocate("gpt-4", 1000)
+	assert.Equal(t, "gpt-4", strategy.ModelID)
+	assert.Equal(t, 1000, strategy.MaxBudget)
+	assert.Equal(t, 800, strategy.ChunkingThreshold)
+	assert.Equal(t, 700, strategy.ChunkSize)
+	assert.Equal(t, 70, strategy.ChunkOverlap)
+
+	assert.Zero(t, Allocate("gpt-4", 0).ChunkSize)
+}
@@ -5,6 +5,8 @@ package risk
 import (
 	"fmt"
 	"sync"
+
+	"github.com/IAM-timmy1t/Quant_WebWork_GO/internal/core/tokens"
 )
 
 // TokenConverter provides functionality for managing tokens across different models
@@ -54,29 +56,7 @@ type ModelTokenCapacity struct {
 }
 
 // TokenAllocationStrategy defines how tokens should be allocated in a multi-model context
-type TokenAllocationStrategy struct {
-	// ModelID is the target model
-	ModelID string
-	
-	// MaxBudget is the maximum token budget to allocate
-	MaxBudget int
-	
-	// InputOutputRatio is the recommended ratio between input and output tokens
-	// (e.g., 0.75 means 75% for input, 25% for output)
-	InputOutputRatio float64
-	
-	// ReservedSystemTokens is tokens reserved for system messages/overhead
-	ReservedSystemTokens int
-	
-	// ChunkingThreshold is when to start chunking content
-	ChunkingThreshold int
-	
-	// ChunkSize is the recommended chunk size when chunking
-	ChunkSize int
-	
-	// ChunkOverlap is the recommended overlap between chunks
-	ChunkOverlap int
-}
+type TokenAllocationStrategy = tokens.AllocationStrategy
 
 // TokenConversionRequest represents a request to convert tokens between models
 type TokenConversionRequest struct {
@@ -355,34 +335,15 @@ func (tc *TokenConverter) ConvertTokens(request TokenConversionRequest) (*TokenC
 
 // CalculateTextTokens estimates the number of tokens in a text string for a specific model
 func (tc *TokenConverter) CalculateTextTokens(text string, modelID string) int {
-	// This is a very rough estimation - in a real implementation, 
-	// you would use model-specific tokenizers
-	
-	// For GPT models, a rough approximation is 4 characters per token
-	// This varies significantly by language and content type
-	
+	// Models without a registered profile use the default rate
 	tc.mu.RLock()
 	profile, hasProfile := tc.modelProfiles[modelID]
 	tc.mu.RUnlock()
 	
-	var tokenizationFactor float64 = 0.25 // Default: 4 chars per token
-	
-	// Adjust based on model if we have a profile
-	if hasProfile {
-		switch profile.ID {
-		case "gpt-3.5-turbo", "gpt-4":
-			tokenizationFactor = 0.25 // 4 chars per token
-		case "claude-3-sonnet", "claude-3-opus":
-			tokenizationFactor = 0.22 // ~4.5 chars per token
-		case "llama-2-70b":
-			tokenizationFactor = 0.23 // ~4.3 chars per token
-		case "gemini-pro":
-			tokenizationFactor = 0.24 // ~4.2 chars per token
-		}
+	if !hasProfile {
+		return tokens.CalculateTextTokens(text, "")
 	}
-	
-	// Calculate rough token count
-	return int(float64(len(text)) * tokenizationFactor)
+	return tokens.CalculateTextTokens(text, profile.ID)
 }
 
 // OptimizeTokenAllocation generates an optimized token allocation plan
@@ -421,15 +382,7 @@ func (tc *TokenConverter) OptimizeTokenAllocation(
 			allocatedBudget = maxContextTokens
 		}
 		
-		result[modelID] = TokenAllocationStrategy{
-			ModelID:             modelID,
-			MaxBudget:           allocatedBudget,
-			InputOutputRatio:    0.8, // Default
-			ReservedSystemTokens: 100,
-			ChunkingThreshold:   int(float64(allocatedBudget) * 0.8),
-			ChunkSize:           int(float64(allocatedBudget) * 0.7),
-			ChunkOverlap:        int(float64(allocatedBudget) * 0.07),
-		}
+		result[modelID] = tokens.Allocate(modelID, allocatedBudget)
 		
 		return result, nil
 	}
@@ -483,15 +436,7 @@ func (tc *TokenConverter) OptimizeTokenAllocation(
 		remainingBudget -= allocation
 		
 		// Create allocation strategy
-		result[modelID] = TokenAllocationStrategy{
-			ModelID:             modelID,
-			MaxBudget:           allocation,
-			InputOutputRatio:    0.8, // Default
-			ReservedSystemTokens: 100,
-			ChunkingThreshold:   int(float64(allocation) * 0.8),
-			ChunkSize:           int(float64(allocation) * 0.7),
-			ChunkOverlap:        int(float64(allocation) * 0.07),
-		}
+		result[modelID] = tokens.Allocate(modelID, allocation)
 	}
 	
 	// Second pass: distribute any remaining budget proportionally
@@ -544,13 +489,7 @@ func (tc *TokenConverter) OptimizeTokenAllocation(
 					}
 					
 					// Update allocation
-					strategy := result[modelID]
-					strategy.MaxBudget += additionalAllocation
-					strategy.ChunkingThreshold = int(float64(strategy.MaxBudget) * 0.8)
-					strategy.ChunkSize = int(float64(strategy.MaxBudget) * 0.7)
-					strategy.ChunkOverlap = int(float64(strategy.MaxBudget) * 0.07)
-					
-					result[modelID] = strategy
+					result[modelID] = tokens.Allocate(modelID, currentAllocation+additionalAllocation)
 					
 					remainingBudget -= additionalAllocation
 					